@@ -0,0 +1,198 @@
+// Package bundle creates and opens offline install bundles: a directory, or a
+// zip archive of one, holding the package and SDK of each platform together
+// with the lock file pinning their checksums, so machines without internet
+// access can install from it
+package bundle
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/lock"
+)
+
+// SumsFile lists the SHA-256 of each archive in the bundle in the format of
+// sha256sum, for checking a copy with standard tools
+const SumsFile = "SHA256SUMS"
+
+// Create downloads the package and SDK each configuration would install, and
+// SQL*Plus if it asks for it, into dir and writes the lock file pinning them,
+// and the SumsFile, alongside
+func Create(ctx context.Context, client *http.Client, confs []*config.InstallConfig, dir string) (*lock.Lock, error) {
+	l, err := lock.Download(ctx, client, confs, dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.Save(filepath.Join(dir, lock.FileName)); err != nil {
+		return nil, err
+	}
+	var sums strings.Builder
+	for i, c := range l.Clients {
+		fmt.Fprintf(&sums, "%s  %s\n%s  %s\n", c.PkgSHA256, c.PkgFile, c.SdkSHA256, c.SdkFile)
+		if c.SQLPlusSHA256 != "" {
+			fmt.Fprintf(&sums, "%s  %s\n", c.SQLPlusSHA256, confs[i].SQLPlusFile())
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, SumsFile), []byte(sums.String()), 0644); err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeInstall, "writing bundle checksums")
+	}
+	return l, nil
+}
+
+// Pack writes the files of the bundle in dir to a zip archive at path, under
+// a directory named after dir. The archives are stored rather than compressed
+// again.
+func Pack(dir, path string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "reading bundle directory")
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "creating bundle archive")
+	}
+	zw := zip.NewWriter(out)
+	root := filepath.Base(dir)
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if err := addFile(zw, filepath.Join(dir, e.Name()), root+"/"+e.Name()); err != nil {
+			out.Close()
+			os.Remove(path)
+			return errs.HandleError(err, errs.ErrorTypeInstall, "writing bundle archive")
+		}
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(path)
+		return errs.HandleError(err, errs.ErrorTypeInstall, "writing bundle archive")
+	}
+	if err := out.Close(); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "writing bundle archive")
+	}
+	return nil
+}
+
+// addFile stores the file at src in zw under name
+func addFile(zw *zip.Writer, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := zip.FileInfoHeader(fi)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	hdr.Method = zip.Store
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Open returns the directory of the bundle at path. A bundle archive written by
+// Pack is extracted into scratch first, where files already extracted with the
+// same size are kept, so an interrupted install does not extract it again.
+func Open(path, scratch string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeValidation, "opening bundle")
+	}
+	dir := path
+	if !fi.IsDir() {
+		if dir, err = unpack(path, scratch); err != nil {
+			return "", err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, lock.FileName)); err != nil {
+		return "", errs.HandleError(
+			fmt.Errorf("%s is not an install bundle: it has no %s", path, lock.FileName),
+			errs.ErrorTypeValidation,
+			"opening bundle")
+	}
+	return dir, nil
+}
+
+// unpack extracts the bundle archive into scratch and returns the
+// directory holding its files
+func unpack(archive, scratch string) (string, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return "", errs.HandleError(fmt.Errorf("%s is neither a directory nor a zip archive: %w", archive, err), errs.ErrorTypeValidation, "opening bundle")
+	}
+	defer r.Close()
+
+	roots := make(map[string]bool)
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		// Bundles hold files at the top or in one directory, and nothing else
+		name := strings.TrimPrefix(f.Name, "./")
+		if !filepath.IsLocal(name) || strings.Count(name, "/") > 1 || strings.Contains(name, `\`) {
+			return "", errs.HandleError(fmt.Errorf("unexpected entry %q", f.Name), errs.ErrorTypeValidation, "opening bundle")
+		}
+		roots[path.Dir(name)] = true
+		if err := extractFile(f, filepath.Join(scratch, filepath.FromSlash(name))); err != nil {
+			return "", errs.HandleError(err, errs.ErrorTypeInstall, "extracting bundle")
+		}
+	}
+	if len(roots) != 1 {
+		dirs := make([]string, 0, len(roots))
+		for d := range roots {
+			dirs = append(dirs, d)
+		}
+		sort.Strings(dirs)
+		return "", errs.HandleError(fmt.Errorf("%s holds files in %d directories (%s); expected one", archive, len(dirs), strings.Join(dirs, ", ")), errs.ErrorTypeValidation, "opening bundle")
+	}
+	var root string
+	for d := range roots {
+		root = d
+	}
+	return filepath.Join(scratch, filepath.FromSlash(root)), nil
+}
+
+// extractFile writes the zip entry f to dst, unless a file of its size is
+// already there
+func extractFile(f *zip.File, dst string) error {
+	if fi, err := os.Stat(dst); err == nil && fi.Mode().IsRegular() && uint64(fi.Size()) == f.UncompressedSize64 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,172 @@
+package bundle_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/bundle"
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/lock"
+	"github.com/mghoff/oraicwinconfig/internal/oic"
+	"github.com/mghoff/oraicwinconfig/internal/testsupport"
+)
+
+// offlineInstall returns a configuration installing the bundle in dir into home
+func offlineInstall(t *testing.T, home, dir string) *config.InstallConfig {
+	t.Helper()
+	conf := config.New()
+	if err := conf.SetScope(env.ScopeUser); err != nil {
+		t.Fatal(err)
+	}
+	l, err := lock.Load(filepath.Join(dir, lock.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Apply(conf); err != nil {
+		t.Fatal(err)
+	}
+	conf.Offline = true
+	conf.DownloadsPath = dir
+	conf.InstallPath = filepath.Join(home, "oracle")
+	conf.Existing = config.ExistingOverwrite
+	conf.NoResume = true
+	// The fixture archives hold no real client library to check
+	conf.SkipSteps = []string{oic.StepSmokeTest}
+	return conf
+}
+
+func TestBundle(t *testing.T) {
+	home := testsupport.Sandbox(t)
+	srv := testsupport.NewServer(t, testsupport.Valid)
+	conf := config.New()
+	srv.Configure(conf)
+
+	dir := filepath.Join(home, "oraic-bundle")
+	if _, err := bundle.Create(context.Background(), srv.Client(), []*config.InstallConfig{conf}, dir); err != nil {
+		t.Fatal(err)
+	}
+	sums, err := os.ReadFile(filepath.Join(dir, bundle.SumsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{conf.PkgFile, conf.SdkFile} {
+		if !strings.Contains(string(sums), "  "+file+"\n") {
+			t.Errorf("%s does not list %s:\n%s", bundle.SumsFile, file, sums)
+		}
+	}
+
+	archive := filepath.Join(home, "oraic-bundle.zip")
+	if err := bundle.Pack(dir, archive); err != nil {
+		t.Fatal(err)
+	}
+	opened, err := bundle.Open(archive, filepath.Join(home, "scratch"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, "scratch", "oraic-bundle"); opened != want {
+		t.Fatalf("bundle opened at %s, want %s", opened, want)
+	}
+
+	// The install reads the archives from the bundle and downloads nothing
+	inst := offlineInstall(t, home, opened)
+	if err := oic.Install(context.Background(), inst, testsupport.NewEnv(env.ScopeUser, opened)); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(filepath.Join(inst.InstallPath, testsupport.ClientDir, "sdk")); err != nil || !fi.IsDir() {
+		t.Errorf("client was not extracted: %v", err)
+	}
+	for _, file := range []string{conf.PkgFile, conf.SdkFile} {
+		if n := srv.Requests(file); n != 1 {
+			t.Errorf("%s requested %d times, want only once for the bundle", file, n)
+		}
+	}
+}
+
+func TestBundleTampered(t *testing.T) {
+	home := testsupport.Sandbox(t)
+	srv := testsupport.NewServer(t, testsupport.Valid)
+	conf := config.New()
+	srv.Configure(conf)
+
+	dir := filepath.Join(home, "oraic-bundle")
+	if _, err := bundle.Create(context.Background(), srv.Client(), []*config.InstallConfig{conf}, dir); err != nil {
+		t.Fatal(err)
+	}
+	sdk := filepath.Join(dir, conf.SdkFile)
+	if err := os.WriteFile(sdk, []byte("altered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inst := offlineInstall(t, home, dir)
+	err := oic.Install(context.Background(), inst, testsupport.NewEnv(env.ScopeUser, dir))
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("install of an altered bundle: %v, want a checksum mismatch", err)
+	}
+	if _, err := os.Stat(sdk); err != nil {
+		t.Errorf("the altered archive was removed from the bundle: %v", err)
+	}
+}
+
+func TestBundleSQLPlus(t *testing.T) {
+	home := testsupport.Sandbox(t)
+	srv := testsupport.NewServer(t, testsupport.Valid)
+	conf := config.New()
+	srv.Configure(conf)
+
+	// A bundle without SQL*Plus cannot install it unpinned
+	plain := filepath.Join(home, "plain")
+	if _, err := bundle.Create(context.Background(), srv.Client(), []*config.InstallConfig{conf}, plain); err != nil {
+		t.Fatal(err)
+	}
+	l, err := lock.Load(filepath.Join(plain, lock.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst := config.New()
+	inst.SQLPlus = true
+	if err := l.Apply(inst); err == nil || !strings.Contains(err.Error(), "does not pin SQL*Plus") {
+		t.Errorf("applying a lock without SQL*Plus to an install with it: %v", err)
+	}
+
+	conf.SQLPlus = true
+	dir := filepath.Join(home, "oraic-bundle")
+	if _, err := bundle.Create(context.Background(), srv.Client(), []*config.InstallConfig{conf}, dir); err != nil {
+		t.Fatal(err)
+	}
+	sums, err := os.ReadFile(filepath.Join(dir, bundle.SumsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(sums), "  "+conf.SQLPlusFile()+"\n") {
+		t.Errorf("%s does not list %s:\n%s", bundle.SumsFile, conf.SQLPlusFile(), sums)
+	}
+
+	// The pinned SQL*Plus archive is verified like the package and SDK
+	sqlplus := filepath.Join(dir, conf.SQLPlusFile())
+	if err := os.WriteFile(sqlplus, []byte("altered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inst = offlineInstall(t, home, dir)
+	inst.SQLPlus = true
+	if err := oic.Install(context.Background(), inst, testsupport.NewEnv(env.ScopeUser, dir)); err == nil || !strings.Contains(err.Error(), "checksum mismatch for "+conf.SQLPlusFile()) {
+		t.Fatalf("install of an altered SQL*Plus archive: %v, want a checksum mismatch", err)
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := bundle.Open(dir, filepath.Join(dir, "scratch")); err == nil || !strings.Contains(err.Error(), lock.FileName) {
+		t.Errorf("opening a directory without a lock file: %v", err)
+	}
+	notZip := filepath.Join(dir, "bundle.zip")
+	if err := os.WriteFile(notZip, []byte("not a zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bundle.Open(notZip, filepath.Join(dir, "scratch")); err == nil {
+		t.Error("opening a file that is not a zip archive succeeded")
+	}
+}
@@ -0,0 +1,158 @@
+// Package catalog discovers the client releases available to install, from
+// Oracle's download pages or a signed mirror index, so one can be chosen and
+// pinned before installing
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/mirror"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// maxPageSize bounds the download pages read
+const maxPageSize = 8 << 20
+
+// Release is a client version and the platforms it is available for
+type Release struct {
+	Version   string   `json:"version"`   // As given to --version, e.g. 19.25 or 23.7.0.25.01
+	Platforms []string `json:"platforms"` // os/arch of each platform it has a client for, sorted
+}
+
+// Oracle lists the releases linked from Oracle's download page of each os/arch
+// platform
+func Oracle(ctx context.Context, client *http.Client, platforms []string) ([]Release, error) {
+	found := make(map[string][]string)
+	for _, platform := range platforms {
+		goos, goarch, _ := strings.Cut(platform, "/")
+		p, ok := config.LookupPlatform(goos, goarch)
+		if !ok {
+			return nil, errs.HandleError(fmt.Errorf("unsupported platform: %s", platform), errs.ErrorTypeValidation, "listing releases")
+		}
+		if p.Page == "" {
+			return nil, errs.HandleError(fmt.Errorf("Oracle has no download page listing the releases for %s; list those of a mirror with --mirror-index", platform), errs.ErrorTypeValidation, "listing releases")
+		}
+		page, err := get(ctx, client, p.Page)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range PageVersions(page, p.Tag) {
+			found[v] = append(found[v], platform)
+		}
+	}
+	return releases(found), nil
+}
+
+// FromIndex lists the releases of a signed mirror index, under the versions
+// it gives them
+func FromIndex(idx *mirror.Index) []Release {
+	found := make(map[string][]string)
+	for _, r := range idx.Releases {
+		for _, c := range r.Clients {
+			found[r.Version] = append(found[r.Version], c.OS+"/"+c.Arch)
+		}
+	}
+	return releases(found)
+}
+
+// Filter returns the releases with a client for any of the os/arch platforms,
+// listing only those platforms
+func Filter(releases []Release, platforms []string) []Release {
+	var out []Release
+	for _, r := range releases {
+		var matched []string
+		for _, p := range r.Platforms {
+			if slices.Contains(platforms, p) {
+				matched = append(matched, p)
+			}
+		}
+		if len(matched) > 0 {
+			out = append(out, Release{Version: r.Version, Platforms: matched})
+		}
+	}
+	return out
+}
+
+// Report writes the releases as a table of versions and their platforms
+func Report(w io.Writer, releases []Release) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tPLATFORMS")
+	for _, r := range releases {
+		fmt.Fprintf(tw, "%s\t%s\n", r.Version, strings.Join(r.Platforms, ", "))
+	}
+	tw.Flush()
+}
+
+// PageVersions returns the version of each package a download page links to
+// for the platform named tag in the archive names, newest first
+func PageVersions(page []byte, tag string) []string {
+	pkg := regexp.MustCompile(`instantclient-basiclite-` + regexp.QuoteMeta(tag) + `-([0-9]{1,2}(?:\.[0-9]{1,2}){4}(?:dbru)?)\.(?:zip|dmg)`)
+	seen := make(map[string]bool)
+	var versions []string
+	for _, m := range pkg.FindAllSubmatch(page, -1) {
+		v := versionArg(string(m[1]))
+		if !seen[v] {
+			seen[v] = true
+			versions = append(versions, v)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return utils.NewerVersion(versions[i], versions[j]) })
+	return versions
+}
+
+// versionArg returns the shortest --version naming the release with the full
+// version in its archive names: 19.25 for 19.25.0.0.0dbru, and the full
+// version otherwise
+func versionArg(full string) string {
+	major, rest, _ := strings.Cut(full, ".")
+	minor, rest, _ := strings.Cut(rest, ".")
+	if rest == "0.0.0dbru" && (len(major) < 2 || major < "23") {
+		return major + "." + minor
+	}
+	return full
+}
+
+// releases sorts the platforms of each version and the versions, newest first
+func releases(found map[string][]string) []Release {
+	list := make([]Release, 0, len(found))
+	for v, platforms := range found {
+		sort.Strings(platforms)
+		list = append(list, Release{Version: v, Platforms: slices.Compact(platforms)})
+	}
+	sort.Slice(list, func(i, j int) bool { return utils.NewerVersion(list[i].Version, list[j].Version) })
+	return list
+}
+
+// get downloads a download page into memory
+func get(ctx context.Context, client *http.Client, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeDownload, "creating HTTP request")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeDownload, "downloading release list")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errs.HandleError(fmt.Errorf("GET %s: %s", u, resp.Status), errs.ErrorTypeDownload, "downloading release list")
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize+1))
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeDownload, "downloading release list")
+	}
+	if len(data) > maxPageSize {
+		return nil, errs.HandleError(fmt.Errorf("%s is larger than %d bytes", u, maxPageSize), errs.ErrorTypeDownload, "downloading release list")
+	}
+	return data, nil
+}
@@ -0,0 +1,59 @@
+package catalog_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/catalog"
+	"github.com/mghoff/oraicwinconfig/internal/lock"
+	"github.com/mghoff/oraicwinconfig/internal/mirror"
+)
+
+// page mimics the links of Oracle's Windows x64 download page
+const page = `
+<a href="https://download.oracle.com/otn_software/nt/instantclient/2370000/instantclient-basic-windows.x64-23.7.0.25.01.zip">Basic</a>
+<a href="https://download.oracle.com/otn_software/nt/instantclient/2370000/instantclient-basiclite-windows.x64-23.7.0.25.01.zip">Basic Light</a>
+<a href="https://download.oracle.com/otn_software/nt/instantclient/1925000/instantclient-basiclite-windows.x64-19.25.0.0.0dbru.zip">Basic Light</a>
+<a href="https://download.oracle.com/otn_software/nt/instantclient/1925000/instantclient-sdk-windows.x64-19.25.0.0.0dbru.zip">SDK</a>
+<a href="https://download.oracle.com/otn_software/nt/instantclient/213000/instantclient-basiclite-windows.x64-21.3.0.0.0.zip">Basic Light</a>
+<a href="https://download.oracle.com/otn_software/nt/instantclient/instantclient-basiclite-windows.zip">Latest</a>
+<a href="https://download.oracle.com/otn_software/nt/instantclient/1925000/instantclient-basiclite-nt-19.25.0.0.0dbru.zip">32-bit</a>
+`
+
+func TestPageVersions(t *testing.T) {
+	got := catalog.PageVersions([]byte(page), "windows.x64")
+	want := []string{"23.7.0.25.01", "21.3.0.0.0", "19.25"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PageVersions = %v, want %v", got, want)
+	}
+	if got := catalog.PageVersions([]byte(page), "nt"); !reflect.DeepEqual(got, []string{"19.25"}) {
+		t.Errorf("PageVersions of the 32-bit client = %v", got)
+	}
+}
+
+func TestFromIndex(t *testing.T) {
+	idx := &mirror.Index{Releases: []mirror.Release{
+		{Version: "19.25", Clients: []lock.Client{{OS: "windows", Arch: "amd64"}}},
+		{Version: "23.7", Clients: []lock.Client{{OS: "windows", Arch: "amd64"}, {OS: "windows", Arch: "386"}}},
+	}}
+	releases := catalog.FromIndex(idx)
+	want := []catalog.Release{
+		{Version: "23.7", Platforms: []string{"windows/386", "windows/amd64"}},
+		{Version: "19.25", Platforms: []string{"windows/amd64"}},
+	}
+	if !reflect.DeepEqual(releases, want) {
+		t.Fatalf("FromIndex = %v, want %v", releases, want)
+	}
+
+	filtered := catalog.Filter(releases, []string{"windows/386"})
+	if !reflect.DeepEqual(filtered, []catalog.Release{{Version: "23.7", Platforms: []string{"windows/386"}}}) {
+		t.Errorf("Filter = %v", filtered)
+	}
+
+	var out strings.Builder
+	catalog.Report(&out, releases)
+	if !strings.Contains(out.String(), "23.7     windows/386, windows/amd64\n") {
+		t.Errorf("report:\n%s", out.String())
+	}
+}
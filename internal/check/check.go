@@ -0,0 +1,169 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// Status is the outcome of a single check
+type Status int
+
+const (
+	StatusPass Status = iota
+	StatusWarn
+	StatusFail
+)
+
+// String returns the label used when printing a status
+func (s Status) String() string {
+	switch s {
+	case StatusPass:
+		return "PASS"
+	case StatusWarn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+// Result holds the outcome of a single check
+type Result struct {
+	Name   string
+	Status Status
+	Detail string // What was found
+	Hint   string // How to fix it, for warnings and failures
+}
+
+// Pass returns a passing result
+func Pass(detail string) Result {
+	return Result{Status: StatusPass, Detail: detail}
+}
+
+// Warn returns a warning result with a remediation hint
+func Warn(detail, hint string) Result {
+	return Result{Status: StatusWarn, Detail: detail, Hint: hint}
+}
+
+// Fail returns a failing result with a remediation hint
+func Fail(detail, hint string) Result {
+	return Result{Status: StatusFail, Detail: detail, Hint: hint}
+}
+
+// Check is a named check
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) Result
+}
+
+// Run executes the checks concurrently and returns their results in the order given.
+// The checks are bounded by timeout; a check still running when it expires fails.
+func Run(ctx context.Context, checks []Check, timeout time.Duration) []Result {
+	ctx, cancel := utils.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			done := make(chan Result, 1)
+			go func() { done <- c.Run(ctx) }()
+			select {
+			case r := <-done:
+				results[i] = r
+			case <-ctx.Done():
+				results[i] = Fail(fmt.Sprintf("did not complete: %v", ctx.Err()), "retry with a longer timeout")
+			}
+			results[i].Name = c.Name
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+// Report writes the results as a table followed by the hints for anything
+// that did not pass, and returns the worst status seen
+func Report(w io.Writer, results []Result) Status {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		fmt.Fprintf(tw, "  [%s]\t%s\t%s\n", r.Status, r.Name, r.Detail)
+	}
+	tw.Flush()
+
+	for _, r := range results {
+		if r.Status != StatusPass && r.Hint != "" {
+			fmt.Fprintf(w, "  -> %s: %s\n", r.Name, r.Hint)
+		}
+	}
+	return Worst(results)
+}
+
+// Worst returns the most severe status among the results
+func Worst(results []Result) Status {
+	worst := StatusPass
+	for _, r := range results {
+		if r.Status > worst {
+			worst = r.Status
+		}
+	}
+	return worst
+}
+
+// Health is a one-line summary of the results for monitoring tools
+type Health struct {
+	Status string   `json:"status"` // healthy, degraded or broken
+	Host   string   `json:"host,omitempty"`
+	Time   string   `json:"time"`
+	Failed []string `json:"failed,omitempty"`
+	Warned []string `json:"warned,omitempty"`
+}
+
+// Summarize condenses the results into a Health summary
+func Summarize(results []Result) Health {
+	h := Health{Time: time.Now().UTC().Format(time.RFC3339)}
+	h.Host, _ = os.Hostname()
+	for _, r := range results {
+		switch r.Status {
+		case StatusFail:
+			h.Failed = append(h.Failed, r.Name)
+		case StatusWarn:
+			h.Warned = append(h.Warned, r.Name)
+		}
+	}
+	switch Worst(results) {
+	case StatusPass:
+		h.Status = "healthy"
+	case StatusWarn:
+		h.Status = "degraded"
+	default:
+		h.Status = "broken"
+	}
+	return h
+}
+
+// Err returns an error naming the failed checks, or nil if none failed
+func Err(results []Result, operation string) error {
+	var failed []string
+	for _, r := range results {
+		if r.Status == StatusFail {
+			failed = append(failed, r.Name)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return errs.HandleError(
+		fmt.Errorf("failed checks: %s", strings.Join(failed, ", ")),
+		errs.ErrorTypeValidation,
+		operation)
+}
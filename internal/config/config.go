@@ -1,9 +1,25 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/mghoff/oraicwinconfig/internal/env"
 	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/notify"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
 )
 
 const (
@@ -13,25 +29,786 @@ const (
 	baseDownloadURL    = "https://download.oracle.com/otn_software/nt/instantclient/"
 )
 
+// DefaultODPNetFeed is the NuGet package content URL the managed ODP.NET
+// driver is downloaded from
+const DefaultODPNetFeed = "https://api.nuget.org/v3-flatcontainer/"
+
+// Platform holds the download details of a supported OS and architecture
+type Platform struct {
+	BaseURL string // Base URL for downloading the files
+	PkgFile string // Name of the package file
+	SdkFile string // Name of the SDK file
+	Tag     string // Name of the platform in Oracle's versioned archive names
+	Page    string // Oracle's download page listing the platform's releases; empty if it has none
+}
+
+// downloadPages is where Oracle lists the Instant Client releases of each platform
+const downloadPages = "https://www.oracle.com/database/technologies/instant-client/"
+
+// platforms lists the supported GOOS/GOARCH combinations
+var platforms = map[string]Platform{
+	"windows/amd64": {BaseURL: baseDownloadURL, PkgFile: pkgFileName, SdkFile: sdkFileName, Tag: "windows.x64", Page: downloadPages + "winx64-64-downloads.html"},
+	"windows/386": {
+		BaseURL: baseDownloadURL,
+		PkgFile: "instantclient-basiclite-nt.zip",
+		SdkFile: "instantclient-sdk-nt.zip",
+		Tag:     "nt",
+		Page:    downloadPages + "microsoft-windows-32-downloads.html",
+	},
+	"windows/arm64": {
+		BaseURL: baseDownloadURL,
+		PkgFile: "instantclient-basiclite-windows-arm64.zip",
+		SdkFile: "instantclient-sdk-windows-arm64.zip",
+		Tag:     "windows.arm64",
+	},
+	"linux/amd64": {
+		BaseURL: "https://download.oracle.com/otn_software/linux/instantclient/",
+		PkgFile: "instantclient-basiclite-linuxx64.zip",
+		SdkFile: "instantclient-sdk-linuxx64.zip",
+		Tag:     "linux.x64",
+		Page:    downloadPages + "linux-x86-64-downloads.html",
+	},
+	"linux/arm64": {
+		BaseURL: "https://download.oracle.com/otn_software/linux/instantclient/",
+		PkgFile: "instantclient-basiclite-linux-arm64.zip",
+		SdkFile: "instantclient-sdk-linux-arm64.zip",
+		Tag:     "linux.arm64",
+		Page:    downloadPages + "linux-arm-aarch64-downloads.html",
+	},
+	"darwin/arm64": {
+		BaseURL: "https://download.oracle.com/otn_software/mac/instantclient/",
+		PkgFile: "instantclient-basiclite-macos-arm64.dmg",
+		SdkFile: "instantclient-sdk-macos-arm64.dmg",
+		Tag:     "macos.arm64",
+		Page:    downloadPages + "macos-arm64-downloads.html",
+	},
+	"darwin/amd64": {
+		BaseURL: "https://download.oracle.com/otn_software/mac/instantclient/1916000/",
+		PkgFile: "instantclient-basiclite-macos.x64-19.16.0.0.0dbru.dmg",
+		SdkFile: "instantclient-sdk-macos.x64-19.16.0.0.0dbru.dmg",
+		Tag:     "macos.x64",
+		Page:    downloadPages + "macos-intel-x86-downloads.html",
+	},
+}
+
+// LookupPlatform returns the download details for the given OS and architecture
+func LookupPlatform(goos, goarch string) (Platform, bool) {
+	p, ok := platforms[goos+"/"+goarch]
+	return p, ok
+}
+
+// PlatformNames returns the os/arch of every supported platform, sorted
+func PlatformNames() []string {
+	names := make([]string, 0, len(platforms))
+	for name := range platforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ArchiveNames returns the file names of the package and SDK archives of
+// every supported platform, sorted
+func ArchiveNames() []string {
+	var names []string
+	for _, p := range platforms {
+		names = append(names, p.PkgFile, p.SdkFile)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultInstallPath returns the default installation directory for the given OS and scope.
+// Machine-wide installs go to C:/Program Files/Oracle on Windows and /opt/oracle elsewhere;
+// per-user installs go to OraClient in the user profile on Windows, ~/lib on macOS and ~/oracle on Linux.
+func DefaultInstallPath(goos string, scope env.Scope) string {
+	if scope == env.ScopeMachine {
+		if goos == "windows" {
+			return "C:/Program Files/Oracle"
+		}
+		return "/opt/oracle"
+	}
+	dir := "oracle"
+	switch goos {
+	case "windows":
+		dir = "OraClient"
+	case "darwin":
+		dir = "lib"
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		if goos == "windows" {
+			return defaultInstallPath
+		}
+		return dir
+	}
+	return filepath.Join(home, dir)
+}
+
+// DefaultScope returns the default scope: machine when running as root on Unix-like systems,
+// user otherwise
+func DefaultScope(goos string) env.Scope {
+	if goos != "windows" && os.Geteuid() == 0 {
+		return env.ScopeMachine
+	}
+	return env.ScopeUser
+}
+
+// Default HTTP client settings used for downloads
+const (
+	defaultDialTimeout           = 30 * time.Second
+	defaultKeepAlive             = 30 * time.Second
+	defaultTLSHandshakeTimeout   = 15 * time.Second
+	defaultResponseHeaderTimeout = 30 * time.Second
+	defaultIdleConnTimeout       = 90 * time.Second
+	defaultRequestTimeout        = 30 * time.Minute
+	defaultMaxIdleConns          = 4
+	defaultBusyRetries           = 5
+	defaultMaxRetryAfter         = 2 * time.Minute
+	defaultDownloadAttempts      = 4
+	defaultRetryBackoff          = 2 * time.Second
+	defaultMaxRetryBackoff       = 30 * time.Second
+)
+
+// Address families downloads may connect over
+const (
+	IPVersionAuto = "auto" // Either, preferring IPv6 with a fast fallback to IPv4
+	IPVersion4    = "4"
+	IPVersion6    = "6"
+)
+
+// Proxy settings other than a proxy URL; an empty setting uses the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+const (
+	ProxyDirect = "direct" // Connect directly, ignoring any proxy variables
+	ProxySystem = "system" // The system settings: WinINet, including PAC files, then WinHTTP on Windows, and the network settings on macOS
+)
+
+// Proxy authentication schemes using the Windows logon; by default a proxy is
+// sent the credentials in its URL, if any, with Basic authentication
+const (
+	ProxyAuthNegotiate = "negotiate" // Kerberos where the domain supports it, otherwise NTLM
+	ProxyAuthNTLM      = "ntlm"
+)
+
+// Ways of making the client's environment available
+const (
+	EnvModeGlobal  = "global"  // Persistent user or machine environment variables
+	EnvModeWrapper = "wrapper" // Launcher scripts configuring only the processes they start
+	EnvModeBoth    = "both"    // Both of the above
+)
+
+// What to do with an existing installation
+const (
+	ExistingPrompt    = ""          // Ask the user
+	ExistingOverwrite = "overwrite" // Uninstall it and install in its place
+	ExistingKeep      = "keep"      // Leave it in place and install alongside it
+)
+
+// What to do when the extracted Windows libraries are not validly signed by Oracle
+const (
+	SignaturesFail = "fail" // Stop the install
+	SignaturesWarn = "warn" // Print a warning and continue
+	SignaturesOff  = "off"  // Do not check signatures
+)
+
+// Instant Client package flavors
+const (
+	FlavorBasicLite = "basiclite" // English-only messages and a few character sets; the smaller download
+	FlavorBasic     = "basic"     // All languages and character sets, for locales Basic Light lacks
+)
+
+// Which ODP.NET drivers to set up with the client
+const (
+	ODPNetManaged   = "managed"   // Stage the managed driver assemblies
+	ODPNetUnmanaged = "unmanaged" // Point the unmanaged driver's registry settings at the client
+	ODPNetBoth      = "both"      // Both of the above
+)
+
+// Default per-phase timeouts
+const (
+	defaultDownloadTimeout    = 45 * time.Minute
+	defaultExtractTimeout     = 10 * time.Minute
+	defaultEnvironmentTimeout = 2 * time.Minute
+	defaultPreflightTimeout   = 20 * time.Second
+	defaultHookTimeout        = 10 * time.Minute
+)
+
+// defaultKeepVersions is the number of previous client versions kept for rolling back
+const defaultKeepVersions = 2
+
+// defaultWalletExpiry is how long before a wallet certificate expires it is warned about
+const defaultWalletExpiry = 30 * 24 * time.Hour
+
+// Plausible download sizes. Packages are tens of megabytes and SDKs and
+// SQL*Plus one or two, while error pages served in their place are far smaller.
+const (
+	defaultPkgMinSize     = 1 << 20
+	defaultPkgMaxSize     = 1 << 30
+	defaultSdkMinSize     = 256 << 10
+	defaultSdkMaxSize     = 256 << 20
+	defaultSQLPlusMinSize = 256 << 10
+	defaultSQLPlusMaxSize = 64 << 20
+)
+
+// TimeoutConfig holds the time limits applied to each phase of the run.
+// A zero duration disables the corresponding limit.
+type TimeoutConfig struct {
+	Overall     time.Duration // Limit for the whole run, including user prompts
+	Download    time.Duration // Limit for downloading the package and SDK
+	Extract     time.Duration // Limit for extracting the downloaded archives
+	Environment time.Duration // Limit for configuring the user environment variables
+	Preflight   time.Duration // Limit for the concurrent preflight checks
+	Hook        time.Duration // Limit for each hook command
+	Command     time.Duration // Limit for each PowerShell or other external command, within its phase
+}
+
+// DefaultTimeoutConfig returns the default per-phase timeouts
+func DefaultTimeoutConfig() TimeoutConfig {
+	return TimeoutConfig{
+		Download:    defaultDownloadTimeout,
+		Extract:     defaultExtractTimeout,
+		Environment: defaultEnvironmentTimeout,
+		Preflight:   defaultPreflightTimeout,
+		Hook:        defaultHookTimeout,
+		Command:     pwsh.DefaultTimeout,
+	}
+}
+
+// Validate checks that none of the timeouts are negative
+func (t TimeoutConfig) Validate() error {
+	for name, d := range map[string]time.Duration{
+		"overall timeout":     t.Overall,
+		"download timeout":    t.Download,
+		"extract timeout":     t.Extract,
+		"environment timeout": t.Environment,
+		"preflight timeout":   t.Preflight,
+		"hook timeout":        t.Hook,
+		"command timeout":     t.Command,
+	} {
+		if d < 0 {
+			return errs.HandleError(
+				fmt.Errorf("%s cannot be negative: %s", name, d),
+				errs.ErrorTypeValidation,
+				"config validation")
+		}
+	}
+	return nil
+}
+
+// HTTPConfig holds the timeout and keep-alive settings of the download client.
+// A zero duration disables the corresponding limit.
+type HTTPConfig struct {
+	DialTimeout           time.Duration // Maximum time to establish a TCP connection
+	KeepAlive             time.Duration // Interval between TCP keep-alive probes
+	TLSHandshakeTimeout   time.Duration // Maximum time to complete the TLS handshake
+	ResponseHeaderTimeout time.Duration // Maximum time to wait for response headers after sending the request
+	IdleConnTimeout       time.Duration // Maximum time an idle keep-alive connection is kept open
+	RequestTimeout        time.Duration // Maximum time for a whole request, including reading the body
+	MaxIdleConns          int           // Maximum number of idle keep-alive connections
+	BusyRetries           int           // Times to retry a request the server answers with 429 Too Many Requests or 503 Service Unavailable
+	MaxRetryAfter         time.Duration // Longest Retry-After wait to honor; a busy server asking for longer fails the request
+	IPVersion             string        // Address family to connect over: IPv4, IPv6 or auto
+	Proxy                 string        // Proxy URL, direct, system, or empty for the proxy environment variables
+	ProxyAuth             string        // Integrated authentication to the proxy: negotiate, ntlm, or empty for none
+	TLSPin                TLSPinConfig  // Certificate pinning of the download host
+	CACerts               []string      // PEM files of root certificates to trust besides the system's, such as a TLS-inspecting proxy's
+	Insecure              bool          // Skip certificate verification, leaving downloads open to interception
+	Mirrors               map[string]MirrorConfig // Settings of authenticated download mirrors, by host
+	Retry                 RetryPolicy   // When to download an archive again after a transient failure
+}
+
+// RetryPolicy says how often, and after how long, a download is started over
+// when it fails in a way that may not happen again, such as a connection reset
+// halfway through or a 502 from a proxy
+type RetryPolicy struct {
+	Attempts   int           // Times to try a download in all; 1 or less never retries
+	Backoff    time.Duration // Wait before the first retry, doubled before each further one
+	MaxBackoff time.Duration // Longest wait between attempts; zero for no limit
+	Statuses   []int         // HTTP status codes worth retrying
+}
+
+// DefaultRetryPolicy returns the default download retry policy. 429 and 503
+// are left to the busy server retries, which honor Retry-After.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:   defaultDownloadAttempts,
+		Backoff:    defaultRetryBackoff,
+		MaxBackoff: defaultMaxRetryBackoff,
+		Statuses: []int{
+			http.StatusRequestTimeout,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// RetryStatus reports whether a response with status code is worth retrying
+func (r RetryPolicy) RetryStatus(code int) bool {
+	return slices.Contains(r.Statuses, code)
+}
+
+// TLSPinConfig pins the certificates the download host may present, so a
+// TLS-intercepting proxy or a hijacked DNS name fails the download instead of
+// serving it through a middlebox. Pinning only restricts the chains the system
+// already trusts; it never adds trust.
+type TLSPinConfig struct {
+	SHA256 []string // Fingerprints of a certificate or public key one of which the chain must contain
+	CAFile string   // PEM file of the CA certificates the chain must lead to
+	Hosts  []string // Hosts the pins apply to; empty applies them to every host
+}
+
+// Enabled reports whether any pin is configured
+func (p TLSPinConfig) Enabled() bool {
+	return len(p.SHA256) > 0 || p.CAFile != ""
+}
+
+// DefaultHTTPConfig returns the default download client settings
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		DialTimeout:           defaultDialTimeout,
+		KeepAlive:             defaultKeepAlive,
+		TLSHandshakeTimeout:   defaultTLSHandshakeTimeout,
+		ResponseHeaderTimeout: defaultResponseHeaderTimeout,
+		IdleConnTimeout:       defaultIdleConnTimeout,
+		RequestTimeout:        defaultRequestTimeout,
+		MaxIdleConns:          defaultMaxIdleConns,
+		BusyRetries:           defaultBusyRetries,
+		MaxRetryAfter:         defaultMaxRetryAfter,
+		IPVersion:             IPVersionAuto,
+		Retry:                 DefaultRetryPolicy(),
+	}
+}
+
+// Validate checks that none of the HTTP settings are negative
+func (h HTTPConfig) Validate() error {
+	durations := map[string]time.Duration{
+		"dial timeout":            h.DialTimeout,
+		"keep-alive":              h.KeepAlive,
+		"TLS handshake timeout":   h.TLSHandshakeTimeout,
+		"response header timeout": h.ResponseHeaderTimeout,
+		"idle connection timeout": h.IdleConnTimeout,
+		"request timeout":         h.RequestTimeout,
+		"maximum Retry-After":     h.MaxRetryAfter,
+		"retry backoff":           h.Retry.Backoff,
+		"maximum retry backoff":   h.Retry.MaxBackoff,
+	}
+	for name, d := range durations {
+		if d < 0 {
+			return errs.HandleError(
+				fmt.Errorf("%s cannot be negative: %s", name, d),
+				errs.ErrorTypeValidation,
+				"config validation")
+		}
+	}
+	if h.MaxIdleConns < 0 {
+		return errs.HandleError(
+			fmt.Errorf("max idle connections cannot be negative: %d", h.MaxIdleConns),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	switch h.IPVersion {
+	case IPVersionAuto, IPVersion4, IPVersion6:
+	default:
+		return errs.HandleError(
+			fmt.Errorf("invalid IP version %q: must be 4, 6 or auto", h.IPVersion),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	if h.BusyRetries < 0 {
+		return errs.HandleError(
+			fmt.Errorf("busy retries cannot be negative: %d", h.BusyRetries),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	switch h.Proxy {
+	case "", ProxyDirect, ProxySystem:
+	default:
+		if _, err := ParseProxy(h.Proxy); err != nil {
+			return err
+		}
+	}
+	switch h.ProxyAuth {
+	case "", ProxyAuthNegotiate, ProxyAuthNTLM:
+	default:
+		return errs.HandleError(
+			fmt.Errorf("invalid proxy authentication %q: must be negotiate or ntlm", h.ProxyAuth),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	for _, code := range h.Retry.Statuses {
+		if code < 100 || code > 599 {
+			return errs.HandleError(
+				fmt.Errorf("invalid HTTP status %d to retry: must be from 100 to 599", code),
+				errs.ErrorTypeValidation,
+				"config validation")
+		}
+	}
+	for _, pin := range h.TLSPin.SHA256 {
+		if _, err := ParseFingerprint(pin); err != nil {
+			return err
+		}
+	}
+	for _, path := range h.CACerts {
+		if _, err := os.Stat(path); err != nil {
+			return errs.HandleError(err, errs.ErrorTypeValidation, "config validation")
+		}
+	}
+	if h.TLSPin.CAFile != "" {
+		if _, err := os.Stat(h.TLSPin.CAFile); err != nil {
+			return errs.HandleError(err, errs.ErrorTypeValidation, "config validation")
+		}
+	}
+	return validateMirrors(h.Mirrors)
+}
+
+// ParseProxy parses a proxy URL. A bare host:port is taken as an HTTP proxy,
+// as in the proxy environment variables.
+func ParseProxy(s string) (*url.URL, error) {
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	// The URL may hold credentials, so errors do not repeat it
+	u, err := url.Parse(s)
+	var urlErr *url.Error
+	switch {
+	case errors.As(err, &urlErr):
+		err = urlErr.Err
+	case err == nil && u.Hostname() == "":
+		err = fmt.Errorf("no host")
+	case err == nil && u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5":
+		err = fmt.Errorf("unsupported scheme %s: must be http, https or socks5", u.Scheme)
+	}
+	if err != nil {
+		return nil, errs.HandleError(fmt.Errorf("invalid proxy: %v", err), errs.ErrorTypeValidation, "config validation")
+	}
+	return u, nil
+}
+
+// ParseFingerprint normalizes a SHA-256 fingerprint to lowercase hex. It
+// accepts hex with or without colons, as printed by openssl x509 -fingerprint
+// or certutil, and sha256/<base64> as used by HTTP public key pinning.
+func ParseFingerprint(s string) (string, error) {
+	var sum []byte
+	var err error
+	if b64, ok := strings.CutPrefix(s, "sha256/"); ok {
+		sum, err = base64.StdEncoding.DecodeString(b64)
+	} else {
+		sum, err = hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	}
+	if err != nil || len(sum) != sha256.Size {
+		return "", errs.HandleError(
+			fmt.Errorf("invalid SHA-256 fingerprint %q: must be 64 hex digits or sha256/<base64>", s),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	return hex.EncodeToString(sum), nil
+}
+
 // InstallConfig holds all installation configurations
 type InstallConfig struct {
 	DownloadsPath string // Path where downloaded files will be stored
 	InstallPath   string // Path where Oracle Instant Client will be installed
+	InstallPathGiven bool // InstallPath was chosen explicitly, so an existing installation does not move the new one to its base directory
 	PkgFile       string // Name of the package file to be downloaded
 	SdkFile       string // Name of the SDK file to be downloaded
 	BaseURL       string // Base URL for downloading the files
+	Fallbacks     []string // Further base URLs tried in order when a download from BaseURL fails
+	Source        string   // Base URL the package was downloaded from, once it has been
+	Version       string   // Client release to install, e.g. 19.25; empty for the latest
+	Flavor        string   // Package flavor, basic or basiclite, checked once extracted; empty installs Basic Light unchecked
+	SQLPlus       bool     // Also install the SQL*Plus package into the client directory
 	Extant				bool   // Indicates if an existing installation was found
+	OS            string // Target operating system, as GOOS
+	Arch          string // Target architecture, as GOARCH
+	HostArch      string // Native architecture of the machine, as GOARCH
+	AllowEmulation bool  // Allow installing an x64 client on an ARM64 Windows host
+	WithX86       bool   // Also install the 32-bit client alongside the 64-bit one
+	Secondary     bool   // Installed alongside a primary client, which keeps TNS_ADMIN
+	Scope         env.Scope // Whether the user's or the machine's environment is configured
+	EnvMode       string    // Whether to write persistent variables, launcher scripts, or both
+	HTTP          HTTPConfig // Timeout and keep-alive settings for the download client
+	Timeouts      TimeoutConfig // Time limits for each phase of the run
+	SkipPreflight bool          // Skip the preflight checks before installing
+	Hooks         HookConfig    // Commands run at points of the install
+	Notify        NotifyConfig  // Webhook reporting the outcome of the run
+	KeepVersions  int           // Previous client versions kept in the install path; negative keeps all
+	WalletExpiry  time.Duration // How long before a wallet certificate expires doctor and status warn about it
+	Existing      string        // What to do with an existing installation, instead of asking
+	NoResume      bool          // Start over instead of resuming an interrupted install
+	SkipSteps     []string      // Install steps not to run, by name
+	PluginsDir    string        // Directory of plugins adding install steps; empty for the one next to the manifest
+	ODPNet        ODPNetConfig  // ODP.NET driver set up with the client
+	NoPlugins     bool          // Do not run the steps of plugins
+	TNSAdmin      string        // Shared TNS_ADMIN directory used instead of the client's network/admin
+	ExtraEnv      map[string]string // Extra variables set with the client's, by name; values may reference install facts
+	ExportSession bool          // Also export the variables into this process and print them for the invoking shell
+	Signatures    string        // Whether unsigned or invalidly signed Windows libraries fail the install, warn, or are not checked
+	Pins          PinConfig     // Checksums the downloads must match, from a lock file or signed mirror index
+	LockFile      string        // Lock file pinning the artifacts to install; empty installs the latest release
+	Offline       bool          // Install the archives of an offline bundle, already in DownloadsPath, instead of downloading them
+	MirrorIndex   string        // URL of a signed mirror index to install the latest release it lists from
+	MirrorKey     string        // Public key file verifying the mirror index signature
+	PkgSize       SizeLimits    // Plausible size of the package download
+	SdkSize       SizeLimits    // Plausible size of the SDK download
+	SQLPlusSize   SizeLimits    // Plausible size of the SQL*Plus download
+}
+
+// SizeLimits bounds the size of a download, so an error page or truncated
+// file is reported as such; a zero bound is not checked
+type SizeLimits struct {
+	Min int64 // Smallest plausible size in bytes
+	Max int64 // Largest plausible size in bytes
+}
+
+// Validate checks the bounds are non-negative and in order
+func (l SizeLimits) Validate(name string) error {
+	if l.Min < 0 || l.Max < 0 || l.Max > 0 && l.Min > l.Max {
+		return errs.HandleError(
+			fmt.Errorf("invalid %s size limits %d-%d: must be non-negative with the minimum no larger than the maximum", name, l.Min, l.Max),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	return nil
+}
+
+// PinConfig holds what the downloads of a locked install must match; empty
+// values are not checked
+type PinConfig struct {
+	PkgSHA256     string // SHA-256 of the package
+	SdkSHA256     string // SHA-256 of the SDK
+	SQLPlusSHA256 string // SHA-256 of the SQL*Plus package
+	ClientDir     string // Versioned directory the package extracts to
+	Source        string // What pinned them, e.g. "the lock file", for messages
+}
+
+// ODPNetConfig holds the ODP.NET drivers set up with the client
+type ODPNetConfig struct {
+	Driver   string   // managed, unmanaged or both; empty for none
+	Version  string   // Version of the managed driver package to stage; empty for the latest
+	Feed     string   // NuGet v3 package content URL the managed driver is downloaded from
+	Versions []string // ODP.NET versions to register the unmanaged driver for, besides those already registered
 }
 
-// NewDefaultConfig creates a new configuration with default values and returns a pointer to it
+// Managed reports whether the managed driver is staged
+func (o ODPNetConfig) Managed() bool {
+	return o.Driver == ODPNetManaged || o.Driver == ODPNetBoth
+}
+
+// Unmanaged reports whether the unmanaged driver is registered
+func (o ODPNetConfig) Unmanaged() bool {
+	return o.Driver == ODPNetUnmanaged || o.Driver == ODPNetBoth
+}
+
+// NotifyConfig holds the webhook the outcome of a run is posted to
+type NotifyConfig struct {
+	URL    string // Webhook URL; empty disables notification
+	Format string // Payload format: json, teams or slack
+}
+
+// HookConfig holds the commands run at points of the install. A command is a
+// PowerShell (.ps1) or shell (.sh) script, or any other executable, optionally
+// followed by arguments.
+type HookConfig struct {
+	PostInstall  []string // Run after a successful install
+	PreUninstall []string // Run before an existing installation is removed
+	PreOverwrite []string // Run before an existing installation is replaced by a new one
+	PreExtract   []string // Run after downloading and before extracting, e.g. to scan the archives; a failure always stops the install
+	Force        bool     // Go ahead with the removal even if a pre-uninstall or pre-overwrite hook fails
+}
+
+// NewDefaultConfig creates a new configuration with default values for the host platform
+// and returns a pointer to it
 func New() *InstallConfig {
-	return &InstallConfig{
-		InstallPath: defaultInstallPath,
-		PkgFile:     pkgFileName,
-		SdkFile:     sdkFileName,
-		BaseURL:     baseDownloadURL,
-		Extant:      false,
+	scope := DefaultScope(runtime.GOOS)
+	c := &InstallConfig{
+		InstallPath:  DefaultInstallPath(runtime.GOOS, scope),
+		Extant:       false,
+		Scope:        scope,
+		EnvMode:      EnvModeGlobal,
+		HostArch:     HostArch(),
+		HTTP:         DefaultHTTPConfig(),
+		Timeouts:     DefaultTimeoutConfig(),
+		Notify:       NotifyConfig{Format: notify.FormatJSON},
+		KeepVersions: defaultKeepVersions,
+		WalletExpiry: defaultWalletExpiry,
+		Signatures:   SignaturesFail,
+		ODPNet:       ODPNetConfig{Feed: DefaultODPNetFeed},
+		PkgSize:      SizeLimits{Min: defaultPkgMinSize, Max: defaultPkgMaxSize},
+		SdkSize:      SizeLimits{Min: defaultSdkMinSize, Max: defaultSdkMaxSize},
+		SQLPlusSize:  SizeLimits{Min: defaultSQLPlusMinSize, Max: defaultSQLPlusMaxSize},
+	}
+	if err := c.SetPlatform(runtime.GOOS, c.HostArch); err != nil {
+		c.SetPlatform("windows", "amd64")
+		c.OS, c.Arch = runtime.GOOS, c.HostArch
+	}
+	return c
+}
+
+// SetScope selects whose environment is configured and, unless the install path
+// was changed from the previous scope's default, moves it to the new scope's default
+func (c *InstallConfig) SetScope(scope env.Scope) error {
+	if _, err := env.ParseScope(string(scope)); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeValidation, "setting scope")
+	}
+	if c.InstallPath == DefaultInstallPath(c.OS, c.Scope) {
+		c.InstallPath = DefaultInstallPath(c.OS, scope)
+	}
+	c.Scope = scope
+	return nil
+}
+
+// SetPlatform selects the OS and architecture of the client to install
+// and updates the download details to match
+func (c *InstallConfig) SetPlatform(goos, goarch string) error {
+	p, ok := LookupPlatform(goos, goarch)
+	if !ok {
+		return errs.HandleError(
+			fmt.Errorf("unsupported platform: %s/%s", goos, goarch),
+			errs.ErrorTypeValidation,
+			"setting platform")
+	}
+	c.OS, c.Arch = goos, goarch
+	c.BaseURL, c.PkgFile, c.SdkFile = p.BaseURL, p.PkgFile, p.SdkFile
+	c.Pins = PinConfig{}
+	if c.Version != "" {
+		if err := c.applyVersion(); err != nil {
+			return err
+		}
+	}
+	c.PkgFile = FlavorFile(c.PkgFile, c.Flavor)
+	return nil
+}
+
+// SetFlavor selects the package flavor to install, basic or basiclite
+func (c *InstallConfig) SetFlavor(flavor string) error {
+	if flavor != FlavorBasic && flavor != FlavorBasicLite {
+		return errs.HandleError(
+			fmt.Errorf("invalid package flavor %q: must be basic or basiclite", flavor),
+			errs.ErrorTypeValidation,
+			"setting package flavor")
+	}
+	c.Flavor = flavor
+	return c.SetPlatform(c.OS, c.Arch)
+}
+
+// FlavorFile returns the name of the package file pkgFile in the given flavor,
+// e.g. instantclient-basic-windows.zip for instantclient-basiclite-windows.zip
+func FlavorFile(pkgFile, flavor string) string {
+	for _, f := range []string{FlavorBasicLite, FlavorBasic} {
+		if rest, ok := strings.CutPrefix(pkgFile, "instantclient-"+f+"-"); ok && flavor != "" {
+			return "instantclient-" + flavor + "-" + rest
+		}
+	}
+	return pkgFile
+}
+
+// SQLPlusFile returns the name of the SQL*Plus package released with the SDK,
+// e.g. instantclient-sqlplus-windows.zip
+func (c *InstallConfig) SQLPlusFile() string {
+	return strings.Replace(c.SdkFile, "instantclient-sdk-", "instantclient-sqlplus-", 1)
+}
+
+// PkgFlavor returns the flavor of the package file pkgFile, or "" if its name
+// does not tell
+func PkgFlavor(pkgFile string) string {
+	for _, f := range []string{FlavorBasicLite, FlavorBasic} {
+		if strings.HasPrefix(pkgFile, "instantclient-"+f+"-") {
+			return f
+		}
+	}
+	return ""
+}
+
+// SetBaseURL points the downloads at the directory base instead of Oracle's,
+// falling back to each of fallbacks in turn when a download from it fails.
+// Each may be an http(s) or file:// URL, a UNC path such as
+// \\fileserver\oracle\ or a local directory, to which a trailing separator
+// is added if missing.
+func (c *InstallConfig) SetBaseURL(base string, fallbacks ...string) error {
+	urls := make([]string, 0, 1+len(fallbacks))
+	for _, u := range append([]string{base}, fallbacks...) {
+		normalized, err := normalizeBaseURL(u)
+		if err != nil {
+			return err
+		}
+		urls = append(urls, normalized)
+	}
+	c.BaseURL, c.Fallbacks = urls[0], urls[1:]
+	return nil
+}
+
+// BaseURLs returns the base URLs the archives are downloaded from, in the
+// order they are tried
+func (c *InstallConfig) BaseURLs() []string {
+	return append([]string{c.BaseURL}, c.Fallbacks...)
+}
+
+// normalizeBaseURL checks base is a URL or path the archives can be downloaded
+// from and ends it with a separator
+func normalizeBaseURL(base string) (string, error) {
+	u, err := url.Parse(base)
+	switch {
+	case strings.HasPrefix(base, `\\`) || filepath.IsAbs(base) || filepath.VolumeName(base) != "":
+		sep := string(filepath.Separator)
+		if strings.HasPrefix(base, `\\`) {
+			sep = `\`
+		}
+		if !strings.HasSuffix(base, `\`) && !strings.HasSuffix(base, "/") {
+			base += sep
+		}
+	case err == nil && (u.Scheme == "file" || (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""):
+		if !strings.HasSuffix(base, "/") {
+			base += "/"
+		}
+	default:
+		return "", errs.HandleError(
+			fmt.Errorf("invalid base URL %q: must be an http(s) or file:// URL, a UNC path or a local directory", base),
+			errs.ErrorTypeValidation,
+			"setting base URL")
+	}
+	return base, nil
+}
+
+// LibVar returns the environment variable pointing at the client directory:
+// OCI_LIB32 for the 32-bit Windows client and OCI_LIB64 otherwise
+func (c *InstallConfig) LibVar() string {
+	if c.Arch == "386" {
+		return "OCI_LIB32"
 	}
+	return "OCI_LIB64"
+}
+
+// TNSAdminPath returns the TNS_ADMIN directory for the client in clientDir: the
+// shared directory if one is configured, and the client's network/admin otherwise.
+// Secondary clients leave TNS_ADMIN to the primary one, so it is empty for them.
+func (c *InstallConfig) TNSAdminPath(clientDir string) string {
+	switch {
+	case c.Secondary:
+		return ""
+	case c.TNSAdmin != "":
+		return c.TNSAdmin
+	}
+	return filepath.Join(clientDir, "network", "admin")
+}
+
+// X86Companion derives the configuration of the 32-bit client installed alongside this one,
+// under the x86 subdirectory of the install path
+func (c *InstallConfig) X86Companion() (*InstallConfig, error) {
+	x86 := *c
+	if err := x86.SetPlatform("windows", "386"); err != nil {
+		return nil, err
+	}
+	// A mirror given in place of Oracle's serves both clients
+	if c.BaseURL != c.oracleBaseURL() {
+		x86.BaseURL = c.BaseURL
+	}
+	x86.InstallPath = filepath.Join(c.InstallPath, "x86")
+	x86.WithX86 = false
+	x86.Secondary = true
+	x86.Extant = false
+	return &x86, nil
 }
 
 // checkPathValidity checks if the provided path is valid
@@ -93,5 +870,96 @@ func (c *InstallConfig) Validate() error {
 			errs.ErrorTypeValidation,
 			"config validation")
 	}
+	if _, ok := LookupPlatform(c.OS, c.Arch); !ok {
+		return errs.HandleError(
+			fmt.Errorf("unsupported platform: %s/%s", c.OS, c.Arch),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	if c.OS == "windows" && c.HostArch == "arm64" && c.Arch == "amd64" && !c.AllowEmulation {
+		return errs.HandleError(
+			fmt.Errorf("refusing to install the x64 client on an ARM64 host; use the arm64 client or allow emulation explicitly"),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	if c.Flavor != "" && c.Flavor != FlavorBasic && c.Flavor != FlavorBasicLite {
+		return errs.HandleError(
+			fmt.Errorf("invalid package flavor %q: must be basic or basiclite", c.Flavor),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	switch c.EnvMode {
+	case EnvModeGlobal, EnvModeWrapper, EnvModeBoth:
+	default:
+		return errs.HandleError(
+			fmt.Errorf("invalid environment mode %q: must be global, wrapper or both", c.EnvMode),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	if c.WithX86 && (c.OS != "windows" || c.Arch == "386") {
+		return errs.HandleError(
+			fmt.Errorf("the 32-bit client can only be installed alongside a 64-bit Windows client"),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	if err := c.HTTP.Validate(); err != nil {
+		return err
+	}
+	if err := c.Timeouts.Validate(); err != nil {
+		return err
+	}
+	if err := c.PkgSize.Validate("package"); err != nil {
+		return err
+	}
+	if err := c.SdkSize.Validate("SDK"); err != nil {
+		return err
+	}
+	if err := c.SQLPlusSize.Validate("SQL*Plus"); err != nil {
+		return err
+	}
+	switch c.Existing {
+	case ExistingPrompt, ExistingOverwrite, ExistingKeep:
+	default:
+		return errs.HandleError(
+			fmt.Errorf("invalid action for an existing installation %q: must be overwrite or keep", c.Existing),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	switch c.Signatures {
+	case SignaturesFail, SignaturesWarn, SignaturesOff:
+	default:
+		return errs.HandleError(
+			fmt.Errorf("invalid signature check %q: must be fail, warn or off", c.Signatures),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	switch c.ODPNet.Driver {
+	case "", ODPNetManaged, ODPNetUnmanaged, ODPNetBoth:
+	default:
+		return errs.HandleError(
+			fmt.Errorf("invalid ODP.NET driver %q: must be managed, unmanaged or both", c.ODPNet.Driver),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	if c.ODPNet.Unmanaged() && (c.OS != "windows" || c.Scope != env.ScopeMachine) {
+		return errs.HandleError(
+			fmt.Errorf("the unmanaged ODP.NET driver reads its settings from the machine registry; it can only be registered for the machine scope on Windows"),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	if err := validateExtraEnv(c.ExtraEnv); err != nil {
+		return err
+	}
+	if c.TNSAdmin != "" && !checkPathValidity(c.TNSAdmin) {
+		return errs.HandleError(
+			fmt.Errorf("shared TNS_ADMIN directory cannot be empty or invalid"),
+			errs.ErrorTypeValidation,
+			"config validation")
+	}
+	if c.Notify.URL != "" {
+		if err := notify.Validate(c.Notify.Format); err != nil {
+			return err
+		}
+	}
 	return nil
 }
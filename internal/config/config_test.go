@@ -0,0 +1,122 @@
+package config_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+)
+
+func TestSetPlatform(t *testing.T) {
+	tests := []struct {
+		arch    string
+		pkgFile string
+		libVar  string
+	}{
+		{"amd64", "instantclient-basiclite-windows.zip", "OCI_LIB64"},
+		{"386", "instantclient-basiclite-nt.zip", "OCI_LIB32"},
+		{"arm64", "instantclient-basiclite-windows-arm64.zip", "OCI_LIB64"},
+	}
+	for _, tt := range tests {
+		conf := config.New()
+		if err := conf.SetPlatform("windows", tt.arch); err != nil {
+			t.Fatal(err)
+		}
+		if conf.PkgFile != tt.pkgFile || conf.LibVar() != tt.libVar {
+			t.Errorf("windows/%s: package %s and %s, want %s and %s", tt.arch, conf.PkgFile, conf.LibVar(), tt.pkgFile, tt.libVar)
+		}
+	}
+	if err := config.New().SetPlatform("windows", "ppc64"); err == nil {
+		t.Error("an unsupported architecture was accepted")
+	}
+}
+
+func TestSetVersion(t *testing.T) {
+	tests := []struct {
+		arch, version string
+		url           string
+	}{
+		{"amd64", "19.25", "https://download.oracle.com/otn_software/nt/instantclient/1925000/instantclient-basiclite-windows.x64-19.25.0.0.0dbru.zip"},
+		{"386", "19.25", "https://download.oracle.com/otn_software/nt/instantclient/1925000/instantclient-basiclite-nt-19.25.0.0.0dbru.zip"},
+		{"amd64", "19.3", "https://download.oracle.com/otn_software/nt/instantclient/193000/instantclient-basiclite-windows.x64-19.3.0.0.0dbru.zip"},
+		{"amd64", "21.3.0.0.0", "https://download.oracle.com/otn_software/nt/instantclient/213000/instantclient-basiclite-windows.x64-21.3.0.0.0.zip"},
+		{"amd64", "23.7.0.25.01", "https://download.oracle.com/otn_software/nt/instantclient/2370000/instantclient-basiclite-windows.x64-23.7.0.25.01.zip"},
+	}
+	for _, tt := range tests {
+		conf := config.New()
+		if err := conf.SetPlatform("windows", tt.arch); err != nil {
+			t.Fatal(err)
+		}
+		if err := conf.SetVersion(tt.version); err != nil {
+			t.Fatal(err)
+		}
+		if got := conf.BaseURL + conf.PkgFile; got != tt.url {
+			t.Errorf("windows/%s %s: %s, want %s", tt.arch, tt.version, got, tt.url)
+		}
+		// Changing the architecture keeps the release
+		if err := conf.SetPlatform("windows", "386"); err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Ext(conf.PkgFile) != ".zip" || conf.Pins.ClientDir == "" {
+			t.Errorf("windows/386 %s: package %s pinned to %q", tt.version, conf.PkgFile, conf.Pins.ClientDir)
+		}
+	}
+}
+
+func TestX86Companion(t *testing.T) {
+	conf := config.New()
+	if err := conf.SetPlatform("windows", "amd64"); err != nil {
+		t.Fatal(err)
+	}
+	conf.InstallPath = filepath.FromSlash("C:/oracle")
+	x86, err := conf.X86Companion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x86.Arch != "386" || x86.LibVar() != "OCI_LIB32" || !x86.Secondary || x86.InstallPath != filepath.Join(conf.InstallPath, "x86") {
+		t.Errorf("companion %s/%s with %s in %s, secondary %t", x86.OS, x86.Arch, x86.LibVar(), x86.InstallPath, x86.Secondary)
+	}
+
+	// A mirror given for the 64-bit client serves the 32-bit one
+	if err := conf.SetBaseURL("https://mirror.example/oracle"); err != nil {
+		t.Fatal(err)
+	}
+	if x86, err = conf.X86Companion(); err != nil {
+		t.Fatal(err)
+	}
+	if x86.BaseURL != "https://mirror.example/oracle/" {
+		t.Errorf("companion downloads from %s", x86.BaseURL)
+	}
+}
+
+func TestSetFlavor(t *testing.T) {
+	conf := config.New()
+	if err := conf.SetPlatform("windows", "386"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.SetFlavor(config.FlavorBasic); err != nil {
+		t.Fatal(err)
+	}
+	if conf.PkgFile != "instantclient-basic-nt.zip" || conf.SdkFile != "instantclient-sdk-nt.zip" {
+		t.Errorf("Basic files %s and %s", conf.PkgFile, conf.SdkFile)
+	}
+	// The flavor applies to releases and other architectures too
+	if err := conf.SetVersion("19.25"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.SetPlatform("windows", "amd64"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "instantclient-basic-windows.x64-19.25.0.0.0dbru.zip"; conf.PkgFile != want {
+		t.Errorf("package %s, want %s", conf.PkgFile, want)
+	}
+	if got := config.PkgFlavor(conf.PkgFile); got != config.FlavorBasic {
+		t.Errorf("PkgFlavor(%s) = %q", conf.PkgFile, got)
+	}
+	if err := conf.SetFlavor(config.FlavorBasicLite); err != nil {
+		t.Fatal(err)
+	}
+	if want := "instantclient-basiclite-windows.x64-19.25.0.0.0dbru.zip"; conf.PkgFile != want {
+		t.Errorf("package %s, want %s", conf.PkgFile, want)
+	}
+}
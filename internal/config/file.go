@@ -0,0 +1,159 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/credential"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// fileConfig is the layout of a configuration file
+type fileConfig struct {
+	// Env holds extra variables to set with the client's, by name. Values may
+	// reference install facts as ${NAME}, e.g. ${ORAICWINCONFIG_CLIENT_DIR}.
+	Env map[string]string `json:"env"`
+	// Mirrors holds the headers to send to authenticated download mirrors, by host
+	Mirrors map[string]MirrorConfig `json:"mirrors"`
+}
+
+// MirrorConfig holds the settings of a download mirror
+type MirrorConfig struct {
+	Headers map[string]HeaderValue `json:"headers"` // Headers to send with every request to the mirror, by name
+}
+
+// HeaderValue says where the value of a header comes from. Exactly one of Env,
+// Credential and Value is set; tokens belong in the first two so the
+// configuration file holds no secrets in plaintext.
+type HeaderValue struct {
+	Env        string `json:"env,omitempty"`        // Environment variable holding the value
+	Credential string `json:"credential,omitempty"` // Target name of a generic Windows Credential Manager entry holding the value
+	Value      string `json:"value,omitempty"`      // Literal value, for headers that are not secret
+	Prefix     string `json:"prefix,omitempty"`     // Text put before the value, e.g. "Bearer "
+}
+
+// Resolve reads the header value from its source
+func (h HeaderValue) Resolve() (string, error) {
+	switch {
+	case h.Env != "":
+		value := os.Getenv(h.Env)
+		if value == "" {
+			return "", errs.HandleError(fmt.Errorf("environment variable %s is not set or empty", h.Env), errs.ErrorTypeValidation, "reading mirror header")
+		}
+		return h.Prefix + value, nil
+	case h.Credential != "":
+		value, err := credential.Read(h.Credential)
+		if err != nil {
+			return "", err
+		}
+		return h.Prefix + value, nil
+	default:
+		return h.Prefix + h.Value, nil
+	}
+}
+
+// reservedEnv are the variables the installer manages itself
+var reservedEnv = []string{"PATH", "TNS_ADMIN", "OCI_LIB64", "OCI_LIB32", "LD_LIBRARY_PATH", "DYLD_LIBRARY_PATH"}
+
+// LoadFile applies the settings of a JSON configuration file to c
+func (c *InstallConfig) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeValidation, "reading config file")
+	}
+	var f fileConfig
+	if err := json.Unmarshal(data, &f); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeValidation, "parsing config file")
+	}
+	if len(f.Env) > 0 {
+		c.ExtraEnv = f.Env
+	}
+	if len(f.Mirrors) > 0 {
+		c.HTTP.Mirrors = f.Mirrors
+	}
+	if err := validateExtraEnv(c.ExtraEnv); err != nil {
+		return err
+	}
+	return validateMirrors(c.HTTP.Mirrors)
+}
+
+// validateMirrors checks the mirror hosts and that each header has exactly one source
+func validateMirrors(mirrors map[string]MirrorConfig) error {
+	for host, m := range mirrors {
+		if host == "" || strings.ContainsAny(host, "/ ") {
+			return errs.HandleError(fmt.Errorf("invalid mirror host %q: must be a host name, optionally with a port", host), errs.ErrorTypeValidation, "config validation")
+		}
+		for name, h := range m.Headers {
+			if name == "" || strings.ContainsAny(name, ": \t\r\n") {
+				return errs.HandleError(fmt.Errorf("%s: invalid header name %q", host, name), errs.ErrorTypeValidation, "config validation")
+			}
+			sources := 0
+			for _, s := range []string{h.Env, h.Credential, h.Value} {
+				if s != "" {
+					sources++
+				}
+			}
+			if sources != 1 {
+				return errs.HandleError(
+					fmt.Errorf("%s: header %s must take its value from exactly one of env, credential or value", host, name),
+					errs.ErrorTypeValidation,
+					"config validation")
+			}
+		}
+	}
+	return nil
+}
+
+// validateExtraEnv checks the names of the extra variables
+func validateExtraEnv(vars map[string]string) error {
+	for name := range vars {
+		if name == "" || strings.ContainsAny(name, "=\x00 ") {
+			return errs.HandleError(fmt.Errorf("invalid variable name %q", name), errs.ErrorTypeValidation, "config validation")
+		}
+		for _, reserved := range reservedEnv {
+			if strings.EqualFold(name, reserved) {
+				return errs.HandleError(
+					fmt.Errorf("%s is managed by the installer and cannot be set as an extra variable", name),
+					errs.ErrorTypeValidation,
+					"config validation")
+			}
+		}
+	}
+	return nil
+}
+
+// ExtraEnvNames returns the names of the extra variables in a stable order
+func (c *InstallConfig) ExtraEnvNames() []string {
+	names := make([]string, 0, len(c.ExtraEnv))
+	for name := range c.ExtraEnv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandExtraEnv returns the extra variables with references to facts
+// substituted. A reference to anything that is not a fact is an error.
+func (c *InstallConfig) ExpandExtraEnv(facts map[string]string) (map[string]string, error) {
+	vars := make(map[string]string, len(c.ExtraEnv))
+	for _, name := range c.ExtraEnvNames() {
+		var unknown []string
+		vars[name] = os.Expand(c.ExtraEnv[name], func(ref string) string {
+			value, ok := facts[ref]
+			if !ok {
+				unknown = append(unknown, ref)
+			}
+			return value
+		})
+		if len(unknown) > 0 {
+			return nil, errs.HandleError(
+				fmt.Errorf("%s references unknown install facts: %s", name, strings.Join(unknown, ", ")),
+				errs.ErrorTypeValidation,
+				"expanding extra variables")
+		}
+	}
+	return vars, nil
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package config
+
+import "runtime"
+
+// HostArch returns the native architecture of the machine as a GOARCH value
+func HostArch() string {
+	return runtime.GOARCH
+}
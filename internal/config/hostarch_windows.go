@@ -0,0 +1,35 @@
+package config
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// Machine types reported by IsWow64Process2
+const (
+	imageFileMachineAMD64 = 0x8664
+	imageFileMachineARM64 = 0xAA64
+)
+
+// HostArch returns the native architecture of the machine as a GOARCH value,
+// which differs from runtime.GOARCH when an x64 build runs emulated on ARM64 Windows
+func HostArch() string {
+	proc := syscall.NewLazyDLL("kernel32.dll").NewProc("IsWow64Process2")
+	if proc.Find() != nil {
+		return runtime.GOARCH
+	}
+	var processMachine, nativeMachine uint16
+	handle, _ := syscall.GetCurrentProcess()
+	if r, _, _ := proc.Call(uintptr(handle), uintptr(unsafe.Pointer(&processMachine)), uintptr(unsafe.Pointer(&nativeMachine))); r == 0 {
+		return runtime.GOARCH
+	}
+	switch nativeMachine {
+	case imageFileMachineARM64:
+		return "arm64"
+	case imageFileMachineAMD64:
+		return "amd64"
+	default:
+		return runtime.GOARCH
+	}
+}
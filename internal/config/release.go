@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// releasePattern matches a client release: a major and minor version, such as
+// 19.25, optionally followed by the rest of the five-part version Oracle names
+// the archives with, such as 23.7.0.25.01
+var releasePattern = regexp.MustCompile(`^([0-9]{1,2})\.([0-9]{1,2})((?:\.[0-9]{1,2}){3})?(dbru)?$`)
+
+// releaseRoots holds the directory on Oracle's site of each OS's versioned
+// archives, which has a directory per release, e.g. 1925000 for 19.25
+var releaseRoots = map[string]string{
+	"windows": baseDownloadURL,
+	"linux":   "https://download.oracle.com/otn_software/linux/instantclient/",
+	"darwin":  "https://download.oracle.com/otn_software/mac/instantclient/",
+}
+
+// Release returns the download details of the given client release for the
+// platform on Oracle's site, and the versioned directory its package extracts
+// to. A full version, such as 23.7.0.25.01 or 21.3.0.0.0, names the archives
+// exactly; releases from 23 on carry their build date in the file names, so
+// only it names them, while 19.25 stands for 19.25.0.0.0dbru.
+func Release(goos, goarch, version string) (Platform, string, error) {
+	p, ok := LookupPlatform(goos, goarch)
+	if !ok {
+		return Platform{}, "", errs.HandleError(fmt.Errorf("unsupported platform: %s/%s", goos, goarch), errs.ErrorTypeValidation, "selecting release")
+	}
+	clientDir, err := ReleaseDir(version)
+	if err != nil {
+		return Platform{}, "", err
+	}
+	m := releasePattern.FindStringSubmatch(version)
+	major, minor, rest := m[1], m[2], m[3]
+	dated := len(major) == 2 && major >= "23"
+	full := version
+	switch {
+	case rest == "" && dated:
+		return Platform{}, "", errs.HandleError(
+			fmt.Errorf("the archives of %s.%s carry their build date: give the full version, such as 23.7.0.25.01, or install it from a --mirror-index", major, minor),
+			errs.ErrorTypeValidation,
+			"selecting release")
+	case rest == "":
+		full = major + "." + minor + ".0.0.0dbru"
+	}
+	// Release directories run the major and minor version together, e.g.
+	// 193000 for 19.3 and 1925000 for 19.25, padded to seven digits from 23 on
+	dir := major + minor + "000"
+	if dated {
+		dir = major + minor + strings.Repeat("0", max(0, 5-len(minor)))
+	}
+
+	ext := filepath.Ext(p.PkgFile)
+	p.BaseURL = releaseRoots[goos] + dir + "/"
+	p.PkgFile = "instantclient-basiclite-" + p.Tag + "-" + full + ext
+	p.SdkFile = "instantclient-sdk-" + p.Tag + "-" + full + ext
+	return p, clientDir, nil
+}
+
+// ReleaseDir returns the versioned directory the package of a client release,
+// such as 19.25, extracts to
+func ReleaseDir(version string) (string, error) {
+	m := releasePattern.FindStringSubmatch(version)
+	if m == nil {
+		return "", errs.HandleError(
+			fmt.Errorf("invalid version %q: must be a release such as 19.25 or 23.7.0.25.01", version),
+			errs.ErrorTypeValidation,
+			"selecting release")
+	}
+	return "instantclient_" + m[1] + "_" + m[2], nil
+}
+
+// SetVersion selects the client release to install instead of the latest,
+// downloading its versioned archives, or the mirror index's, and checking the
+// package extracts to its versioned directory
+func (c *InstallConfig) SetVersion(version string) error {
+	var err error
+	if c.MirrorIndex != "" {
+		_, err = ReleaseDir(version)
+	} else {
+		_, _, err = Release(c.OS, c.Arch, version)
+	}
+	if err != nil {
+		return err
+	}
+	c.Version = version
+	return c.SetPlatform(c.OS, c.Arch)
+}
+
+// applyVersion points the downloads at the archives of the selected release.
+// A mirror index lists the archives of its releases itself.
+func (c *InstallConfig) applyVersion() error {
+	if c.MirrorIndex != "" {
+		clientDir, err := ReleaseDir(c.Version)
+		c.Pins = PinConfig{ClientDir: clientDir, Source: "--version " + c.Version}
+		return err
+	}
+	p, clientDir, err := Release(c.OS, c.Arch, c.Version)
+	if err != nil {
+		return err
+	}
+	c.BaseURL, c.PkgFile, c.SdkFile = p.BaseURL, p.PkgFile, p.SdkFile
+	c.Pins = PinConfig{ClientDir: clientDir, Source: "--version " + c.Version}
+	return nil
+}
+
+// oracleBaseURL returns the directory on Oracle's site the archives of the
+// platform and release are downloaded from
+func (c *InstallConfig) oracleBaseURL() string {
+	if c.Version != "" {
+		if p, _, err := Release(c.OS, c.Arch, c.Version); err == nil {
+			return p.BaseURL
+		}
+	}
+	p, _ := LookupPlatform(c.OS, c.Arch)
+	return p.BaseURL
+}
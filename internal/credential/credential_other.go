@@ -0,0 +1,18 @@
+//go:build !windows
+
+package credential
+
+import (
+	"fmt"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// Read returns the secret of a credential stored in Windows Credential
+// Manager, which only exists on Windows
+func Read(target string) (string, error) {
+	return "", errs.HandleError(
+		fmt.Errorf("credential %q: Windows Credential Manager is not available on this platform; use an environment variable instead", target),
+		errs.ErrorTypeValidation,
+		"reading credential")
+}
@@ -0,0 +1,64 @@
+package credential
+
+import (
+	"bytes"
+	"fmt"
+	"syscall"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+var (
+	advapi32  = syscall.NewLazyDLL("advapi32.dll")
+	credReadW = advapi32.NewProc("CredReadW")
+	credFree  = advapi32.NewProc("CredFree")
+)
+
+// credTypeGeneric is the CRED_TYPE_GENERIC credential type, as stored by
+// cmdkey /generic and the Windows Credentials tab of Credential Manager
+const credTypeGeneric = 1
+
+// credential mirrors the leading fields of the Win32 CREDENTIALW structure
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+}
+
+// Read returns the secret of the generic credential stored under target in
+// the current user's Windows Credential Manager
+func Read(target string) (string, error) {
+	p, err := syscall.UTF16PtrFromString(target)
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeValidation, "reading credential")
+	}
+	var cred *credential
+	if r, _, err := credReadW.Call(uintptr(unsafe.Pointer(p)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred))); r == 0 {
+		return "", errs.HandleError(fmt.Errorf("credential %q: %w", target, err), errs.ErrorTypeValidation, "reading credential")
+	}
+	defer credFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+	return decodeBlob(blob), nil
+}
+
+// decodeBlob returns the secret of a credential blob. Credential Manager and
+// cmdkey store passwords as UTF-16; other writers may store UTF-8 bytes,
+// which unlike UTF-16 text never contain zero bytes.
+func decodeBlob(blob []byte) string {
+	if len(blob)%2 != 0 || utf8.Valid(blob) && bytes.IndexByte(blob, 0) < 0 {
+		return string(blob)
+	}
+	u := make([]uint16, len(blob)/2)
+	for i := range u {
+		u[i] = uint16(blob[2*i]) | uint16(blob[2*i+1])<<8
+	}
+	return string(utf16.Decode(u))
+}
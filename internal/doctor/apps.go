@@ -0,0 +1,270 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/check"
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+)
+
+// App is an application that uses the Oracle client
+type App struct {
+	Name     string
+	Patterns []string // Glob patterns of its executable
+	Library  bool     // Loads the client libraries; false for applications with their own driver, which only read TNS_ADMIN
+	SDK      bool     // Builds its driver against the SDK headers, as ROracle does
+	GUI      bool     // Usually started from the Dock or Finder on macOS, which do not read the shell profile
+	Hint     string   // How the application finds the client, added to remediation hints
+}
+
+// FoundApp is an installed application and the executable it was found by
+type FoundApp struct {
+	App
+	Path string
+}
+
+// knownApps returns the applications looked for on goos
+func knownApps(goos string) []App {
+	home, _ := os.UserHomeDir()
+	python := App{
+		Name:    "Python",
+		Library: true,
+		Hint:    "python-oracledb in thin mode needs no client; thick mode and cx_Oracle load it from PATH, or from the lib_dir passed to oracledb.init_oracle_client()",
+	}
+	for _, conda := range []string{"anaconda3", "miniconda3", "miniforge3"} {
+		base := filepath.Join(home, conda)
+		if goos == "windows" {
+			python.Patterns = append(python.Patterns, filepath.Join(base, "python.exe"), filepath.Join(base, "envs", "*", "python.exe"))
+		} else {
+			python.Patterns = append(python.Patterns, filepath.Join(base, "bin", "python"), filepath.Join(base, "envs", "*", "bin", "python"))
+		}
+	}
+
+	switch goos {
+	case "windows":
+		var programFiles []string
+		for _, name := range []string{"ProgramFiles", "ProgramFiles(x86)"} {
+			if dir := os.Getenv(name); dir != "" {
+				programFiles = append(programFiles, dir)
+			}
+		}
+		in := func(elem ...string) []string {
+			var patterns []string
+			for _, dir := range programFiles {
+				patterns = append(patterns, filepath.Join(append([]string{dir}, elem...)...))
+			}
+			return patterns
+		}
+		return []App{
+			{
+				Name:     "Power BI Desktop",
+				Patterns: in("Microsoft Power BI Desktop", "bin", "PBIDesktop.exe"),
+				Library:  true,
+				Hint:     "Power BI Desktop only sees PATH as it was when it started, so restart it after installing; if its Oracle connector still cannot load the client, set up ODP.NET with oraicwinconfig --odp-net unmanaged",
+			},
+			{
+				Name:     "Tableau Desktop",
+				Patterns: in("Tableau", "Tableau *", "bin", "tableau.exe"),
+				Hint:     "Tableau connects with the Oracle JDBC driver in its Drivers folder, and only needs TNS_ADMIN to resolve net service names",
+			},
+			{
+				Name:     "Toad for Oracle",
+				Patterns: append(in("Quest Software", "Toad for Oracle*", "Toad.exe"), in("Quest Software", "Toad for Oracle*", "*", "Toad.exe")...),
+				Library:  true,
+				Hint:     "Toad uses the client it finds on PATH unless another home is chosen under Connect using in its login window",
+			},
+			{
+				Name:     "R",
+				Patterns: append(in("R", "R-*", "bin", "x64", "Rterm.exe"), in("R", "R-*", "bin", "i386", "Rterm.exe")...),
+				Library:  true,
+				SDK:      true,
+				Hint:     "ROracle is built against OCI_LIB64 (OCI_LIB32 for 32-bit R) and loads the client from PATH",
+			},
+			python,
+		}
+	case "darwin":
+		return []App{
+			{
+				Name:     "Tableau Desktop",
+				Patterns: []string{"/Applications/Tableau Desktop*.app/Contents/MacOS/Tableau"},
+				GUI:      true,
+				Hint:     "Tableau connects with the Oracle JDBC driver in its Drivers folder, and only needs TNS_ADMIN to resolve net service names",
+			},
+			{
+				Name:     "R",
+				Patterns: []string{"/Library/Frameworks/R.framework/Resources/bin/exec/R", "/opt/homebrew/lib/R/bin/exec/R", "/usr/local/lib/R/bin/exec/R"},
+				Library:  true,
+				SDK:      true,
+				Hint:     "ROracle is built against the SDK headers and loads the client from DYLD_LIBRARY_PATH; R.app started from the Dock does not read the shell profile, so start R from a terminal",
+			},
+			python,
+		}
+	}
+	return []App{
+		{
+			Name:     "R",
+			Patterns: []string{"/usr/lib/R/bin/exec/R", "/usr/lib64/R/bin/exec/R", "/opt/R/*/lib/R/bin/exec/R"},
+			Library:  true,
+			SDK:      true,
+			Hint:     "ROracle is built against the SDK headers and loads the client from LD_LIBRARY_PATH",
+		},
+		python,
+	}
+}
+
+// FindApps returns the known applications installed on goos, once for each
+// executable found. Python is also looked up on PATH; launchers there, such
+// as pyenv shims, are asked for the interpreter they run.
+func FindApps(ctx context.Context, goos string) []FoundApp {
+	var found []FoundApp
+	seen := make(map[string]bool)
+	add := func(app App, path string) {
+		key := path
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			key = resolved
+		}
+		key = env.PathKey(key)
+		// The Microsoft Store aliases are stubs installing Python, not Python
+		if seen[key] || strings.Contains(key, env.PathKey(filepath.Join("Microsoft", "WindowsApps"))) {
+			return
+		}
+		seen[key] = true
+		found = append(found, FoundApp{App: app, Path: path})
+	}
+	for _, app := range knownApps(goos) {
+		var paths []string
+		if app.Name == "Python" {
+			for _, name := range []string{"python3", "python"} {
+				if path, err := exec.LookPath(name); err == nil {
+					if abs, err := filepath.Abs(path); err == nil {
+						path = abs
+					}
+					paths = append(paths, pythonExecutable(ctx, path))
+				}
+			}
+		}
+		for _, pattern := range app.Patterns {
+			matches, _ := filepath.Glob(pattern)
+			paths = append(paths, matches...)
+		}
+		for _, path := range paths {
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				add(app, path)
+			}
+		}
+	}
+	return found
+}
+
+// pythonExecutable returns the interpreter the Python at path runs, which is
+// path itself unless it is a launcher script
+func pythonExecutable(ctx context.Context, path string) string {
+	if _, err := LibraryArch(path); err == nil {
+		return path
+	}
+	out, err := pwsh.Output(ctx, path, "-c", "import sys; print(sys.executable)")
+	if exe := strings.TrimSpace(string(out)); err == nil && exe != "" {
+		return exe
+	}
+	return path
+}
+
+// AppChecks returns a check for each application found, reporting whether it
+// will find the client configured in the manager's scope
+func AppChecks(ctx context.Context, conf *config.InstallConfig, m env.Manager) []check.Check {
+	c := *conf
+	apps := FindApps(ctx, c.OS)
+	if len(apps) == 0 {
+		return []check.Check{{Name: "applications", Run: func(context.Context) check.Result {
+			return check.Pass("none of Power BI Desktop, Tableau, Toad, R or Python found")
+		}}}
+	}
+	checks := make([]check.Check, len(apps))
+	for i, app := range apps {
+		app := app
+		checks[i] = check.Check{Name: app.Name, Run: func(ctx context.Context) check.Result { return checkApp(ctx, c, m, app) }}
+	}
+	return checks
+}
+
+// checkApp verifies an application will find the client: one built for its
+// architecture, on PATH ahead of other clients, and TNS_ADMIN
+func checkApp(ctx context.Context, conf config.InstallConfig, m env.Manager, app FoundApp) check.Result {
+	m = m.WithContext(ctx)
+	admin, adminErr := m.GetEnvVar("TNS_ADMIN")
+	if !app.Library {
+		if adminErr != nil {
+			return check.Warn(fmt.Sprintf("%s: TNS_ADMIN is not set, so net service names will not resolve", app.Path), app.Hint)
+		}
+		return guiResult(conf, app, fmt.Sprintf("%s reads net service names from %s", app.Path, admin))
+	}
+
+	arch, err := LibraryArch(app.Path)
+	if err != nil {
+		return check.Warn(fmt.Sprintf("%s: could not tell its architecture: %v", app.Path, err), app.Hint)
+	}
+	libVar := conf.LibVar()
+	if conf.OS == "windows" {
+		libVar = "OCI_LIB64"
+		if arch == "386" {
+			libVar = "OCI_LIB32"
+		}
+	}
+	dir, err := m.GetEnvVar(libVar)
+	if err != nil {
+		hint := "install the " + arch + " client with oraicwinconfig --arch " + arch
+		if libVar == "OCI_LIB32" {
+			hint = "install the 32-bit client alongside the 64-bit one with oraicwinconfig --with-x86"
+		}
+		return check.Fail(fmt.Sprintf("%s is %s, and no %s client is configured (%s is not set)", app.Path, arch, arch, libVar), hint+"; "+app.Hint)
+	}
+	lib := filepath.Join(dir, LibraryName(conf.OS))
+	if libArch, err := LibraryArch(lib); err != nil {
+		return check.Fail(fmt.Sprintf("%s: could not read %s: %v", app.Path, lib, err), "reinstall the client")
+	} else if libArch != arch {
+		hint := "install the " + arch + " client with oraicwinconfig --arch " + arch
+		if arch == "amd64" && conf.HostArch == "arm64" {
+			hint += " --allow-emulation"
+		}
+		return check.Fail(fmt.Sprintf("%s is %s, but the client in %s is %s and cannot be loaded by it", app.Path, arch, dir, libArch), hint)
+	}
+
+	pathConf := conf
+	pathConf.Arch = arch
+	conflict, found, err := FindPathConflict(ctx, pathConf, m, dir)
+	if err != nil {
+		return check.Fail(fmt.Sprintf("%s is not on PATH, so %s cannot load the client", dir, app.Name),
+			"rerun oraicwinconfig with --env-mode global or both, or start "+app.Name+" through the launcher script in "+dir+"; "+app.Hint)
+	}
+	if found {
+		hint := "run oraicwinconfig doctor --fix-path"
+		if arch != conf.Arch {
+			hint += " --arch " + arch
+		}
+		return check.Fail(fmt.Sprintf("%s comes before %s on PATH, so %s loads that client", conflict.Shadow.Dir, dir, app.Name), hint)
+	}
+	if app.SDK && !isDir(filepath.Join(dir, "sdk", "include")) {
+		return check.Warn(fmt.Sprintf("%s loads %s, but its SDK headers are missing", app.Path, dir), "reinstall the client with its SDK to build drivers such as ROracle")
+	}
+	if adminErr != nil {
+		return check.Warn(fmt.Sprintf("%s loads %s, but TNS_ADMIN is not set, so net service names will not resolve", app.Path, dir), "set TNS_ADMIN, or rerun oraicwinconfig")
+	}
+	return guiResult(conf, app, fmt.Sprintf("%s (%s) loads %s", app.Path, arch, dir))
+}
+
+// guiResult passes detail, unless the application is started where the
+// variables written to the shell profile are not seen
+func guiResult(conf config.InstallConfig, app FoundApp, detail string) check.Result {
+	if app.GUI && conf.OS == "darwin" {
+		return check.Warn(detail+", when started from a terminal",
+			"apps started from the Dock or Finder do not read the shell profile; start "+app.Name+" with open -a from a terminal, or set the variables with launchctl setenv; "+app.Hint)
+	}
+	return check.Pass(detail)
+}
@@ -0,0 +1,92 @@
+package doctor_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/check"
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/doctor"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/testsupport"
+)
+
+// copyBinary copies the test binary, which is built for the host, to path
+// as a stand-in for an application or client library
+func copyBinary(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppChecks(t *testing.T) {
+	home := testsupport.Sandbox(t)
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("ProgramFiles", t.TempDir())
+	t.Setenv("ProgramFiles(x86)", "")
+	python := filepath.Join(home, "miniconda3", "envs", "etl", "bin", "python")
+	if runtime.GOOS == "windows" {
+		python = filepath.Join(home, "miniconda3", "envs", "etl", "python.exe")
+	}
+	copyBinary(t, python)
+
+	conf := config.New()
+	conf.Arch = runtime.GOARCH
+	client, other := t.TempDir(), t.TempDir()
+	copyBinary(t, filepath.Join(client, doctor.LibraryName(conf.OS)))
+	copyBinary(t, filepath.Join(other, doctor.LibraryName(conf.OS)))
+	os.MkdirAll(filepath.Join(client, "sdk", "include"), 0755)
+
+	m := testsupport.NewEnv(env.ScopeUser, t.TempDir())
+	m.SetEnvVar(conf.LibVar(), client)
+	m.SetEnvVar("TNS_ADMIN", filepath.Join(client, "network", "admin"))
+	m.SetEnvVar("PATH", client)
+
+	apps := doctor.FindApps(context.Background(), conf.OS)
+	found := false
+	for _, app := range apps {
+		found = found || app.Name == "Python" && app.Path == python
+	}
+	if !found {
+		t.Fatalf("found %+v, want the conda environment's Python", apps)
+	}
+	if len(apps) > 1 {
+		t.Skip("other Oracle-dependent applications are installed system-wide")
+	}
+	run := func() check.Result {
+		t.Helper()
+		results := check.Run(context.Background(), doctor.AppChecks(context.Background(), conf, m), 0)
+		if len(results) != 1 {
+			t.Fatalf("results = %+v", results)
+		}
+		return results[0]
+	}
+	if r := run(); r.Status != check.StatusPass || !strings.Contains(r.Detail, client) {
+		t.Errorf("configured client: %+v", r)
+	}
+
+	m.SetEnvVar("PATH", strings.Join([]string{other, client}, string(os.PathListSeparator)))
+	if r := run(); r.Status != check.StatusFail || !strings.Contains(r.Detail, other) || !strings.Contains(r.Hint, "--fix-path") {
+		t.Errorf("shadowed client: %+v", r)
+	}
+	m.SetEnvVar("PATH", other)
+	if r := run(); r.Status != check.StatusFail || !strings.Contains(r.Detail, "not on PATH") {
+		t.Errorf("client off PATH: %+v", r)
+	}
+	m.RemoveEnvVar(conf.LibVar())
+	if r := run(); r.Status != check.StatusFail || !strings.Contains(r.Detail, "is not set") {
+		t.Errorf("no client: %+v", r)
+	}
+}
@@ -0,0 +1,130 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/mghoff/oraicwinconfig/internal/check"
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+)
+
+// Provider is a directory holding a copy of the client library
+type Provider struct {
+	Dir    string
+	Source string // Where it was found: system directory, PATH, registry or common location
+	Arch   string // Architecture the library is built for, empty if unreadable
+	Rank   int    // Position in the loader's search order, or 0 when it is not searched
+}
+
+// FindProviders returns every directory providing the client library that can be
+// found on the loader's search path, in the registry and in common install locations.
+// Providers the loader searches come first, in the order it searches them.
+func FindProviders(ctx context.Context, goos string, m env.Manager) []Provider {
+	lib := LibraryName(goos)
+	seen := make(map[string]bool)
+	var providers []Provider
+	add := func(dir, source string, searched bool) {
+		key := filepath.Clean(dir)
+		if seen[env.PathKey(key)] {
+			return
+		}
+		path := filepath.Join(key, lib)
+		if _, err := os.Stat(path); err != nil {
+			return
+		}
+		seen[env.PathKey(key)] = true
+		p := Provider{Dir: key, Source: source}
+		p.Arch, _ = LibraryArch(path)
+		if searched {
+			p.Rank = len(providers) + 1
+		}
+		providers = append(providers, p)
+	}
+
+	for _, dir := range systemDirs() {
+		add(dir, "system directory", true)
+	}
+	for _, dir := range SearchPath(ctx, goos, m) {
+		add(dir, "PATH", true)
+	}
+	for _, home := range registryHomes(ctx) {
+		add(home, "registry", false)
+		add(filepath.Join(home, "bin"), "registry", false)
+	}
+	for _, pattern := range commonLocations() {
+		matches, _ := filepath.Glob(pattern)
+		for _, dir := range matches {
+			add(dir, "common location", false)
+		}
+	}
+	return providers
+}
+
+// LoadedFirst returns the provider an application of the given architecture
+// would load, skipping copies built for another architecture
+func LoadedFirst(providers []Provider, arch string) (Provider, bool) {
+	for _, p := range providers {
+		if p.Rank > 0 && (p.Arch == "" || p.Arch == arch) {
+			return p, true
+		}
+	}
+	return Provider{}, false
+}
+
+// ReportProviders writes the providers as a table, marking the one loaded first
+func ReportProviders(w io.Writer, providers []Provider, arch string) {
+	if len(providers) == 0 {
+		fmt.Fprintln(w, "  no copies of the client library found")
+		return
+	}
+	first, _ := LoadedFirst(providers, arch)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  \tORDER\tARCH\tSOURCE\tDIRECTORY")
+	for _, p := range providers {
+		mark, order, a := " ", "-", p.Arch
+		if p.Rank > 0 {
+			order = fmt.Sprint(p.Rank)
+		}
+		if p.Dir == first.Dir {
+			mark = "*"
+		}
+		if a == "" {
+			a = "?"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", mark, order, a, p.Source, p.Dir)
+	}
+	tw.Flush()
+	if first.Dir != "" {
+		fmt.Fprintf(w, "  * loaded first by %s applications\n", arch)
+	}
+}
+
+// checkConflicts reports other copies of the client library and which copy wins
+func checkConflicts(ctx context.Context, conf config.InstallConfig, m env.Manager) check.Result {
+	dir, err := m.WithContext(ctx).GetEnvVar(conf.LibVar())
+	if err != nil {
+		return check.Warn("skipped, "+conf.LibVar()+" is not set", "")
+	}
+	providers := FindProviders(ctx, conf.OS, m)
+	var others int
+	for _, p := range providers {
+		if !env.SamePath(p.Dir, dir) {
+			others++
+		}
+	}
+	first, ok := LoadedFirst(providers, conf.Arch)
+	switch {
+	case ok && !env.SamePath(first.Dir, dir):
+		return check.Fail(fmt.Sprintf("%s from %s (%s) is loaded instead of %s", LibraryName(conf.OS), first.Dir, first.Source, dir),
+			"remove or reorder the other client; run oraicwinconfig doctor --clients to list every copy")
+	case others > 0:
+		return check.Warn(fmt.Sprintf("%d other copies of %s found, %s is loaded first", others, LibraryName(conf.OS), dir),
+			"run oraicwinconfig doctor --clients to list them; uninstall any that are unused")
+	}
+	return check.Pass("no other copies of " + LibraryName(conf.OS) + " found")
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package doctor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// systemDirs returns the directories searched before the managed library path;
+// the dynamic linker searches LD_LIBRARY_PATH first, so there are none
+func systemDirs() []string {
+	return nil
+}
+
+// registryHomes returns nothing; there is no registry outside Windows
+func registryHomes(ctx context.Context) []string {
+	return nil
+}
+
+// commonLocations returns glob patterns of directories where clients are often installed
+func commonLocations() []string {
+	patterns := []string{
+		"/usr/lib/oracle/*/client64/lib",
+		"/usr/lib/oracle/*/client/lib",
+		"/opt/oracle/instantclient*",
+		"/opt/oracle/product/*/*/lib",
+		"/u01/app/oracle/product/*/*/lib",
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		patterns = append(patterns, filepath.Join(home, "oracle", "instantclient*"), filepath.Join(home, "lib"))
+	}
+	return patterns
+}
@@ -0,0 +1,56 @@
+package doctor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+)
+
+// systemDirs returns the directories Windows searches for DLLs before PATH
+func systemDirs() []string {
+	root := os.Getenv("SystemRoot")
+	if root == "" {
+		root = `C:\Windows`
+	}
+	return []string{filepath.Join(root, "System32"), filepath.Join(root, "SysWOW64"), root}
+}
+
+// registryHomes returns the ORACLE_HOME values registered by Oracle installers,
+// for both 64-bit and 32-bit homes
+func registryHomes(ctx context.Context) []string {
+	script := `Get-ChildItem 'HKLM:\SOFTWARE\ORACLE','HKLM:\SOFTWARE\WOW6432Node\ORACLE' -ErrorAction SilentlyContinue | ` +
+		`ForEach-Object { $_.GetValue('ORACLE_HOME') } | Where-Object { $_ }`
+	out, err := pwsh.Run(ctx, script)
+	if err != nil {
+		return nil
+	}
+	var homes []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if home := strings.TrimSpace(line); home != "" {
+			homes = append(homes, home)
+		}
+	}
+	return homes
+}
+
+// commonLocations returns glob patterns of directories where clients are often installed
+func commonLocations() []string {
+	var patterns []string
+	for _, base := range []string{`C:\oracle`, `C:\app\*\product\*`, os.Getenv("ProgramFiles"), os.Getenv("ProgramFiles(x86)")} {
+		if base == "" {
+			continue
+		}
+		patterns = append(patterns,
+			filepath.Join(base, "*"),
+			filepath.Join(base, "*", "bin"),
+			filepath.Join(base, "*", "instantclient*"),
+		)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		patterns = append(patterns, filepath.Join(home, "OraClient", "instantclient*"))
+	}
+	return append(patterns, `C:\OraClient\instantclient*`)
+}
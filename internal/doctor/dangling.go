@@ -0,0 +1,126 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+)
+
+// Dangling lists variables pointing at directories that no longer exist,
+// and the valid installs they could be re-pointed at
+type Dangling struct {
+	LibVar     string
+	Vars       map[string]string // Missing directory by variable name
+	Candidates []string          // Existing client directories for the same architecture
+}
+
+// Found reports whether any variable is dangling
+func (d Dangling) Found() bool {
+	return len(d.Vars) > 0
+}
+
+// FindDangling checks the client variable and TNS_ADMIN, and when either points at
+// a missing directory, looks for valid installs in the manifest and on disk
+func FindDangling(ctx context.Context, conf config.InstallConfig, m env.Manager) Dangling {
+	m = m.WithContext(ctx)
+	d := Dangling{LibVar: conf.LibVar(), Vars: make(map[string]string)}
+	for _, name := range []string{d.LibVar, "TNS_ADMIN"} {
+		if dir, err := m.GetEnvVar(name); err == nil && !isDir(dir) {
+			d.Vars[name] = dir
+		}
+	}
+	if !d.Found() {
+		return d
+	}
+
+	seen := make(map[string]bool)
+	add := func(dir string) {
+		if key := env.PathKey(dir); !seen[key] && isDir(dir) {
+			seen[key] = true
+			d.Candidates = append(d.Candidates, dir)
+		}
+	}
+	if man, err := manifest.Load(m.Scope()); err == nil {
+		if c, ok := man.Client(d.LibVar); ok {
+			add(c.ClientDir)
+		}
+	}
+	for _, p := range FindProviders(ctx, conf.OS, m) {
+		if p.Arch == conf.Arch {
+			add(p.Dir)
+		}
+	}
+	return d
+}
+
+// Clear removes the dangling variables, and the client's stale PATH entry
+func (d Dangling) Clear(m env.Manager) error {
+	for name, dir := range d.Vars {
+		fmt.Printf("removing %s=%s\n", name, dir)
+		if err := m.RemoveEnvVar(name); err != nil {
+			return err
+		}
+		if name == d.LibVar {
+			if err := m.RemoveFromPath(dir); err != nil {
+				return err
+			}
+		}
+	}
+	if _, ok := d.Vars[d.LibVar]; ok {
+		return manifest.Record(m.Scope(), func(man *manifest.Manifest) { man.Remove(d.LibVar) })
+	}
+	return nil
+}
+
+// Repoint points the dangling variables at the client in dir, replacing
+// the stale PATH entry; TNS_ADMIN moves to dir's network/admin directory
+func (d Dangling) Repoint(m env.Manager, dir string) error {
+	for name, old := range d.Vars {
+		value := dir
+		if name == "TNS_ADMIN" {
+			value = filepath.Join(dir, "network", "admin")
+			if err := os.MkdirAll(value, 0755); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("setting %s=%s (was %s)\n", name, value, old)
+		if err := m.SetEnvVar(name, value); err != nil {
+			return err
+		}
+		if name == d.LibVar {
+			if err := m.RemoveFromPath(old); err != nil {
+				return err
+			}
+			if err := m.AppendToPath(dir); err != nil {
+				return err
+			}
+		}
+	}
+	return manifest.Record(m.Scope(), func(man *manifest.Manifest) {
+		c, ok := man.Client(d.LibVar)
+		if !ok || c.Vars == nil {
+			return
+		}
+		for name := range d.Vars {
+			c.Vars[name] = dir
+			if name == "TNS_ADMIN" {
+				c.Vars[name] = filepath.Join(dir, "network", "admin")
+			}
+		}
+		if _, ok := d.Vars[d.LibVar]; ok {
+			c.ClientDir, c.InstallPath, c.Path = dir, filepath.Dir(dir), []string{dir}
+		}
+		man.Put(c)
+	})
+}
+
+// isDir reports whether dir is an existing directory
+func isDir(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,246 @@
+package doctor
+
+import (
+	"context"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mghoff/oraicwinconfig/internal/check"
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/preflight"
+	"github.com/mghoff/oraicwinconfig/internal/tns"
+)
+
+// Checks returns the diagnostics for the client described by conf in the manager's scope
+func Checks(conf *config.InstallConfig, env env.Manager) []check.Check {
+	c := *conf
+	return []check.Check{
+		{Name: c.LibVar(), Run: func(ctx context.Context) check.Result { return checkLibVar(ctx, c, env) }},
+		{Name: "TNS_ADMIN", Run: func(ctx context.Context) check.Result { return checkTNSAdmin(ctx, env) }},
+		{Name: "tnsnames.ora", Run: func(ctx context.Context) check.Result { return checkTNSNames(ctx, env) }},
+		WalletCheck(conf, env),
+		{Name: "PATH", Run: func(ctx context.Context) check.Result { return checkPath(ctx, c, env) }},
+		{Name: "client library", Run: func(ctx context.Context) check.Result { return checkLibrary(ctx, c, env) }},
+		{Name: "other clients", Run: func(ctx context.Context) check.Result { return checkConflicts(ctx, c, env) }},
+		{Name: "VC++ runtime", Run: func(ctx context.Context) check.Result { return checkVCRuntime(c) }},
+		preflight.ConnectivityCheck(c),
+	}
+}
+
+// LibraryName returns the file name of the main client library on the given OS
+func LibraryName(goos string) string {
+	switch goos {
+	case "windows":
+		return "oci.dll"
+	case "darwin":
+		return "libclntsh.dylib"
+	default:
+		return "libclntsh.so"
+	}
+}
+
+// SQLPlusName returns the file name of the SQL*Plus executable on the given OS
+func SQLPlusName(goos string) string {
+	if goos == "windows" {
+		return "sqlplus.exe"
+	}
+	return "sqlplus"
+}
+
+// checkLibVar verifies the client variable is set and points at an existing directory
+func checkLibVar(ctx context.Context, conf config.InstallConfig, env env.Manager) check.Result {
+	libVar := conf.LibVar()
+	dir, err := env.WithContext(ctx).GetEnvVar(libVar)
+	if err != nil {
+		return check.Fail(fmt.Sprintf("%s is not set in %s scope", libVar, env.Scope()), "run oraicwinconfig to install and configure the client")
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return check.Fail(fmt.Sprintf("%s points to missing directory %s", libVar, dir), "run oraicwinconfig doctor --fix-dangling to clear it or re-point it at another install")
+	}
+	return check.Pass(dir)
+}
+
+// checkTNSAdmin verifies TNS_ADMIN is set and points at an existing directory
+func checkTNSAdmin(ctx context.Context, env env.Manager) check.Result {
+	dir, err := env.WithContext(ctx).GetEnvVar("TNS_ADMIN")
+	if err != nil {
+		return check.Warn(fmt.Sprintf("TNS_ADMIN is not set in %s scope", env.Scope()), "set TNS_ADMIN to the directory holding tnsnames.ora, usually network/admin under the client directory")
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return check.Fail(fmt.Sprintf("TNS_ADMIN points to missing directory %s", dir), "run oraicwinconfig doctor --fix-dangling, or create the directory")
+	}
+	return check.Pass(dir)
+}
+
+// checkTNSNames verifies tnsnames.ora exists in TNS_ADMIN and parses
+func checkTNSNames(ctx context.Context, env env.Manager) check.Result {
+	dir, err := env.WithContext(ctx).GetEnvVar("TNS_ADMIN")
+	if err != nil {
+		return check.Warn("skipped, TNS_ADMIN is not set", "")
+	}
+	path := filepath.Join(dir, "tnsnames.ora")
+	entries, err := tns.ParseFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return check.Warn(fmt.Sprintf("%s does not exist", path), "copy your tnsnames.ora into TNS_ADMIN, or connect with EZCONNECT strings")
+	}
+	var syntaxErr *tns.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return check.Fail(fmt.Sprintf("%s: %v", path, err), "fix the syntax error; every entry needs the form ALIAS = (DESCRIPTION = ...) with balanced parentheses")
+	} else if err != nil {
+		return check.Fail(fmt.Sprintf("reading %s: %v", path, err), "check the file's permissions")
+	}
+	if len(entries) == 0 {
+		return check.Warn(fmt.Sprintf("%s has no entries", path), "add your net service names to tnsnames.ora")
+	}
+	return check.Pass(fmt.Sprintf("%d entries in %s", len(entries), path))
+}
+
+// checkPath verifies the client directory is on PATH and that no other copy of
+// the client library for the same architecture comes before it
+func checkPath(ctx context.Context, conf config.InstallConfig, env env.Manager) check.Result {
+	dir, err := env.WithContext(ctx).GetEnvVar(conf.LibVar())
+	if err != nil {
+		return check.Warn("skipped, "+conf.LibVar()+" is not set", "")
+	}
+	conflict, found, err := FindPathConflict(ctx, conf, env, dir)
+	if err != nil {
+		return check.Fail(err.Error(), "add "+dir+" to PATH, or rerun oraicwinconfig")
+	}
+	if found {
+		return check.Fail(fmt.Sprintf("%s in %s comes before %s on PATH", LibraryName(conf.OS), conflict.Shadow.Dir, dir),
+			"run oraicwinconfig doctor --fix-path to move it ahead, or uninstall the other client")
+	}
+	return check.Pass(fmt.Sprintf("%s is the first directory on PATH providing %s", dir, LibraryName(conf.OS)))
+}
+
+// PathEntry is a directory on the persistent PATH and the scope it is set in
+type PathEntry struct {
+	Dir   string
+	Scope env.Scope
+}
+
+// SearchPath returns the directories of the persistent PATH in the order the
+// OS searches them: Machine before User entries on Windows, and the user's
+// profile entries, which are prepended last, before the machine's elsewhere
+func SearchPath(ctx context.Context, goos string, m env.Manager) []string {
+	var dirs []string
+	for _, e := range searchPathEntries(ctx, goos, m) {
+		dirs = append(dirs, e.Dir)
+	}
+	return dirs
+}
+
+// FindOnPath returns the first directory of the persistent PATH holding the
+// file name, the one the OS runs it from
+func FindOnPath(ctx context.Context, goos string, m env.Manager, name string) (string, bool) {
+	for _, dir := range SearchPath(ctx, goos, m) {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// searchPathEntries returns the entries of the persistent PATH in search order
+func searchPathEntries(ctx context.Context, goos string, m env.Manager) []PathEntry {
+	scopes := []env.Scope{env.ScopeMachine, env.ScopeUser}
+	if goos != "windows" {
+		scopes = []env.Scope{env.ScopeUser, env.ScopeMachine}
+	}
+	var entries []PathEntry
+	for _, s := range scopes {
+		if p, err := m.WithScope(s).WithContext(ctx).GetEnvVar("PATH"); err == nil {
+			for _, d := range filepath.SplitList(p) {
+				if d != "" {
+					entries = append(entries, PathEntry{Dir: d, Scope: s})
+				}
+			}
+		}
+	}
+	return entries
+}
+
+// checkLibrary verifies the client library is present and built for the expected architecture
+func checkLibrary(ctx context.Context, conf config.InstallConfig, env env.Manager) check.Result {
+	dir, err := env.WithContext(ctx).GetEnvVar(conf.LibVar())
+	if err != nil {
+		return check.Warn("skipped, "+conf.LibVar()+" is not set", "")
+	}
+	path := filepath.Join(dir, LibraryName(conf.OS))
+	if _, err := os.Stat(path); err != nil {
+		return check.Fail(fmt.Sprintf("%s not found", path), "reinstall the client; the directory is incomplete")
+	}
+	arch, err := LibraryArch(path)
+	if err != nil {
+		return check.Warn(fmt.Sprintf("could not read %s: %v", path, err), "reinstall the client if it fails to load")
+	}
+	if arch != conf.Arch {
+		return check.Fail(fmt.Sprintf("%s is built for %s, expected %s", path, arch, conf.Arch), "install the client matching your applications, e.g. with --arch "+conf.Arch)
+	}
+	return check.Pass(fmt.Sprintf("%s (%s)", path, arch))
+}
+
+// LibraryArch returns the GOARCH value of the machine type a PE, ELF or Mach-O binary is built for
+func LibraryArch(path string) (string, error) {
+	if f, err := pe.Open(path); err == nil {
+		defer f.Close()
+		switch f.Machine {
+		case pe.IMAGE_FILE_MACHINE_AMD64:
+			return "amd64", nil
+		case pe.IMAGE_FILE_MACHINE_I386:
+			return "386", nil
+		case pe.IMAGE_FILE_MACHINE_ARM64:
+			return "arm64", nil
+		}
+		return "", fmt.Errorf("unknown PE machine type %#x", f.Machine)
+	}
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		switch f.Machine {
+		case elf.EM_X86_64:
+			return "amd64", nil
+		case elf.EM_386:
+			return "386", nil
+		case elf.EM_AARCH64:
+			return "arm64", nil
+		}
+		return "", fmt.Errorf("unknown ELF machine type %s", f.Machine)
+	}
+	if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		switch f.Cpu {
+		case macho.CpuAmd64:
+			return "amd64", nil
+		case macho.CpuArm64:
+			return "arm64", nil
+		}
+		return "", fmt.Errorf("unknown Mach-O CPU type %s", f.Cpu)
+	}
+	return "", fmt.Errorf("not a recognised library format")
+}
+
+// checkVCRuntime verifies the Visual C++ runtime the Windows client links against is installed
+func checkVCRuntime(conf config.InstallConfig) check.Result {
+	if conf.OS != "windows" {
+		return check.Pass("not required on " + conf.OS)
+	}
+	sysDir := "System32"
+	if conf.Arch == "386" && conf.HostArch != "386" {
+		sysDir = "SysWOW64"
+	}
+	root := os.Getenv("SystemRoot")
+	if root == "" {
+		root = `C:\Windows`
+	}
+	path := filepath.Join(root, sysDir, "vcruntime140.dll")
+	if _, err := os.Stat(path); err != nil {
+		return check.Fail(fmt.Sprintf("%s not found", path), "install the latest Microsoft Visual C++ Redistributable for Visual Studio 2015-2022 ("+conf.Arch+")")
+	}
+	return check.Pass(path)
+}
@@ -0,0 +1,43 @@
+package doctor_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/doctor"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/testsupport"
+)
+
+func TestFindOnPath(t *testing.T) {
+	root := t.TempDir()
+	machine, user := filepath.Join(root, "machine"), filepath.Join(root, "user")
+	name := doctor.SQLPlusName("windows")
+	m := testsupport.NewEnv(env.ScopeUser, root)
+	if err := m.AppendToPath(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WithScope(env.ScopeMachine).AppendToPath(machine); err != nil {
+		t.Fatal(err)
+	}
+	if dir, ok := doctor.FindOnPath(context.Background(), "windows", m, name); ok {
+		t.Errorf("found %s in %s before it existed", name, dir)
+	}
+
+	for _, dir := range []string{machine, user} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Windows searches the Machine PATH first, other systems the user's
+	for goos, want := range map[string]string{"windows": machine, "linux": user} {
+		if dir, ok := doctor.FindOnPath(context.Background(), goos, m, name); !ok || dir != want {
+			t.Errorf("%s: found in %q, want %q", goos, dir, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/input"
+)
+
+// PathConflict is a directory providing the client library that the loader
+// finds before the managed client directory
+type PathConflict struct {
+	Managed PathEntry // The managed client directory, with the scope it is on PATH in
+	Shadow  PathEntry // The directory found first
+}
+
+// FindPathConflict returns the first directory on the combined PATH ahead of dir
+// that provides a client library for conf.Arch. Copies built for another
+// architecture are ignored, since those applications load a different client.
+// An error is returned if dir is not on PATH at all.
+func FindPathConflict(ctx context.Context, conf config.InstallConfig, m env.Manager, dir string) (PathConflict, bool, error) {
+	lib := LibraryName(conf.OS)
+	var shadow *PathEntry
+	for _, e := range searchPathEntries(ctx, conf.OS, m) {
+		if env.SamePath(e.Dir, dir) {
+			if shadow == nil {
+				return PathConflict{}, false, nil
+			}
+			return PathConflict{Managed: e, Shadow: *shadow}, true, nil
+		}
+		if shadow != nil {
+			continue
+		}
+		path := filepath.Join(e.Dir, lib)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if arch, err := LibraryArch(path); err == nil && arch != conf.Arch {
+			continue
+		}
+		e := e
+		shadow = &e
+	}
+	return PathConflict{}, false, errs.HandleError(fmt.Errorf("%s is not on PATH", dir), errs.ErrorTypeValidation, "analysing PATH")
+}
+
+// Explain describes what FixPathConflict will change
+func (c PathConflict) Explain() string {
+	if c.Managed.Scope == c.Shadow.Scope {
+		return fmt.Sprintf("%s will be moved ahead of %s in the %s PATH; no entries are added or removed", c.Managed.Dir, c.Shadow.Dir, c.Shadow.Scope)
+	}
+	msg := fmt.Sprintf("%s is in the %s PATH, which is searched before the %s PATH holding %s. "+
+		"%s will be added to the %s PATH ahead of %s; the %s PATH is left unchanged",
+		c.Shadow.Dir, c.Shadow.Scope, c.Managed.Scope, c.Managed.Dir,
+		c.Managed.Dir, c.Shadow.Scope, c.Shadow.Dir, c.Managed.Scope)
+	if c.Shadow.Scope == env.ScopeMachine {
+		msg += " (requires administrator rights)"
+	}
+	return msg
+}
+
+// FixPathConflict prioritises the managed directory over the shadowing one,
+// in the scope of the PATH holding the shadowing directory
+func FixPathConflict(m env.Manager, c PathConflict) error {
+	target := m.WithScope(c.Shadow.Scope)
+	if c.Managed.Scope != c.Shadow.Scope {
+		if err := target.AppendToPath(c.Managed.Dir); err != nil {
+			return err
+		}
+	}
+	return target.MovePathBefore(c.Managed.Dir, c.Shadow.Dir)
+}
+
+// OfferPathFix explains how another client shadows dir on PATH and,
+// once confirmed, moves dir ahead of it
+func OfferPathFix(ctx context.Context, conf config.InstallConfig, m env.Manager, dir string) error {
+	conflict, found, err := FindPathConflict(ctx, conf, m, dir)
+	if err != nil || !found {
+		return err
+	}
+	fmt.Printf("\n%s in %s comes before %s on PATH, so applications will load that client instead.\n",
+		LibraryName(conf.OS), conflict.Shadow.Dir, dir)
+	fmt.Println(conflict.Explain() + ".")
+	if ok, err := input.Confirmation("fix-path", "Reorder PATH?", false); err != nil {
+		return err
+	} else if !ok {
+		fmt.Println("PATH left unchanged")
+		return nil
+	}
+	if err := FixPathConflict(m, conflict); err != nil {
+		return err
+	}
+	fmt.Printf("%s now comes first on PATH\n", dir)
+	return nil
+}
@@ -0,0 +1,60 @@
+package doctor_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/doctor"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/input"
+	"github.com/mghoff/oraicwinconfig/internal/testsupport"
+)
+
+func TestOfferPathFixSilent(t *testing.T) {
+	conf := config.New()
+	root := t.TempDir()
+	shadow, managed := filepath.Join(root, "other"), filepath.Join(root, "managed")
+	// Another client's library, found first on PATH
+	if err := os.MkdirAll(shadow, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(shadow, doctor.LibraryName(conf.OS)), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := testsupport.NewEnv(env.ScopeUser, root)
+	scope := env.ScopeUser
+	if conf.OS == "windows" {
+		// Windows searches the Machine PATH first
+		scope = env.ScopeMachine
+	}
+	for _, dir := range []string{shadow, managed} {
+		if err := m.WithScope(scope).AppendToPath(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := m.Vars(scope)["PATH"]
+	if _, found, err := doctor.FindPathConflict(context.Background(), *conf, m, managed); err != nil || !found {
+		t.Fatalf("no conflict to fix: %t, %v", found, err)
+	}
+
+	input.Silent = true
+	t.Cleanup(func() { input.Silent, input.AssumeYes = false, false })
+	if err := doctor.OfferPathFix(context.Background(), *conf, m, managed); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Vars(scope)["PATH"]; got != before {
+		t.Errorf("--silent changed PATH from %q to %q", before, got)
+	}
+
+	// --yes accepts the fix
+	input.AssumeYes = true
+	if err := doctor.OfferPathFix(context.Background(), *conf, m, managed); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err := doctor.FindPathConflict(context.Background(), *conf, m, managed); err != nil || found {
+		t.Errorf("PATH still shadowed after --yes: %t, %v", found, err)
+	}
+}
@@ -0,0 +1,64 @@
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+)
+
+// TLSProbe is what a TLS handshake with a TCPS listener revealed
+type TLSProbe struct {
+	Chain             []*x509.Certificate // Certificates the server presented, its own first
+	Version           uint16
+	CipherSuite       uint16
+	ClientCertRequest bool  // The server asked for a client certificate, as with mutual TLS
+	VerifyErr         error // Why the chain is not trusted by the roots given, if it is not
+}
+
+// ServerDN returns the distinguished name of the server certificate, in
+// the form SSL_SERVER_CERT_DN takes
+func (p TLSProbe) ServerDN() string {
+	if len(p.Chain) == 0 {
+		return ""
+	}
+	return p.Chain[0].Subject.String()
+}
+
+// ProbeTLS performs a TLS handshake with the listener at addr within
+// timeout, presenting client if the server asks for a certificate, and
+// checks the chain it presents against roots. The host name is not
+// checked, as Oracle clients match the DN instead when asked to.
+func ProbeTLS(ctx context.Context, addr string, roots *x509.CertPool, client *tls.Certificate, timeout time.Duration) (TLSProbe, error) {
+	var p TLSProbe
+	conf := &tls.Config{
+		// The chain is verified below, against the wallet's certificates
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			p.ClientCertRequest = true
+			if client != nil {
+				return client, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	d := tls.Dialer{Config: conf}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return p, err
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	p.Chain, p.Version, p.CipherSuite = state.PeerCertificates, state.Version, state.CipherSuite
+	if len(p.Chain) > 0 {
+		intermediates := x509.NewCertPool()
+		for _, c := range p.Chain[1:] {
+			intermediates.AddCert(c)
+		}
+		_, p.VerifyErr = p.Chain[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+	}
+	return p, nil
+}
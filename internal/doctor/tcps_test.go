@@ -0,0 +1,143 @@
+package doctor_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/doctor"
+	"github.com/mghoff/oraicwinconfig/internal/tns"
+)
+
+// issue returns a certificate for name signed by parent, or self-signed
+// when parent is nil, with its key
+func issue(t *testing.T, name string, ca bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name, Organization: []string{"Example"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  ca,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// listen starts a TLS listener presenting a certificate for db signed by
+// ca, asking for a client certificate when mutual is set
+func listen(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, mutual bool) string {
+	t.Helper()
+	cert, key := issue(t, "db.example.com", false, ca, caKey)
+	conf := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{cert.Raw}, PrivateKey: key}}}
+	if mutual {
+		conf.ClientAuth = tls.RequestClientCert
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestProbeTLS(t *testing.T) {
+	ca, caKey := issue(t, "Example CA", true, nil, nil)
+	other, _ := issue(t, "Other CA", true, nil, nil)
+
+	// A PEM wallet trusting the CA and holding a client certificate
+	dir := t.TempDir()
+	client, clientKey := issue(t, "analyst", false, ca, caKey)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pemData []byte
+	for _, b := range []*pem.Block{{Type: "CERTIFICATE", Bytes: ca.Raw}, {Type: "CERTIFICATE", Bytes: client.Raw}, {Type: "PRIVATE KEY", Bytes: keyDER}} {
+		pemData = append(pemData, pem.EncodeToMemory(b)...)
+	}
+	for name, data := range map[string][]byte{"ewallet.pem": pemData, "cwallet.sso": nil} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w, err := tns.ReadWallet(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.AutoLogin || !w.PEM || w.PKCS12 || len(w.Certs) != 2 || w.Client == nil || w.Client.Leaf.Subject.CommonName != "analyst" {
+		t.Fatalf("wallet read as %+v", w)
+	}
+
+	addr := listen(t, ca, caKey, false)
+	p, err := doctor.ProbeTLS(context.Background(), addr, w.Roots(), w.Client, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.VerifyErr != nil || p.ClientCertRequest || p.ServerDN() != "CN=db.example.com,O=Example" {
+		t.Errorf("probe = %+v, DN %s", p, p.ServerDN())
+	}
+
+	untrusted := x509.NewCertPool()
+	untrusted.AddCert(other)
+	if p, err = doctor.ProbeTLS(context.Background(), addr, untrusted, nil, 5*time.Second); err != nil || p.VerifyErr == nil {
+		t.Errorf("certificate from an untrusted CA accepted: %v", err)
+	}
+
+	if p, err = doctor.ProbeTLS(context.Background(), listen(t, ca, caKey, true), w.Roots(), w.Client, 5*time.Second); err != nil || !p.ClientCertRequest {
+		t.Errorf("client certificate request not noticed: %+v, %v", p, err)
+	}
+
+	if _, err := doctor.ProbeTLS(context.Background(), closedAddr(t), w.Roots(), nil, time.Second); err == nil {
+		t.Error("handshake with a closed port succeeded")
+	}
+}
+
+// closedAddr returns an address nothing listens on
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
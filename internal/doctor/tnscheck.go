@@ -0,0 +1,180 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+)
+
+// AliasCheck is the outcome of checking one entry of tnsnames.ora: whether
+// any of its addresses accepts connections and, when asked, whether the
+// client can log on through it
+type AliasCheck struct {
+	Alias     string        `json:"alias"`
+	Status    string        `json:"status"`    // reachable, unreachable, dns failure, invalid, skipped, connected or connect failed
+	Reachable int           `json:"reachable"` // Addresses accepting connections
+	Addresses int           `json:"addresses"`
+	Latency   time.Duration `json:"latency_ns"` // Quickest connection to any address, when reachable
+	Detail    string        `json:"detail,omitempty"`
+	Endpoints []Endpoint    `json:"endpoints"`
+}
+
+// Failed reports whether the alias cannot be used from this machine
+func (c AliasCheck) Failed() bool {
+	switch c.Status {
+	case "reachable", "connected", "skipped":
+		return false
+	}
+	return true
+}
+
+// SummarizeEndpoints groups the endpoints returned by ProbeEntries by alias
+func SummarizeEndpoints(endpoints []Endpoint) []AliasCheck {
+	var checks []AliasCheck
+	for _, ep := range endpoints {
+		if len(checks) == 0 || checks[len(checks)-1].Alias != ep.Alias {
+			checks = append(checks, AliasCheck{Alias: ep.Alias})
+		}
+		c := &checks[len(checks)-1]
+		c.Endpoints = append(c.Endpoints, ep)
+		if ep.Status != "invalid" {
+			c.Addresses++
+		}
+		if ep.Reachable() {
+			c.Reachable++
+			if c.Latency == 0 || ep.Latency < c.Latency {
+				c.Latency = ep.Latency
+			}
+		}
+	}
+
+	for i := range checks {
+		c := &checks[i]
+		skipped := 0
+		for _, ep := range c.Endpoints {
+			if ep.Status == "skipped" {
+				skipped++
+			}
+		}
+		switch {
+		case c.Reachable > 0:
+			c.Status = "reachable"
+		case skipped == len(c.Endpoints):
+			c.Status, c.Detail = "skipped", c.Endpoints[0].Detail
+		default:
+			// Report the first failure
+			for _, ep := range c.Endpoints {
+				if ep.Status != "skipped" {
+					c.Status, c.Detail = ep.Status, ep.Detail
+					break
+				}
+			}
+		}
+	}
+	return checks
+}
+
+// Login is how ConnectAliases logs on: through the wallet configured in
+// sqlnet.ora when User is empty, or as User with Password
+type Login struct {
+	User     string
+	Password string
+}
+
+// ConnectAliases logs on through every reachable alias with SQL*Plus,
+// several at once, marking each connected or connect failed. Logons are
+// bounded by the command timeout.
+func ConnectAliases(ctx context.Context, checks []AliasCheck, login Login) error {
+	sqlplus, err := exec.LookPath("sqlplus")
+	if err != nil {
+		return fmt.Errorf("testing logons needs SQL*Plus, which was not found on PATH: %w", err)
+	}
+
+	sem := make(chan struct{}, maxProbes)
+	var wg sync.WaitGroup
+	for i := range checks {
+		if checks[i].Status != "reachable" {
+			continue
+		}
+		wg.Add(1)
+		go func(c *AliasCheck) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// Entries may have several aliases; any of them will do
+			alias := strings.TrimSpace(strings.Split(c.Alias, ",")[0])
+			connect, input := "/@"+alias, "exit\n"
+			if login.User != "" {
+				connect, input = login.User+"@"+alias, login.Password+"\nexit\n"
+			}
+			out, err := pwsh.CombinedOutputInput(ctx, input, sqlplus, "-L", "-S", connect)
+			if err == nil && !strings.Contains(string(out), "ERROR") {
+				c.Status = "connected"
+				return
+			}
+			c.Status, c.Detail = "connect failed", sqlplusError(string(out), err)
+		}(&checks[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+// sqlplusError picks the Oracle error out of SQL*Plus output, falling back on err
+func sqlplusError(out string, err error) string {
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		for _, prefix := range []string{"ORA-", "TNS-", "SP2-"} {
+			if strings.HasPrefix(line, prefix) {
+				return line
+			}
+		}
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return strings.TrimSpace(out)
+}
+
+// ReportAliases writes the checks as a table and returns how many failed
+func ReportAliases(w io.Writer, checks []AliasCheck) int {
+	failed := 0
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ALIAS\tSTATUS\tADDRESSES\tTIME\tDETAIL")
+	for _, c := range checks {
+		latency := "-"
+		if c.Reachable > 0 {
+			latency = c.Latency.Round(time.Millisecond).String()
+		}
+		if c.Failed() {
+			failed++
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%d/%d\t%s\t%s\n", c.Alias, c.Status, c.Reachable, c.Addresses, latency, c.Detail)
+	}
+	tw.Flush()
+	return failed
+}
+
+// WriteAliasesJSON writes the checks as an indented JSON array and returns how many failed
+func WriteAliasesJSON(w io.Writer, checks []AliasCheck) (int, error) {
+	failed := 0
+	for _, c := range checks {
+		if c.Failed() {
+			failed++
+		}
+	}
+	if checks == nil {
+		checks = []AliasCheck{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return failed, enc.Encode(checks)
+}
@@ -0,0 +1,115 @@
+package doctor_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/doctor"
+	"github.com/mghoff/oraicwinconfig/internal/tns"
+)
+
+// fakeSQLPlus logs on as scott with the password tiger, or through the
+// wallet for the alias WALLET, and fails like SQL*Plus otherwise
+const fakeSQLPlus = `#!/bin/sh
+read password
+case "$3:$password" in
+  /@WALLET:exit|scott@*:tiger) exit 0 ;;
+esac
+echo "ERROR:"
+echo "ORA-01017: invalid username/password; logon denied"
+exit 1
+`
+
+// checkEntries returns entries for a listening and a closed local port
+func checkEntries(t *testing.T) []tns.Entry {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().(*net.TCPAddr)
+	closed.Close()
+	open := ln.Addr().(*net.TCPAddr)
+
+	ora := fmt.Sprintf(`WALLET, UP = (DESCRIPTION = (ADDRESS_LIST = (ADDRESS = (HOST = 127.0.0.1)(PORT = %d))(ADDRESS = (HOST = 127.0.0.1)(PORT = %d)))(CONNECT_DATA = (SERVICE_NAME = up)))
+DOWN = (DESCRIPTION = (ADDRESS = (HOST = 127.0.0.1)(PORT = %d))(CONNECT_DATA = (SERVICE_NAME = down)))
+LOCAL = (DESCRIPTION = (ADDRESS = (PROTOCOL = IPC)(KEY = db))(CONNECT_DATA = (SERVICE_NAME = local)))
+`, closedAddr.Port, open.Port, closedAddr.Port)
+	entries, err := tns.Parse(strings.NewReader(ora))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestCheckAll(t *testing.T) {
+	checks := doctor.SummarizeEndpoints(doctor.ProbeEntries(context.Background(), checkEntries(t), 2*time.Second))
+	var got []string
+	for _, c := range checks {
+		got = append(got, fmt.Sprintf("%s %s %d/%d", c.Alias, c.Status, c.Reachable, c.Addresses))
+	}
+	want := []string{"WALLET, UP reachable 1/2", "DOWN unreachable 0/1", "LOCAL skipped 0/1"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("checks:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	var buf bytes.Buffer
+	failed, err := doctor.WriteAliasesJSON(&buf, checks)
+	if err != nil || failed != 1 {
+		t.Errorf("failed = %d, %v; want 1", failed, err)
+	}
+	var decoded []doctor.AliasCheck
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 3 || len(decoded[0].Endpoints) != 2 {
+		t.Errorf("JSON output does not round-trip: %v\n%s", err, buf.String())
+	}
+}
+
+func TestConnectAliases(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake sqlplus is a shell script")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sqlplus"), []byte(fakeSQLPlus), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	entries := checkEntries(t)
+
+	for _, tc := range []struct {
+		login doctor.Login
+		want  string
+	}{
+		{doctor.Login{}, "connected"},
+		{doctor.Login{User: "scott", Password: "tiger"}, "connected"},
+		{doctor.Login{User: "scott", Password: "lion"}, "connect failed: ORA-01017: invalid username/password; logon denied"},
+	} {
+		checks := doctor.SummarizeEndpoints(doctor.ProbeEntries(context.Background(), entries, 2*time.Second))
+		if err := doctor.ConnectAliases(context.Background(), checks, tc.login); err != nil {
+			t.Fatal(err)
+		}
+		got := checks[0].Status
+		if checks[0].Detail != "" {
+			got += ": " + checks[0].Detail
+		}
+		if got != tc.want {
+			t.Errorf("%+v: %s, want %s", tc.login, got, tc.want)
+		}
+		if checks[1].Status != "unreachable" {
+			t.Errorf("logon attempted through unreachable alias: %s", checks[1].Status)
+		}
+	}
+}
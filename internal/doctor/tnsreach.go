@@ -0,0 +1,116 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/tns"
+)
+
+// maxProbes bounds the number of endpoints probed at once
+const maxProbes = 16
+
+// Endpoint is the reachability of one address of a net service name
+type Endpoint struct {
+	Alias   string        `json:"-"`
+	Address tns.Address   `json:"address"`
+	Status  string        `json:"status"` // reachable, dns failure, unreachable, skipped or invalid
+	Detail  string        `json:"detail,omitempty"`
+	Latency time.Duration `json:"latency_ns"` // Time taken to connect, when reachable
+}
+
+// Reachable reports whether the endpoint accepted a connection
+func (e Endpoint) Reachable() bool {
+	return e.Status == "reachable"
+}
+
+// ProbeEntries resolves and connects to every address of the entries concurrently,
+// giving each connection attempt up to timeout
+func ProbeEntries(ctx context.Context, entries []tns.Entry, timeout time.Duration) []Endpoint {
+	var endpoints []Endpoint
+	for _, e := range entries {
+		alias := strings.Join(e.Aliases, ", ")
+		addrs, err := e.Addresses()
+		switch {
+		case err != nil:
+			endpoints = append(endpoints, Endpoint{Alias: alias, Status: "invalid", Detail: err.Error()})
+		case len(addrs) == 0:
+			endpoints = append(endpoints, Endpoint{Alias: alias, Status: "invalid", Detail: "no ADDRESS with a HOST"})
+		}
+		for _, a := range addrs {
+			endpoints = append(endpoints, Endpoint{Alias: alias, Address: a})
+		}
+	}
+
+	sem := make(chan struct{}, maxProbes)
+	var wg sync.WaitGroup
+	for i := range endpoints {
+		if endpoints[i].Status != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(ep *Endpoint) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			probeEndpoint(ctx, ep, timeout)
+		}(&endpoints[i])
+	}
+	wg.Wait()
+	return endpoints
+}
+
+// probeEndpoint resolves the endpoint's host and opens a TCP connection to it
+func probeEndpoint(ctx context.Context, ep *Endpoint, timeout time.Duration) {
+	if p := ep.Address.Protocol; p != "TCP" && p != "TCPS" {
+		ep.Status, ep.Detail = "skipped", "protocol "+p+" is local to the database host"
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ips, err := net.DefaultResolver.LookupHost(ctx, ep.Address.Host)
+	if err != nil {
+		ep.Status, ep.Detail = "dns failure", err.Error()
+		return
+	}
+	start := time.Now()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ep.Address.Host, ep.Address.Port))
+	if err != nil {
+		ep.Status, ep.Detail = "unreachable", err.Error()
+		return
+	}
+	conn.Close()
+	ep.Latency = time.Since(start)
+	ep.Status, ep.Detail = "reachable", strings.Join(ips, " ")
+}
+
+// ReportEndpoints writes the endpoints as a table and returns how many were unreachable
+func ReportEndpoints(w io.Writer, endpoints []Endpoint) int {
+	failed := 0
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ALIAS\tADDRESS\tSTATUS\tTIME\tDETAIL")
+	for _, ep := range endpoints {
+		addr, latency := "-", "-"
+		if ep.Address.Host != "" {
+			addr = strings.ToLower(ep.Address.Protocol) + "://" + ep.Address.String()
+		} else if ep.Address.Protocol != "" {
+			addr = strings.ToLower(ep.Address.Protocol)
+		}
+		if ep.Reachable() {
+			latency = ep.Latency.Round(time.Millisecond).String()
+		} else if ep.Status != "skipped" {
+			failed++
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", ep.Alias, addr, ep.Status, latency, ep.Detail)
+	}
+	tw.Flush()
+	return failed
+}
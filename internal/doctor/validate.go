@@ -0,0 +1,79 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/mghoff/oraicwinconfig/internal/check"
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+)
+
+// EnvChecks returns a check for each Oracle-related variable of the manager's
+// scope: the client variables and TNS_ADMIN must be set and point at existing
+// directories, TNS_ADMIN must belong to the client, and the client must come
+// first on PATH
+func EnvChecks(conf *config.InstallConfig, env env.Manager) []check.Check {
+	c := *conf
+	checks := []check.Check{
+		{Name: c.LibVar(), Run: func(ctx context.Context) check.Result { return checkEnvVar(ctx, env, c.LibVar(), true) }},
+	}
+	// The 32-bit Windows client is optional alongside the 64-bit one
+	if c.OS == "windows" && c.LibVar() != "OCI_LIB32" {
+		checks = append(checks, check.Check{Name: "OCI_LIB32", Run: func(ctx context.Context) check.Result { return checkEnvVar(ctx, env, "OCI_LIB32", false) }})
+	}
+	return append(checks,
+		check.Check{Name: "TNS_ADMIN", Run: func(ctx context.Context) check.Result { return checkEnvVar(ctx, env, "TNS_ADMIN", true) }},
+		check.Check{Name: "TNS_ADMIN consistency", Run: func(ctx context.Context) check.Result { return checkTNSAdminOwner(ctx, c, env) }},
+		check.Check{Name: "PATH", Run: func(ctx context.Context) check.Result { return checkPath(ctx, c, env) }},
+	)
+}
+
+// checkEnvVar verifies a variable is set, non-empty and points at an existing
+// directory; an optional variable may be unset
+func checkEnvVar(ctx context.Context, m env.Manager, name string, required bool) check.Result {
+	m = m.WithContext(ctx)
+	if _, err := m.GetEnvVar(name); err != nil {
+		if !required {
+			return check.Pass("not set")
+		}
+		return check.Fail(fmt.Sprintf("%s is not set or empty in %s scope", name, m.Scope()), "run oraicwinconfig to install and configure the client")
+	}
+	dir, err := env.CheckEnvVar(m, name)
+	if err != nil {
+		return check.Fail(err.Error(), "run oraicwinconfig doctor --fix-dangling to clear it or re-point it at another install")
+	}
+	return check.Pass(dir)
+}
+
+// checkTNSAdminOwner verifies TNS_ADMIN is the network/admin directory of the
+// client, or a shared directory or profile recorded in the manifest
+func checkTNSAdminOwner(ctx context.Context, conf config.InstallConfig, m env.Manager) check.Result {
+	m = m.WithContext(ctx)
+	dir, err := m.GetEnvVar(conf.LibVar())
+	if err != nil {
+		return check.Warn("skipped, "+conf.LibVar()+" is not set", "")
+	}
+	admin, err := m.GetEnvVar("TNS_ADMIN")
+	if err != nil {
+		return check.Warn("skipped, TNS_ADMIN is not set", "")
+	}
+	own := filepath.Join(dir, "network", "admin")
+	if env.SamePath(admin, own) {
+		return check.Pass("network/admin of " + dir)
+	}
+	if rec, err := manifest.Load(m.Scope()); err == nil {
+		for name, profile := range rec.TNSProfiles {
+			if env.SamePath(admin, profile) {
+				return check.Pass(fmt.Sprintf("TNS_ADMIN profile %s", name))
+			}
+		}
+		if c, ok := rec.Client(conf.LibVar()); ok && env.SamePath(admin, c.Vars["TNS_ADMIN"]) {
+			return check.Pass("shared directory " + admin)
+		}
+	}
+	return check.Warn(fmt.Sprintf("TNS_ADMIN %s is not %s or a directory recorded by the installer", admin, own),
+		"point TNS_ADMIN at the client's network/admin directory, or add the directory with oraicwinconfig tns add")
+}
@@ -0,0 +1,51 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mghoff/oraicwinconfig/internal/check"
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/status"
+)
+
+// InstallChecks returns the checks verifying the client described by conf is
+// in place, loadable and configured as the manifest records. Unlike Checks,
+// they neither reach the network nor look at wallets.
+func InstallChecks(conf *config.InstallConfig, env env.Manager) []check.Check {
+	c := *conf
+	return []check.Check{
+		{Name: c.LibVar(), Run: func(ctx context.Context) check.Result { return checkLibVar(ctx, c, env) }},
+		{Name: "TNS_ADMIN", Run: func(ctx context.Context) check.Result { return checkTNSAdmin(ctx, env) }},
+		{Name: "tnsnames.ora", Run: func(ctx context.Context) check.Result { return checkTNSNames(ctx, env) }},
+		{Name: "PATH", Run: func(ctx context.Context) check.Result { return checkPath(ctx, c, env) }},
+		{Name: "client library", Run: func(ctx context.Context) check.Result { return checkLibrary(ctx, c, env) }},
+		{Name: "other clients", Run: func(ctx context.Context) check.Result { return checkConflicts(ctx, c, env) }},
+		{Name: "VC++ runtime", Run: func(ctx context.Context) check.Result { return checkVCRuntime(c) }},
+		{Name: "manifest", Run: func(ctx context.Context) check.Result { return checkManifest(ctx, env) }},
+	}
+}
+
+// checkManifest verifies the environment still holds the settings recorded in
+// the manifest of the manager's scope
+func checkManifest(ctx context.Context, env env.Manager) check.Result {
+	m, err := manifest.Load(env.Scope())
+	if err != nil {
+		return check.Fail(err.Error(), "")
+	}
+	if len(m.Clients) == 0 {
+		return check.Warn(fmt.Sprintf("no install is recorded in the %s scope", env.Scope()), "install the client with oraicwinconfig install")
+	}
+	drifted := 0
+	for _, row := range status.Compare(m, env.WithContext(ctx)) {
+		if row.State.Drifted() {
+			drifted++
+		}
+	}
+	if drifted > 0 {
+		return check.Fail(fmt.Sprintf("%d settings differ from the manifest", drifted), "run oraicwinconfig status to see them, and oraicwinconfig install to configure the client again")
+	}
+	return check.Pass("the environment matches the manifest")
+}
@@ -0,0 +1,46 @@
+package doctor_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/check"
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/doctor"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/testsupport"
+)
+
+func TestInstallChecksManifest(t *testing.T) {
+	testsupport.Sandbox(t)
+	conf := config.New()
+	m := testsupport.NewEnv(env.ScopeUser, t.TempDir())
+	run := func() check.Result {
+		t.Helper()
+		for _, r := range check.Run(context.Background(), doctor.InstallChecks(conf, m), 0) {
+			if r.Name == "manifest" {
+				return r
+			}
+		}
+		t.Fatal("no manifest check")
+		return check.Result{}
+	}
+	if r := run(); r.Status != check.StatusWarn {
+		t.Errorf("nothing recorded: %+v", r)
+	}
+
+	client := t.TempDir()
+	rec := &manifest.Manifest{Clients: []manifest.Client{{LibVar: conf.LibVar(), ClientDir: client, Vars: map[string]string{conf.LibVar(): client}}}}
+	if err := rec.Save(env.ScopeUser); err != nil {
+		t.Fatal(err)
+	}
+	m.SetEnvVar(conf.LibVar(), client)
+	if r := run(); r.Status != check.StatusPass {
+		t.Errorf("as recorded: %+v", r)
+	}
+	m.SetEnvVar(conf.LibVar(), t.TempDir())
+	if r := run(); r.Status != check.StatusFail {
+		t.Errorf("edited: %+v", r)
+	}
+}
@@ -0,0 +1,125 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/check"
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/tns"
+)
+
+// WalletCert is a certificate held by a wallet and when it expires
+type WalletCert struct {
+	Wallet   string // Directory of the wallet
+	Subject  string
+	NotAfter time.Time
+}
+
+// WalletCerts returns the certificates of the wallets under tnsAdmin, the
+// one in the directory itself, as Autonomous Database wallets are unzipped,
+// and the one sqlnet.ora's WALLET_LOCATION names, soonest to expire first.
+// Wallets without an ewallet.pem, whose certificates cannot be read, are
+// returned separately.
+func WalletCerts(tnsAdmin string) (certs []WalletCert, unreadable []string, err error) {
+	dirs := []string{tnsAdmin}
+	data, err := os.ReadFile(filepath.Join(tnsAdmin, "sqlnet.ora"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+	// A relative directory, or one under the client's home (?), depends on
+	// the process opening it and is not followed
+	if dir, err := tns.WalletDirectory(data); err != nil {
+		return nil, nil, fmt.Errorf("sqlnet.ora: %w", err)
+	} else if filepath.IsAbs(dir) && !env.SamePath(dir, tnsAdmin) {
+		dirs = append(dirs, dir)
+	}
+
+	for _, dir := range dirs {
+		w, err := tns.ReadWallet(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("wallet in %s: %w", dir, err)
+		}
+		if !w.PEM {
+			if w.AutoLogin || w.PKCS12 {
+				unreadable = append(unreadable, dir)
+			}
+			continue
+		}
+		for _, c := range w.Certs {
+			certs = append(certs, WalletCert{Wallet: dir, Subject: c.Subject.String(), NotAfter: c.NotAfter})
+		}
+	}
+	sort.SliceStable(certs, func(i, j int) bool { return certs[i].NotAfter.Before(certs[j].NotAfter) })
+	return certs, unreadable, nil
+}
+
+// ExpiringCerts returns the certificates expiring before now plus window,
+// including those already expired
+func ExpiringCerts(certs []WalletCert, now time.Time, window time.Duration) []WalletCert {
+	var out []WalletCert
+	for _, c := range certs {
+		if c.NotAfter.Before(now.Add(window)) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Describe returns a one-line account of the certificate's expiry relative to now
+func (c WalletCert) Describe(now time.Time) string {
+	date := c.NotAfter.Format("2006-01-02")
+	if !c.NotAfter.After(now) {
+		return fmt.Sprintf("%s in %s expired on %s", c.Subject, c.Wallet, date)
+	}
+	return fmt.Sprintf("%s in %s expires on %s, in %d days", c.Subject, c.Wallet, date, int(c.NotAfter.Sub(now).Hours()/24))
+}
+
+// WalletCheck returns the check warning about wallet certificates under
+// TNS_ADMIN that expire within conf.WalletExpiry, and failing on expired ones
+func WalletCheck(conf *config.InstallConfig, env env.Manager) check.Check {
+	window := conf.WalletExpiry
+	return check.Check{Name: "wallet expiry", Run: func(ctx context.Context) check.Result { return checkWalletExpiry(ctx, env, window) }}
+}
+
+// checkWalletExpiry verifies no wallet certificate under TNS_ADMIN has
+// expired or expires within window
+func checkWalletExpiry(ctx context.Context, env env.Manager, window time.Duration) check.Result {
+	dir, err := env.WithContext(ctx).GetEnvVar("TNS_ADMIN")
+	if err != nil {
+		return check.Pass("skipped, TNS_ADMIN is not set")
+	}
+	certs, unreadable, err := WalletCerts(dir)
+	if err != nil {
+		return check.Warn(err.Error(), "check the wallet files and the WALLET_LOCATION in sqlnet.ora")
+	}
+	if len(certs) == 0 {
+		if len(unreadable) > 0 {
+			return check.Pass(fmt.Sprintf("expiry not checked, the wallet in %s holds no ewallet.pem", unreadable[0]))
+		}
+		return check.Pass("no wallet under TNS_ADMIN")
+	}
+
+	now := time.Now()
+	expiring := ExpiringCerts(certs, now, window)
+	if len(expiring) == 0 {
+		return check.Pass(fmt.Sprintf("%d certificates, the first to expire on %s", len(certs), certs[0].NotAfter.Format("2006-01-02")))
+	}
+	detail := expiring[0].Describe(now)
+	if len(expiring) > 1 {
+		detail += fmt.Sprintf(" (and %d more)", len(expiring)-1)
+	}
+	hint := "download a new wallet (for Autonomous Database, from the console's Database connection page) or renew the certificate, and replace the wallet files"
+	if !expiring[0].NotAfter.After(now) {
+		return check.Fail(detail, hint)
+	}
+	return check.Warn(detail, hint)
+}
@@ -0,0 +1,67 @@
+package doctor_test
+
+import (
+	"context"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/check"
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/doctor"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/testsupport"
+)
+
+func TestWalletExpiry(t *testing.T) {
+	// An Autonomous Database style wallet unzipped into TNS_ADMIN, whose
+	// sqlnet.ora points at a second wallet holding only cwallet.sso
+	tnsAdmin, other := t.TempDir(), t.TempDir()
+	ca, caKey := issue(t, "Example CA", true, nil, nil)
+	server, _ := issue(t, "db.example.com", false, ca, caKey)
+	var pemData []byte
+	for _, der := range [][]byte{ca.Raw, server.Raw} {
+		pemData = append(pemData, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	files := map[string][]byte{
+		filepath.Join(tnsAdmin, "ewallet.pem"): pemData,
+		filepath.Join(tnsAdmin, "cwallet.sso"): nil,
+		filepath.Join(tnsAdmin, "sqlnet.ora"):  []byte("WALLET_LOCATION = (SOURCE = (METHOD = FILE) (METHOD_DATA = (DIRECTORY = \"" + other + "\")))\n"),
+		filepath.Join(other, "cwallet.sso"):    nil,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(name, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	certs, unreadable, err := doctor.WalletCerts(tnsAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 2 || certs[0].Wallet != tnsAdmin || len(unreadable) != 1 || unreadable[0] != other {
+		t.Fatalf("certs %+v, unreadable %v", certs, unreadable)
+	}
+	now := time.Now()
+	if got := doctor.ExpiringCerts(certs, now, 0); len(got) != 0 {
+		t.Errorf("certificates valid for an hour expiring now: %+v", got)
+	}
+	if got := doctor.ExpiringCerts(certs, now.Add(2*time.Hour), 0); len(got) != 2 {
+		t.Errorf("expired certificates not reported: %+v", got)
+	}
+
+	m := testsupport.NewEnv(env.ScopeUser, t.TempDir())
+	if err := m.SetEnvVar("TNS_ADMIN", tnsAdmin); err != nil {
+		t.Fatal(err)
+	}
+	conf := config.New()
+	for window, want := range map[time.Duration]check.Status{0: check.StatusPass, 30 * 24 * time.Hour: check.StatusWarn} {
+		conf.WalletExpiry = window
+		c := doctor.WalletCheck(conf, m)
+		if r := c.Run(context.Background()); r.Status != want {
+			t.Errorf("window %s: %s %q, want %s", window, r.Status, r.Detail, want)
+		}
+	}
+}
@@ -1,33 +1,125 @@
 package env
 
 import (
+	"context"
 	"fmt"
 	"errors"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
 )
 
-// EnvVarManager handles environment variable operations
+// EnvVarManager handles Windows User or Machine environment variable operations through PowerShell
 type EnvVarManager struct {
 	powershell string
+	ctx        context.Context
+	cache      *envCache
+	scope      Scope
 }
 
-// NewEnvVarManager creates a new environment variable manager
-func New() *EnvVarManager {
+// envCache holds user environment variable values read during a run,
+// keyed by upper-cased name since Windows variable names are case-insensitive
+type envCache struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// NewEnvVarManager creates a new environment variable manager for the given scope
+func NewEnvVarManager(scope Scope) *EnvVarManager {
 	return &EnvVarManager{
 		powershell: "powershell",
+		cache:      &envCache{values: make(map[string]string)},
+		scope:      scope,
+	}
+}
+
+// target returns the .NET EnvironmentVariableTarget name for the manager's scope
+func (e *EnvVarManager) target() string {
+	if e.scope == ScopeMachine {
+		return "Machine"
+	}
+	return "User"
+}
+
+// cacheKey identifies a variable within the manager's scope
+func (e *EnvVarManager) cacheKey(name string) string {
+	return e.target() + ":" + name
+}
+
+// Scope returns the scope variables are read from and written to
+func (e *EnvVarManager) Scope() Scope {
+	return e.scope
+}
+
+// WithScope returns a copy of the manager reading and writing the given scope;
+// the copy shares the manager's cache
+func (e *EnvVarManager) WithScope(scope Scope) Manager {
+	c := *e
+	c.scope = scope
+	return &c
+}
+
+// lookup returns the cached raw value of a variable, if it has been read
+func (c *envCache) lookup(name string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[strings.ToUpper(name)]
+	return v, ok
+}
+
+// store records the raw value of a variable
+func (c *envCache) store(name, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[strings.ToUpper(name)] = value
+}
+
+// InvalidateCache drops the cached values of the named variables,
+// or of all variables when no names are given
+func (e *EnvVarManager) InvalidateCache(names ...string) {
+	e.cache.mu.Lock()
+	defer e.cache.mu.Unlock()
+	if len(names) == 0 {
+		e.cache.values = make(map[string]string)
+		return
+	}
+	for _, name := range names {
+		delete(e.cache.values, strings.ToUpper(e.cacheKey(name)))
+	}
+}
+
+// WithContext returns a copy of the manager whose PowerShell commands
+// are killed once ctx is done
+func (e *EnvVarManager) WithContext(ctx context.Context) Manager {
+	c := *e
+	c.ctx = ctx
+	return &c
+}
+
+// output runs a PowerShell script bound to the manager's context and the
+// command timeout, and returns its output. The script is passed encoded, so
+// values quoted with pwsh.Quote reach PowerShell intact.
+func (e *EnvVarManager) output(script string) ([]byte, error) {
+	return pwsh.Output(e.ctx, e.powershell, pwsh.Args(script)...)
+}
+
+// checkName rejects variable names Windows cannot store
+func checkName(name string) error {
+	if name == "" || strings.ContainsAny(name, "=\x00") {
+		return errs.HandleError(fmt.Errorf("invalid environment variable name %q", name), errs.ErrorTypeValidation, "checking variable name")
 	}
+	return nil
 }
 
 // FetchUserDownloadsPath retrieves the user profile directory for a given endpoint
 // and checks if the directory exists
 func (e *EnvVarManager) FetchUserDownloadsPath() (string, error) {
 	cmd := "$env:USERPROFILE"
-	out, err := exec.Command(e.powershell, cmd).Output()
+	out, err := e.output(cmd)
 	if err != nil {
 		return "", errs.HandleError(err, errs.ErrorTypeUserPath, "getting user profile directory")
 	}
@@ -43,14 +135,22 @@ func (e *EnvVarManager) FetchUserDownloadsPath() (string, error) {
 	return usrDownloadsPath, nil
 }
 
-// GetEnvVar retrieves a user environment variable
+// GetEnvVar retrieves an environment variable of the manager's scope.
+// Values are cached for the rest of the run until the variable is written or invalidated.
 func (e *EnvVarManager) GetEnvVar(name string) (string, error) {
-	cmd := fmt.Sprintf("[System.Environment]::GetEnvironmentVariable('%s', 'User')", name)
-	out, err := exec.Command(e.powershell, cmd).Output()
-	if err != nil {
-		return "", errs.HandleError(err, errs.ErrorTypeEnvVarNotFound, fmt.Sprintf("getting %s environment variable", name))
+	path, ok := e.cache.lookup(e.cacheKey(name))
+	if !ok {
+		if err := checkName(name); err != nil {
+			return "", err
+		}
+		cmd := fmt.Sprintf("[System.Environment]::GetEnvironmentVariable(%s, '%s')", pwsh.Quote(name), e.target())
+		out, err := e.output(cmd)
+		if err != nil {
+			return "", errs.HandleError(err, errs.ErrorTypeEnvVarNotFound, fmt.Sprintf("getting %s environment variable", name))
+		}
+		path = strings.TrimSpace(string(out)) // Trim whitespace including newlines
+		e.cache.store(e.cacheKey(name), path)
 	}
-	path := strings.TrimSpace(string(out)) // Trim whitespace including newlines
 	if path == ""  || path == "." || path == ".." || path == "/" || path == "\\" {
 		return "", errs.HandleError(
 			fmt.Errorf("environment variable %s not found", name),
@@ -63,7 +163,24 @@ func (e *EnvVarManager) GetEnvVar(name string) (string, error) {
 
 // ValidateEnvVar checks if an environment variable is set and points to a valid directory
 func (e *EnvVarManager) ValidateEnvVar(name string) (string, error) {
-	path, err := e.GetEnvVar(name)
+	return validateEnvVar(e, name)
+}
+
+// validateEnvVar checks that the named variable of m is set and points to a valid directory
+func validateEnvVar(m Manager, name string) (string, error) {
+	path, err := CheckEnvVar(m, name)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("%s environment variable found: %s\n", name, path)
+	return path, nil
+}
+
+// CheckEnvVar checks that the named variable of m is set, non-empty and points
+// to an existing directory, returning its cleaned value. Unlike ValidateEnvVar
+// it prints nothing, for callers reporting the outcome themselves.
+func CheckEnvVar(m Manager, name string) (string, error) {
+	path, err := m.GetEnvVar(name)
 	if errs.IsErrorType(err, errs.ErrorTypeEnvVarNotFound) {
 		return "", err
 	}
@@ -71,7 +188,8 @@ func (e *EnvVarManager) ValidateEnvVar(name string) (string, error) {
 	// If exists, check if it points to a valid directory
 	// This is the directory where the Oracle Instant Client files are expected to be located
 	path = filepath.Clean(path)
-	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
 		return "", errs.HandleError(fmt.Errorf("environment variable %s points to a non-existent directory: %s", name, path),
 			errs.ErrorTypeEnvironment,
 			fmt.Sprintf("checking %s path", name))
@@ -79,30 +197,82 @@ func (e *EnvVarManager) ValidateEnvVar(name string) (string, error) {
 	if err != nil {
 		return "", errs.HandleError(err, errs.ErrorTypeEnvironment, fmt.Sprintf("checking %s path", name))
 	}
-
-	fmt.Printf("%s environment variable found: %s\n", name, path)
+	if !info.IsDir() {
+		return "", errs.HandleError(fmt.Errorf("environment variable %s points to a file, not a directory: %s", name, path),
+			errs.ErrorTypeEnvironment,
+			fmt.Sprintf("checking %s path", name))
+	}
 	return path, nil
 }
 
-// SetEnvVar sets a user environment variable
+// SetEnvVar sets an environment variable in the manager's scope
 func (e *EnvVarManager) SetEnvVar(name, value string) error {
-	cmd := fmt.Sprintf("[Environment]::SetEnvironmentVariable('%s', '%s', 'User')", name, value)
-	if _, err := exec.Command(e.powershell, cmd).Output(); err != nil {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if strings.ContainsRune(value, 0) {
+		return errs.HandleError(fmt.Errorf("value of %s contains a NUL character", name), errs.ErrorTypeValidation, "checking variable value")
+	}
+	cmd := fmt.Sprintf("[Environment]::SetEnvironmentVariable(%s, %s, '%s')", pwsh.Quote(name), pwsh.Quote(value), e.target())
+	defer e.InvalidateCache(name)
+	if _, err := e.output(cmd); err != nil {
 		return errs.HandleError(err, errs.ErrorTypeEnvironment, fmt.Sprintf("setting %s environment variable", name))
 	}
 	return nil
 }
 
-// RemoveEnvVar removes a user environment variable
+// RemoveEnvVar removes an environment variable from the manager's scope
 func (e *EnvVarManager) RemoveEnvVar(name string) error {
-	cmd := fmt.Sprintf("[Environment]::SetEnvironmentVariable('%s', $null, 'User')", name)
-	if _, err := exec.Command(e.powershell, cmd).Output(); err != nil {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("[Environment]::SetEnvironmentVariable(%s, $null, '%s')", pwsh.Quote(name), e.target())
+	defer e.InvalidateCache(name)
+	if _, err := e.output(cmd); err != nil {
 		return errs.HandleError(err, errs.ErrorTypeEnvironment, fmt.Sprintf("removing %s environment variable", name))
 	}
 	return nil
 }
 
-// AppendToPath adds a new path to the PATH environment variable
+// Snapshot captures the current values of the named variables of the manager's scope
+func (e *EnvVarManager) Snapshot(names ...string) (Snapshot, error) {
+	s := Snapshot{Names: names, Values: make(map[string]string)}
+	for _, name := range names {
+		value, err := e.GetEnvVar(name)
+		if err == nil {
+			s.Values[name] = value
+			continue
+		}
+		// Values read are cached even when empty, so an uncached variable means PowerShell failed
+		if _, read := e.cache.lookup(e.cacheKey(name)); !read {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// Restore puts back the captured values with a single PowerShell command
+func (e *EnvVarManager) Restore(s Snapshot) error {
+	var script strings.Builder
+	for _, name := range s.Names {
+		if err := checkName(name); err != nil {
+			return err
+		}
+		value := "$null"
+		if v, ok := s.Values[name]; ok {
+			value = pwsh.Quote(v)
+		}
+		fmt.Fprintf(&script, "[Environment]::SetEnvironmentVariable(%s, %s, '%s')\n", pwsh.Quote(name), value, e.target())
+	}
+	defer e.InvalidateCache(s.Names...)
+	if _, err := e.output(script.String()); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, "restoring environment variables")
+	}
+	return nil
+}
+
+// AppendToPath adds a new path to the PATH environment variable, unless it is
+// already in the PATH of this scope or in the Machine PATH
 func (e *EnvVarManager) AppendToPath(newPath string) error {
 	currentPath, err := e.GetEnvVar("PATH")
 	if err != nil {
@@ -110,18 +280,12 @@ func (e *EnvVarManager) AppendToPath(newPath string) error {
 	}
 
 	// Check if path already exists
-	if strings.Contains(currentPath, newPath) {
-		fmt.Printf("path %s already exists in PATH\n", newPath)
+	if scope, ok := pathProvider(e, newPath); ok {
+		fmt.Printf("path %s already exists in the %s PATH\n", newPath, scope)
 		return nil
 	}
 
-	// Ensure path ends with semicolon
-	if !strings.HasSuffix(currentPath, ";") {
-		currentPath += ";"
-	}
-
-	newFullPath := currentPath + newPath + ";"
-	return e.SetEnvVar("PATH", newFullPath)
+	return e.setPath(currentPath, append(strings.Split(currentPath, ";"), newPath))
 }
 
 // RemoveFromPath removes a specified path from the PATH environment variable
@@ -137,12 +301,65 @@ func (e *EnvVarManager) RemoveFromPath(pathToRemove string) error {
 
 	// Filter out the segment to remove
 	for _, segment := range segments {
-		if segment != pathToRemove {
+		if !SamePath(segment, pathToRemove) {
 			newSegments = append(newSegments, segment)
 		}
 	}
 
-	// Join the remaining segments back into a single string
-	newPath := strings.Join(newSegments, ";")
-	return e.SetEnvVar("PATH", newPath)
+	// PATH is left untouched when it does not have the entry
+	if len(newSegments) == len(segments) {
+		return nil
+	}
+	return e.setPath(currentPath, newSegments)
+}
+
+// MovePathBefore moves a path ahead of another in the PATH environment variable
+func (e *EnvVarManager) MovePathBefore(path, before string) error {
+	currentPath, err := e.GetEnvVar("PATH")
+	if err != nil {
+		return err
+	}
+
+	segments, moved := moveBefore(strings.Split(currentPath, ";"), path, before)
+	if !moved {
+		return nil
+	}
+	return e.setPath(currentPath, segments)
+}
+
+// setPath writes the PATH segments without empty segments or a trailing
+// semicolon, and only if that changes the current value
+func (e *EnvVarManager) setPath(current string, segments []string) error {
+	value := strings.Join(cleanPath(segments), ";")
+	if value == current {
+		return nil
+	}
+	return e.SetEnvVar("PATH", value)
+}
+
+// moveBefore moves item ahead of before in list, reporting whether anything changed
+func moveBefore(list []string, item, before string) ([]string, bool) {
+	itemIdx, beforeIdx := -1, -1
+	for i, v := range list {
+		if strings.EqualFold(v, item) && itemIdx < 0 {
+			itemIdx = i
+		}
+		if strings.EqualFold(v, before) && beforeIdx < 0 {
+			beforeIdx = i
+		}
+	}
+	if itemIdx < 0 || beforeIdx < 0 || itemIdx < beforeIdx {
+		return list, false
+	}
+
+	out := make([]string, 0, len(list))
+	for i, v := range list {
+		if i == beforeIdx {
+			out = append(out, list[itemIdx])
+		}
+		if i != itemIdx {
+			out = append(out, v)
+		}
+	}
+	return out, true
 }
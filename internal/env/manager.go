@@ -0,0 +1,166 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// Scope selects whose environment is configured
+type Scope string
+
+const (
+	ScopeUser    Scope = "user"    // The current user only
+	ScopeMachine Scope = "machine" // All users of the machine; requires administrator rights
+)
+
+// ParseScope converts a command-line value into a Scope
+func ParseScope(s string) (Scope, error) {
+	switch Scope(s) {
+	case ScopeUser, ScopeMachine:
+		return Scope(s), nil
+	default:
+		return "", fmt.Errorf("invalid scope %q: must be user or machine", s)
+	}
+}
+
+// Other returns the opposite scope
+func (s Scope) Other() Scope {
+	if s == ScopeMachine {
+		return ScopeUser
+	}
+	return ScopeMachine
+}
+
+// Manager reads and writes the persistent user environment of the host OS
+type Manager interface {
+	// FetchUserDownloadsPath returns the directory downloads are saved to
+	FetchUserDownloadsPath() (string, error)
+	// GetEnvVar retrieves a persistent user environment variable
+	GetEnvVar(name string) (string, error)
+	// ValidateEnvVar checks that a variable is set and points to an existing directory
+	ValidateEnvVar(name string) (string, error)
+	// SetEnvVar sets a persistent user environment variable
+	SetEnvVar(name, value string) error
+	// RemoveEnvVar removes a persistent user environment variable
+	RemoveEnvVar(name string) error
+	// AppendToPath adds a directory to the library search path
+	AppendToPath(newPath string) error
+	// RemoveFromPath removes a directory from the library search path
+	RemoveFromPath(pathToRemove string) error
+	// MovePathBefore moves a directory ahead of another on the library search path,
+	// if both are present and it currently comes after
+	MovePathBefore(path, before string) error
+	// InvalidateCache drops any cached variable values
+	InvalidateCache(names ...string)
+	// WithContext returns a copy of the manager bound to ctx
+	WithContext(ctx context.Context) Manager
+	// Scope returns the scope variables are read from and written to
+	Scope() Scope
+	// WithScope returns a copy of the manager for another scope
+	WithScope(scope Scope) Manager
+	// Snapshot captures the current values of the named variables; PATH may be among them
+	Snapshot(names ...string) (Snapshot, error)
+	// Restore puts back the values captured by Snapshot, unsetting those that were unset
+	Restore(s Snapshot) error
+}
+
+// Snapshot holds the values of variables at a point in time
+type Snapshot struct {
+	Names  []string          // Variables captured
+	Values map[string]string // Values of the captured variables that were set
+}
+
+// restoreTimeout bounds rolling back a failed update, which runs even when the
+// update itself ran out of time
+const restoreTimeout = 2 * time.Minute
+
+// Update applies fn to m as one unit: the named variables and PATH are captured
+// first and, if fn fails, restored, so a failure part-way through never leaves
+// a mix of old and new values behind
+func Update(m Manager, names []string, fn func(Manager) error) error {
+	// A copy, so PATH is never written into spare capacity of the caller's slice
+	snap, err := m.Snapshot(append(append([]string(nil), names...), "PATH")...)
+	if err != nil {
+		return err
+	}
+	err = fn(m)
+	if err == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	defer cancel()
+	if rerr := m.WithContext(ctx).Restore(snap); rerr != nil {
+		return errs.HandleError(
+			fmt.Errorf("%w; restoring the previous values also failed: %v", err, rerr),
+			errs.ErrorTypeEnvironment,
+			"rolling back environment changes")
+	}
+	fmt.Printf("environment changes rolled back: %v\n", err)
+	return err
+}
+
+// pathProvider returns the scope whose PATH already contains dir for a manager
+// writing m's scope: its own, or the machine PATH that every user's PATH
+// extends. An entry in the user PATH does not count for the machine scope,
+// since it only serves that user, but is reported.
+func pathProvider(m Manager, dir string) (Scope, bool) {
+	scopes := []Scope{m.Scope(), m.Scope().Other()}
+	for _, scope := range scopes {
+		value, err := m.WithScope(scope).GetEnvVar("PATH")
+		if err != nil || !PathContains(value, dir) {
+			continue
+		}
+		if scope == m.Scope() || scope == ScopeMachine {
+			return scope, true
+		}
+		fmt.Printf("path %s is also in the %s PATH, which only serves that user\n", dir, scope)
+	}
+	return "", false
+}
+
+// PathContains reports whether the PATH value holds dir as one of its entries
+func PathContains(value, dir string) bool {
+	for _, entry := range filepath.SplitList(value) {
+		if SamePath(entry, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// SamePath reports whether a and b name the same directory, ignoring
+// trailing slashes and backslashes and, on Windows, slash direction and case
+func SamePath(a, b string) bool {
+	return PathKey(a) == PathKey(b)
+}
+
+// PathKey normalises a directory for comparison, as SamePath compares them,
+// for use as a map key
+func PathKey(dir string) string {
+	if trimmed := strings.TrimRight(dir, `\/`); trimmed != "" {
+		dir = trimmed
+	}
+	dir = filepath.Clean(dir)
+	if filepath.Separator == '\\' {
+		return strings.ToLower(dir)
+	}
+	return dir
+}
+
+// cleanPath drops the empty segments that stray, doubled or trailing
+// separators leave in a PATH value
+func cleanPath(segments []string) []string {
+	out := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if strings.TrimSpace(segment) != "" {
+			out = append(out, segment)
+		}
+	}
+	return out
+}
@@ -0,0 +1,189 @@
+package env
+
+import (
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// pathList joins entries as a PATH value of the host OS
+func pathList(entries ...string) string {
+	return strings.Join(entries, string(os.PathListSeparator))
+}
+
+func TestPathContains(t *testing.T) {
+	windows := runtime.GOOS == "windows"
+	tests := []struct {
+		name  string
+		value string
+		dir   string
+		want  bool
+	}{
+		{"exact", pathList("/usr/bin", "/opt/oracle/ic"), "/opt/oracle/ic", true},
+		{"trailing slash in PATH", pathList("/opt/oracle/ic/"), "/opt/oracle/ic", true},
+		{"trailing slash in dir", pathList("/opt/oracle/ic"), "/opt/oracle/ic/", true},
+		{"trailing backslash", pathList(`/opt/oracle/ic\`), "/opt/oracle/ic", true},
+		{"case", pathList("/OPT/Oracle/IC"), "/opt/oracle/ic", windows},
+		{"duplicate entries", pathList("/opt/oracle/ic", "/usr/bin", "/opt/oracle/ic"), "/opt/oracle/ic", true},
+		{"empty segments", pathList("", "/usr/bin", "", "/opt/oracle/ic", ""), "/opt/oracle/ic", true},
+		{"prefix only", pathList("/opt/oracle/ic2"), "/opt/oracle/ic", false},
+		{"empty PATH", "", "/opt/oracle/ic", false},
+	}
+	for _, tt := range tests {
+		if got := PathContains(tt.value, tt.dir); got != tt.want {
+			t.Errorf("%s: PathContains(%q, %q) = %t, want %t", tt.name, tt.value, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestSamePath(t *testing.T) {
+	windows := runtime.GOOS == "windows"
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"exact", "/opt/oracle/ic", "/opt/oracle/ic", true},
+		{"trailing slash", "/opt/oracle/ic/", "/opt/oracle/ic", true},
+		{"trailing backslash", `/opt/oracle/ic\`, "/opt/oracle/ic", true},
+		{"dot segments", "/opt/oracle/./x/../ic", "/opt/oracle/ic", true},
+		{"root", "/", "/", true},
+		{"case", "/OPT/Oracle/IC", "/opt/oracle/ic", windows},
+		{"slash direction", "/opt/oracle/ic", `\opt\oracle\ic`, windows},
+		{"prefix only", "/opt/oracle/ic2", "/opt/oracle/ic", false},
+	}
+	for _, tt := range tests {
+		if got := SamePath(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: SamePath(%q, %q) = %t, want %t", tt.name, tt.a, tt.b, got, tt.want)
+		}
+		if got := PathKey(tt.a) == PathKey(tt.b); got != tt.want {
+			t.Errorf("%s: PathKey(%q) == PathKey(%q) is %t, want %t", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		segments []string
+		want     []string
+	}{
+		{[]string{"a", "b"}, []string{"a", "b"}},
+		{[]string{"", "a", "", "", "b", ""}, []string{"a", "b"}},
+		{[]string{"a", " ", "\t", "b"}, []string{"a", "b"}},
+		// Duplicates are kept: only empty segments are dropped
+		{[]string{"a", "b", "a"}, []string{"a", "b", "a"}},
+		{[]string{""}, []string{}},
+	}
+	for _, tt := range tests {
+		if got := cleanPath(tt.segments); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cleanPath(%q) = %q, want %q", tt.segments, got, tt.want)
+		}
+	}
+}
+
+func TestMoveBefore(t *testing.T) {
+	tests := []struct {
+		list         []string
+		item, before string
+		want         []string
+		moved        bool
+	}{
+		{[]string{"old", "new"}, "new", "old", []string{"new", "old"}, true},
+		{[]string{`C:\Old`, "x", `C:\new`}, `c:\NEW`, `c:\old`, []string{`C:\new`, `C:\Old`, "x"}, true},
+		{[]string{"new", "old"}, "new", "old", []string{"new", "old"}, false},
+		{[]string{"old"}, "new", "old", []string{"old"}, false},
+		// The first of duplicate entries is the one that counts
+		{[]string{"new", "old", "new"}, "new", "old", []string{"new", "old", "new"}, false},
+	}
+	for _, tt := range tests {
+		list := append([]string(nil), tt.list...)
+		got, moved := moveBefore(list, tt.item, tt.before)
+		if moved != tt.moved || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("moveBefore(%q, %q, %q) = %q, %t; want %q, %t", tt.list, tt.item, tt.before, got, moved, tt.want, tt.moved)
+		}
+	}
+}
+
+// scopedPath is a manager holding just the PATH of each scope, as far as
+// pathProvider reads it
+type scopedPath struct {
+	Manager
+	scope Scope
+	paths map[Scope]string
+}
+
+func (m scopedPath) Scope() Scope { return m.scope }
+
+func (m scopedPath) WithScope(scope Scope) Manager {
+	m.scope = scope
+	return m
+}
+
+func (m scopedPath) GetEnvVar(name string) (string, error) {
+	if value, ok := m.paths[m.scope]; ok && name == "PATH" {
+		return value, nil
+	}
+	return "", os.ErrNotExist
+}
+
+func TestPathProvider(t *testing.T) {
+	const dir, stale = "/opt/oracle/instantclient_23_7", "/opt/oracle/instantclient_21_9"
+	tests := []struct {
+		name          string
+		scope         Scope
+		user, machine string
+		want          Scope
+		found         bool
+	}{
+		{"both scopes, user manager", ScopeUser, pathList("/usr/bin", dir), pathList(dir), ScopeUser, true},
+		{"both scopes, machine manager", ScopeMachine, pathList(dir), pathList(dir + "/"), ScopeMachine, true},
+		{"machine PATH serves the user", ScopeUser, pathList("/usr/bin"), pathList(dir), ScopeMachine, true},
+		{"user PATH does not serve the machine", ScopeMachine, pathList(dir), pathList("/usr/bin"), "", false},
+		{"stale client in the user PATH", ScopeUser, pathList(stale), pathList("/usr/bin"), "", false},
+		{"stale client in the machine PATH", ScopeMachine, pathList("/usr/bin"), pathList(stale, "/usr/bin"), "", false},
+		{"stale machine client, current user one", ScopeUser, pathList(dir), pathList(stale), ScopeUser, true},
+		{"neither PATH set", ScopeUser, "", "", "", false},
+	}
+	for _, tt := range tests {
+		paths := map[Scope]string{}
+		if tt.user != "" {
+			paths[ScopeUser] = tt.user
+		}
+		if tt.machine != "" {
+			paths[ScopeMachine] = tt.machine
+		}
+		got, found := pathProvider(scopedPath{scope: tt.scope, paths: paths}, dir)
+		if got != tt.want || found != tt.found {
+			t.Errorf("%s: pathProvider = %q, %t; want %q, %t", tt.name, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+// snapshotRecorder records the names Snapshot is asked for
+type snapshotRecorder struct {
+	Manager
+	names []string
+}
+
+func (r *snapshotRecorder) Snapshot(names ...string) (Snapshot, error) {
+	r.names = names
+	return Snapshot{Names: names}, nil
+}
+
+func TestUpdateKeepsNames(t *testing.T) {
+	// Spare capacity behind the names must not be overwritten with PATH
+	backing := []string{"OCI_LIB64", "TNS_ADMIN"}
+	names := backing[:1]
+	r := &snapshotRecorder{}
+	if err := Update(r, names, func(Manager) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"OCI_LIB64", "PATH"}; !reflect.DeepEqual(r.names, want) {
+		t.Errorf("Snapshot of %q, want %q", r.names, want)
+	}
+	if backing[1] != "TNS_ADMIN" {
+		t.Errorf("Update overwrote the caller's slice: %q", backing)
+	}
+}
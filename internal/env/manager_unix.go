@@ -0,0 +1,8 @@
+//go:build !windows
+
+package env
+
+// New returns the environment manager for Unix-like systems, which writes a shell profile snippet
+func New(scope Scope) Manager {
+	return NewProfileManager(scope)
+}
@@ -0,0 +1,6 @@
+package env
+
+// New returns the environment manager for Windows, which writes User or Machine variables
+func New(scope Scope) Manager {
+	return NewEnvVarManager(scope)
+}
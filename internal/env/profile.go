@@ -0,0 +1,418 @@
+package env
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+const (
+	blockStart      = "# >>> oraicwinconfig >>>"
+	blockEnd        = "# <<< oraicwinconfig <<<"
+	profileDSnippet = "/etc/profile.d/oracle-instantclient.sh"
+)
+
+// ProfileManager persists environment variables as export statements
+// inside a managed block of a shell profile, for Unix-like systems
+type ProfileManager struct {
+	profile string
+	ctx     context.Context
+	mu      *sync.Mutex
+	scope   Scope
+}
+
+// profileVars is the parsed content of the managed block
+type profileVars struct {
+	names  []string          // Variable names in the order they were written
+	values map[string]string // Variable values by name
+	paths  []string          // Directories prepended to PATH and the library path
+}
+
+// NewProfileManager creates a manager writing to /etc/profile.d for the machine scope,
+// and to the user's login shell profile for the user scope
+func NewProfileManager(scope Scope) *ProfileManager {
+	profile := userProfile()
+	if scope == ScopeMachine {
+		profile = profileDSnippet
+	}
+	m := NewProfileManagerFor(profile)
+	m.scope = scope
+	return m
+}
+
+// NewProfileManagerFor creates a manager writing to the given profile file
+func NewProfileManagerFor(profile string) *ProfileManager {
+	return &ProfileManager{profile: profile, mu: &sync.Mutex{}, scope: ScopeUser}
+}
+
+// ManagedBlock returns the lines opening and closing the block of a profile
+// the manager writes its variables in
+func ManagedBlock() (start, end string) {
+	return blockStart, blockEnd
+}
+
+// Scope returns the scope variables are read from and written to
+func (p *ProfileManager) Scope() Scope {
+	return p.scope
+}
+
+// WithScope returns a manager for the profile of another scope
+func (p *ProfileManager) WithScope(scope Scope) Manager {
+	m := NewProfileManager(scope)
+	m.ctx = p.ctx
+	return m
+}
+
+// userProfile picks the login profile read by the user's shell
+func userProfile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".profile"
+	}
+	if strings.HasSuffix(os.Getenv("SHELL"), "zsh") {
+		return filepath.Join(home, ".zprofile")
+	}
+	if _, err := os.Stat(filepath.Join(home, ".bash_profile")); err == nil {
+		return filepath.Join(home, ".bash_profile")
+	}
+	return filepath.Join(home, ".profile")
+}
+
+// Profile returns the path of the file holding the managed block
+func (p *ProfileManager) Profile() string {
+	return p.profile
+}
+
+// WithContext returns a copy of the manager bound to ctx
+func (p *ProfileManager) WithContext(ctx context.Context) Manager {
+	c := *p
+	c.ctx = ctx
+	return &c
+}
+
+// InvalidateCache is a no-op; the profile is re-read on every lookup
+func (p *ProfileManager) InvalidateCache(names ...string) {}
+
+// FetchUserDownloadsPath returns the XDG download directory, falling back to ~/Downloads
+// and then to a cache directory created for the purpose
+func (p *ProfileManager) FetchUserDownloadsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeUserPath, "getting user home directory")
+	}
+	candidates := []string{filepath.Join(home, "Downloads")}
+	if xdg := os.Getenv("XDG_DOWNLOAD_DIR"); xdg != "" {
+		candidates = append([]string{xdg}, candidates...)
+	}
+	for _, dir := range candidates {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, nil
+		}
+	}
+
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeUserPath, "getting user cache directory")
+	}
+	dir := filepath.Join(cache, "oraicwinconfig")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeUserPath, "creating downloads directory")
+	}
+	return dir, nil
+}
+
+// GetEnvVar retrieves a variable from the managed block.
+// PATH returns the managed directories joined by the list separator.
+func (p *ProfileManager) GetEnvVar(name string) (string, error) {
+	p.mu.Lock()
+	vars, err := p.read()
+	p.mu.Unlock()
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeEnvVarNotFound, fmt.Sprintf("getting %s environment variable", name))
+	}
+
+	value := vars.values[name]
+	if name == "PATH" {
+		value = strings.Join(vars.paths, string(os.PathListSeparator))
+	}
+	if value == "" || value == "." || value == ".." || value == "/" {
+		return "", errs.HandleError(
+			fmt.Errorf("environment variable %s not found", name),
+			errs.ErrorTypeEnvVarNotFound,
+			fmt.Sprintf("getting %s environment variable", name))
+	}
+	return value, nil
+}
+
+// ValidateEnvVar checks if an environment variable is set and points to a valid directory
+func (p *ProfileManager) ValidateEnvVar(name string) (string, error) {
+	return validateEnvVar(p, name)
+}
+
+// SetEnvVar sets a variable in the managed block
+func (p *ProfileManager) SetEnvVar(name, value string) error {
+	return p.update(fmt.Sprintf("setting %s environment variable", name), func(vars *profileVars) {
+		if _, ok := vars.values[name]; !ok {
+			vars.names = append(vars.names, name)
+		}
+		vars.values[name] = value
+	})
+}
+
+// RemoveEnvVar removes a variable from the managed block
+func (p *ProfileManager) RemoveEnvVar(name string) error {
+	return p.update(fmt.Sprintf("removing %s environment variable", name), func(vars *profileVars) {
+		delete(vars.values, name)
+		vars.names = remove(vars.names, name)
+	})
+}
+
+// Snapshot captures the current values of the named variables of the managed block
+func (p *ProfileManager) Snapshot(names ...string) (Snapshot, error) {
+	p.mu.Lock()
+	vars, err := p.read()
+	p.mu.Unlock()
+	if err != nil {
+		return Snapshot{}, errs.HandleError(err, errs.ErrorTypeEnvironment, "reading environment variables")
+	}
+	s := Snapshot{Names: names, Values: make(map[string]string)}
+	for _, name := range names {
+		if name == "PATH" {
+			if len(vars.paths) > 0 {
+				s.Values[name] = strings.Join(vars.paths, string(os.PathListSeparator))
+			}
+		} else if value, ok := vars.values[name]; ok {
+			s.Values[name] = value
+		}
+	}
+	return s, nil
+}
+
+// Restore puts back the captured values with a single write of the profile
+func (p *ProfileManager) Restore(s Snapshot) error {
+	return p.update("restoring environment variables", func(vars *profileVars) {
+		for _, name := range s.Names {
+			value, ok := s.Values[name]
+			switch {
+			case name == "PATH":
+				vars.paths = nil
+				if ok {
+					vars.paths = filepath.SplitList(value)
+				}
+			case ok:
+				if _, exists := vars.values[name]; !exists {
+					vars.names = append(vars.names, name)
+				}
+				vars.values[name] = value
+			default:
+				delete(vars.values, name)
+				vars.names = remove(vars.names, name)
+			}
+		}
+	})
+}
+
+// AppendToPath adds a directory to the managed PATH and library path entries,
+// unless the block of this scope or of the machine scope already has it
+func (p *ProfileManager) AppendToPath(newPath string) error {
+	if scope, ok := pathProvider(p, newPath); ok {
+		fmt.Printf("path %s already exists in the %s PATH\n", newPath, scope)
+		return nil
+	}
+	return p.update("updating PATH", func(vars *profileVars) {
+		vars.paths = append(vars.paths, newPath)
+	})
+}
+
+// RemoveFromPath removes a directory from the managed PATH and library path entries
+func (p *ProfileManager) RemoveFromPath(pathToRemove string) error {
+	return p.update("updating PATH", func(vars *profileVars) {
+		var paths []string
+		for _, dir := range vars.paths {
+			if !SamePath(dir, pathToRemove) {
+				paths = append(paths, dir)
+			}
+		}
+		vars.paths = paths
+	})
+}
+
+// MovePathBefore moves a directory ahead of another in the managed path entries
+func (p *ProfileManager) MovePathBefore(path, before string) error {
+	return p.update("updating PATH", func(vars *profileVars) {
+		vars.paths, _ = moveBefore(vars.paths, path, before)
+	})
+}
+
+// update reads the managed block, applies fn and writes the block back
+func (p *ProfileManager) update(operation string, fn func(*profileVars)) error {
+	if p.ctx != nil {
+		if err := p.ctx.Err(); err != nil {
+			return errs.HandleError(err, errs.ErrorTypeEnvironment, "context cancellation")
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	vars, err := p.read()
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, operation)
+	}
+	fn(vars)
+	if err := p.write(vars); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, operation)
+	}
+	return nil
+}
+
+// read parses the managed block of the profile; a missing profile yields an empty block
+func (p *ProfileManager) read() (*profileVars, error) {
+	vars := &profileVars{values: make(map[string]string)}
+	_, block, _, err := p.split()
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range block {
+		name, value, ok := parseExport(line)
+		if !ok {
+			continue
+		}
+		switch name {
+		case "ORAICWINCONFIG_PATH":
+			vars.paths = cleanPath(filepath.SplitList(value))
+		case "PATH", "LD_LIBRARY_PATH", "DYLD_LIBRARY_PATH":
+			// Derived from ORAICWINCONFIG_PATH when written
+		default:
+			vars.names = append(vars.names, name)
+			vars.values[name] = value
+		}
+	}
+	return vars, nil
+}
+
+// write replaces the managed block of the profile, keeping everything around it
+func (p *ProfileManager) write(vars *profileVars) error {
+	before, _, after, err := p.split()
+	if err != nil {
+		return err
+	}
+
+	var block []string
+	if len(vars.names) > 0 || len(vars.paths) > 0 {
+		block = append(block, blockStart, "# Managed by oraicwinconfig; changes inside this block are overwritten.")
+		for _, name := range vars.names {
+			block = append(block, fmt.Sprintf("export %s=%s", name, quote(vars.values[name])))
+		}
+		if len(vars.paths) > 0 {
+			block = append(block,
+				fmt.Sprintf("export ORAICWINCONFIG_PATH=%s", quote(strings.Join(vars.paths, string(os.PathListSeparator)))),
+				`export PATH="$ORAICWINCONFIG_PATH:$PATH"`,
+				fmt.Sprintf(`export %[1]s="$ORAICWINCONFIG_PATH${%[1]s:+:$%[1]s}"`, libraryPathVar()),
+			)
+		}
+		block = append(block, blockEnd)
+	}
+
+	lines := append(append(before, block...), after...)
+	content := strings.Join(lines, "\n")
+	if content != "" {
+		content += "\n"
+	}
+
+	// Leave the profile alone when nothing changed
+	if current, err := os.ReadFile(p.profile); err == nil && string(current) == content {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.profile), 0755); err != nil {
+		return err
+	}
+	tmp := p.profile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.profile)
+}
+
+// split returns the profile lines before, inside and after the managed block
+func (p *ProfileManager) split() (before, block, after []string, err error) {
+	f, err := os.Open(p.profile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil, nil
+	} else if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+
+	state := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case state == 0 && line == blockStart:
+			state = 1
+		case state == 1 && line == blockEnd:
+			state = 2
+		case state == 0:
+			before = append(before, line)
+		case state == 1:
+			block = append(block, line)
+		default:
+			after = append(after, line)
+		}
+	}
+	return before, block, after, scanner.Err()
+}
+
+// parseExport parses a line of the form: export NAME="value"
+func parseExport(line string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "export ")
+	if !ok {
+		return "", "", false
+	}
+	name, value, ok := strings.Cut(rest, "=")
+	if !ok {
+		return "", "", false
+	}
+	return name, unquote(value), true
+}
+
+// quote wraps a value in double quotes, escaping the characters the shell expands
+func quote(value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	return `"` + r.Replace(value) + `"`
+}
+
+// unquote reverses quote
+func unquote(value string) string {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, `"`), `"`)
+	r := strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\$`, "$", "\\`", "`")
+	return r.Replace(value)
+}
+
+// remove returns list without any occurrence of item
+func remove(list []string, item string) []string {
+	var out []string
+	for _, v := range list {
+		if v != item {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// libraryPathVar returns the variable the dynamic linker searches for shared libraries
+func libraryPathVar() string {
+	if runtime.GOOS == "darwin" {
+		return "DYLD_LIBRARY_PATH"
+	}
+	return "LD_LIBRARY_PATH"
+}
@@ -1,6 +1,9 @@
 package errs
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 type ErrorType int
 
@@ -49,4 +52,31 @@ func IsErrorType(err error, errorType ErrorType) bool {
 		return installErr.Type == errorType
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// Code returns a stable name for the type of err, for machine-readable
+// reports: "" for no error, "error" for one without a type
+func Code(err error) string {
+	if err == nil {
+		return ""
+	}
+	var installErr *InstallError
+	if !errors.As(err, &installErr) {
+		return "error"
+	}
+	switch installErr.Type {
+	case ErrorTypeDownload:
+		return "download"
+	case ErrorTypeInstall:
+		return "install"
+	case ErrorTypeEnvironment:
+		return "environment"
+	case ErrorTypeEnvVarNotFound:
+		return "env-var-not-found"
+	case ErrorTypeValidation:
+		return "validation"
+	case ErrorTypeUserPath:
+		return "user-path"
+	}
+	return "error"
+}
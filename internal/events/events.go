@@ -0,0 +1,155 @@
+// Package events carries typed notifications of an install's progress from
+// the installation engine to whatever presents it: the CLI's output, a
+// machine-readable stream, or a remote orchestrator following the installer
+// on another host. The engine emits events through the context, so code deep
+// in a step, such as a download, reports progress without knowing who listens.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind identifies what happened to a step
+type Kind string
+
+const (
+	StepStarted   Kind = "step-started"   // The step began running
+	StepProgress  Kind = "step-progress"  // The running step advanced, e.g. bytes downloaded
+	StepSucceeded Kind = "step-succeeded" // The step completed, or was skipped
+	StepFailed    Kind = "step-failed"    // The step returned an error
+)
+
+// Event is a notification about one step of an install
+type Event struct {
+	Kind    Kind      `json:"kind"`
+	Step    string    `json:"step"`
+	Index   int       `json:"index"`             // Position of the step, from 1
+	Total   int       `json:"total"`             // Number of steps in the install
+	Skipped string    `json:"skipped,omitempty"` // Why a succeeded step did not run, if it did not
+	Message string    `json:"message,omitempty"`
+	Current int64     `json:"current,omitempty"` // Progress so far, in units such as bytes
+	Size    int64     `json:"size,omitempty"`    // Expected final progress; 0 when unknown
+	Error   string    `json:"error,omitempty"`   // Why a step failed
+	Host    string    `json:"host,omitempty"`    // Machine the event came from, set by remote installs
+	Time    time.Time `json:"time"`
+}
+
+// String describes the event on one line
+func (e Event) String() string {
+	s := fmt.Sprintf("[%d/%d] %s", e.Index, e.Total, e.Step)
+	switch e.Kind {
+	case StepStarted:
+		return s
+	case StepProgress:
+		if e.Size > 0 {
+			s += fmt.Sprintf(": %d%%", e.Current*100/e.Size)
+		}
+		if e.Message != "" {
+			s += " " + e.Message
+		}
+		return s
+	case StepSucceeded:
+		if e.Skipped != "" {
+			return s + " skipped (" + e.Skipped + ")"
+		}
+		return s + " done"
+	case StepFailed:
+		return s + " failed: " + e.Error
+	}
+	return s + " " + string(e.Kind)
+}
+
+// Handler receives events. Handlers are called synchronously on the
+// installing goroutine, so they must return promptly.
+type Handler func(Event)
+
+// handlersKey and stepKey are the context keys of the handlers and the current step
+type handlersKey struct{}
+type stepKey struct{}
+
+// step is the step events emitted through a context belong to
+type step struct {
+	name         string
+	index, total int
+}
+
+// WithHandler returns a context whose events are also delivered to h
+func WithHandler(ctx context.Context, h Handler) context.Context {
+	hs, _ := ctx.Value(handlersKey{}).([]Handler)
+	return context.WithValue(ctx, handlersKey{}, append(hs[:len(hs):len(hs)], h))
+}
+
+// WithStep returns a context whose events belong to the named step, the
+// index-th of total
+func WithStep(ctx context.Context, name string, index, total int) context.Context {
+	return context.WithValue(ctx, stepKey{}, step{name: name, index: index, total: total})
+}
+
+// Emit delivers e to every handler of ctx, filling in the current step and
+// the time where e leaves them unset
+func Emit(ctx context.Context, e Event) {
+	if ctx == nil {
+		return
+	}
+	hs, _ := ctx.Value(handlersKey{}).([]Handler)
+	if len(hs) == 0 {
+		return
+	}
+	if s, ok := ctx.Value(stepKey{}).(step); ok && e.Step == "" {
+		e.Step, e.Index, e.Total = s.name, s.index, s.total
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	for _, h := range hs {
+		h(e)
+	}
+}
+
+// Progress emits a StepProgress event for the current step
+func Progress(ctx context.Context, message string, current, size int64) {
+	Emit(ctx, Event{Kind: StepProgress, Message: message, Current: current, Size: size})
+}
+
+// Channel returns a handler sending events on ch. The install waits while
+// ch is full, so whoever reads ch must keep reading until the install returns.
+func Channel(ch chan<- Event) Handler {
+	return func(e Event) { ch <- e }
+}
+
+// linePrefix marks event lines in the installer's output
+const linePrefix = "oraicwinconfig-event: "
+
+// Writer returns a handler writing each event to w as a line of JSON, for
+// remote orchestrators reading the installer's output
+func Writer(w io.Writer) Handler {
+	var mu sync.Mutex
+	return func(e Event) {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "%s%s\n", linePrefix, data)
+	}
+}
+
+// Parse decodes a line written by Writer, reporting false for other output
+func Parse(line string) (Event, bool) {
+	i := strings.Index(line, linePrefix)
+	if i < 0 {
+		return Event{}, false
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(strings.TrimSpace(line[i+len(linePrefix):])), &e); err != nil {
+		return Event{}, false
+	}
+	return e, true
+}
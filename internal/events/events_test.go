@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmit(t *testing.T) {
+	var first, second []Event
+	ctx := WithHandler(context.Background(), func(e Event) { first = append(first, e) })
+	other := WithHandler(ctx, func(e Event) { second = append(second, e) })
+	// Adding to a context must not change the handlers of its siblings
+	WithHandler(ctx, func(Event) { t.Error("sibling handler called") })
+
+	Emit(WithStep(other, "download", 1, 9), Event{Kind: StepStarted})
+	Progress(WithStep(ctx, "download", 1, 9), "pkg.zip", 50, 100)
+	if len(first) != 2 || len(second) != 1 {
+		t.Fatalf("first got %d events, second %d; want 2 and 1", len(first), len(second))
+	}
+	if e := first[1]; e.Step != "download" || e.Index != 1 || e.Total != 9 || e.Time.IsZero() {
+		t.Errorf("step and time not filled in: %+v", e)
+	}
+	if got, want := first[1].String(), "[1/9] download: 50% pkg.zip"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	Emit(context.Background(), Event{Kind: StepStarted})
+}
+
+func TestWriterParse(t *testing.T) {
+	var b strings.Builder
+	want := Event{Kind: StepFailed, Step: "extract", Index: 3, Total: 9, Error: errors.New("zip: not a valid zip file").Error(), Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	Writer(&b)(want)
+	line := strings.TrimSuffix(b.String(), "\n")
+
+	// Remote transports may prefix the line, e.g. with the host
+	got, ok := Parse("[db01] " + line)
+	if !ok || got != want {
+		t.Errorf("Parse(%q) = %+v, %t; want %+v", line, got, ok, want)
+	}
+	for _, s := range []string{"", "downloading package...", linePrefix + "{not json"} {
+		if _, ok := Parse(s); ok {
+			t.Errorf("Parse(%q) reported an event", s)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package generate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// DockerfileSpec describes the Instant Client layer to generate
+type DockerfileSpec struct {
+	OS        string // Container OS: windows or linux
+	From      string // Optional base image; a fragment without FROM is emitted when empty
+	Prefix    string // Directory the client is extracted under inside the image
+	PkgURL    string // URL of the package zip
+	SdkURL    string // URL of the SDK zip
+	PkgSHA256 string // Pinned SHA-256 of the package zip
+	SdkSHA256 string // Pinned SHA-256 of the SDK zip
+	ClientDir string // Versioned directory inside the zips, e.g. instantclient_23_7
+}
+
+// DefaultPrefix returns the default extraction directory inside an image of the given OS
+func DefaultPrefix(goos string) string {
+	if goos == "windows" {
+		return `C:\oracle`
+	}
+	return "/opt/oracle"
+}
+
+// Pin downloads the package and SDK to a temporary directory and fills in any
+// missing checksums and the client directory from what was downloaded
+func Pin(ctx context.Context, client *http.Client, spec *DockerfileSpec) error {
+	if spec.PkgSHA256 != "" && spec.SdkSHA256 != "" && spec.ClientDir != "" {
+		return nil
+	}
+	tmp, err := os.MkdirTemp("", "oraicwinconfig-pin-")
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeDownload, "creating temporary directory")
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, f := range []struct {
+		url string
+		sum *string
+		pkg bool
+	}{
+		{spec.PkgURL, &spec.PkgSHA256, true},
+		{spec.SdkURL, &spec.SdkSHA256, false},
+	} {
+		if *f.sum != "" && !(f.pkg && spec.ClientDir == "") {
+			continue
+		}
+		dst := filepath.Join(tmp, path.Base(f.url))
+		// Progress goes to stderr, as the Dockerfile may be written to stdout
+		fmt.Fprintf(os.Stderr, "downloading %s to pin its checksum...\n", f.url)
+		if err := utils.DownloadZip(ctx, client, f.url, dst); err != nil {
+			return err
+		}
+		sum, err := utils.FileSHA256(dst)
+		if err != nil {
+			return err
+		}
+		if *f.sum != "" && !strings.EqualFold(*f.sum, sum) {
+			return errs.HandleError(
+				fmt.Errorf("checksum mismatch for %s: expected %s, got %s", f.url, *f.sum, sum),
+				errs.ErrorTypeValidation,
+				"pinning checksums")
+		}
+		*f.sum = sum
+		if f.pkg && spec.ClientDir == "" {
+			if spec.ClientDir, err = utils.ZipClientDir(dst); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Dockerfile renders the Dockerfile fragment described by spec
+func Dockerfile(spec DockerfileSpec) (string, error) {
+	var tmpl *template.Template
+	switch spec.OS {
+	case "windows":
+		tmpl = windowsTemplate
+	case "linux":
+		tmpl = linuxTemplate
+	default:
+		return "", errs.HandleError(fmt.Errorf("unsupported container OS: %s", spec.OS), errs.ErrorTypeValidation, "generating Dockerfile")
+	}
+	if spec.PkgSHA256 == "" || spec.SdkSHA256 == "" || spec.ClientDir == "" {
+		return "", errs.HandleError(fmt.Errorf("checksums and client directory must be pinned"), errs.ErrorTypeValidation, "generating Dockerfile")
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, spec); err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeValidation, "generating Dockerfile")
+	}
+	return b.String(), nil
+}
+
+var windowsTemplate = template.Must(template.New("windows").Parse(`# escape=` + "`" + `
+# Oracle Instant Client layer generated by oraicwinconfig
+{{- if .From}}
+FROM {{.From}}
+{{- end}}
+SHELL ["powershell", "-Command", "$ErrorActionPreference = 'Stop'; $ProgressPreference = 'SilentlyContinue';"]
+
+ARG IC_PKG_URL={{.PkgURL}}
+ARG IC_PKG_SHA256={{.PkgSHA256}}
+ARG IC_SDK_URL={{.SdkURL}}
+ARG IC_SDK_SHA256={{.SdkSHA256}}
+
+RUN foreach ($f in @(@($env:IC_PKG_URL, $env:IC_PKG_SHA256), @($env:IC_SDK_URL, $env:IC_SDK_SHA256))) { ` + "`" + `
+      Invoke-WebRequest -UseBasicParsing -Uri $f[0] -OutFile C:\ic.zip; ` + "`" + `
+      if ((Get-FileHash C:\ic.zip -Algorithm SHA256).Hash -ne $f[1]) { throw ('checksum mismatch for ' + $f[0]) }; ` + "`" + `
+      Expand-Archive C:\ic.zip -DestinationPath '{{.Prefix}}' -Force; ` + "`" + `
+      Remove-Item C:\ic.zip ` + "`" + `
+    }; ` + "`" + `
+    New-Item -ItemType Directory -Force -Path '{{.Prefix}}\{{.ClientDir}}\network\admin' | Out-Null; ` + "`" + `
+    [Environment]::SetEnvironmentVariable('PATH', [Environment]::GetEnvironmentVariable('PATH', 'Machine') + ';{{.Prefix}}\{{.ClientDir}}', 'Machine')
+
+ENV OCI_LIB64={{.Prefix}}\{{.ClientDir}} ` + "`" + `
+    TNS_ADMIN={{.Prefix}}\{{.ClientDir}}\network\admin
+`))
+
+var linuxTemplate = template.Must(template.New("linux").Parse(`# Oracle Instant Client layer generated by oraicwinconfig
+# Requires curl, unzip and libaio in the base image
+{{- if .From}}
+FROM {{.From}}
+{{- end}}
+
+ARG IC_PKG_URL={{.PkgURL}}
+ARG IC_PKG_SHA256={{.PkgSHA256}}
+ARG IC_SDK_URL={{.SdkURL}}
+ARG IC_SDK_SHA256={{.SdkSHA256}}
+
+RUN set -eux; \
+    mkdir -p {{.Prefix}}; \
+    curl -fsSLo /tmp/ic-pkg.zip "$IC_PKG_URL"; \
+    echo "$IC_PKG_SHA256  /tmp/ic-pkg.zip" | sha256sum -c -; \
+    curl -fsSLo /tmp/ic-sdk.zip "$IC_SDK_URL"; \
+    echo "$IC_SDK_SHA256  /tmp/ic-sdk.zip" | sha256sum -c -; \
+    unzip -oq /tmp/ic-pkg.zip -d {{.Prefix}}; \
+    unzip -oq /tmp/ic-sdk.zip -d {{.Prefix}}; \
+    rm -f /tmp/ic-pkg.zip /tmp/ic-sdk.zip; \
+    mkdir -p {{.Prefix}}/{{.ClientDir}}/network/admin; \
+    echo {{.Prefix}}/{{.ClientDir}} > /etc/ld.so.conf.d/oracle-instantclient.conf; \
+    ldconfig
+
+ENV OCI_LIB64={{.Prefix}}/{{.ClientDir}} \
+    TNS_ADMIN={{.Prefix}}/{{.ClientDir}}/network/admin \
+    PATH={{.Prefix}}/{{.ClientDir}}:$PATH
+`))
@@ -0,0 +1,27 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDockerfileWindowsPath(t *testing.T) {
+	out, err := Dockerfile(DockerfileSpec{
+		OS:        "windows",
+		Prefix:    DefaultPrefix("windows"),
+		PkgSHA256: "aa",
+		SdkSHA256: "bb",
+		ClientDir: "instantclient_23_7",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The process PATH holds user entries, which must not reach Machine scope
+	want := `[Environment]::SetEnvironmentVariable('PATH', [Environment]::GetEnvironmentVariable('PATH', 'Machine') + ';C:\oracle\instantclient_23_7', 'Machine')`
+	if !strings.Contains(out, want) {
+		t.Errorf("Dockerfile does not append to the Machine PATH:\n%s", out)
+	}
+	if strings.Contains(out, "$env:PATH") {
+		t.Errorf("Dockerfile writes the process PATH:\n%s", out)
+	}
+}
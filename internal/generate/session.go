@@ -0,0 +1,45 @@
+package generate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+)
+
+// SessionSnippet renders commands that apply vars and prepend dirs to PATH in an
+// already open shell: PowerShell `$env:` assignments on Windows and POSIX exports
+// elsewhere, where the dirs also go on the library search path.
+func SessionSnippet(goos string, vars map[string]string, dirs []string) string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	if goos == "windows" {
+		for _, name := range names {
+			fmt.Fprintf(&b, "$env:%s = %s\n", name, pwsh.Quote(vars[name]))
+		}
+		for _, dir := range dirs {
+			fmt.Fprintf(&b, "$env:PATH = %s + ';' + $env:PATH\n", pwsh.Quote(dir))
+		}
+		return b.String()
+	}
+
+	q := func(s string) string { return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'" }
+	libraryPathVar := "LD_LIBRARY_PATH"
+	if goos == "darwin" {
+		libraryPathVar = "DYLD_LIBRARY_PATH"
+	}
+	for _, name := range names {
+		fmt.Fprintf(&b, "export %s=%s\n", name, q(vars[name]))
+	}
+	for _, dir := range dirs {
+		fmt.Fprintf(&b, "export PATH=%s:\"$PATH\"\n", q(dir))
+		fmt.Fprintf(&b, "export %s=%s\"${%s:+:$%s}\"\n", libraryPathVar, q(dir), libraryPathVar, libraryPathVar)
+	}
+	return b.String()
+}
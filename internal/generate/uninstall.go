@@ -0,0 +1,175 @@
+package generate
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// UninstallSpec describes what an uninstall script removes
+type UninstallSpec struct {
+	Machine     bool              // The clients were configured for every user of the machine
+	Clients     []manifest.Client // Clients recorded in the manifest of the scope
+	ManifestDir string            // Directory of the manifest, journal and logs
+	Profile     string            // Shell profile holding the managed block, on Unix-like systems
+	BlockStart  string            // Lines opening and closing the managed block
+	BlockEnd    string
+}
+
+// UninstallScript renders a script removing the clients and environment
+// recorded in the manifest without oraicwinconfig: uninstall-oraic.ps1 on
+// Windows and uninstall-oraic.sh elsewhere. Variables are only removed while
+// they still hold the values recorded, and client directories on network
+// shares are left in place, as Uninstall leaves them.
+func UninstallScript(goos string, spec UninstallSpec) Wrapper {
+	var b strings.Builder
+	if goos == "windows" {
+		psUninstallTemplate.Execute(&b, uninstallData(spec, pwsh.Quote))
+		return Wrapper{Name: "uninstall-oraic.ps1", Content: b.String(), Mode: 0644}
+	}
+	shUninstallTemplate.Execute(&b, uninstallData(spec, func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}))
+	return Wrapper{Name: "uninstall-oraic.sh", Content: b.String(), Mode: 0755}
+}
+
+// uninstallVar is a variable to remove and the value it was set to, quoted
+type uninstallVar struct {
+	Name, Value string
+}
+
+// uninstallTemplateData holds the quoted values substituted into an uninstall template
+type uninstallTemplateData struct {
+	Machine              bool
+	Names                string
+	Vars                 []uninstallVar
+	Path, ClientDirs     []string
+	Shared               []string // Client directories on network shares, unquoted for comments
+	ManifestDir, Profile string
+	BlockStart, BlockEnd string
+}
+
+// uninstallData quotes the spec's values with q
+func uninstallData(spec UninstallSpec, q func(string) string) uninstallTemplateData {
+	data := uninstallTemplateData{Machine: spec.Machine, ManifestDir: q(spec.ManifestDir), Profile: q(spec.Profile), BlockStart: spec.BlockStart, BlockEnd: spec.BlockEnd}
+	vars := make(map[string]string)
+	var names []string
+	for _, c := range spec.Clients {
+		names = append(names, filepath.Base(c.ClientDir))
+		for name, value := range c.Vars {
+			vars[name] = value
+		}
+		for _, dir := range c.Path {
+			data.Path = append(data.Path, q(dir))
+		}
+		if utils.IsUNC(c.ClientDir) {
+			data.Shared = append(data.Shared, c.ClientDir)
+		} else {
+			data.ClientDirs = append(data.ClientDirs, q(c.ClientDir))
+		}
+	}
+	for name, value := range vars {
+		data.Vars = append(data.Vars, uninstallVar{Name: name, Value: q(value)})
+	}
+	sort.Slice(data.Vars, func(i, j int) bool { return data.Vars[i].Name < data.Vars[j].Name })
+	data.Names = strings.Join(names, ", ")
+	return data
+}
+
+var psUninstallTemplate = template.Must(template.New("ps1").Parse(`# Removes Oracle Instant Client {{.Names}} and the environment configured for it,
+# without needing oraicwinconfig. Generated by oraicwinconfig.
+# Usage: uninstall-oraic.ps1 [-WhatIf]
+[CmdletBinding(SupportsShouldProcess)]
+param()
+$ErrorActionPreference = 'Stop'
+{{- if .Machine}}
+$target = 'Machine'
+$principal = New-Object Security.Principal.WindowsPrincipal([Security.Principal.WindowsIdentity]::GetCurrent())
+if (-not $principal.IsInRole([Security.Principal.WindowsBuiltInRole]::Administrator)) {
+    Write-Error 'run this script as administrator, as it changes the environment of every user'
+    exit 1
+}
+{{- else}}
+$target = 'User'
+{{- end}}
+
+# Variables are only removed while they still hold the values that were set
+$vars = [ordered]@{
+{{- range .Vars}}
+    '{{.Name}}' = {{.Value}}
+{{- end}}
+}
+foreach ($name in $vars.Keys) {
+    $value = [Environment]::GetEnvironmentVariable($name, $target)
+    if ($null -eq $value) { continue }
+    if ($value -ne $vars[$name]) {
+        Write-Warning "leaving $name, which now holds $value"
+        continue
+    }
+    if ($PSCmdlet.ShouldProcess($name, 'Remove environment variable')) {
+        [Environment]::SetEnvironmentVariable($name, $null, $target)
+    }
+}
+
+$dirs = @({{range $i, $d := .Path}}{{if $i}}, {{end}}{{$d}}{{end}})
+$path = [Environment]::GetEnvironmentVariable('Path', $target)
+if ($path) {
+    $kept = @($path -split ';' | Where-Object {
+        $entry = $_.TrimEnd('\')
+        $_ -ne '' -and -not ($dirs | Where-Object { $_.TrimEnd('\') -ieq $entry })
+    })
+    $newPath = $kept -join ';'
+    if ($newPath -ne $path -and $PSCmdlet.ShouldProcess('Path', 'Remove the client directories')) {
+        [Environment]::SetEnvironmentVariable('Path', $newPath, $target)
+    }
+}
+{{range .Shared}}
+# {{.}} is on a network share other machines may use, so it is left in place
+{{- end}}
+foreach ($dir in @({{range $i, $d := .ClientDirs}}{{if $i}}, {{end}}{{$d}}{{end}})) {
+    if ((Test-Path -LiteralPath $dir) -and $PSCmdlet.ShouldProcess($dir, 'Remove client directory')) {
+        Remove-Item -LiteralPath $dir -Recurse -Force
+    }
+}
+
+$state = {{.ManifestDir}}
+if ((Test-Path -LiteralPath $state) -and $PSCmdlet.ShouldProcess($state, 'Remove installation manifest')) {
+    Remove-Item -LiteralPath $state -Recurse -Force
+}
+Write-Host 'Oracle Instant Client removed; open a new shell or restart applications to pick it up'
+`))
+
+var shUninstallTemplate = template.Must(template.New("sh").Parse(`#!/bin/sh
+# Removes Oracle Instant Client {{.Names}} and the environment configured for it,
+# without needing oraicwinconfig. Generated by oraicwinconfig.
+# Usage: uninstall-oraic.sh
+set -e
+{{- if .Machine}}
+if [ "$(id -u)" -ne 0 ]; then
+    echo "run this script as root, as it changes the environment of every user" >&2
+    exit 1
+fi
+{{- end}}
+
+# The variables and PATH entries are all held in the profile's managed block
+profile={{.Profile}}
+if [ -f "$profile" ]; then
+    tmp="$profile.uninstall.$$"
+    sed '/^{{.BlockStart}}$/,/^{{.BlockEnd}}$/d' "$profile" > "$tmp"
+    cat "$tmp" > "$profile"
+    rm -f "$tmp"
+fi
+{{range .Shared}}
+# {{.}} is on a network share other machines may use, so it is left in place
+{{- end}}
+{{- range .ClientDirs}}
+rm -rf {{.}}
+{{- end}}
+rm -rf {{.ManifestDir}}
+echo "Oracle Instant Client removed; open a new login shell to pick it up"
+`))
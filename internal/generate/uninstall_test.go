@@ -0,0 +1,99 @@
+package generate
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+)
+
+func TestUninstallScriptPowerShell(t *testing.T) {
+	spec := UninstallSpec{
+		Machine: true,
+		Clients: []manifest.Client{{
+			LibVar:    "OCI_LIB64",
+			ClientDir: `C:\oracle\instantclient_23_7`,
+			Vars:      map[string]string{"OCI_LIB64": `C:\oracle\instantclient_23_7`, "TNS_ADMIN": `C:\oracle\it's\admin`},
+			Path:      []string{`C:\oracle\instantclient_23_7`},
+		}, {
+			LibVar:    "OCI_LIB32",
+			ClientDir: `\\files\oracle\instantclient_23_7`,
+		}},
+		ManifestDir: `C:\ProgramData\oraicwinconfig`,
+	}
+	script := UninstallScript("windows", spec)
+	if script.Name != "uninstall-oraic.ps1" {
+		t.Errorf("name = %s", script.Name)
+	}
+	for _, want := range []string{
+		"$target = 'Machine'",
+		`'OCI_LIB64' = 'C:\oracle\instantclient_23_7'`,
+		`'TNS_ADMIN' = 'C:\oracle\it''s\admin'`,
+		`$dirs = @('C:\oracle\instantclient_23_7')`,
+		`# \\files\oracle\instantclient_23_7 is on a network share`,
+		`foreach ($dir in @('C:\oracle\instantclient_23_7'))`,
+		`$state = 'C:\ProgramData\oraicwinconfig'`,
+	} {
+		if !strings.Contains(script.Content, want) {
+			t.Errorf("script lacks %q:\n%s", want, script.Content)
+		}
+	}
+}
+
+func TestUninstallScriptShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	root := t.TempDir()
+	clientDir := filepath.Join(root, "oracle", "instantclient_23_7")
+	manifestDir := filepath.Join(root, "config", "oraicwinconfig")
+	profile := filepath.Join(root, ".profile")
+	for _, dir := range []string{clientDir, manifestDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := env.NewProfileManagerFor(profile)
+	if err := os.WriteFile(profile, []byte("export EDITOR=vi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetEnvVar("OCI_LIB64", clientDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AppendToPath(clientDir); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := UninstallSpec{
+		Clients:     []manifest.Client{{LibVar: "OCI_LIB64", ClientDir: clientDir}},
+		ManifestDir: manifestDir,
+		Profile:     profile,
+	}
+	spec.BlockStart, spec.BlockEnd = env.ManagedBlock()
+	script := UninstallScript("linux", spec)
+	path := filepath.Join(root, script.Name)
+	if err := os.WriteFile(path, []byte(script.Content), script.Mode); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("/bin/sh", path).CombinedOutput(); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+
+	data, err := os.ReadFile(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "export EDITOR=vi\n" {
+		t.Errorf("profile = %q, want only the line outside the managed block", data)
+	}
+	for _, dir := range []string{clientDir, manifestDir} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", dir)
+		}
+	}
+}
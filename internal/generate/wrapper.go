@@ -0,0 +1,124 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+)
+
+// WrapperSpec describes the client a launcher script configures
+type WrapperSpec struct {
+	LibVar    string // OCI_LIB64 or OCI_LIB32
+	ClientDir string // Versioned client directory
+	TNSAdmin  string // TNS_ADMIN directory; inherited from the caller when empty
+}
+
+// Wrapper is a generated launcher script
+type Wrapper struct {
+	Name    string
+	Content string
+	Mode    os.FileMode
+}
+
+// Wrappers renders the launcher scripts for the given OS: with-oracle.ps1 on Windows
+// and with-oracle.sh elsewhere. Each runs `with-oracle -- <command> [args...]` with the
+// client configured for that command and its children only.
+func Wrappers(goos string, spec WrapperSpec) []Wrapper {
+	var ps, sh strings.Builder
+	if goos == "windows" {
+		psWrapperTemplate.Execute(&ps, psWrapperData(spec))
+		return []Wrapper{{Name: "with-oracle.ps1", Content: ps.String(), Mode: 0644}}
+	}
+	shWrapperTemplate.Execute(&sh, shWrapperData(goos, spec))
+	return []Wrapper{{Name: "with-oracle.sh", Content: sh.String(), Mode: 0755}}
+}
+
+// wrapperData holds the quoted values substituted into a launcher template
+type wrapperData struct {
+	Name, LibVar, ClientDir, TNSAdmin, LibraryPathVar string
+}
+
+// psWrapperData quotes the spec's values as PowerShell single-quoted strings
+func psWrapperData(spec WrapperSpec) wrapperData {
+	q := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return pwsh.Quote(s)
+	}
+	return wrapperData{Name: filepath.Base(spec.ClientDir), LibVar: spec.LibVar, ClientDir: q(spec.ClientDir), TNSAdmin: q(spec.TNSAdmin)}
+}
+
+// shWrapperData quotes the spec's values as POSIX shell single-quoted strings
+func shWrapperData(goos string, spec WrapperSpec) wrapperData {
+	q := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	libraryPathVar := "LD_LIBRARY_PATH"
+	if goos == "darwin" {
+		libraryPathVar = "DYLD_LIBRARY_PATH"
+	}
+	return wrapperData{Name: filepath.Base(spec.ClientDir), LibVar: spec.LibVar, ClientDir: q(spec.ClientDir), TNSAdmin: q(spec.TNSAdmin), LibraryPathVar: libraryPathVar}
+}
+
+var psWrapperTemplate = template.Must(template.New("ps1").Parse(`# Runs a command with Oracle Instant Client {{.Name}} configured for it and its child processes only.
+# Generated by oraicwinconfig.
+# Usage: with-oracle.ps1 -- <command> [args...]
+$cmdArgs = @($args)
+if ($cmdArgs.Count -gt 0 -and $cmdArgs[0] -eq '--') { $cmdArgs = @($cmdArgs | Select-Object -Skip 1) }
+if ($cmdArgs.Count -eq 0) {
+    Write-Error 'usage: with-oracle.ps1 -- <command> [args...]'
+    exit 2
+}
+
+$saved = @{ {{.LibVar}} = $env:{{.LibVar}}; TNS_ADMIN = $env:TNS_ADMIN; PATH = $env:PATH }
+try {
+    $env:{{.LibVar}} = {{.ClientDir}}
+{{- if .TNSAdmin}}
+    $env:TNS_ADMIN = {{.TNSAdmin}}
+{{- end}}
+    $env:PATH = {{.ClientDir}} + ';' + $env:PATH
+    $exe, $rest = $cmdArgs
+    $global:LASTEXITCODE = $null
+    & $exe @rest
+    $ok = $?
+    # Cmdlets and functions set no exit code, only whether they succeeded
+    $code = $LASTEXITCODE
+    if ($null -eq $code) { $code = [int](-not $ok) }
+} finally {
+    # Restore the caller's session when the script is run from PowerShell
+    foreach ($k in $saved.Keys) {
+        if ($null -eq $saved[$k]) { Remove-Item -Path "env:$k" -ErrorAction SilentlyContinue }
+        else { Set-Item -Path "env:$k" -Value $saved[$k] }
+    }
+}
+exit $code
+`))
+
+var shWrapperTemplate = template.Must(template.New("sh").Parse(`#!/bin/sh
+# Runs a command with Oracle Instant Client {{.Name}} configured for it and its child processes only.
+# Generated by oraicwinconfig.
+# Usage: with-oracle.sh -- <command> [args...]
+[ "$1" = "--" ] && shift
+if [ $# -eq 0 ]; then
+    echo "usage: with-oracle.sh -- <command> [args...]" >&2
+    exit 2
+fi
+
+{{.LibVar}}={{.ClientDir}}
+export {{.LibVar}}
+{{- if .TNSAdmin}}
+TNS_ADMIN={{.TNSAdmin}}
+export TNS_ADMIN
+{{- end}}
+PATH={{.ClientDir}}:$PATH
+{{.LibraryPathVar}}={{.ClientDir}}${ {{- .LibraryPathVar}}:+:${{.LibraryPathVar}}}
+export PATH {{.LibraryPathVar}}
+exec "$@"
+`))
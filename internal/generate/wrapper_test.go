@@ -0,0 +1,23 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrapperPowerShellExitCode(t *testing.T) {
+	w := Wrappers("windows", WrapperSpec{LibVar: "OCI_LIB64", ClientDir: `C:\oracle\instantclient_23_7`})
+	if len(w) != 1 || w[0].Name != "with-oracle.ps1" {
+		t.Fatalf("wrappers = %+v", w)
+	}
+	// A failed cmdlet leaves $LASTEXITCODE unset, so its status comes from $?
+	for _, want := range []string{
+		"$global:LASTEXITCODE = $null\n    & $exe @rest\n    $ok = $?\n",
+		"if ($null -eq $code) { $code = [int](-not $ok) }",
+		"exit $code",
+	} {
+		if !strings.Contains(w[0].Content, want) {
+			t.Errorf("with-oracle.ps1 lacks %q:\n%s", want, w[0].Content)
+		}
+	}
+}
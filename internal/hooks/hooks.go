@@ -0,0 +1,114 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// Event names the point of the install a hook runs at
+type Event string
+
+const (
+	EventPostInstall  Event = "post-install"
+	EventPreUninstall Event = "pre-uninstall"
+	EventPreOverwrite Event = "pre-overwrite"
+	EventPreExtract   Event = "pre-extract"
+)
+
+// Run executes the hook commands in order with vars added to their environment,
+// each bounded by timeout, and stops at the first failure.
+// ORAICWINCONFIG_EVENT is set to the event name.
+func Run(ctx context.Context, event Event, commands []string, vars map[string]string, timeout time.Duration) error {
+	if len(commands) == 0 {
+		return nil
+	}
+	environ := append(os.Environ(), "ORAICWINCONFIG_EVENT="+string(event))
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		environ = append(environ, name+"="+vars[name])
+	}
+
+	for _, command := range commands {
+		fmt.Printf("running %s hook: %s\n", event, command)
+		if err := runOne(ctx, command, environ, timeout); err != nil {
+			return errs.HandleError(fmt.Errorf("%s: %w", command, err), errs.ErrorTypeInstall, fmt.Sprintf("running %s hook", event))
+		}
+	}
+	return nil
+}
+
+// runOne runs a single hook command with its output passed through
+func runOne(ctx context.Context, command string, environ []string, timeout time.Duration) error {
+	ctx, cancel := utils.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	fields := splitCommand(command)
+	if len(fields) == 0 {
+		return fmt.Errorf("empty hook command")
+	}
+	name, args := Interpreter(fields[0])
+	cmd := exec.CommandContext(ctx, name, append(args, fields[1:]...)...)
+	cmd.Env = environ
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		return err
+	}
+	return nil
+}
+
+// Interpreter returns the program and leading arguments that run the script at path
+func Interpreter(path string) (string, []string) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ps1":
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", path}
+	case ".sh":
+		return "sh", []string{path}
+	case ".cmd", ".bat":
+		return "cmd", []string{"/c", path}
+	}
+	return path, nil
+}
+
+// splitCommand splits a command line on whitespace, keeping double-quoted
+// fields such as paths with spaces together
+func splitCommand(command string) []string {
+	var fields []string
+	var cur strings.Builder
+	quoted, inField := false, false
+	for _, r := range command {
+		switch {
+		case r == '"':
+			quoted, inField = !quoted, true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		default:
+			cur.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
@@ -0,0 +1,58 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// answers holds the pre-recorded answers to prompts by key. Every prompt
+// takes its answer from here before AssumeYes, Silent or stdin are consulted.
+var answers map[string]string
+
+// SetAnswers makes the prompts named in a take their answers from it
+func SetAnswers(a map[string]string) {
+	answers = a
+}
+
+// Answer returns the pre-recorded answer to the prompt named key
+func Answer(key string) (string, bool) {
+	answer, ok := answers[key]
+	return answer, ok
+}
+
+// invalidAnswer reports a pre-recorded answer the prompt cannot take. Asking
+// instead would make installs from the same answer file differ.
+func invalidAnswer(key, answer, want string) error {
+	return errs.HandleError(fmt.Errorf("answer %q to %s %s", answer, key, want), errs.ErrorTypeValidation, "reading answer file")
+}
+
+// LoadAnswers reads an answer file: a JSON object of answers by key, each a
+// string, number or boolean. true and false answer confirmations as y and n.
+func LoadAnswers(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "reading answer file")
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errs.HandleError(fmt.Errorf("%s: %w", path, err), errs.ErrorTypeValidation, "parsing answer file")
+	}
+	a := make(map[string]string, len(raw))
+	for key, v := range raw {
+		switch v := v.(type) {
+		case string:
+			a[key] = v
+		case bool:
+			a[key] = strconv.FormatBool(v)
+		case float64:
+			a[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return nil, errs.HandleError(fmt.Errorf("%s: the answer to %s must be a string, number or boolean", path, key), errs.ErrorTypeValidation, "parsing answer file")
+		}
+	}
+	return a, nil
+}
@@ -2,61 +2,222 @@ package input
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 )
 
-// Confirmation prompts the user for a yes/no confirmation 
-// and returns true for 'y' and false for 'n'
-func Confirmation(label string) bool {
+// AssumeYes answers every confirmation with 'y' without reading stdin,
+// for unattended runs such as remote installs
+var AssumeYes bool
+
+// Silent never reads stdin: prompts with an answer to assume take it, and the
+// others fail at once with ErrNoInput, so a script or CI job is stopped
+// instead of left waiting on a terminal nobody watches. Unlike AssumeYes,
+// confirmations take their suggested answer, which declines changes the user
+// did not ask for.
+var Silent bool
+
+// ErrNoInput is returned when a prompt needs an answer but stdin is closed or
+// at its end, as in CI jobs and services, or Silent is set
+var ErrNoInput = errors.New("a prompt needs an answer but standard input is closed or --silent was given; pass --yes, or flags such as --force-overwrite or --keep-existing that answer it, to run unattended")
+
+// ExitNoInput is the exit status of runs stopped by ErrNoInput, so automation
+// can tell an unanswered prompt from a failed install
+const ExitNoInput = 3
+
+// maxAttempts is how many invalid answers a prompt accepts before giving up
+const maxAttempts = 3
+
+// stdin is shared by the prompts, so answers piped in ahead of a prompt are
+// not lost in the buffer of an earlier one
+var stdin = bufio.NewReader(os.Stdin)
+
+// Capture copies everything later read from stdin to w, so the answers
+// given can be recorded in a trace
+func Capture(w io.Writer) {
+	stdin = bufio.NewReader(io.TeeReader(os.Stdin, w))
+}
+
+// Feed answers the later prompts from r instead of stdin, to replay the
+// answers recorded in a trace
+func Feed(r io.Reader) {
+	stdin = bufio.NewReader(r)
+}
+
+// Confirmation prompts the user for a yes/no confirmation
+// and returns true for 'y' and false for 'n'. Silent takes the suggested
+// answer, and AssumeYes answers yes.
+func Confirmation(key, label string, suggested bool) (bool, error) {
+	if answer, ok := Answer(key); ok {
+		fmt.Fprintf(os.Stderr, "%s (y/n): %s (answered)\n", label, answer)
+		switch strings.ToLower(answer) {
+		case "y", "yes", "true":
+			return true, nil
+		case "n", "no", "false":
+			return false, nil
+		}
+		return false, invalidAnswer(key, answer, "must be y or n")
+	}
+	if AssumeYes {
+		fmt.Fprintf(os.Stderr, "%s (y/n): y (assumed)\n", label)
+		return true, nil
+	}
+	if Silent {
+		answer := "n"
+		if suggested {
+			answer = "y"
+		}
+		fmt.Fprintf(os.Stderr, "%s (y/n): %s (assumed)\n", label, answer)
+		return suggested, nil
+	}
 	choices := "y/n"
-	r := bufio.NewReader(os.Stdin)
-	attempts := 0
-	maxAttempts := 3
-	for attempts < maxAttempts {
+	r := stdin
+	for attempts := 1; attempts <= maxAttempts; attempts++ {
 		fmt.Fprintf(os.Stderr, "%s (%s): ", label, choices)
-		s, err := r.ReadString('\n')
+		s, err := readLine(r)
 		if err != nil {
-			log.Fatal("error reading input: ", err)
+			return false, err
 		}
-		s = strings.ToLower(strings.TrimSpace(s))
-		switch s {
+		switch strings.ToLower(s) {
 		case "y":
-			return true
+			return true, nil
 		case "n":
-			return false
+			return false, nil
 		default:
-			attempts++
 			fmt.Printf("must enter 'y' or 'n' (%d attempts remaining)\n", maxAttempts-attempts)
 		}
 	}
-	log.Fatal("maximum input attempts exceeded")
-	return false
+	return false, errors.New("maximum input attempts exceeded")
+}
+
+// Choice prompts the user to pick one of the options by number
+// and returns its index; with AssumeYes or Silent the first option is picked
+func Choice(key, label string, options []string) (int, error) {
+	for i, o := range options {
+		fmt.Fprintf(os.Stderr, "  %d) %s\n", i+1, o)
+	}
+	if answer, ok := Answer(key); ok {
+		fmt.Fprintf(os.Stderr, "%s (1-%d): %s (answered)\n", label, len(options), answer)
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		for i, o := range options {
+			if strings.EqualFold(o, answer) {
+				return i, nil
+			}
+		}
+		return 0, invalidAnswer(key, answer, fmt.Sprintf("must be a number from 1 to %d or the text of an option", len(options)))
+	}
+	if AssumeYes || Silent {
+		fmt.Fprintf(os.Stderr, "%s (1-%d): 1 (assumed)\n", label, len(options))
+		return 0, nil
+	}
+	r := stdin
+	for attempts := 1; attempts <= maxAttempts; attempts++ {
+		fmt.Fprintf(os.Stderr, "%s (1-%d): ", label, len(options))
+		s, err := readLine(r)
+		if err != nil {
+			return 0, err
+		}
+		if n, err := strconv.Atoi(s); err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		fmt.Printf("must enter a number from 1 to %d (%d attempts remaining)\n", len(options), maxAttempts-attempts)
+	}
+	return 0, errors.New("maximum input attempts exceeded")
+}
+
+// Text prompts the user for a line of text and returns it, or def for an
+// empty answer; with AssumeYes or Silent def is taken. An empty def makes an
+// answer required, which fails with ErrNoInput when Silent.
+func Text(key, label, def string) (string, error) {
+	prompt := label
+	if def != "" {
+		prompt += " [" + def + "]"
+	}
+	if answer, ok := Answer(key); ok && answer != "" {
+		fmt.Fprintf(os.Stderr, "%s: %s (answered)\n", prompt, answer)
+		return answer, nil
+	}
+	if (AssumeYes || Silent) && def != "" {
+		fmt.Fprintf(os.Stderr, "%s: %s (assumed)\n", prompt, def)
+		return def, nil
+	}
+	if Silent {
+		return "", silenced(key, prompt)
+	}
+	for attempts := 1; attempts <= maxAttempts; attempts++ {
+		fmt.Fprintf(os.Stderr, "%s: ", prompt)
+		s, err := readLine(stdin)
+		if err != nil {
+			return "", err
+		}
+		if s == "" {
+			s = def
+		}
+		if s != "" {
+			return s, nil
+		}
+		fmt.Printf("an answer is required (%d attempts remaining)\n", maxAttempts-attempts)
+	}
+	return "", errors.New("maximum input attempts exceeded")
 }
 
 // InstallPath prompts the user for a valid installation path
 // and validates that it is an existing directory
-func InstallPath(label string) string {
-	r := bufio.NewReader(os.Stdin)
-	attempts := 0
-	maxAttempts := 3
-	for attempts < maxAttempts {
+func InstallPath(key, label string) (string, error) {
+	if path, ok := Answer(key); ok {
+		fmt.Fprintf(os.Stderr, "%s%s (answered)\n", label, path)
+		if stat, err := os.Stat(path); err != nil || !stat.IsDir() {
+			return "", invalidAnswer(key, path, "must be an existing directory")
+		}
+		return path, nil
+	}
+	if Silent {
+		return "", silenced(key, strings.TrimSpace(label))
+	}
+	r := stdin
+	for attempts := 1; attempts <= maxAttempts; attempts++ {
 		fmt.Fprintf(os.Stderr, "%s", label)
-		path, err := r.ReadString('\n')
-		if err != nil || path == "" {
-			log.Fatal("error reading input: ", err)
+		path, err := readLine(r)
+		if err != nil {
+			return "", err
 		}
-		path = strings.TrimSpace(path)
 		if stat, err := os.Stat(path); err == nil && stat.IsDir() {
-			return path
+			return path, nil
 		} else {
 			fmt.Printf("Invalid path provided: %s (error: %v)\n", path, err)
 			fmt.Printf("Please provide a valid existing directory (%d attempts remaining)\n", maxAttempts-attempts)
 		}
-		attempts++
 	}
-	log.Fatal("maximum input attempts exceeded, installation aborted")
-	return "" // This line will never be reached due to log.Fatal above
+	return "", errors.New("maximum input attempts exceeded, installation aborted")
+}
+
+// silenced reports the prompt Silent stopped at, and the key answering it in
+// an answer file, and returns ErrNoInput
+func silenced(key, prompt string) error {
+	fmt.Fprintf(os.Stderr, "%s: no answer in silent mode; answer %q in an answer file\n", prompt, key)
+	return ErrNoInput
+}
+
+// readLine reads one answer. An unterminated last line still counts, but once
+// stdin is exhausted or unreadable ErrNoInput is returned at once, since
+// asking again cannot help.
+func readLine(r *bufio.Reader) (string, error) {
+	s, err := r.ReadString('\n')
+	s = strings.TrimSpace(s)
+	switch {
+	case err == nil || (errors.Is(err, io.EOF) && s != ""):
+		return s, nil
+	case errors.Is(err, io.EOF):
+		fmt.Fprintln(os.Stderr)
+		return "", ErrNoInput
+	default:
+		fmt.Fprintln(os.Stderr)
+		return "", fmt.Errorf("%w: %v", ErrNoInput, err)
+	}
 }
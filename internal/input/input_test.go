@@ -0,0 +1,117 @@
+package input_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/mghoff/oraicwinconfig/internal/input"
+)
+
+func TestSilent(t *testing.T) {
+	input.Silent = true
+	t.Cleanup(func() { input.Silent = false })
+	// Answers waiting on stdin are never read in silent mode
+	input.Feed(strings.NewReader("n\n2\nanswer\n"))
+
+	if ok, err := input.Confirmation("continue", "Continue?", true); !ok || err != nil {
+		t.Errorf("Confirmation = %t, %v; want the suggested yes", ok, err)
+	}
+	if ok, err := input.Confirmation("fix-path", "Reorder PATH?", false); ok || err != nil {
+		t.Errorf("Confirmation = %t, %v; want the suggested no", ok, err)
+	}
+	// --yes still answers yes to every confirmation
+	input.AssumeYes = true
+	t.Cleanup(func() { input.AssumeYes = false })
+	if ok, err := input.Confirmation("fix-path", "Reorder PATH?", false); !ok || err != nil {
+		t.Errorf("Confirmation with AssumeYes = %t, %v; want yes", ok, err)
+	}
+	input.AssumeYes = false
+	if i, err := input.Choice("pick", "Pick", []string{"a", "b"}); i != 0 || err != nil {
+		t.Errorf("Choice = %d, %v; want the first option", i, err)
+	}
+	if s, err := input.Text("name", "Name", "default"); s != "default" || err != nil {
+		t.Errorf("Text = %q, %v; want the default", s, err)
+	}
+	if _, err := input.Text("name", "Name", ""); !errors.Is(err, input.ErrNoInput) {
+		t.Errorf("Text without a default = %v, want ErrNoInput", err)
+	}
+	if _, err := input.InstallPath("install-location", "Path"); !errors.Is(err, input.ErrNoInput) {
+		t.Errorf("InstallPath = %v, want ErrNoInput", err)
+	}
+}
+
+func TestClosedStdin(t *testing.T) {
+	t.Cleanup(func() { input.Feed(os.Stdin) })
+
+	// Every prompt fails at once on closed stdin instead of asking again
+	prompts := map[string]func() error{
+		"Confirmation": func() error { _, err := input.Confirmation("continue", "Continue?", true); return err },
+		"Choice":       func() error { _, err := input.Choice("pick", "Pick", []string{"a", "b"}); return err },
+		"Text":         func() error { _, err := input.Text("name", "Name", "default"); return err },
+		"InstallPath":  func() error { _, err := input.InstallPath("install-location", "Path"); return err },
+	}
+	for name, prompt := range prompts {
+		input.Feed(strings.NewReader(""))
+		if err := prompt(); !errors.Is(err, input.ErrNoInput) {
+			t.Errorf("%s on closed stdin = %v, want ErrNoInput", name, err)
+		}
+	}
+
+	// stdin running out after an invalid answer stops there too
+	input.Feed(strings.NewReader("maybe\n"))
+	if _, err := input.Confirmation("continue", "Continue?", true); !errors.Is(err, input.ErrNoInput) {
+		t.Errorf("Confirmation after an invalid answer = %v, want ErrNoInput", err)
+	}
+	// An unterminated last line is still an answer
+	input.Feed(strings.NewReader("y"))
+	if ok, err := input.Confirmation("continue", "Continue?", false); !ok || err != nil {
+		t.Errorf("Confirmation of an unterminated y = %t, %v", ok, err)
+	}
+	// Unreadable stdin is treated as closed
+	input.Feed(iotest.ErrReader(errors.New("read failed")))
+	if _, err := input.Text("name", "Name", ""); !errors.Is(err, input.ErrNoInput) || !strings.Contains(err.Error(), "read failed") {
+		t.Errorf("Text on unreadable stdin = %v, want ErrNoInput wrapping the read error", err)
+	}
+}
+
+func TestAnswers(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "answers.json")
+	os.WriteFile(path, []byte(`{"continue": false, "pick": "B", "index": 2, "name": "db1", "install-location": "`+filepath.ToSlash(dir)+`", "bad": "maybe"}`), 0644)
+	a, err := input.LoadAnswers(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.SetAnswers(a)
+	input.Silent = true
+	t.Cleanup(func() { input.SetAnswers(nil); input.Silent = false })
+
+	// Recorded answers win over the answers assumed in silent mode
+	if ok, err := input.Confirmation("continue", "Continue?", true); ok || err != nil {
+		t.Errorf("Confirmation = %t, %v; want the recorded no", ok, err)
+	}
+	if i, err := input.Choice("pick", "Pick", []string{"a", "b"}); i != 1 || err != nil {
+		t.Errorf("Choice by text = %d, %v", i, err)
+	}
+	if i, err := input.Choice("index", "Pick", []string{"a", "b"}); i != 1 || err != nil {
+		t.Errorf("Choice by number = %d, %v", i, err)
+	}
+	if s, err := input.Text("name", "Name", "default"); s != "db1" || err != nil {
+		t.Errorf("Text = %q, %v", s, err)
+	}
+	if p, err := input.InstallPath("install-location", "Path"); err != nil || filepath.Clean(p) != dir {
+		t.Errorf("InstallPath = %q, %v", p, err)
+	}
+	if _, err := input.Confirmation("bad", "Continue?", true); err == nil || errors.Is(err, input.ErrNoInput) {
+		t.Errorf("an invalid answer gave %v, want a validation error", err)
+	}
+
+	os.WriteFile(path, []byte(`{"continue": null}`), 0644)
+	if _, err := input.LoadAnswers(path); err == nil {
+		t.Error("a null answer was accepted")
+	}
+}
@@ -0,0 +1,173 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+)
+
+// Step names a unit of the install that is recorded once it completes
+type Step string
+
+// Journal records the completed steps of an install in progress, so an
+// interrupted install can resume instead of starting over. A nil Journal
+// records nothing.
+type Journal struct {
+	Package     string            `json:"package"`     // URL of the package being installed
+	SDK         string            `json:"sdk"`         // URL of the SDK being installed
+	Arch        string            `json:"arch"`        // Architecture of the client
+	InstallPath string            `json:"installPath"` // Directory the client is extracted into
+	Extant      bool              `json:"extant"`      // Whether a tnsnames.ora from a previous install is carried over
+	ClientDir   string            `json:"clientDir,omitempty"`
+	Checksums   map[string]string `json:"checksums,omitempty"` // SHA-256 of the downloaded archives by path
+	Done        []Step            `json:"done"`
+	Updated     time.Time         `json:"updated"`
+
+	path string
+}
+
+// Path returns the journal file for installs of the given configuration's scope and architecture
+func Path(conf *config.InstallConfig) (string, error) {
+	dir, err := manifest.Dir(conf.Scope)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("journal-%s.json", conf.Arch)), nil
+}
+
+// Load returns the journal of an interrupted install of the same package,
+// or nil if there is none
+func Load(conf *config.InstallConfig) (*Journal, error) {
+	path, err := Path(conf)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeInstall, "reading install journal")
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeInstall, "parsing install journal")
+	}
+	j.path = path
+	if j.Checksums == nil {
+		j.Checksums = make(map[string]string)
+	}
+	if j.Package != conf.BaseURL+conf.PkgFile || j.SDK != conf.BaseURL+conf.SdkFile || j.Arch != conf.Arch {
+		return nil, nil
+	}
+	return &j, nil
+}
+
+// Open returns the journal for installing conf: the interrupted install's journal
+// when it installs to the same path, or a new one
+func Open(conf *config.InstallConfig) *Journal {
+	if j, err := Load(conf); err == nil && j != nil && j.InstallPath == conf.InstallPath {
+		return j
+	}
+	path, err := Path(conf)
+	if err != nil {
+		fmt.Printf("warning: install journal disabled: %v\n", err)
+		return nil
+	}
+	return &Journal{
+		Package:     conf.BaseURL + conf.PkgFile,
+		SDK:         conf.BaseURL + conf.SdkFile,
+		Arch:        conf.Arch,
+		InstallPath: conf.InstallPath,
+		Extant:      conf.Extant,
+		Checksums:   make(map[string]string),
+		path:        path,
+	}
+}
+
+// Completed reports whether step has been recorded
+func (j *Journal) Completed(step Step) bool {
+	if j == nil {
+		return false
+	}
+	for _, s := range j.Done {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// Complete records step and saves the journal. A journal that cannot be
+// saved only costs the ability to resume, so the failure is reported, not returned.
+func (j *Journal) Complete(step Step) {
+	if j == nil || j.Completed(step) {
+		return
+	}
+	j.Done = append(j.Done, step)
+	if err := j.save(); err != nil {
+		fmt.Printf("warning: could not save the install journal: %v\n", err)
+	}
+}
+
+// Invalidate forgets step and every step after it, when a re-validation fails
+// or the step is undone, and saves the journal if anything was forgotten
+func (j *Journal) Invalidate(step Step) {
+	if j == nil {
+		return
+	}
+	for i, s := range j.Done {
+		if s == step {
+			j.Done = j.Done[:i]
+			if err := j.save(); err != nil {
+				fmt.Printf("warning: could not save the install journal: %v\n", err)
+			}
+			return
+		}
+	}
+}
+
+// Finish deletes the journal once the install has completed
+func (j *Journal) Finish() {
+	if j == nil {
+		return
+	}
+	if err := os.Remove(j.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("warning: could not remove the install journal: %v\n", err)
+	}
+}
+
+// Discard deletes any journal for conf, so the next install starts over
+func Discard(conf *config.InstallConfig) error {
+	path, err := Path(conf)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "removing install journal")
+	}
+	return nil
+}
+
+// save writes the journal atomically
+func (j *Journal) save() error {
+	j.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return err
+	}
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
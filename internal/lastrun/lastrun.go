@@ -0,0 +1,116 @@
+// Package lastrun keeps a small status file describing the last run of the
+// installer, next to the manifest of its scope, so monitoring and inventory
+// agents can read the state of the client without running the installer.
+package lastrun
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// FileName is the name of the status file in the manifest directory
+const FileName = "last-run.json"
+
+// Results of a run
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Status describes a run and the client it left configured
+type Status struct {
+	Time          time.Time `json:"time"`   // When the run ended, in UTC
+	Action        string    `json:"action"` // Subcommand run, or install for the installer itself
+	Version       string    `json:"version"`
+	Scope         string    `json:"scope"`
+	Result        string    `json:"result"`
+	ExitCode      int       `json:"exitCode"`
+	ErrorCode     string    `json:"errorCode,omitempty"` // Type of the failure, as named by errs.Code
+	Error         string    `json:"error,omitempty"`
+	ClientDir     string    `json:"clientDir,omitempty"` // Client recorded in the manifest after the run
+	ClientVersion string    `json:"clientVersion,omitempty"`
+}
+
+// New describes a run of action that ended now with err and exit code,
+// along with the client recorded for libVar in the scope's manifest
+func New(scope env.Scope, action, version, libVar string, err error, code int) Status {
+	s := Status{
+		Time:     time.Now().UTC(),
+		Action:   action,
+		Version:  version,
+		Scope:    string(scope),
+		Result:   ResultSuccess,
+		ExitCode: code,
+	}
+	if err != nil {
+		s.Result, s.ErrorCode, s.Error = ResultFailure, errs.Code(err), err.Error()
+	}
+	if m, err := manifest.Load(scope); err == nil {
+		c, ok := m.Client(libVar)
+		if !ok && len(m.Clients) > 0 {
+			c, ok = m.Clients[0], true
+		}
+		if ok {
+			s.ClientDir, s.ClientVersion = c.ClientDir, utils.ClientVersion(c.ClientDir)
+		}
+	}
+	return s
+}
+
+// Path returns where the status file of scope is kept
+func Path(scope env.Scope) (string, error) {
+	dir, err := manifest.Dir(scope)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Write replaces the status file of scope with s
+func Write(scope env.Scope, s Status) error {
+	path, err := Path(scope)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, "encoding status file")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, "creating status file directory")
+	}
+	// Readers polling the file never see it half written
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, "writing status file")
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, "writing status file")
+	}
+	return nil
+}
+
+// Read returns the status file of scope
+func Read(scope env.Scope) (Status, error) {
+	var s Status
+	path, err := Path(scope)
+	if err != nil {
+		return s, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, errs.HandleError(err, errs.ErrorTypeEnvironment, "reading status file")
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, errs.HandleError(fmt.Errorf("%s: %w", path, err), errs.ErrorTypeEnvironment, "reading status file")
+	}
+	return s, nil
+}
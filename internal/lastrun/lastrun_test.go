@@ -0,0 +1,51 @@
+package lastrun_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/lastrun"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/testsupport"
+)
+
+func TestWriteRead(t *testing.T) {
+	home := testsupport.Sandbox(t)
+	if _, err := lastrun.Read(env.ScopeUser); err == nil {
+		t.Error("read a status file before any run")
+	}
+
+	clientDir := filepath.Join(home, "oracle", "instantclient_23_7")
+	m := &manifest.Manifest{Clients: []manifest.Client{{LibVar: "OCI_LIB64", ClientDir: clientDir}}}
+	if err := m.Save(env.ScopeUser); err != nil {
+		t.Fatal(err)
+	}
+	runErr := fmt.Errorf("upgrade failed: %w", errs.HandleError(errors.New("timed out"), errs.ErrorTypeDownload, "downloading package"))
+	s := lastrun.New(env.ScopeUser, "upgrade", "1.2.3", "OCI_LIB64", runErr, 1)
+	if err := lastrun.Write(env.ScopeUser, s); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := lastrun.Read(env.ScopeUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Action != "upgrade" || got.Version != "1.2.3" || got.Scope != "user" || got.Result != lastrun.ResultFailure ||
+		got.ExitCode != 1 || got.ErrorCode != "download" || got.Error != runErr.Error() {
+		t.Errorf("status = %+v", got)
+	}
+	if got.ClientDir != clientDir || got.ClientVersion != "23.7" {
+		t.Errorf("client = %s %s, want %s 23.7", got.ClientDir, got.ClientVersion, clientDir)
+	}
+	if !got.Time.Equal(s.Time) {
+		t.Errorf("time = %v, want %v", got.Time, s.Time)
+	}
+
+	if s := lastrun.New(env.ScopeUser, "install", "1.2.3", "OCI_LIB64", nil, 0); s.Result != lastrun.ResultSuccess || s.ErrorCode != "" {
+		t.Errorf("successful run = %+v", s)
+	}
+}
@@ -0,0 +1,195 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+	"github.com/mghoff/oraicwinconfig/internal/version"
+)
+
+// FileName is the default name of the lock file
+const FileName = "oraic.lock"
+
+// FormatVersion is the version of the lock file format written by this installer
+const FormatVersion = 1
+
+// Lock pins the exact artifacts installed for each platform, so every machine
+// installing from it gets byte-identical clients
+type Lock struct {
+	FormatVersion int       `json:"formatVersion"`
+	Version       string    `json:"version"` // Installer version that wrote the lock
+	Created       time.Time `json:"created"`
+	Clients       []Client  `json:"clients"`
+}
+
+// Client pins the package and SDK of one platform, and SQL*Plus if it was
+// pinned with them
+type Client struct {
+	OS            string `json:"os"`
+	Arch          string `json:"arch"`
+	ClientDir     string `json:"clientDir,omitempty"` // Versioned directory the package extracts to; unknown for disk images
+	BaseURL       string `json:"baseURL"`
+	PkgFile       string `json:"pkgFile"`
+	PkgSHA256     string `json:"pkgSHA256"`
+	SdkFile       string `json:"sdkFile"`
+	SdkSHA256     string `json:"sdkSHA256"`
+	SQLPlusSHA256 string `json:"sqlplusSHA256,omitempty"` // SQL*Plus package released with the SDK and named after it
+}
+
+// Generate downloads the package and SDK each configuration would install, and
+// SQL*Plus if it asks for it, to a temporary directory and pins their checksums and client directory
+func Generate(ctx context.Context, client *http.Client, confs []*config.InstallConfig) (*Lock, error) {
+	tmp, err := os.MkdirTemp("", "oraicwinconfig-lock-")
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeDownload, "creating temporary directory")
+	}
+	defer os.RemoveAll(tmp)
+	return pin(ctx, client, confs, func(conf *config.InstallConfig, file string) string {
+		return filepath.Join(tmp, conf.OS+"-"+conf.Arch+"-"+file)
+	})
+}
+
+// Download downloads the archives each configuration would install into dir under their own names, keeping them, and pins them as Generate does. The
+// configurations must not share a file name.
+func Download(ctx context.Context, client *http.Client, confs []*config.InstallConfig, dir string) (*Lock, error) {
+	seen := make(map[string]string)
+	for _, conf := range confs {
+		files := []string{conf.PkgFile, conf.SdkFile}
+		if conf.SQLPlus {
+			files = append(files, conf.SQLPlusFile())
+		}
+		for _, file := range files {
+			if other, ok := seen[file]; ok {
+				return nil, errs.HandleError(
+					fmt.Errorf("%s and %s/%s both use the file name %s", other, conf.OS, conf.Arch, file),
+					errs.ErrorTypeValidation,
+					"pinning artifacts")
+			}
+			seen[file] = conf.OS + "/" + conf.Arch
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeDownload, "creating directory")
+	}
+	return pin(ctx, client, confs, func(conf *config.InstallConfig, file string) string {
+		return filepath.Join(dir, file)
+	})
+}
+
+// pin downloads the package and SDK of each configuration, and SQL*Plus if it
+// asks for it, to the path dst gives and records their checksums and client
+// directory
+func pin(ctx context.Context, client *http.Client, confs []*config.InstallConfig, dst func(conf *config.InstallConfig, file string) string) (*Lock, error) {
+	l := &Lock{FormatVersion: FormatVersion, Version: version.Version, Created: time.Now().UTC()}
+	for _, conf := range confs {
+		c := Client{OS: conf.OS, Arch: conf.Arch, BaseURL: conf.BaseURL, PkgFile: conf.PkgFile, SdkFile: conf.SdkFile}
+		type artifact struct {
+			file   string
+			sum    *string
+			limits config.SizeLimits
+		}
+		artifacts := []artifact{
+			{conf.PkgFile, &c.PkgSHA256, conf.PkgSize},
+			{conf.SdkFile, &c.SdkSHA256, conf.SdkSize},
+		}
+		if conf.SQLPlus {
+			artifacts = append(artifacts, artifact{conf.SQLPlusFile(), &c.SQLPlusSHA256, conf.SQLPlusSize})
+		}
+		for _, f := range artifacts {
+			path := dst(conf, f.file)
+			fmt.Fprintf(os.Stderr, "downloading %s to pin its checksum...\n", conf.BaseURL+f.file)
+			if err := utils.DownloadArchive(ctx, client, conf.BaseURL+f.file, path, f.limits, conf.HTTP.Retry); err != nil {
+				return nil, err
+			}
+			var err error
+			if *f.sum, err = utils.FileSHA256(path); err != nil {
+				return nil, err
+			}
+			if f.file == conf.PkgFile && strings.HasSuffix(f.file, ".zip") {
+				if c.ClientDir, err = utils.ZipClientDir(path); err != nil {
+					return nil, err
+				}
+			}
+		}
+		l.Clients = append(l.Clients, c)
+	}
+	return l, nil
+}
+
+// Client returns the pinned artifacts of a platform
+func (l *Lock) Client(goos, goarch string) (Client, bool) {
+	for _, c := range l.Clients {
+		if c.OS == goos && c.Arch == goarch {
+			return c, true
+		}
+	}
+	return Client{}, false
+}
+
+// Apply points conf at the artifacts pinned for its platform and makes the
+// install verify them against the pinned checksums. An install with SQL*Plus
+// needs the lock to pin it too.
+func (l *Lock) Apply(conf *config.InstallConfig) error {
+	c, ok := l.Client(conf.OS, conf.Arch)
+	if !ok {
+		return errs.HandleError(
+			fmt.Errorf("the lock file pins no client for %s/%s; regenerate it with lock --platform %s/%s", conf.OS, conf.Arch, conf.OS, conf.Arch),
+			errs.ErrorTypeValidation,
+			"applying lock file")
+	}
+	if conf.SQLPlus && c.SQLPlusSHA256 == "" {
+		return errs.HandleError(
+			fmt.Errorf("the lock file does not pin SQL*Plus for %s/%s; regenerate it with lock --sqlplus", conf.OS, conf.Arch),
+			errs.ErrorTypeValidation,
+			"applying lock file")
+	}
+	conf.BaseURL, conf.PkgFile, conf.SdkFile = c.BaseURL, c.PkgFile, c.SdkFile
+	conf.Pins = config.PinConfig{PkgSHA256: c.PkgSHA256, SdkSHA256: c.SdkSHA256, SQLPlusSHA256: c.SQLPlusSHA256, ClientDir: c.ClientDir, Source: "the lock file"}
+	conf.Flavor = config.PkgFlavor(c.PkgFile)
+	return nil
+}
+
+// Save writes the lock to path as JSON
+func (l *Lock) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "encoding lock file")
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "writing lock file")
+	}
+	return nil
+}
+
+// Load reads a lock written by Save
+func Load(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "reading lock file")
+	}
+	var l Lock
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "parsing lock file")
+	}
+	if l.FormatVersion != FormatVersion {
+		return nil, errs.HandleError(
+			fmt.Errorf("lock format version %d is not supported; this installer reads version %d", l.FormatVersion, FormatVersion),
+			errs.ErrorTypeValidation,
+			"parsing lock file")
+	}
+	for _, c := range l.Clients {
+		if c.PkgSHA256 == "" || c.SdkSHA256 == "" {
+			return nil, errs.HandleError(fmt.Errorf("%s/%s has no pinned checksums", c.OS, c.Arch), errs.ErrorTypeValidation, "parsing lock file")
+		}
+	}
+	return &l, nil
+}
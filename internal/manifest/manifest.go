@@ -0,0 +1,194 @@
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// fileName is the name of the manifest file within its directory
+const fileName = "manifest.json"
+
+// Manifest records what the installer configured in one scope
+type Manifest struct {
+	Clients     []Client          `json:"clients"`
+	TNSProfiles map[string]string `json:"tnsProfiles,omitempty"` // Named TNS_ADMIN directories, by name
+	TNSSource   *TNSSource        `json:"tnsSource,omitempty"`   // Canonical tnsnames.ora installed by tns sync
+	History     []State           `json:"history,omitempty"`     // Recorded sets of clients, oldest first, for rollback
+}
+
+// State is the set of clients recorded at a point in time
+type State struct {
+	Time    time.Time `json:"time"`
+	Clients []Client  `json:"clients"`
+}
+
+// maxHistory bounds the states kept for rollback
+const maxHistory = 20
+
+// TNSSource is where tns sync fetches the canonical tnsnames.ora from
+type TNSSource struct {
+	URL  string `json:"url"`            // HTTPS URL of the file, or URL of a Git repository holding it
+	Git  bool   `json:"git,omitempty"`  // URL is a Git repository
+	Ref  string `json:"ref,omitempty"`  // Branch or tag to take the file from; the default branch when empty
+	Path string `json:"path,omitempty"` // Path of the file within the repository
+	// SQLNet locates a sqlnet.ora to install as well: a URL for HTTPS
+	// sources, a path within the repository for Git ones
+	SQLNet string `json:"sqlnet,omitempty"`
+}
+
+// Client records one installed client and the environment it was configured with
+type Client struct {
+	LibVar      string            `json:"libVar"`         // OCI_LIB64 or OCI_LIB32
+	ClientDir   string            `json:"clientDir"`      // Versioned client directory
+	InstallPath string            `json:"installPath"`    // Directory the client was extracted into
+	Arch        string            `json:"arch"`           // Architecture of the client
+	PkgFile     string            `json:"pkgFile"`        // Package the client was installed from
+	Source      string            `json:"source,omitempty"` // Base URL or file share the package was downloaded from
+	EnvMode     string            `json:"envMode"`        // global, wrapper or both
+	Vars        map[string]string `json:"vars,omitempty"` // Environment variables set, by name
+	Path        []string          `json:"path,omitempty"` // Directories added to PATH
+	ExtraEnv    map[string]string `json:"extraEnv,omitempty"` // Templates of the extra variables set, by name
+	ODPNet      string            `json:"odpNet,omitempty"` // ODP.NET drivers set up with the client: managed, unmanaged or both
+	SQLPlus     bool              `json:"sqlplus,omitempty"` // Whether SQL*Plus was installed with the client
+	Version     string            `json:"version"`        // Installer version that wrote the record
+	InstalledAt time.Time         `json:"installedAt"`
+}
+
+// Dir returns the directory holding the manifest of the given scope:
+// the user's config directory, or a machine-wide data directory
+func Dir(scope env.Scope) (string, error) {
+	if scope == env.ScopeMachine {
+		if runtime.GOOS == "windows" {
+			data := os.Getenv("ProgramData")
+			if data == "" {
+				data = `C:\ProgramData`
+			}
+			return filepath.Join(data, "oraicwinconfig"), nil
+		}
+		return "/var/lib/oraicwinconfig", nil
+	}
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeUserPath, "getting user config directory")
+	}
+	return filepath.Join(config, "oraicwinconfig"), nil
+}
+
+// Load reads the manifest of the given scope; a missing manifest is empty
+func Load(scope env.Scope) (*Manifest, error) {
+	dir, err := Dir(scope)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, fileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return &Manifest{}, nil
+	} else if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeEnvironment, "reading manifest")
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "parsing manifest")
+	}
+	return &m, nil
+}
+
+// Save writes the manifest of the given scope, replacing the previous one atomically
+func (m *Manifest) Save(scope env.Scope) error {
+	dir, err := Dir(scope)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, "encoding manifest")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, "creating manifest directory")
+	}
+	tmp := filepath.Join(dir, fileName+".tmp")
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, "writing manifest")
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, fileName)); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, "writing manifest")
+	}
+	return nil
+}
+
+// Client returns the record of the client configured through libVar
+func (m *Manifest) Client(libVar string) (Client, bool) {
+	for _, c := range m.Clients {
+		if c.LibVar == libVar {
+			return c, true
+		}
+	}
+	return Client{}, false
+}
+
+// Put records c, replacing any client configured through the same variable
+func (m *Manifest) Put(c Client) {
+	m.Remove(c.LibVar)
+	m.Clients = append(m.Clients, c)
+}
+
+// Remove drops the record of the client configured through libVar
+func (m *Manifest) Remove(libVar string) {
+	clients := m.Clients[:0]
+	for _, c := range m.Clients {
+		if c.LibVar != libVar {
+			clients = append(clients, c)
+		}
+	}
+	m.Clients = clients
+}
+
+// Record loads the manifest of the given scope, applies fn and saves it. A
+// change to the recorded clients is added to the history, so it can be
+// rolled back to later.
+func Record(scope env.Scope, fn func(*Manifest)) error {
+	m, err := Load(scope)
+	if err != nil {
+		return err
+	}
+	before := append([]Client(nil), m.Clients...)
+	fn(m)
+	m.remember(before, time.Now().UTC())
+	return m.Save(scope)
+}
+
+// remember adds the current clients to the history if they differ from
+// before. Manifests written before the history was kept start it with the
+// clients they already held, dated by their latest install.
+func (m *Manifest) remember(before []Client, now time.Time) {
+	if sameClients(before, m.Clients) {
+		return
+	}
+	if len(m.History) == 0 && len(before) > 0 {
+		var at time.Time
+		for _, c := range before {
+			if c.InstalledAt.After(at) {
+				at = c.InstalledAt
+			}
+		}
+		m.History = append(m.History, State{Time: at, Clients: before})
+	}
+	m.History = append(m.History, State{Time: now, Clients: append([]Client(nil), m.Clients...)})
+	if len(m.History) > maxHistory {
+		m.History = append([]State(nil), m.History[len(m.History)-maxHistory:]...)
+	}
+}
+
+// sameClients reports whether a and b record the same clients
+func sameClients(a, b []Client) bool {
+	da, _ := json.Marshal(a)
+	db, _ := json.Marshal(b)
+	return string(da) == string(db)
+}
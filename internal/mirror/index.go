@@ -0,0 +1,148 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/lock"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// FormatVersion is the version of the index format read by this installer
+const FormatVersion = 1
+
+// maxIndexSize bounds the index and signature downloads
+const maxIndexSize = 1 << 20
+
+// Index lists the releases an internal mirror serves, with the checksums of
+// their artifacts. It is only trusted once its detached signature verifies.
+type Index struct {
+	FormatVersion int       `json:"formatVersion"`
+	Releases      []Release `json:"releases"`
+}
+
+// Release holds the artifacts of one client version, e.g. 23.7, for each
+// platform. A client's base URL may be relative to the index, and defaults
+// to the index's directory.
+type Release struct {
+	Version string        `json:"version"`
+	Clients []lock.Client `json:"clients"`
+}
+
+// Fetch downloads the index at indexURL and its signature, next to it with
+// the key's suffix, and parses the index only after the signature verifies.
+// The signature proves who published the index, not that it is the latest:
+// a mirror can serve any index ever signed with the key.
+func Fetch(ctx context.Context, client *http.Client, indexURL string, key Verifier) (*Index, error) {
+	base, err := url.Parse(indexURL)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "parsing mirror index URL")
+	}
+	data, err := get(ctx, client, indexURL)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := get(ctx, client, indexURL+key.SigSuffix())
+	if err != nil {
+		return nil, err
+	}
+	if err := key.Verify(data, sig); err != nil {
+		return nil, err
+	}
+
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "parsing mirror index")
+	}
+	if idx.FormatVersion != FormatVersion {
+		return nil, errs.HandleError(
+			fmt.Errorf("index format version %d is not supported; this installer reads version %d", idx.FormatVersion, FormatVersion),
+			errs.ErrorTypeValidation,
+			"parsing mirror index")
+	}
+	for _, r := range idx.Releases {
+		for i, c := range r.Clients {
+			if c.PkgSHA256 == "" || c.SdkSHA256 == "" {
+				return nil, errs.HandleError(fmt.Errorf("%s %s/%s has no checksums", r.Version, c.OS, c.Arch), errs.ErrorTypeValidation, "parsing mirror index")
+			}
+			// An empty reference resolves to the index itself, not its directory
+			if c.BaseURL == "" {
+				c.BaseURL = "./"
+			}
+			ref, err := url.Parse(c.BaseURL)
+			if err != nil {
+				return nil, errs.HandleError(err, errs.ErrorTypeValidation, "parsing mirror index")
+			}
+			r.Clients[i].BaseURL = base.ResolveReference(ref).String()
+			if !strings.HasSuffix(r.Clients[i].BaseURL, "/") {
+				r.Clients[i].BaseURL += "/"
+			}
+		}
+	}
+	return &idx, nil
+}
+
+// Latest returns the newest release serving a client for the platform, as a
+// lock pinning that client
+func (idx *Index) Latest(goos, goarch string) (string, *lock.Lock, error) {
+	var version string
+	var latest lock.Client
+	for _, r := range idx.Releases {
+		for _, c := range r.Clients {
+			if c.OS == goos && c.Arch == goarch && (version == "" || utils.NewerVersion(r.Version, version)) {
+				version, latest = r.Version, c
+			}
+		}
+	}
+	if version == "" {
+		return "", nil, errs.HandleError(fmt.Errorf("the mirror index has no client for %s/%s", goos, goarch), errs.ErrorTypeValidation, "selecting release")
+	}
+	return version, &lock.Lock{FormatVersion: lock.FormatVersion, Clients: []lock.Client{latest}}, nil
+}
+
+// Release returns the release of the given version serving a client for the
+// platform, as a lock pinning that client. A version of major.minor, such as
+// 23.7, matches a release listed under its full version, and the other way round.
+func (idx *Index) Release(version, goos, goarch string) (string, *lock.Lock, error) {
+	for _, r := range idx.Releases {
+		if r.Version != version && !strings.HasPrefix(r.Version, version+".") && !strings.HasPrefix(version, r.Version+".") {
+			continue
+		}
+		for _, c := range r.Clients {
+			if c.OS == goos && c.Arch == goarch {
+				return r.Version, &lock.Lock{FormatVersion: lock.FormatVersion, Clients: []lock.Client{c}}, nil
+			}
+		}
+	}
+	return "", nil, errs.HandleError(fmt.Errorf("the mirror index has no %s client for %s/%s", version, goos, goarch), errs.ErrorTypeValidation, "selecting release")
+}
+
+// get downloads a small file into memory
+func get(ctx context.Context, client *http.Client, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeDownload, "creating HTTP request")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeDownload, "downloading mirror index")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errs.HandleError(fmt.Errorf("GET %s: %s", u, resp.Status), errs.ErrorTypeDownload, "downloading mirror index")
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIndexSize+1))
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeDownload, "downloading mirror index")
+	}
+	if len(data) > maxIndexSize {
+		return nil, errs.HandleError(fmt.Errorf("%s is larger than %d bytes", u, maxIndexSize), errs.ErrorTypeDownload, "downloading mirror index")
+	}
+	return data, nil
+}
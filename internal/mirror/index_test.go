@@ -0,0 +1,87 @@
+package mirror
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetch(t *testing.T) {
+	signer := newMinisigner(t, 0xaa)
+	key := loadKey(t, signer.publicKey())
+	files := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+	publish := func(index string) {
+		files["/oic/index.json"] = []byte(index)
+		files["/oic/index.json.minisig"] = signer.sign([]byte(index), "ED", "timestamp:1760000000")
+	}
+
+	publish(`{"formatVersion": 1, "releases": [
+		{"version": "23.7.0.25.01", "clients": [
+			{"os": "windows", "arch": "amd64", "clientDir": "instantclient_23_7", "pkgFile": "p.zip", "pkgSHA256": "aa", "sdkFile": "s.zip", "sdkSHA256": "bb"},
+			{"os": "linux", "arch": "amd64", "baseURL": "https://other.example/23.7", "pkgFile": "p.zip", "pkgSHA256": "cc", "sdkFile": "s.zip", "sdkSHA256": "dd"}
+		]},
+		{"version": "21.9", "clients": [
+			{"os": "windows", "arch": "amd64", "baseURL": "21.9/", "pkgFile": "p.zip", "pkgSHA256": "ee", "sdkFile": "s.zip", "sdkSHA256": "ff"}
+		]}
+	]}`)
+	idx, err := Fetch(context.Background(), srv.Client(), srv.URL+"/oic/index.json", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Base URLs resolve against the index and end in a slash
+	for _, tt := range []struct{ got, want string }{
+		{idx.Releases[0].Clients[0].BaseURL, srv.URL + "/oic/"},
+		{idx.Releases[0].Clients[1].BaseURL, "https://other.example/23.7/"},
+		{idx.Releases[1].Clients[0].BaseURL, srv.URL + "/oic/21.9/"},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("base URL = %q, want %q", tt.got, tt.want)
+		}
+	}
+	if version, l, err := idx.Latest("windows", "amd64"); err != nil || version != "23.7.0.25.01" || l.Clients[0].PkgSHA256 != "aa" {
+		t.Errorf("Latest = %q, %+v, %v", version, l, err)
+	}
+	if version, l, err := idx.Release("21.9.0.0.0", "windows", "amd64"); err != nil || version != "21.9" || l.Clients[0].PkgSHA256 != "ee" {
+		t.Errorf("Release(21.9.0.0.0) = %q, %+v, %v", version, l, err)
+	}
+	if _, _, err := idx.Release("21.9", "linux", "amd64"); err == nil {
+		t.Error("Release found a client for a platform the release lacks")
+	}
+	if _, _, err := idx.Latest("darwin", "arm64"); err == nil {
+		t.Error("Latest found a client for a platform no release has")
+	}
+
+	// An index altered after signing is rejected before it is read
+	signed := files["/oic/index.json"]
+	files["/oic/index.json"] = []byte(strings.Replace(string(signed), `"aa"`, `"00"`, 1))
+	if _, err := Fetch(context.Background(), srv.Client(), srv.URL+"/oic/index.json", key); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("tampered index: error = %v", err)
+	}
+	delete(files, "/oic/index.json.minisig")
+	if _, err := Fetch(context.Background(), srv.Client(), srv.URL+"/oic/index.json", key); err == nil {
+		t.Error("unsigned index accepted")
+	}
+
+	// Signed indexes must still be well-formed
+	for _, tt := range []struct{ index, want string }{
+		{`{"formatVersion": 2, "releases": []}`, "not supported"},
+		{`{"formatVersion": 1, "releases": [{"version": "23.7", "clients": [{"os": "linux", "arch": "amd64", "pkgSHA256": "aa"}]}]}`, "no checksums"},
+		{`not json`, "invalid character"},
+	} {
+		publish(tt.index)
+		if _, err := Fetch(context.Background(), srv.Client(), srv.URL+"/oic/index.json", key); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("index %s: error = %v, want %q", tt.index, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package mirror
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// Verifier checks detached signatures made by the holder of one key
+type Verifier interface {
+	// Verify returns an error unless sig is a valid signature of data
+	Verify(data, sig []byte) error
+	// SigSuffix is the extension of signature files next to the signed file
+	SigSuffix() string
+}
+
+// LoadKey reads a public key file: a minisign public key, or a PEM public key
+// as written by cosign generate-key-pair
+func LoadKey(path string) (Verifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "reading mirror public key")
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errs.HandleError(err, errs.ErrorTypeValidation, "parsing mirror public key")
+		}
+		switch pub := pub.(type) {
+		case *ecdsa.PublicKey, ed25519.PublicKey:
+			return cosignKey{pub}, nil
+		default:
+			return nil, errs.HandleError(fmt.Errorf("unsupported public key type %T", pub), errs.ErrorTypeValidation, "parsing mirror public key")
+		}
+	}
+	return parseMinisignKey(data)
+}
+
+// minisignKey is an Ed25519 public key in minisign's format
+type minisignKey struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+// parseMinisignKey decodes a minisign public key file, or the key line alone
+func parseMinisignKey(data []byte) (minisignKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(lastLine(data))
+	if err != nil || len(raw) != 42 || string(raw[:2]) != "Ed" {
+		return minisignKey{}, errs.HandleError(fmt.Errorf("not a minisign or PEM public key"), errs.ErrorTypeValidation, "parsing mirror public key")
+	}
+	k := minisignKey{key: ed25519.PublicKey(raw[10:])}
+	copy(k.id[:], raw[2:10])
+	return k, nil
+}
+
+// SigSuffix implements Verifier
+func (minisignKey) SigSuffix() string { return ".minisig" }
+
+// Verify checks a minisign signature file: the signature of the file, or of
+// its BLAKE2b-512 digest for prehashed signatures, and the global signature
+// binding the trusted comment to it. The trusted comment is not interpreted:
+// its timestamp is not compared with that of an index seen before, so an older
+// index signed with the same key is accepted again.
+func (k minisignKey) Verify(data, sig []byte) error {
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(string(sig)), "\r\n", "\n"), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return signatureError(fmt.Errorf("malformed minisign signature"))
+	}
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(raw) != 74 {
+		return signatureError(fmt.Errorf("malformed minisign signature"))
+	}
+	alg, id, s := string(raw[:2]), raw[2:10], raw[10:]
+	if !bytes.Equal(id, k.id[:]) {
+		return signatureError(fmt.Errorf("signed with key %X, not the trusted key %X", reverse(id), reverse(k.id[:])))
+	}
+	msg := data
+	switch alg {
+	case "Ed":
+	case "ED":
+		sum := blake2b512(data)
+		msg = sum[:]
+	default:
+		return signatureError(fmt.Errorf("unsupported minisign algorithm %q", alg))
+	}
+	if !ed25519.Verify(k.key, msg, s) {
+		return signatureError(fmt.Errorf("the signature does not match the file"))
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || !ed25519.Verify(k.key, append(append([]byte{}, s...), strings.TrimPrefix(lines[2], "trusted comment: ")...), global) {
+		return signatureError(fmt.Errorf("the trusted comment signature is invalid"))
+	}
+	return nil
+}
+
+// cosignKey is a public key verifying cosign sign-blob signatures
+type cosignKey struct {
+	key any
+}
+
+// SigSuffix implements Verifier
+func (cosignKey) SigSuffix() string { return ".sig" }
+
+// Verify checks a base64 signature as written by cosign sign-blob: ECDSA over
+// the SHA-256 digest of the file, or Ed25519 over the file itself
+func (k cosignKey) Verify(data, sig []byte) error {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return signatureError(fmt.Errorf("malformed cosign signature: %w", err))
+	}
+	ok := false
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(data)
+		ok = ecdsa.VerifyASN1(key, sum[:], raw)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, data, raw)
+	}
+	if !ok {
+		return signatureError(fmt.Errorf("the signature does not match the file"))
+	}
+	return nil
+}
+
+// signatureError wraps a verification failure
+func signatureError(err error) error {
+	return errs.HandleError(err, errs.ErrorTypeValidation, "verifying mirror index signature")
+}
+
+// lastLine returns the last non-empty line of data, skipping minisign's untrusted comment
+func lastLine(data []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// reverse returns b in reverse order; minisign prints key IDs little-endian
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
@@ -0,0 +1,173 @@
+package mirror
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// minisigner signs as minisign does, with a key of the given ID
+type minisigner struct {
+	id   [8]byte
+	priv ed25519.PrivateKey
+}
+
+func newMinisigner(t *testing.T, id byte) minisigner {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return minisigner{id: [8]byte{id, 1, 2, 3, 4, 5, 6, 7}, priv: priv}
+}
+
+// publicKey returns the public key file minisign -G writes
+func (m minisigner) publicKey() []byte {
+	raw := append(append([]byte("Ed"), m.id[:]...), m.priv.Public().(ed25519.PublicKey)...)
+	return []byte("untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(raw) + "\n")
+}
+
+// sign returns the signature file of data, prehashed as minisign does by
+// default when alg is "ED", with the trusted comment
+func (m minisigner) sign(data []byte, alg, comment string) []byte {
+	msg := data
+	if alg == "ED" {
+		sum := blake2b512(data)
+		msg = sum[:]
+	}
+	sig := ed25519.Sign(m.priv, msg)
+	global := ed25519.Sign(m.priv, append(append([]byte{}, sig...), comment...))
+	raw := append(append([]byte(alg), m.id[:]...), sig...)
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(raw) + "\n" +
+		"trusted comment: " + comment + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+// loadKey writes key to a file and loads it
+func loadKey(t *testing.T, key []byte) Verifier {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mirror.pub")
+	if err := os.WriteFile(path, key, 0644); err != nil {
+		t.Fatal(err)
+	}
+	v, err := LoadKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestMinisignVerify(t *testing.T) {
+	index := []byte(`{"formatVersion": 1, "releases": []}`)
+	const comment = "timestamp:1760000000\tfile:index.json\thashed"
+	signer := newMinisigner(t, 0xaa)
+	key := loadKey(t, signer.publicKey())
+	if got := key.SigSuffix(); got != ".minisig" {
+		t.Errorf("SigSuffix = %q", got)
+	}
+
+	for _, alg := range []string{"ED", "Ed"} {
+		if err := key.Verify(index, signer.sign(index, alg, comment)); err != nil {
+			t.Errorf("%s signature rejected: %v", alg, err)
+		}
+	}
+
+	// Another key, under another ID or posing as the trusted one
+	other := newMinisigner(t, 0xbb)
+	impostor := minisigner{id: signer.id, priv: other.priv}
+	tamperedComment := strings.Replace(string(signer.sign(index, "ED", comment)), "timestamp:1760000000", "timestamp:1790000000", 1)
+	tests := []struct {
+		name string
+		data []byte
+		sig  []byte
+		want string
+	}{
+		{"wrong key ID", index, other.sign(index, "ED", comment), "not the trusted key"},
+		{"wrong key", index, impostor.sign(index, "ED", comment), "does not match"},
+		{"tampered index", []byte(`{"formatVersion": 1, "releases": [{}]}`), signer.sign(index, "ED", comment), "does not match"},
+		{"tampered legacy index", append(index, ' '), signer.sign(index, "Ed", comment), "does not match"},
+		{"tampered trusted comment", index, []byte(tamperedComment), "trusted comment"},
+		{"unknown algorithm", index, signer.sign(index, "EX", comment), "unsupported"},
+		{"truncated", index, []byte(strings.Join(strings.SplitN(string(signer.sign(index, "ED", comment)), "\n", 3)[:2], "\n")), "malformed"},
+		{"empty", index, nil, "malformed"},
+	}
+	for _, tt := range tests {
+		err := key.Verify(tt.data, tt.sig)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestCosignVerify(t *testing.T) {
+	index := []byte(`{"formatVersion": 1, "releases": []}`)
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := loadKey(t, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if got := key.SigSuffix(); got != ".sig" {
+		t.Errorf("SigSuffix = %q", got)
+	}
+	sign := func(data []byte) []byte {
+		sum := sha256.Sum256(data)
+		sig, err := ecdsa.SignASN1(rand.Reader, priv, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+	}
+
+	if err := key.Verify(index, sign(index)); err != nil {
+		t.Errorf("signature rejected: %v", err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(index)
+	otherSig, err := ecdsa.SignASN1(rand.Reader, other, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		sig  []byte
+	}{
+		{"wrong key", index, []byte(base64.StdEncoding.EncodeToString(otherSig))},
+		{"tampered index", append(index, ' '), sign(index)},
+		{"not base64", index, []byte("not a signature")},
+	}
+	for _, tt := range tests {
+		if err := key.Verify(tt.data, tt.sig); err == nil {
+			t.Errorf("%s: signature accepted", tt.name)
+		}
+	}
+}
+
+func TestLoadKeyInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mirror.pub")
+	for _, key := range []string{"", "not a key", base64.StdEncoding.EncodeToString([]byte("Ed too short"))} {
+		if err := os.WriteFile(path, []byte(key), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadKey(path); err == nil {
+			t.Errorf("LoadKey accepted %q", key)
+		}
+	}
+}
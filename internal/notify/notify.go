@@ -0,0 +1,220 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// Formats of the webhook payload
+const (
+	FormatJSON  = "json"  // The Summary as a JSON object
+	FormatTeams = "teams" // A Microsoft Teams message with an Adaptive Card
+	FormatSlack = "slack" // A Slack message
+)
+
+// Outcomes of a run
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// Summary describes the outcome of an installer run
+type Summary struct {
+	Status        string    `json:"status"`
+	Host          string    `json:"host"`
+	User          string    `json:"user"`
+	Scope         string    `json:"scope"`
+	Arch          string    `json:"arch"`
+	ClientDir     string    `json:"clientDir,omitempty"`
+	ClientVersion string    `json:"clientVersion,omitempty"`
+	Version       string    `json:"version"` // Installer version
+	Error         string    `json:"error,omitempty"`
+	Started       time.Time `json:"started"`
+	Duration      string    `json:"duration"`
+}
+
+// NewSummary starts a summary for a run beginning now
+func NewSummary(version, scope, arch string) *Summary {
+	s := &Summary{Version: version, Scope: scope, Arch: arch, Started: time.Now().UTC()}
+	s.Host, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		s.User = u.Username
+	}
+	return s
+}
+
+// Finish records the outcome of the run and its duration
+func (s *Summary) Finish(err error) {
+	s.Status = StatusSuccess
+	if err != nil {
+		s.Status, s.Error = StatusFailure, err.Error()
+	}
+	s.Duration = time.Since(s.Started).Round(time.Second).String()
+}
+
+// Validate checks the payload format is known
+func Validate(format string) error {
+	switch format {
+	case FormatJSON, FormatTeams, FormatSlack:
+		return nil
+	}
+	return errs.HandleError(fmt.Errorf("invalid notification format %q: must be json, teams or slack", format), errs.ErrorTypeValidation, "config validation")
+}
+
+// Alert is a problem with a machine's client configuration found outside an
+// install, such as wallet certificates about to expire
+type Alert struct {
+	Host    string   `json:"host"`
+	User    string   `json:"user"`
+	Scope   string   `json:"scope"`
+	Title   string   `json:"title"`
+	Details []string `json:"details"`
+	Version string   `json:"version"` // Installer version
+}
+
+// NewAlert returns an alert raised on this machine
+func NewAlert(version, scope, title string, details []string) *Alert {
+	a := &Alert{Version: version, Scope: scope, Title: title, Details: details}
+	a.Host, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		a.User = u.Username
+	}
+	return a
+}
+
+// Send posts the summary to url in the given format
+func Send(ctx context.Context, client *http.Client, url, format string, s *Summary) error {
+	body, err := payload(format, s)
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeValidation, "building notification")
+	}
+	return post(ctx, client, url, body)
+}
+
+// SendAlert posts the alert to url in the given format
+func SendAlert(ctx context.Context, client *http.Client, url, format string, a *Alert) error {
+	var body []byte
+	var err error
+	heading := fmt.Sprintf("%s on %s", a.Title, a.Host)
+	switch format {
+	case FormatTeams:
+		var blocks []map[string]any
+		for _, d := range a.Details {
+			blocks = append(blocks, map[string]any{"type": "TextBlock", "text": d, "wrap": true})
+		}
+		body, err = json.Marshal(teamsCard(heading, "Warning", blocks))
+	case FormatSlack:
+		body, err = json.Marshal(map[string]string{"text": heading + "\n" + strings.Join(a.Details, "\n")})
+	case FormatJSON:
+		body, err = json.Marshal(a)
+	default:
+		err = Validate(format)
+	}
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeValidation, "building notification")
+	}
+	return post(ctx, client, url, body)
+}
+
+// post sends a JSON payload to the webhook at url
+func post(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeValidation, "building notification")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeDownload, "sending notification")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errs.HandleError(fmt.Errorf("webhook returned HTTP status %s", resp.Status), errs.ErrorTypeDownload, "sending notification")
+	}
+	return nil
+}
+
+// payload encodes the summary in the given format
+func payload(format string, s *Summary) ([]byte, error) {
+	switch format {
+	case FormatTeams:
+		return json.Marshal(teamsMessage(s))
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": title(s) + "\n" + text(s)})
+	case FormatJSON:
+		return json.Marshal(s)
+	}
+	return nil, Validate(format)
+}
+
+// title is the one-line headline of a chat message
+func title(s *Summary) string {
+	if s.Status == StatusSuccess {
+		return fmt.Sprintf("Oracle Instant Client %s installed on %s", s.ClientVersion, s.Host)
+	}
+	return fmt.Sprintf("Oracle Instant Client install failed on %s", s.Host)
+}
+
+// facts are the labelled details of a chat message
+func facts(s *Summary) [][2]string {
+	f := [][2]string{{"User", s.User}, {"Scope", s.Scope}, {"Architecture", s.Arch}}
+	if s.ClientDir != "" {
+		f = append(f, [2]string{"Client directory", s.ClientDir})
+	}
+	if s.Error != "" {
+		f = append(f, [2]string{"Error", s.Error})
+	}
+	return append(f, [2]string{"Duration", s.Duration}, [2]string{"Installer version", s.Version})
+}
+
+// text renders the facts as plain lines
+func text(s *Summary) string {
+	var b bytes.Buffer
+	for _, f := range facts(s) {
+		fmt.Fprintf(&b, "%s: %s\n", f[0], f[1])
+	}
+	return b.String()
+}
+
+// teamsMessage wraps the summary in an Adaptive Card, which both Teams
+// incoming webhooks and Workflows webhooks accept
+func teamsMessage(s *Summary) map[string]any {
+	var factSet []map[string]string
+	for _, f := range facts(s) {
+		factSet = append(factSet, map[string]string{"title": f[0], "value": f[1]})
+	}
+	color := "Good"
+	if s.Status != StatusSuccess {
+		color = "Attention"
+	}
+	return teamsCard(title(s), color, []map[string]any{{"type": "FactSet", "facts": factSet}})
+}
+
+// teamsCard returns a Teams message holding an Adaptive Card headed by
+// title in color above the given elements
+func teamsCard(title, color string, elements []map[string]any) map[string]any {
+	body := append([]map[string]any{
+		{"type": "TextBlock", "text": title, "weight": "Bolder", "size": "Medium", "color": color, "wrap": true},
+	}, elements...)
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+}
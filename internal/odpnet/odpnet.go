@@ -0,0 +1,165 @@
+// Package odpnet sets up the Oracle Data Provider for .NET with an installed
+// client: it stages the managed driver from a NuGet feed, and points the
+// registry settings of the unmanaged driver, which loads the client's
+// libraries, at the client.
+package odpnet
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// ManagedPackage is the NuGet package of the managed driver for .NET
+const ManagedPackage = "Oracle.ManagedDataAccess.Core"
+
+// managedAssembly is the driver assembly staged from the package
+const managedAssembly = "Oracle.ManagedDataAccess.dll"
+
+// DirName is the directory of the client the managed driver is staged in
+const DirName = "odp.net"
+
+// maxPackageSize bounds the package download
+const maxPackageSize = 200 << 20
+
+// packageURL returns the URL of a file of the package in a NuGet v3 package
+// content feed, which uses lowercase ids and versions
+func packageURL(feed string, elem ...string) string {
+	return strings.TrimSuffix(feed, "/") + "/" + strings.ToLower(path.Join(append([]string{ManagedPackage}, elem...)...))
+}
+
+// LatestVersion returns the latest stable version of the managed driver in feed
+func LatestVersion(ctx context.Context, client *http.Client, feed string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, packageURL(feed, "index.json"), nil)
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeDownload, "creating HTTP request")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeDownload, "listing ODP.NET versions")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errs.HandleError(fmt.Errorf("HTTP status %s", resp.Status), errs.ErrorTypeDownload, "listing ODP.NET versions")
+	}
+	var index struct {
+		Versions []string `json:"versions"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&index); err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeDownload, "parsing ODP.NET versions")
+	}
+	latest := ""
+	for _, v := range index.Versions {
+		// Prereleases carry a suffix such as -beta1
+		if !strings.Contains(v, "-") && (latest == "" || utils.NewerVersion(v, latest)) {
+			latest = v
+		}
+	}
+	if latest == "" {
+		return "", errs.HandleError(fmt.Errorf("no stable version of %s in %s", ManagedPackage, feed), errs.ErrorTypeDownload, "listing ODP.NET versions")
+	}
+	return latest, nil
+}
+
+// StageManaged downloads version of the managed driver package from feed,
+// the latest when empty, and copies its driver assembly for each target
+// framework into dir/<framework>. A failed download is retried as retry says.
+// It returns the version staged and the frameworks.
+func StageManaged(ctx context.Context, client *http.Client, retry config.RetryPolicy, feed, version, dir string) (string, []string, error) {
+	if version == "" {
+		latest, err := LatestVersion(ctx, client, feed)
+		if err != nil {
+			return "", nil, err
+		}
+		version = latest
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", nil, errs.HandleError(err, errs.ErrorTypeInstall, "creating ODP.NET directory")
+	}
+	name := strings.ToLower(ManagedPackage + "." + version + ".nupkg")
+	pkg := filepath.Join(dir, name)
+	fmt.Printf("downloading %s %s\n", ManagedPackage, version)
+	if err := utils.DownloadArchive(ctx, client, packageURL(feed, version, name), pkg, config.SizeLimits{Max: maxPackageSize}, retry); err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(pkg)
+
+	frameworks, err := extractAssemblies(pkg, dir)
+	if err != nil {
+		return "", nil, err
+	}
+	return version, frameworks, nil
+}
+
+// extractAssemblies copies lib/<framework>/Oracle.ManagedDataAccess.dll of
+// the package at pkg into dir/<framework>
+func extractAssemblies(pkg, dir string) ([]string, error) {
+	r, err := zip.OpenReader(pkg)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeInstall, "opening ODP.NET package")
+	}
+	defer r.Close()
+	var frameworks []string
+	for _, f := range r.File {
+		parts := strings.Split(f.Name, "/")
+		if len(parts) != 3 || parts[0] != "lib" || !strings.EqualFold(parts[2], managedAssembly) || !validFramework(parts[1]) {
+			continue
+		}
+		if err := extractFile(f, filepath.Join(dir, parts[1], managedAssembly)); err != nil {
+			return nil, errs.HandleError(err, errs.ErrorTypeInstall, "extracting ODP.NET assembly")
+		}
+		frameworks = append(frameworks, parts[1])
+	}
+	if len(frameworks) == 0 {
+		return nil, errs.HandleError(fmt.Errorf("%s holds no %s", filepath.Base(pkg), managedAssembly), errs.ErrorTypeInstall, "extracting ODP.NET assembly")
+	}
+	sort.Strings(frameworks)
+	return frameworks, nil
+}
+
+// validFramework reports whether a package directory name is a target
+// framework moniker, such as net8.0, and safe to use as a directory name
+func validFramework(name string) bool {
+	if !strings.HasPrefix(name, "net") {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// extractFile writes the package entry f to path
+func extractFile(f *zip.File, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	in, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
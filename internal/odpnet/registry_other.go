@@ -0,0 +1,25 @@
+//go:build !windows
+
+package odpnet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// Register points the unmanaged driver at the client; the unmanaged driver
+// only exists on Windows
+func Register(ctx context.Context, wow64 bool, clientDir, tnsAdmin string, versions []string) ([]string, error) {
+	return nil, errs.HandleError(
+		fmt.Errorf("the unmanaged ODP.NET driver is only available on Windows"),
+		errs.ErrorTypeValidation,
+		"registering unmanaged ODP.NET driver")
+}
+
+// Unregister removes the unmanaged driver's settings, of which there are none
+// outside Windows
+func Unregister(ctx context.Context, clientDir string) error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package odpnet
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+)
+
+// registryKeys are the keys the unmanaged driver reads its settings from,
+// one subkey per ODP.NET version, in the 64-bit and 32-bit registry views
+const (
+	registryKey64 = `HKLM:\SOFTWARE\Oracle\ODP.NET`
+	registryKey32 = `HKLM:\SOFTWARE\WOW6432Node\Oracle\ODP.NET`
+)
+
+// Register points the unmanaged driver at the client in clientDir: DllPath
+// is set to it and TNS_ADMIN to tnsAdmin, when not empty, for the given
+// ODP.NET versions and every version already registered, in the 32-bit
+// registry view for a 32-bit client. It returns the versions registered.
+func Register(ctx context.Context, wow64 bool, clientDir, tnsAdmin string, versions []string) ([]string, error) {
+	key := registryKey64
+	if wow64 {
+		key = registryKey32
+	}
+	quoted := make([]string, len(versions))
+	for i, v := range versions {
+		quoted[i] = pwsh.Quote(v)
+	}
+	script := `$ErrorActionPreference = 'Stop'
+$base = ` + pwsh.Quote(key) + `
+$versions = @(` + strings.Join(quoted, ",") + `) + @(Get-ChildItem $base -ErrorAction SilentlyContinue | ForEach-Object { $_.PSChildName }) | Sort-Object -Unique
+foreach ($v in $versions) {
+	$k = Join-Path $base $v
+	New-Item $k -Force | Out-Null
+	Set-ItemProperty $k DllPath ` + pwsh.Quote(clientDir) + `
+	if (` + pwsh.Quote(tnsAdmin) + `) { Set-ItemProperty $k TNS_ADMIN ` + pwsh.Quote(tnsAdmin) + ` }
+	Write-Output $v
+}`
+	out, err := pwsh.Run(ctx, script)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeEnvironment, "registering unmanaged ODP.NET driver")
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// Unregister removes the settings pointing the unmanaged driver at the client
+// in clientDir, in both registry views, and the version keys left empty
+func Unregister(ctx context.Context, clientDir string) error {
+	script := `$ErrorActionPreference = 'Stop'
+foreach ($base in ` + pwsh.Quote(registryKey64) + `, ` + pwsh.Quote(registryKey32) + `) {
+	foreach ($k in @(Get-ChildItem $base -ErrorAction SilentlyContinue)) {
+		if ($k.GetValue('DllPath') -ne ` + pwsh.Quote(clientDir) + `) { continue }
+		Remove-ItemProperty $k.PSPath DllPath, TNS_ADMIN -ErrorAction SilentlyContinue
+		$left = Get-Item $k.PSPath
+		if ($left.ValueCount -eq 0 -and $left.SubKeyCount -eq 0) { Remove-Item $k.PSPath }
+	}
+}`
+	if _, err := pwsh.Run(ctx, script); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, "unregistering unmanaged ODP.NET driver")
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package oic_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/oic"
+	"github.com/mghoff/oraicwinconfig/internal/testsupport"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// tnsnames is the tnsnames.ora saved from a replaced install, which a
+// cancelled install must put back
+const tnsnames = "ORCL = (DESCRIPTION = (ADDRESS = (PROTOCOL = TCP)(HOST = db)(PORT = 1521)))\n"
+
+// checkUndone fails unless the cancelled install left the environment, the
+// install path and the manifest as they were, and the downloads folder holds
+// nothing but complete archives and the saved tnsnames.ora
+func checkUndone(t *testing.T, h *harness, envBefore string) {
+	t.Helper()
+	if got := h.env.Dump(h.root); got != envBefore {
+		t.Errorf("environment not restored:\n%s\nwant:\n%s", got, envBefore)
+	}
+	if entries, err := os.ReadDir(h.install); err == nil && len(entries) > 0 {
+		t.Errorf("install path not cleaned up:\n%s", testsupport.Tree(t, h.root, h.install))
+	}
+	entries, err := os.ReadDir(h.conf.DownloadsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		path := filepath.Join(h.conf.DownloadsPath, e.Name())
+		switch e.Name() {
+		case "tnsnames.ora":
+			if data, err := os.ReadFile(path); err != nil || string(data) != tnsnames {
+				t.Errorf("tnsnames.ora not put back: %q, %v", data, err)
+			}
+		case h.conf.PkgFile, h.conf.SdkFile:
+			if _, err := utils.ZipClientDir(path); err != nil {
+				t.Errorf("%s left partially downloaded: %v", e.Name(), err)
+			}
+		default:
+			t.Errorf("%s left in the downloads folder", e.Name())
+		}
+	}
+	m, err := manifest.Load(env.ScopeUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := m.Client(h.conf.LibVar()); ok {
+		t.Errorf("manifest still records %s", c.ClientDir)
+	}
+}
+
+// newCancelHarness returns a harness replacing an install whose tnsnames.ora
+// was saved to the downloads folder
+func newCancelHarness(t *testing.T) *harness {
+	t.Helper()
+	h := newHarness(t)
+	h.conf.Extant = true
+	if err := os.WriteFile(filepath.Join(h.conf.DownloadsPath, "tnsnames.ora"), []byte(tnsnames), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestCancelAtStepBoundaries(t *testing.T) {
+	// Cancel before the first step and after each of the others
+	boundaries := append([]string{""}, oic.DefaultPipeline().Names()...)
+	for _, after := range boundaries[:len(boundaries)-1] {
+		name := "after " + after
+		if after == "" {
+			name = "before download"
+		}
+		t.Run(name, func(t *testing.T) {
+			h := newCancelHarness(t)
+			envBefore := h.env.Dump(h.root)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			p := oic.DefaultPipeline()
+			if err := p.Insert(after, oic.NewStep("cancel", func(context.Context, *oic.State) error {
+				cancel()
+				return nil
+			})); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Run(ctx, h.conf, h.env); !errors.Is(err, context.Canceled) {
+				t.Fatalf("error = %v, want cancellation", err)
+			}
+			checkUndone(t, h, envBefore)
+			if _, err := os.Stat(filepath.Join(h.conf.DownloadsPath, "tnsnames.ora")); err != nil {
+				t.Errorf("tnsnames.ora lost: %v", err)
+			}
+
+			// The next attempt starts from the downloads that were kept
+			if err := oic.Install(context.Background(), h.conf, h.env); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(h.install, testsupport.ClientDir, "network", "admin", "tnsnames.ora")); err != nil {
+				t.Errorf("tnsnames.ora not migrated after the cancelled install: %v", err)
+			}
+		})
+	}
+}
+
+func TestCancelDuringDownload(t *testing.T) {
+	conf, srv := newInstall(t, testsupport.Slow)
+	if err := conf.SetPlatform("linux", "amd64"); err != nil {
+		t.Fatal(err)
+	}
+	srv.Configure(conf)
+	h := &harness{t: t, root: filepath.Dir(conf.InstallPath), install: conf.InstallPath, conf: conf,
+		env: testsupport.NewEnv(env.ScopeUser, conf.DownloadsPath), server: srv}
+	envBefore := h.env.Dump(h.root)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := oic.Install(ctx, h.conf, h.env); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want cancellation", err)
+	}
+	if _, err := os.Stat(filepath.Join(conf.DownloadsPath, conf.PkgFile)); !os.IsNotExist(err) {
+		t.Errorf("partial download left behind: %v", err)
+	}
+	checkUndone(t, h, envBefore)
+}
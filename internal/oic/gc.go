@@ -0,0 +1,83 @@
+package oic
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// DefaultBackupAge is the age past which gc removes backups
+const DefaultBackupAge = 30 * 24 * time.Hour
+
+// GCPolicy says what gc keeps
+type GCPolicy struct {
+	KeepVersions int           // Previous client versions to keep in each install path; -1 keeps all
+	BackupAge    time.Duration // Age past which backups are removed; 0 removes all of them
+}
+
+// Garbage returns the items gc removes under policy: the downloaded archives,
+// backups older than policy.BackupAge, and the client versions not in use
+// beyond the newest policy.KeepVersions in each install path. As with Prune,
+// clients on network shares are never removed.
+func Garbage(conf *config.InstallConfig, m env.Manager, policy GCPolicy, now time.Time) ([]Usage, error) {
+	usage, err := DiskUsage(conf, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// The expired clients of each install path the usage covers
+	expired := make(map[string]bool)
+	if policy.KeepVersions >= 0 {
+		inUse := clientsInUse(m)
+		seen := make(map[string]bool)
+		for _, u := range usage {
+			base := filepath.Dir(u.Path)
+			if u.Kind != UsageClient || seen[env.PathKey(base)] || utils.IsUNC(u.Path) {
+				continue
+			}
+			seen[env.PathKey(base)] = true
+			dirs, err := expiredClients(base, policy.KeepVersions, inUse)
+			if err != nil {
+				return nil, err
+			}
+			for _, dir := range dirs {
+				expired[env.PathKey(dir)] = true
+			}
+		}
+	}
+
+	var garbage []Usage
+	for _, u := range usage {
+		switch {
+		case u.Kind == UsageClient && expired[env.PathKey(u.Path)],
+			u.Kind == UsageDownload,
+			u.Kind == UsageBackup && now.Sub(u.Modified) >= policy.BackupAge:
+			garbage = append(garbage, u)
+		}
+	}
+	return garbage, nil
+}
+
+// RemoveGarbage removes the items and returns the bytes freed. It carries on
+// past items it cannot remove, and returns their errors together.
+func RemoveGarbage(items []Usage) (int64, error) {
+	var freed int64
+	var failed []error
+	for _, u := range items {
+		if err := os.RemoveAll(u.Path); err != nil {
+			failed = append(failed, err)
+			continue
+		}
+		freed += u.Size
+	}
+	if len(failed) > 0 {
+		return freed, errs.HandleError(errors.Join(failed...), errs.ErrorTypeInstall, "removing unused files")
+	}
+	return freed, nil
+}
@@ -0,0 +1,133 @@
+package oic_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/oic"
+)
+
+func TestGarbage(t *testing.T) {
+	h := newHarness(t)
+	if err := oic.Install(context.Background(), h.conf, h.env); err != nil {
+		t.Fatal(err)
+	}
+	client := func(name string) string {
+		dir := filepath.Join(h.conf.InstallPath, name)
+		os.MkdirAll(filepath.Join(dir, "network", "admin"), 0755)
+		os.WriteFile(filepath.Join(dir, "libclntsh.so"), make([]byte, 100), 0644)
+		return dir
+	}
+	newer, kept, expired, profile := client("instantclient_23_5"), client("instantclient_21_9"), client("instantclient_19_3"), client("instantclient_18_5")
+	h.env.SetEnvVar("TNS_ADMIN", filepath.Join(profile, "network", "admin"))
+
+	now := time.Now()
+	oldBackup := filepath.Join(profile, "network", "admin", "tnsnames.ora.20260101-120000.bak")
+	newBackup := filepath.Join(profile, "network", "admin", "tnsnames.ora.20260301-120000.bak")
+	os.WriteFile(oldBackup, make([]byte, 10), 0644)
+	os.WriteFile(newBackup, make([]byte, 10), 0644)
+	os.Chtimes(oldBackup, now.Add(-40*24*time.Hour), now.Add(-40*24*time.Hour))
+
+	garbage, err := oic.Garbage(h.conf, h.env, oic.GCPolicy{KeepVersions: 2, BackupAge: oic.DefaultBackupAge}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{
+		expired:   true,
+		oldBackup: true,
+		filepath.Join(h.conf.DownloadsPath, h.conf.PkgFile): true,
+		filepath.Join(h.conf.DownloadsPath, h.conf.SdkFile): true,
+	}
+	for _, u := range garbage {
+		if !want[u.Path] {
+			t.Errorf("%s would be removed", u.Path)
+		}
+		delete(want, u.Path)
+	}
+	for path := range want {
+		t.Errorf("%s would be kept", path)
+	}
+
+	freed, err := oic.RemoveGarbage(garbage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if freed < 110 {
+		t.Errorf("freed %d bytes, want at least the client and backup", freed)
+	}
+	for _, path := range []string{newer, kept, profile, newBackup, filepath.Join(h.conf.InstallPath, "instantclient_23_7")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed", path)
+		}
+	}
+	if _, err := os.Stat(expired); err == nil {
+		t.Errorf("%s was kept", expired)
+	}
+
+	garbage, err = oic.Garbage(h.conf, h.env, oic.GCPolicy{KeepVersions: -1}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range garbage {
+		if u.Kind == oic.UsageClient {
+			t.Errorf("%s would be removed with every version kept", u.Path)
+		}
+	}
+}
+
+func TestPruneKeepsRollbackClients(t *testing.T) {
+	h := newHarness(t)
+	if err := oic.Install(context.Background(), h.conf, h.env); err != nil {
+		t.Fatal(err)
+	}
+	client := func(name string) string {
+		dir := filepath.Join(h.conf.InstallPath, name)
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "libclntsh.so"), make([]byte, 100), 0644)
+		return dir
+	}
+	expired, previous, onPath := client("instantclient_21_9"), client("instantclient_19_3"), client("instantclient_18_5")
+
+	// An earlier upgrade left the previous client in the history, and
+	// another application put an older one on the Machine PATH
+	m, err := manifest.Load(h.env.Scope())
+	if err != nil {
+		t.Fatal(err)
+	}
+	libVar := h.conf.LibVar()
+	state := manifest.State{Time: time.Now().Add(-time.Hour), Clients: []manifest.Client{
+		{LibVar: libVar, ClientDir: previous, InstallPath: h.conf.InstallPath, Vars: map[string]string{libVar: previous}},
+	}}
+	m.History = append([]manifest.State{state}, m.History...)
+	if err := m.Save(h.env.Scope()); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.env.WithScope(env.ScopeMachine).AppendToPath(filepath.Join(onPath, "bin")); err != nil {
+		t.Fatal(err)
+	}
+
+	h.conf.KeepVersions = 0
+	if err := oic.Prune(h.conf, h.env); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(expired); err == nil {
+		t.Errorf("%s was kept", expired)
+	}
+	for _, dir := range []string{previous, onPath, filepath.Join(h.conf.InstallPath, "instantclient_23_7")} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s was removed", dir)
+		}
+	}
+
+	if err := oic.Rollback(context.Background(), h.conf, h.env, state); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := h.env.GetEnvVar(libVar); got != previous {
+		t.Errorf("after rollback %s = %q, want %q", libVar, got, previous)
+	}
+}
@@ -0,0 +1,196 @@
+package oic_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/oic"
+	"github.com/mghoff/oraicwinconfig/internal/plan"
+	"github.com/mghoff/oraicwinconfig/internal/testsupport"
+)
+
+// harness runs Install, Exists and Uninstall against the in-memory
+// environment and a sandboxed file system, recording each step's outcome for
+// comparison with a golden file
+type harness struct {
+	t       *testing.T
+	root    string // Sandboxed home directory, shown as $ROOT
+	install string // Base install directory; Exists moves conf.InstallPath into it
+	conf    *config.InstallConfig
+	env     *testsupport.Env
+	server  *testsupport.Server
+	log     strings.Builder
+}
+
+// newHarness installs the linux/amd64 client, whose file names and variables
+// are the same whatever the host, for the user scope
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	conf, srv := newInstall(t, testsupport.Valid)
+	if err := conf.SetPlatform("linux", "amd64"); err != nil {
+		t.Fatal(err)
+	}
+	srv.Configure(conf)
+	return &harness{
+		t:       t,
+		root:    filepath.Dir(conf.InstallPath),
+		install: conf.InstallPath,
+		conf:    conf,
+		env:     testsupport.NewEnv(env.ScopeUser, conf.DownloadsPath),
+		server:  srv,
+	}
+}
+
+// runInstall runs Install
+func (h *harness) runInstall() {
+	h.t.Helper()
+	h.step("install", oic.Install(context.Background(), h.conf, h.env))
+}
+
+// runExists runs Exists and records what it found
+func (h *harness) runExists() {
+	h.t.Helper()
+	found, err := oic.Exists(context.Background(), h.conf, h.env)
+	h.step(fmt.Sprintf("exists: %t, extant: %t, install path: %s", found, h.conf.Extant, testsupport.Normalize(h.conf.InstallPath, h.root)), err)
+}
+
+// runUninstall runs Uninstall
+func (h *harness) runUninstall() {
+	h.t.Helper()
+	h.step("uninstall", oic.Uninstall(context.Background(), h.conf, h.env))
+}
+
+// runRollback runs Rollback to the n-th state recorded in the manifest's
+// history, oldest first, and records the states
+func (h *harness) runRollback(n int) {
+	h.t.Helper()
+	m, err := manifest.Load(h.env.Scope())
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if n >= len(m.History) {
+		h.t.Fatalf("%d states recorded, want more than %d", len(m.History), n)
+	}
+	h.step(fmt.Sprintf("rollback to %d of %d", n+1, len(m.History)), oic.Rollback(context.Background(), h.conf, h.env, m.History[n]))
+}
+
+// step records the outcome of a step; errors are part of the expected output
+func (h *harness) step(name string, err error) {
+	fmt.Fprintf(&h.log, "%s\n", name)
+	if err != nil {
+		fmt.Fprintf(&h.log, "  error: %s\n", testsupport.Normalize(err.Error(), h.root))
+	}
+}
+
+// check compares the step outcomes, environment and file tree with the golden file
+func (h *harness) check(name string) {
+	h.t.Helper()
+	var b strings.Builder
+	fmt.Fprintf(&b, "== steps\n%s== environment\n%s== files\n", h.log.String(), h.env.Dump(h.root))
+	b.WriteString(testsupport.Tree(h.t, h.root, h.conf.DownloadsPath))
+	b.WriteString(testsupport.Tree(h.t, h.root, h.install))
+	testsupport.Golden(h.t, name, b.String())
+}
+
+func TestHarness(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(h *harness)
+	}{
+		{"install", func(h *harness) {
+			h.runInstall()
+		}},
+		{"exists_absent", func(h *harness) {
+			h.runExists()
+		}},
+		{"exists_misconfigured", func(h *harness) {
+			h.runInstall()
+			h.runExists()
+		}},
+		{"exists_configured", func(h *harness) {
+			h.runInstall()
+			admin := filepath.Join(h.install, testsupport.ClientDir, "network", "admin")
+			if err := os.MkdirAll(admin, 0755); err != nil {
+				h.t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(admin, "tnsnames.ora"), nil, 0644); err != nil {
+				h.t.Fatal(err)
+			}
+			h.runExists()
+		}},
+		{"exists_dangling", func(h *harness) {
+			h.runInstall()
+			if err := os.RemoveAll(h.install); err != nil {
+				h.t.Fatal(err)
+			}
+			h.runExists()
+		}},
+		{"uninstall", func(h *harness) {
+			h.runInstall()
+			h.runExists()
+			h.runUninstall()
+		}},
+		{"uninstall_absent", func(h *harness) {
+			h.runUninstall()
+		}},
+		{"rollback", func(h *harness) {
+			h.runInstall()
+			h.runExists()
+			h.runUninstall()
+			h.runInstall()
+			h.runRollback(1)
+			h.runRollback(0)
+		}},
+		{"rollback_removed", func(h *harness) {
+			h.runInstall()
+			h.runExists()
+			h.runUninstall()
+			h.runRollback(0)
+		}},
+		{"reinstall", func(h *harness) {
+			h.runInstall()
+			h.runExists()
+			h.runUninstall()
+			h.runInstall()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t)
+			tt.run(h)
+			h.check(tt.name)
+		})
+	}
+}
+
+func TestPlanStable(t *testing.T) {
+	for _, format := range []string{plan.FormatStable, plan.FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			h := newHarness(t)
+			h.conf.EnvMode = config.EnvModeBoth
+			h.conf.Hooks.PostInstall = []string{"echo installed"}
+			p, err := oic.BuildPlan(context.Background(), h.conf, h.env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			if err := p.Render(&b, format); err != nil {
+				t.Fatal(err)
+			}
+			got := strings.ReplaceAll(b.String(), h.server.URL, "$SERVER")
+			if format == plan.FormatJSON && runtime.GOOS == "windows" {
+				// Backslashes in the paths are escaped inside JSON strings
+				got = strings.ReplaceAll(got, `\\`, `\`)
+			}
+			testsupport.Golden(t, "plan_"+format, testsupport.Normalize(got, h.root))
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package oic_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/oic"
+	"github.com/mghoff/oraicwinconfig/internal/testsupport"
+	"github.com/mghoff/oraicwinconfig/internal/testutil"
+)
+
+// newInstall returns a user-scope configuration installing into a sandboxed
+// home directory from a server with the given behavior
+func newInstall(t *testing.T, behavior testsupport.Behavior) (*config.InstallConfig, *testsupport.Server) {
+	t.Helper()
+	home := testsupport.Sandbox(t)
+	conf := config.New()
+	if err := conf.SetScope(env.ScopeUser); err != nil {
+		t.Fatal(err)
+	}
+	conf.InstallPath = filepath.Join(home, "oracle")
+	conf.DownloadsPath = filepath.Join(home, "downloads")
+	if err := os.MkdirAll(conf.DownloadsPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	conf.Existing = config.ExistingOverwrite
+	conf.NoResume = true
+	// The fixture archives hold no real client library to check
+	conf.SkipSteps = []string{oic.StepSmokeTest}
+
+	srv := testsupport.NewServer(t, behavior)
+	srv.Configure(conf)
+	return conf, srv
+}
+
+// skipOnWindows skips tests using the host environment manager, which writes
+// the registry on Windows
+func skipOnWindows(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("installs write the user environment in the registry")
+	}
+}
+
+func TestInstall(t *testing.T) {
+	skipOnWindows(t)
+	conf, srv := newInstall(t, testsupport.Valid)
+	m := env.New(env.ScopeUser)
+	if err := oic.Install(context.Background(), conf, m); err != nil {
+		t.Fatal(err)
+	}
+
+	clientDir := filepath.Join(conf.InstallPath, testsupport.ClientDir)
+	for _, dir := range []string{clientDir, filepath.Join(clientDir, "sdk")} {
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			t.Errorf("%s was not extracted: %v", dir, err)
+		}
+	}
+	if got, err := m.GetEnvVar(conf.LibVar()); err != nil || got != clientDir {
+		t.Errorf("%s = %q, %v; want %q", conf.LibVar(), got, err, clientDir)
+	}
+	for _, file := range []string{conf.PkgFile, conf.SdkFile} {
+		if n := srv.Requests(file); n != 1 {
+			t.Errorf("%s requested %d times, want 1", file, n)
+		}
+	}
+}
+
+func TestInstallFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		behavior testsupport.Behavior
+		timeout  time.Duration
+		want     string
+	}{
+		{"corrupt", testsupport.Corrupt, 0, "zip"},
+		{"version mismatch", testsupport.VersionMismatch, 0, "does not match"},
+		{"not found", testsupport.NotFound, 0, "404"},
+		{"slow", testsupport.Slow, 100 * time.Millisecond, "download"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skipOnWindows(t)
+			conf, _ := newInstall(t, tt.behavior)
+			if tt.timeout > 0 {
+				conf.Timeouts.Download = tt.timeout
+			}
+			m := env.New(env.ScopeUser)
+			err := oic.Install(context.Background(), conf, m)
+			if err == nil {
+				t.Fatal("install succeeded")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not mention %q", err, tt.want)
+			}
+			if got, _ := m.GetEnvVar(conf.LibVar()); got != "" {
+				t.Errorf("%s = %q after a failed install", conf.LibVar(), got)
+			}
+		})
+	}
+}
+
+func TestInstallDownloadFailureCancelsSibling(t *testing.T) {
+	skipOnWindows(t)
+	conf, srv := newInstall(t, testsupport.Slow)
+	// The package trickles out for seconds while the SDK fails at once
+	srv.SlowDelay = time.Second
+	srv.Sdk = nil
+	start := time.Now()
+	err := oic.Install(context.Background(), conf, env.New(env.ScopeUser))
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("error %v does not mention the failed SDK download", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("install took %s; the package download was not cancelled", elapsed)
+	}
+	if _, err := os.Stat(filepath.Join(conf.DownloadsPath, conf.PkgFile)); err == nil {
+		t.Error("the partial package download was left behind")
+	}
+}
+
+func TestInstallMirrorFailover(t *testing.T) {
+	skipOnWindows(t)
+	conf, srv := newInstall(t, testsupport.Valid)
+	dead := testsupport.NewServer(t, testsupport.NotFound)
+	dead.Configure(conf)
+	srv.Configure(conf)
+	if err := conf.SetBaseURL(dead.URL, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := oic.Install(context.Background(), conf, env.New(env.ScopeUser)); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{conf.PkgFile, conf.SdkFile} {
+		if dead.Requests(file) != 1 || srv.Requests(file) != 1 {
+			t.Errorf("%s requested %d times from the first mirror and %d from the second, want once from each", file, dead.Requests(file), srv.Requests(file))
+		}
+	}
+	m, err := manifest.Load(env.ScopeUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := m.Client(conf.LibVar()); !ok || c.Source != srv.URL+"/" {
+		t.Errorf("manifest records the source %q, want %q", c.Source, srv.URL+"/")
+	}
+}
+
+func TestInstallVersion(t *testing.T) {
+	skipOnWindows(t)
+	conf, srv := newInstall(t, testsupport.Valid)
+	if err := conf.SetVersion("23.7.0.25.01"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "instantclient-basiclite-" + mustPlatform(t, conf).Tag + "-23.7.0.25.01"; !strings.HasPrefix(conf.PkgFile, want) {
+		t.Errorf("package file %s, want %s", conf.PkgFile, want)
+	}
+	if !strings.HasSuffix(conf.BaseURL, "/2370000/") {
+		t.Errorf("base URL %s is not the release's directory", conf.BaseURL)
+	}
+	srv.Configure(conf)
+	if err := oic.Install(context.Background(), conf, env.New(env.ScopeUser)); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Requests(conf.PkgFile); n != 1 {
+		t.Errorf("%s requested %d times, want once", conf.PkgFile, n)
+	}
+
+	// A package of another version fails the install once extracted
+	conf, srv = newInstall(t, testsupport.Valid)
+	if err := conf.SetVersion("19.25"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(conf.PkgFile, "-19.25.0.0.0dbru"+filepath.Ext(conf.PkgFile)) {
+		t.Errorf("package file %s is not the 19.25 release's", conf.PkgFile)
+	}
+	srv.Configure(conf)
+	err := oic.Install(context.Background(), conf, env.New(env.ScopeUser))
+	if err == nil || !strings.Contains(err.Error(), "--version 19.25 pins instantclient_19_25") {
+		t.Fatalf("install of a mismatched release: %v", err)
+	}
+
+	for _, v := range []string{"23.7", "19", "latest"} {
+		if err := conf.SetVersion(v); err == nil {
+			t.Errorf("SetVersion(%q) succeeded", v)
+		}
+	}
+}
+
+func TestInstallFlavor(t *testing.T) {
+	skipOnWindows(t)
+	conf, srv := newInstall(t, testsupport.Valid)
+	if err := conf.SetFlavor(config.FlavorBasic); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(conf.PkgFile, "instantclient-basic-") {
+		t.Errorf("package file %s is not the Basic package", conf.PkgFile)
+	}
+	srv.Configure(conf)
+	srv.Pkg = flavorPackage(t, "libociei.so")
+	if err := oic.Install(context.Background(), conf, env.New(env.ScopeUser)); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Requests(conf.PkgFile); n != 1 {
+		t.Errorf("%s requested %d times, want once", conf.PkgFile, n)
+	}
+
+	// A Basic Light package served under the Basic package's name is caught
+	conf, srv = newInstall(t, testsupport.Valid)
+	if err := conf.SetFlavor(config.FlavorBasic); err != nil {
+		t.Fatal(err)
+	}
+	srv.Configure(conf)
+	srv.Pkg = flavorPackage(t, "libociicus.so")
+	err := oic.Install(context.Background(), conf, env.New(env.ScopeUser))
+	if err == nil || !strings.Contains(err.Error(), "holds the basiclite package") {
+		t.Fatalf("install of the wrong flavor: %v", err)
+	}
+	if err := conf.SetFlavor("full"); err == nil {
+		t.Error("an unknown flavor was accepted")
+	}
+}
+
+// flavorPackage returns a package archive holding the NLS data library lib,
+// which tells its flavor
+func flavorPackage(t *testing.T, lib string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{testsupport.ClientDir + "/", testsupport.ClientDir + "/" + lib} {
+		if _, err := zw.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// mustPlatform returns the download details of conf's platform
+func mustPlatform(t *testing.T, conf *config.InstallConfig) config.Platform {
+	t.Helper()
+	p, ok := config.LookupPlatform(conf.OS, conf.Arch)
+	if !ok {
+		t.Skipf("%s/%s is not a supported platform", conf.OS, conf.Arch)
+	}
+	return p
+}
+
+func TestInstallSQLPlus(t *testing.T) {
+	skipOnWindows(t)
+	conf, srv := newInstall(t, testsupport.Valid)
+	conf.SQLPlus = true
+	srv.Configure(conf)
+	if !strings.HasPrefix(conf.SQLPlusFile(), "instantclient-sqlplus-") {
+		t.Errorf("SQL*Plus file %s", conf.SQLPlusFile())
+	}
+	if err := oic.Install(context.Background(), conf, env.New(env.ScopeUser)); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Requests(conf.SQLPlusFile()); n != 1 {
+		t.Errorf("%s requested %d times, want once", conf.SQLPlusFile(), n)
+	}
+	m, err := manifest.Load(env.ScopeUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := m.Client(conf.LibVar()); !ok || !c.SQLPlus {
+		t.Errorf("manifest does not record SQL*Plus: %+v", c)
+	}
+
+	// SQL*Plus of another release than the package fails the install
+	conf, srv = newInstall(t, testsupport.Valid)
+	conf.SQLPlus = true
+	srv.Configure(conf)
+	var buf bytes.Buffer
+	if err := (testutil.ZipFixture{Dir: "instantclient_21_9", Files: 1, FileSize: 16, Seed: 3}).Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	srv.SQLPlus = buf.Bytes()
+	err = oic.Install(context.Background(), conf, env.New(env.ScopeUser))
+	if err == nil || !strings.Contains(err.Error(), "SQL*Plus version (instantclient_21_9) does not match package version (instantclient_23_7)") {
+		t.Fatalf("install of mismatched SQL*Plus: %v", err)
+	}
+}
+
+func TestInstallMirrorsAllFail(t *testing.T) {
+	skipOnWindows(t)
+	conf, srv := newInstall(t, testsupport.NotFound)
+	if err := conf.SetBaseURL(srv.URL, filepath.Join(t.TempDir(), "share")); err != nil {
+		t.Fatal(err)
+	}
+	err := oic.Install(context.Background(), conf, env.New(env.ScopeUser))
+	if err == nil {
+		t.Fatal("install succeeded")
+	}
+	// Each mirror's reason is given
+	for _, want := range []string{"any of 2 mirrors", "404", "share"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestOverwriteHooks(t *testing.T) {
+	for _, name := range []string{"true", "false"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("no %s", name)
+		}
+	}
+	h := newHarness(t)
+	if err := oic.Install(context.Background(), h.conf, h.env); err != nil {
+		t.Fatal(err)
+	}
+	client := filepath.Join(h.conf.InstallPath, testsupport.ClientDir)
+	if found, err := oic.Exists(context.Background(), h.conf, h.env); err != nil || !found {
+		t.Fatalf("installed client not found: %v", err)
+	}
+
+	// A failing pre-overwrite hook leaves the client as it was
+	h.conf.Hooks.PreOverwrite = []string{"false"}
+	if err := oic.Overwrite(context.Background(), h.conf, h.env); err == nil {
+		t.Fatal("overwrite went ahead despite the failing hook")
+	}
+	if _, err := os.Stat(client); err != nil {
+		t.Fatalf("client removed despite the failing hook: %v", err)
+	}
+	if got, _ := h.env.GetEnvVar(h.conf.LibVar()); got != client {
+		t.Errorf("%s = %q after the failing hook, want %q", h.conf.LibVar(), got, client)
+	}
+
+	// Replacing the client is not uninstalling it
+	h.conf.Hooks.PreOverwrite = []string{"true"}
+	h.conf.Hooks.PreUninstall = []string{"false"}
+	if err := oic.Overwrite(context.Background(), h.conf, h.env); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(client); err == nil {
+		t.Error("client kept after the overwrite")
+	}
+}
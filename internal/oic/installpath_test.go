@@ -0,0 +1,51 @@
+package oic_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/oic"
+	"github.com/mghoff/oraicwinconfig/internal/plan"
+)
+
+func TestPlanInstallPathGiven(t *testing.T) {
+	h := newHarness(t)
+	if err := oic.Install(context.Background(), h.conf, h.env); err != nil {
+		t.Fatal(err)
+	}
+	extractDirs := func() []string {
+		t.Helper()
+		p, err := oic.BuildPlan(context.Background(), h.conf, h.env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dirs []string
+		for _, a := range p.Actions {
+			if a.Kind == plan.KindExtract {
+				dirs = append(dirs, a.Dir)
+			}
+		}
+		return dirs
+	}
+
+	// Beside the existing installation by default
+	h.conf.Existing = config.ExistingKeep
+	for _, dir := range extractDirs() {
+		if dir != h.install {
+			t.Errorf("extracts to %s, want the existing installation's base %s", dir, h.install)
+		}
+	}
+
+	given := filepath.Join(h.root, "elsewhere")
+	if err := h.conf.SetInstallPath(given); err != nil {
+		t.Fatal(err)
+	}
+	h.conf.InstallPathGiven = true
+	for _, dir := range extractDirs() {
+		if dir != given {
+			t.Errorf("extracts to %s, want the given %s", dir, given)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package oic
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/odpnet"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// StepODPNet names the step setting up ODP.NET, which runs after
+// configure-env when an ODP.NET driver is requested
+const StepODPNet = "configure-odp-net"
+
+// odpNetStep stages and registers the requested ODP.NET drivers
+type odpNetStep struct{}
+
+func (odpNetStep) Name() string { return StepODPNet }
+
+func (odpNetStep) Run(ctx context.Context, s *State) error {
+	if err := s.client(); err != nil {
+		return err
+	}
+	dir := s.OCILibPath()
+	if s.Conf.ODPNet.Unmanaged() {
+		s.OnCancel(func(ctx context.Context) error { return odpnet.Unregister(ctx, dir) })
+	}
+	return setupODPNet(ctx, s.Conf, dir, s.TNSAdminPath())
+}
+
+// setupODPNet stages the managed driver in the client in clientDir and points
+// the unmanaged driver at it, as conf asks. The managed driver is only staged
+// with the primary client, as it does not depend on the architecture.
+func setupODPNet(ctx context.Context, conf *config.InstallConfig, clientDir, tnsAdminPath string) error {
+	if conf.ODPNet.Managed() && !conf.Secondary {
+		dctx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Download)
+		defer cancel()
+		dir := filepath.Join(clientDir, odpnet.DirName)
+		version, frameworks, err := odpnet.StageManaged(dctx, utils.NewHTTPClient(conf.HTTP), conf.HTTP.Retry, conf.ODPNet.Feed, conf.ODPNet.Version, dir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("staged %s %s for %s in %s\n", odpnet.ManagedPackage, version, strings.Join(frameworks, ", "), dir)
+	}
+	if conf.ODPNet.Unmanaged() {
+		ectx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Environment)
+		defer cancel()
+		versions, err := odpnet.Register(ectx, conf.Arch == "386", clientDir, tnsAdminPath, conf.ODPNet.Versions)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			fmt.Println("warning: no ODP.NET version is registered on this machine; give the version of the unmanaged driver with --odp-net-register")
+		} else {
+			fmt.Printf("pointed the unmanaged ODP.NET driver %s at %s\n", strings.Join(versions, ", "), clientDir)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package oic_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/oic"
+)
+
+// newFeed serves the managed driver package from a NuGet package content feed
+func newFeed(t *testing.T) *httptest.Server {
+	t.Helper()
+	var pkg bytes.Buffer
+	zw := zip.NewWriter(&pkg)
+	for _, name := range []string{
+		"lib/net8.0/Oracle.ManagedDataAccess.dll",
+		"lib/netstandard2.1/Oracle.ManagedDataAccess.dll",
+		"lib/net8.0/Oracle.ManagedDataAccess.xml",
+		"lib/../../evil/Oracle.ManagedDataAccess.dll",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(name))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oracle.manageddataaccess.core/index.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"versions": ["23.5.0", "23.7.0", "23.9.0-beta1"]}`))
+	})
+	mux.HandleFunc("/oracle.manageddataaccess.core/23.7.0/oracle.manageddataaccess.core.23.7.0.nupkg", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(pkg.Bytes())
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestODPNetManaged(t *testing.T) {
+	h := newHarness(t)
+	h.conf.ODPNet = config.ODPNetConfig{Driver: config.ODPNetManaged, Feed: newFeed(t).URL}
+
+	p, err := oic.InstallPipeline(context.Background(), h.conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := p.Names()
+	if len(names) < 5 || names[3] != oic.StepConfigureEnv || names[4] != oic.StepODPNet {
+		t.Errorf("steps = %v, want %s after %s", names, oic.StepODPNet, oic.StepConfigureEnv)
+	}
+	if err := oic.Install(context.Background(), h.conf, h.env); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(h.conf.InstallPath, "instantclient_23_7", "odp.net")
+	for _, framework := range []string{"net8.0", "netstandard2.1"} {
+		data, err := os.ReadFile(filepath.Join(dir, framework, "Oracle.ManagedDataAccess.dll"))
+		if err != nil || string(data) != "lib/"+framework+"/Oracle.ManagedDataAccess.dll" {
+			t.Errorf("%s assembly = %q, %v", framework, data, err)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("staged %d entries, want only the two frameworks", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(h.root, "evil")); err == nil {
+		t.Error("an entry outside lib/<framework> was extracted")
+	}
+	m, err := manifest.Load(h.env.Scope())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec, ok := m.Client(h.conf.LibVar()); !ok || rec.ODPNet != config.ODPNetManaged {
+		t.Errorf("recorded client = %+v, want the managed driver", rec)
+	}
+}
+
+func TestODPNetUnmanagedValidation(t *testing.T) {
+	conf := config.New()
+	if err := conf.SetPlatform("linux", "amd64"); err != nil {
+		t.Fatal(err)
+	}
+	conf.DownloadsPath = t.TempDir()
+	conf.ODPNet.Driver = config.ODPNetUnmanaged
+	if err := conf.Validate(); err == nil {
+		t.Error("registering the unmanaged driver outside Windows passed validation")
+	}
+	conf.ODPNet.Driver = "odbc"
+	if err := conf.Validate(); err == nil {
+		t.Error("an unknown driver passed validation")
+	}
+}
@@ -4,18 +4,25 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"context"
 	"strings"
+	"sync"
+	"context"
 	"errors"
+	"time"
 
 	"github.com/mghoff/oraicwinconfig/internal/config"
 	"github.com/mghoff/oraicwinconfig/internal/env"
 	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/hooks"
+	"github.com/mghoff/oraicwinconfig/internal/journal"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/odpnet"
 	"github.com/mghoff/oraicwinconfig/internal/utils"
+	"github.com/mghoff/oraicwinconfig/internal/version"
 )
 
 // Exists checks if Oracle InstantClient is already installed
-func Exists(ctx context.Context, conf *config.InstallConfig, env *env.EnvVarManager) (bool, error) {
+func Exists(ctx context.Context, conf *config.InstallConfig, env env.Manager) (bool, error) {
 	ctx = utils.EnsureContext(ctx)
 	// Check for context cancellation
 	if err := ctx.Err(); err != nil {
@@ -23,15 +30,26 @@ func Exists(ctx context.Context, conf *config.InstallConfig, env *env.EnvVarMana
 	}
 	fmt.Println("Checking for existing Oracle InstantClient installation...")
 
-	// Check if OCI_LIB64 environment variable exists
+	ctx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Environment)
+	defer cancel()
+	env = env.WithContext(ctx)
+
+	// Check if OCI_LIB64 (or OCI_LIB32) environment variable exists
 	// This variable should point to the directory where the Oracle Instant Client files are located
 	// If it exists and points to a valid directory, it indicates an existing installation
-	ociLibPath, err := env.ValidateEnvVar("OCI_LIB64")
+	libVar := conf.LibVar()
+	ociLibPath, err := env.ValidateEnvVar(libVar)
+	reportOtherScope(env, libVar)
+	if errs.IsErrorType(err, errs.ErrorTypeEnvironment) {
+		fmt.Printf("%s points to a directory that no longer exists, so the installation it referred to was deleted.\n", libVar)
+		fmt.Println("Run 'oraicwinconfig doctor --fix-dangling' to clear the stale variables or re-point them at another install.")
+		return false, err
+	}
 	if err != nil {
-		fmt.Println("OCI_LIB64 environment variable not found or invalid, indicating no existing installation.")
+		fmt.Printf("%s environment variable not found or invalid in %s scope, indicating no existing installation.\n", libVar, env.Scope())
 		return false, err
 	}
-	fmt.Println("OCI_LIB64 environment variable is set and is valid, indicating an existing installation.")
+	fmt.Printf("%s environment variable is set and is valid, indicating an existing installation.\n", libVar)
 
 	// Update the config with the existing installation path
 	if err := conf.SetInstallPath(ociLibPath); errs.IsErrorType(err, errs.ErrorTypeValidation) {
@@ -41,13 +59,14 @@ func Exists(ctx context.Context, conf *config.InstallConfig, env *env.EnvVarMana
 	// Check if TNS_ADMIN environment variable exists
 	// This variable should point to the directory containing the Oracle Net configuration files
 	// If it exists and points to a valid subdirectory of OCI_LIB64, it indicates a valid existing installation
+	// A shared TNS_ADMIN directory is expected instead of network/admin when one is configured
 	tnsAdminPath, err := env.ValidateEnvVar("TNS_ADMIN")
-	if err != nil || !strings.Contains(tnsAdminPath, ociLibPath) || tnsAdminPath == ociLibPath || tnsAdminPath != filepath.Join(ociLibPath, "network", "admin"){
+	if err != nil || filepath.Clean(tnsAdminPath) != filepath.Clean(conf.TNSAdminPath(ociLibPath)) {
 		fmt.Println("TNS_ADMIN environment variable not found or invalid, indicating a misconfigured existing installation.")
 		fmt.Println("\nAn existing Oracle InstantClient installation was found, but appears misconfigured.")
 		return true, nil
 	}
-	fmt.Println("TNS_ADMIN environment variable is set and points to a subdirectory of OCI_LIB64, indicating a valid existing installation.")
+	fmt.Printf("TNS_ADMIN environment variable is set and points to %s, indicating a valid existing installation.\n", tnsAdminPath)
 
 	// Check if the TNS_ADMIN directory contains tnsnames.ora file
 	// This file is essential for Oracle Net configuration and should exist in the TNS_ADMIN directory
@@ -69,37 +88,74 @@ func Exists(ctx context.Context, conf *config.InstallConfig, env *env.EnvVarMana
 
 // Uninstall removes the Oracle InstantClient installation
 // It cleans up the environment variables and removes the installation directory
-func Uninstall(ctx context.Context, conf *config.InstallConfig, env *env.EnvVarManager) error {
+func Uninstall(ctx context.Context, conf *config.InstallConfig, env env.Manager) error {
+	return uninstall(ctx, conf, env, hooks.EventPreUninstall, conf.Hooks.PreUninstall)
+}
+
+// Overwrite removes the existing client at conf.InstallPath as Uninstall
+// does, for a new one to replace it. Only the pre-overwrite hooks guard the
+// removal, as it is part of an install rather than an uninstall.
+func Overwrite(ctx context.Context, conf *config.InstallConfig, env env.Manager) error {
+	return uninstall(ctx, conf, env, hooks.EventPreOverwrite, conf.Hooks.PreOverwrite)
+}
+
+// uninstall removes the client, once the guard hooks of event allow it
+func uninstall(ctx context.Context, conf *config.InstallConfig, env env.Manager, event hooks.Event, guard []string) error {
 	ctx = utils.EnsureContext(ctx)
 	if err := ctx.Err(); err != nil {
 		return errs.HandleError(err, errs.ErrorTypeInstall, "context cancellation")
 	}
 
-	// Remove OCI_LIB64 from PATH
-	envVar, err := env.GetEnvVar("OCI_LIB64")
+	envCtx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Environment)
+	defer cancel()
+	env = env.WithContext(envCtx)
+
+	// Remove OCI_LIB64 (or OCI_LIB32) from PATH
+	libVar := conf.LibVar()
+	envVar, err := env.GetEnvVar(libVar)
 	if err != nil {
 		if errs.IsErrorType(err, errs.ErrorTypeEnvVarNotFound) {
-			fmt.Println("OCI_LIB64 environment variable not found, skipping removal from PATH.")
+			fmt.Printf("%s environment variable not found, skipping removal from PATH.\n", libVar)
 			return nil
 		}
 		return err
 	}
-	if err := env.RemoveFromPath(envVar); err != nil {
+	// Let the guard hooks stop services or back up files before anything is removed
+	if err := runGuardHooks(ctx, conf, event, guard, libVar, envVar); err != nil {
 		return err
 	}
 
-	// Remove OCI_LIB64 environment variable
-	if err := env.RemoveEnvVar("OCI_LIB64"); err != nil {
+	// Extra variables set with the client are removed with it
+	var extra []string
+	unmanaged := false
+	if m, err := manifest.Load(env.Scope()); err == nil {
+		if rec, ok := m.Client(libVar); ok {
+			extra = sortedKeys(rec.ExtraEnv)
+			unmanaged = (config.ODPNetConfig{Driver: rec.ODPNet}).Unmanaged()
+		}
+	}
+	if err := unconfigureEnv(env, libVar, envVar, extra); err != nil {
 		return err
 	}
+	// So is the unmanaged ODP.NET driver's registration
+	if unmanaged {
+		if err := odpnet.Unregister(envCtx, envVar); err != nil {
+			fmt.Printf("warning: could not remove the unmanaged ODP.NET driver settings: %v\n", err)
+		}
+	}
 
-	// Remove TNS_ADMIN environment variable
-	if err := env.RemoveEnvVar("TNS_ADMIN"); err != nil {
-		return err
+	if err := manifest.Record(env.Scope(), func(m *manifest.Manifest) { m.Remove(libVar) }); err != nil {
+		fmt.Printf("warning: could not update the installation manifest: %v\n", err)
 	}
 
+	// A client configured in the other scope is left alone, but may still shadow the new one
+	reportOtherScope(env, libVar)
+
 	// Remove installation directory with safety checks
-	if err := os.RemoveAll(conf.InstallPath); err != nil {
+	// Clients on network shares may be in use by other machines, so only this machine's configuration is removed
+	if utils.IsUNC(conf.InstallPath) {
+		fmt.Printf("leaving %s in place since network shares may be shared by other machines\n", conf.InstallPath)
+	} else if err := os.RemoveAll(conf.InstallPath); err != nil {
 		return errs.HandleError(err, errs.ErrorTypeInstall, "removing installation directory")
 	}
 
@@ -112,89 +168,468 @@ func Uninstall(ctx context.Context, conf *config.InstallConfig, env *env.EnvVarM
 }
 
 // Install performs the installation and configuration of Oracle Instant Client
-func Install(ctx context.Context, conf *config.InstallConfig, env *env.EnvVarManager) error {
-	ctx = utils.EnsureContext(ctx)
-	if err := ctx.Err(); err != nil {
-		return errs.HandleError(err, errs.ErrorTypeInstall, "context cancellation")
+// by running the default pipeline, with the steps of any plugins added
+func Install(ctx context.Context, conf *config.InstallConfig, env env.Manager) error {
+	p, err := InstallPipeline(ctx, conf)
+	if err != nil {
+		return err
 	}
+	return p.Run(ctx, conf, env)
+}
 
-	// INSTALLATION STEPS
-	fmt.Println("\nStarting Oracle InstantClient installation...")
-	// Set paths for downloads
-	pkgZipPath := filepath.Join(conf.DownloadsPath, conf.PkgFile)
-	sdkZipPath := filepath.Join(conf.DownloadsPath, conf.SdkFile)
+// runGuardHooks runs hooks guarding a destructive operation on the client in clientDir.
+// A failure aborts the operation unless the hooks are forced.
+func runGuardHooks(ctx context.Context, conf *config.InstallConfig, event hooks.Event, commands []string, libVar, clientDir string) error {
+	err := hooks.Run(ctx, event, commands, hookVars(conf, libVar, clientDir, conf.TNSAdminPath(clientDir)), conf.Timeouts.Hook)
+	if err != nil && conf.Hooks.Force {
+		fmt.Printf("warning: %v; continuing since hooks are forced\n", err)
+		return nil
+	}
+	return err
+}
 
-	// Download package files
-	fmt.Printf("downloading package: %s...\n", pkgZipPath)
-	if err := utils.DownloadZip(ctx, conf.BaseURL+conf.PkgFile, pkgZipPath); err != nil {
-		return err
+// hookVars returns the variables describing the installed client passed to hooks
+func hookVars(conf *config.InstallConfig, libVar, ociLibPath, tnsAdminPath string) map[string]string {
+	vars := map[string]string{
+		libVar:                          ociLibPath,
+		"ORAICWINCONFIG_CLIENT_DIR":     ociLibPath,
+		"ORAICWINCONFIG_CLIENT_VERSION": utils.ClientVersion(ociLibPath),
+		"ORAICWINCONFIG_ARCH":           conf.Arch,
+		"ORAICWINCONFIG_SCOPE":          string(conf.Scope),
+		"ORAICWINCONFIG_VERSION":        version.Version,
 	}
+	if tnsAdminPath != "" {
+		vars["TNS_ADMIN"] = tnsAdminPath
+	}
+	return vars
+}
 
-	// Download SDK files
-	fmt.Printf("downloading SDK: %s...\n", sdkZipPath)
-	if err := utils.DownloadZip(ctx, conf.BaseURL+conf.SdkFile, sdkZipPath); err != nil {
+// scanVars returns the variables passed to pre-extract hooks: the archives
+// to scan, and the facts known before extraction
+func scanVars(conf *config.InstallConfig, pkgZipPath, sdkZipPath string) map[string]string {
+	vars := map[string]string{
+		"ORAICWINCONFIG_PKG_ARCHIVE": pkgZipPath,
+		"ORAICWINCONFIG_SDK_ARCHIVE": sdkZipPath,
+		"ORAICWINCONFIG_ARCH":        conf.Arch,
+		"ORAICWINCONFIG_SCOPE":       string(conf.Scope),
+		"ORAICWINCONFIG_VERSION":     version.Version,
+	}
+	if path := sqlplusZipPath(conf); path != "" {
+		vars["ORAICWINCONFIG_SQLPLUS_ARCHIVE"] = path
+	}
+	return vars
+}
+
+// sqlplusZipPath returns where the SQL*Plus package is downloaded to, or ""
+// if it is not installed
+func sqlplusZipPath(conf *config.InstallConfig) string {
+	if !conf.SQLPlus {
+		return ""
+	}
+	return filepath.Join(conf.DownloadsPath, conf.SQLPlusFile())
+}
+
+// recordManifest saves the installed client and its environment to the manifest of the scope
+func recordManifest(conf *config.InstallConfig, env env.Manager, libVar, ociLibPath, tnsAdminPath string) error {
+	client := manifestClient(conf, libVar, ociLibPath, tnsAdminPath)
+	return manifest.Record(env.Scope(), func(m *manifest.Manifest) { m.Put(client) })
+}
+
+// manifestClient describes the installed client and the environment configured for it
+func manifestClient(conf *config.InstallConfig, libVar, ociLibPath, tnsAdminPath string) manifest.Client {
+	client := manifest.Client{
+		LibVar:      libVar,
+		ClientDir:   ociLibPath,
+		InstallPath: conf.InstallPath,
+		Arch:        conf.Arch,
+		PkgFile:     conf.PkgFile,
+		Source:      conf.Source,
+		EnvMode:     conf.EnvMode,
+		ODPNet:      conf.ODPNet.Driver,
+		SQLPlus:     conf.SQLPlus,
+		Version:     version.Version,
+		InstalledAt: time.Now().UTC(),
+	}
+	if conf.EnvMode != config.EnvModeWrapper {
+		client.Vars = map[string]string{libVar: ociLibPath}
+		if tnsAdminPath != "" {
+			client.Vars["TNS_ADMIN"] = tnsAdminPath
+		}
+		client.Path = []string{ociLibPath}
+		if extra, err := extraEnv(conf, libVar, ociLibPath, tnsAdminPath); err == nil && len(extra) > 0 {
+			for name, value := range extra {
+				client.Vars[name] = value
+			}
+			client.ExtraEnv = conf.ExtraEnv
+		}
+	}
+	return client
+}
+
+// extraEnv expands the configured extra variables for the client in ociLibPath,
+// with the facts passed to hooks available as ${NAME}. Secondary clients leave
+// them to the primary one.
+func extraEnv(conf *config.InstallConfig, libVar, ociLibPath, tnsAdminPath string) (map[string]string, error) {
+	if conf.Secondary || len(conf.ExtraEnv) == 0 {
+		return nil, nil
+	}
+	return conf.ExpandExtraEnv(hookVars(conf, libVar, ociLibPath, tnsAdminPath))
+}
+
+// configureEnv points the client variable, PATH and TNS_ADMIN at the new client
+// within the environment timeout; an empty tnsAdminPath leaves TNS_ADMIN alone.
+// The variables are updated as one unit, so a failure restores all of them.
+func configureEnv(ctx context.Context, conf *config.InstallConfig, m env.Manager, libVar, ociLibPath, tnsAdminPath string) error {
+	extra, err := extraEnv(conf, libVar, ociLibPath, tnsAdminPath)
+	if err != nil {
 		return err
 	}
+	ctx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Environment)
+	defer cancel()
+
+	names := append([]string{libVar, "TNS_ADMIN"}, conf.ExtraEnvNames()...)
+	return env.Update(m.WithContext(ctx), names, func(env env.Manager) error {
+		// Set OCI_LIB64 (or OCI_LIB32) environment variable
+		fmt.Printf("setting %s=%s\n", libVar, ociLibPath)
+		if err := env.SetEnvVar(libVar, ociLibPath); err != nil {
+			return err
+		}
+
+		// Add OCI_LIB64 to PATH
+		fmt.Printf("updating PATH to include %s\n", ociLibPath)
+		if err := env.AppendToPath(ociLibPath); err != nil {
+			return err
+		}
+		if err := orderPath(env, conf, ociLibPath); err != nil {
+			return err
+		}
+
+		// Set TNS_ADMIN environment variable
+		if tnsAdminPath != "" {
+			fmt.Printf("setting TNS_ADMIN=%s\n", tnsAdminPath)
+			if err := env.SetEnvVar("TNS_ADMIN", tnsAdminPath); err != nil {
+				return err
+			}
+		}
+
+		// Set the extra variables from the configuration file
+		for _, name := range conf.ExtraEnvNames() {
+			if value, ok := extra[name]; ok {
+				fmt.Printf("setting %s=%s\n", name, value)
+				if err := env.SetEnvVar(name, value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// unconfigureEnv removes the client directory from PATH and the client
+// variable, TNS_ADMIN and the extra variables, together or not at all
+func unconfigureEnv(m env.Manager, libVar, dir string, extra []string) error {
+	return env.Update(m, append([]string{libVar, "TNS_ADMIN"}, extra...), func(env env.Manager) error {
+		if err := env.RemoveFromPath(dir); err != nil {
+			return err
+		}
+
+		// Remove OCI_LIB64 (or OCI_LIB32) environment variable
+		if err := env.RemoveEnvVar(libVar); err != nil {
+			return err
+		}
+
+		// Remove TNS_ADMIN environment variable
+		if err := env.RemoveEnvVar("TNS_ADMIN"); err != nil {
+			return err
+		}
+
+		for _, name := range extra {
+			fmt.Printf("removing %s\n", name)
+			if err := env.RemoveEnvVar(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// reportOtherScope warns when the client variable is also set in the scope not being managed.
+// Machine PATH entries come before User ones on Windows, so mixed installs shadow each other.
+func reportOtherScope(env env.Manager, libVar string) {
+	other := env.Scope().Other()
+	if path, err := env.WithScope(other).GetEnvVar(libVar); err == nil {
+		fmt.Printf("warning: %s is also set in %s scope to %s; this mixed installation is not managed by the current --scope %s run\n", libVar, other, path, env.Scope())
+	}
+}
+
+// orderPath keeps the 64-bit client ahead of the 32-bit one on PATH,
+// so 64-bit applications load the matching oci.dll first
+func orderPath(env env.Manager, conf *config.InstallConfig, ociLibPath string) error {
+	if conf.OS != "windows" {
+		return nil
+	}
+	if conf.Arch == "386" {
+		lib64, err := env.GetEnvVar("OCI_LIB64")
+		if err != nil {
+			return nil
+		}
+		return env.MovePathBefore(lib64, ociLibPath)
+	}
+	lib32, err := env.GetEnvVar("OCI_LIB32")
+	if err != nil {
+		return nil
+	}
+	return env.MovePathBefore(ociLibPath, lib32)
+}
+
+// download fetches the package and SDK zip files, and SQL*Plus when asked
+// for, concurrently within the download timeout. The first failure cancels
+// the other downloads, and is the one returned.
+func download(ctx context.Context, conf *config.InstallConfig, pkgZipPath, sdkZipPath string) error {
+	ctx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Download)
+	defer cancel()
+	dlCtx, cancelSibling := context.WithCancel(ctx)
+	defer cancelSibling()
+	client := utils.NewHTTPClient(conf.HTTP)
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	sources := make([]string, 3)
+	fetch := func(i int, label, file, path string, limits config.SizeLimits) {
+		defer wg.Done()
+		fmt.Printf("downloading %s: %s...\n", label, path)
+		base, err := utils.DownloadMirrored(dlCtx, client, conf.BaseURLs(), file, path, limits, conf.HTTP.Retry)
+		if err != nil {
+			once.Do(func() {
+				firstErr = err
+				cancelSibling()
+			})
+		}
+		sources[i] = base
+	}
+	wg.Add(2)
+	go fetch(0, "package", conf.PkgFile, pkgZipPath, conf.PkgSize)
+	go fetch(1, "SDK", conf.SdkFile, sdkZipPath, conf.SdkSize)
+	if path := sqlplusZipPath(conf); path != "" {
+		wg.Add(1)
+		go fetch(2, "SQL*Plus", conf.SQLPlusFile(), path, conf.SQLPlusSize)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return phaseError(ctx, firstErr, "download")
+	}
+	// The manifest records where the client came from
+	conf.Source = sources[0]
+	return nil
+}
+
+// extract unzips the package and SDK, and SQL*Plus into the package's
+// directory, into the install path within the extract timeout and returns the
+// top-level directory of the package and SDK
+func extract(ctx context.Context, conf *config.InstallConfig, pkgZipPath, sdkZipPath string) (string, string, error) {
+	// Let the configured scanners inspect the archives before anything is unpacked
+	if err := hooks.Run(ctx, hooks.EventPreExtract, conf.Hooks.PreExtract, scanVars(conf, pkgZipPath, sdkZipPath), conf.Timeouts.Hook); err != nil {
+		return "", "", err
+	}
+
+	ctx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Extract)
+	defer cancel()
 
 	// Unzip package files
 	fmt.Printf("extracting: %s to %s\n", pkgZipPath, conf.InstallPath)
-	pkgDir, err := utils.UnZip(pkgZipPath, conf.InstallPath)
+	pkgDir, err := utils.Extract(ctx, pkgZipPath, conf.InstallPath)
 	if err != nil {
-		return errs.HandleError(err, errs.ErrorTypeInstall, "unzip package")
+		return "", "", phaseError(ctx, errs.HandleError(err, errs.ErrorTypeInstall, "unzip package"), "extract")
 	}
 
 	// Unzip SDK files
 	fmt.Printf("extracting: %s to %s\n", sdkZipPath, filepath.Join(conf.InstallPath, pkgDir, "sdk"))
-	sdkDir, err := utils.UnZip(sdkZipPath, conf.InstallPath)
+	sdkDir, err := utils.Extract(ctx, sdkZipPath, conf.InstallPath)
 	if err != nil {
-		return errs.HandleError(err, errs.ErrorTypeInstall, "unzip SDK")
+		return "", "", phaseError(ctx, errs.HandleError(err, errs.ErrorTypeInstall, "unzip SDK"), "extract")
 	}
 
-	// Verify version match
-	if pkgDir != sdkDir {
-		return errs.HandleError(
-			fmt.Errorf("package version (%s) does not match SDK version (%s)", pkgDir, sdkDir),
-			errs.ErrorTypeInstall,
-			"version verification",
-		)
+	// SQL*Plus goes into the client directory and must be the package's version
+	if path := sqlplusZipPath(conf); path != "" {
+		fmt.Printf("extracting: %s to %s\n", path, filepath.Join(conf.InstallPath, pkgDir))
+		dir, err := utils.Extract(ctx, path, conf.InstallPath)
+		if err != nil {
+			return "", "", phaseError(ctx, errs.HandleError(err, errs.ErrorTypeInstall, "unzip SQL*Plus"), "extract")
+		}
+		if dir != pkgDir {
+			return "", "", errs.HandleError(
+				fmt.Errorf("SQL*Plus version (%s) does not match package version (%s)", dir, pkgDir),
+				errs.ErrorTypeInstall,
+				"version verification")
+		}
 	}
-	fmt.Println("package and SDK versions match, continuing...")
 
-	// CONFIGURATION STEPS
-	fmt.Println("\nConfiguring Oracle InstantClient...")
+	// Downloaded libraries must not carry the quarantine attribute on macOS
+	if conf.OS == "darwin" {
+		fmt.Println("clearing quarantine attributes and verifying code signatures...")
+		if err := utils.ClearQuarantine(ctx, filepath.Join(conf.InstallPath, pkgDir)); err != nil {
+			return "", "", err
+		}
+	}
+	if err := verifySignatures(ctx, conf, filepath.Join(conf.InstallPath, pkgDir)); err != nil {
+		return "", "", err
+	}
+	// A package republished or mirrored under the other flavor's name lacks or adds the NLS data
+	if flavor := utils.ClientFlavor(filepath.Join(conf.InstallPath, pkgDir)); flavor != "" && conf.Flavor != "" && flavor != conf.Flavor {
+		return "", "", errs.HandleError(
+			fmt.Errorf("%s holds the %s package, but the %s package was requested", filepath.Base(pkgZipPath), flavor, conf.Flavor),
+			errs.ErrorTypeValidation,
+			"verifying package flavor")
+	}
+	return pkgDir, sdkDir, nil
+}
 
-	// Set OCI_LIB64 environment variable
-	ociLibPath := filepath.Join(conf.InstallPath, pkgDir)
-	fmt.Printf("setting OCI_LIB64=%s\n", ociLibPath)
-	if err := env.SetEnvVar("OCI_LIB64", ociLibPath); err != nil {
+// verifySignatures checks the extracted Windows libraries are validly signed by
+// Oracle, so a compromised mirror cannot slip in modified binaries. Depending on
+// conf.Signatures a problem fails the install, removing the untrusted
+// libraries, or is only reported.
+func verifySignatures(ctx context.Context, conf *config.InstallConfig, dir string) error {
+	if conf.OS != "windows" || conf.Signatures == config.SignaturesOff {
+		return nil
+	}
+	fmt.Println("verifying Authenticode signatures...")
+	sigs, err := utils.VerifyAuthenticode(ctx, dir)
+	if err != nil {
 		return err
 	}
+	var bad, untrusted []string
+	for _, sig := range sigs {
+		if !sig.Trusted() {
+			bad = append(bad, sig.String())
+			untrusted = append(untrusted, sig.Path)
+		}
+	}
+	if len(sigs) == 0 {
+		bad = append(bad, "no libraries found in "+dir)
+	}
+	if len(bad) == 0 {
+		fmt.Printf("%d libraries are validly signed by Oracle\n", len(sigs))
+		return nil
+	}
+	if conf.Signatures == config.SignaturesWarn {
+		for _, b := range bad {
+			fmt.Printf("warning: untrusted signature: %s\n", b)
+		}
+		return nil
+	}
+	// Nothing may load the rejected libraries from the install path
+	for _, path := range untrusted {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("warning: could not remove %s: %v\n", path, err)
+		}
+	}
+	return errs.HandleError(
+		fmt.Errorf("libraries are not validly signed by Oracle, and were removed:\n  %s", strings.Join(bad, "\n  ")),
+		errs.ErrorTypeInstall,
+		"verifying signatures")
+}
 
-	// Add OCI_LIB64 to PATH
-	fmt.Printf("updating PATH to include %s\n", ociLibPath)
-	if err := env.AppendToPath(ociLibPath); err != nil {
-		return err
+// checkTNSAdmin verifies the shared TNS_ADMIN directory is reachable within the
+// preflight timeout, and warns when it holds no tnsnames.ora
+func checkTNSAdmin(ctx context.Context, conf *config.InstallConfig) error {
+	if conf.TNSAdmin == "" || conf.Secondary {
+		return nil
 	}
+	ctx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Preflight)
+	defer cancel()
+	if err := utils.CheckDir(ctx, conf.TNSAdmin); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeValidation, "checking shared TNS_ADMIN")
+	}
+	if _, err := os.Stat(filepath.Join(conf.TNSAdmin, "tnsnames.ora")); err != nil {
+		fmt.Printf("warning: shared TNS_ADMIN %s has no tnsnames.ora\n", conf.TNSAdmin)
+	}
+	return nil
+}
 
-	// Set TNS_ADMIN environment variable
-	tnsAdminPath := filepath.Join(ociLibPath, "network", "admin")
-	fmt.Printf("setting TNS_ADMIN=%s\n", tnsAdminPath)
-	if err := env.SetEnvVar("TNS_ADMIN", tnsAdminPath); err != nil {
-		return err
+// checksumsMatch re-validates the downloads recorded in the journal
+func checksumsMatch(j *journal.Journal, paths ...string) bool {
+	for _, path := range paths {
+		sum, err := utils.FileSHA256(path)
+		if err != nil || sum != j.Checksums[path] {
+			return false
+		}
 	}
+	return true
+}
 
-	// Move tnsnames.ora file to TNS_ADMIN directory
-	if conf.Extant {
-		fmt.Printf("moving tnsnames.ora from %s to %s\n", filepath.Join(conf.DownloadsPath, "tnsnames.ora"), tnsAdminPath)
-		if err := utils.MigrateFile(
-			filepath.Join(conf.DownloadsPath, "tnsnames.ora"),
-			filepath.Join(tnsAdminPath, "tnsnames.ora"),
-			false,
-		); err != nil {
+// verifyPins checks the downloads, SQL*Plus included, against the checksums
+// pinned by a lock file or mirror index.
+// A mismatching download is deleted, so the next run fetches it again.
+func verifyPins(conf *config.InstallConfig, pkgZipPath, sdkZipPath string) error {
+	for _, f := range []struct{ path, want string }{
+		{pkgZipPath, conf.Pins.PkgSHA256},
+		{sdkZipPath, conf.Pins.SdkSHA256},
+		{sqlplusZipPath(conf), conf.Pins.SQLPlusSHA256},
+	} {
+		if f.want == "" || f.path == "" {
+			continue
+		}
+		sum, err := utils.FileSHA256(f.path)
+		if err != nil {
 			return err
 		}
+		if !strings.EqualFold(sum, f.want) {
+			// The archives of an offline bundle are not this run's to delete
+			if !conf.Offline {
+				os.Remove(f.path)
+			}
+			return errs.HandleError(
+				fmt.Errorf("checksum mismatch for %s: %s pins %s, got %s; the artifact may have been republished or altered", filepath.Base(f.path), conf.Pins.Source, f.want, sum),
+				errs.ErrorTypeValidation,
+				"verifying locked download")
+		}
+	}
+	if conf.Pins.PkgSHA256 != "" {
+		fmt.Printf("downloads match %s\n", conf.Pins.Source)
 	}
+	return nil
+}
 
-	fmt.Println("\nOracle InstantClient installation and configuration completed successfully!")
+// recordChecksums stores the SHA-256 of the downloads in the journal
+func recordChecksums(j *journal.Journal, paths ...string) error {
+	if j == nil {
+		return nil
+	}
+	for _, path := range paths {
+		sum, err := utils.FileSHA256(path)
+		if err != nil {
+			return err
+		}
+		j.Checksums[path] = sum
+	}
 	return nil
 }
+
+// envConfigured reports whether the client variable already points at dir
+func envConfigured(env env.Manager, libVar, dir string) bool {
+	value, err := env.GetEnvVar(libVar)
+	return err == nil && filepath.Clean(value) == filepath.Clean(dir)
+}
+
+// isDir reports whether dir is an existing directory
+func isDir(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// phaseError annotates err when it was caused by the phase running out of time
+func phaseError(ctx context.Context, err error, phase string) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		var installErr *errs.InstallError
+		errorType := errs.ErrorTypeInstall
+		if errors.As(err, &installErr) {
+			errorType = installErr.Type
+		}
+		return errs.HandleError(
+			fmt.Errorf("%s phase timed out: %w", phase, err),
+			errorType,
+			phase+" timeout")
+	}
+	return err
+}
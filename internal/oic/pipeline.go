@@ -0,0 +1,606 @@
+package oic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/doctor"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/events"
+	"github.com/mghoff/oraicwinconfig/internal/generate"
+	"github.com/mghoff/oraicwinconfig/internal/hooks"
+	"github.com/mghoff/oraicwinconfig/internal/journal"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// Names of the default install steps, in the order they run
+const (
+	StepDownload     = "download"
+	StepVerify       = "verify"
+	StepExtract      = "extract"
+	StepConfigureEnv = "configure-env"
+	StepLaunchers    = "write-launchers"
+	StepMigrateTNS   = "migrate-tns"
+	StepRecord       = "record-manifest"
+	StepSmokeTest    = "smoke-test"
+	StepHooks        = "post-install-hooks"
+)
+
+// Step is one named stage of an install
+type Step interface {
+	// Name identifies the step in the journal, in --skip-step and in Insert
+	Name() string
+	// Run performs the step
+	Run(ctx context.Context, s *State) error
+}
+
+// Resumable is implemented by steps worth skipping when an interrupted
+// install is resumed. They are recorded in the install journal once
+// completed; a resumed install skips them as long as Done confirms their
+// outputs are still in place, and otherwise runs them again along with every
+// resumable step after them. Steps that are not resumable run every time.
+type Resumable interface {
+	Step
+	// Done reports whether the outputs of a completed previous run of the
+	// step are still valid; it is only asked once the journal records the step
+	Done(s *State) bool
+}
+
+// State is shared by the steps of an install
+type State struct {
+	Conf       *config.InstallConfig
+	Env        env.Manager
+	Journal    *journal.Journal // Nil when the journal is unavailable
+	PkgZipPath string           // Downloaded package archive
+	SdkZipPath string           // Downloaded SDK archive
+	ClientDir  string           // Client directory under Conf.InstallPath, once extracted
+
+	undo []func(ctx context.Context) error
+}
+
+// OnCancel registers fn to undo what a step has done, should the install be
+// cancelled before it completes. The functions run newest first, with a
+// context that is not cancelled.
+func (s *State) OnCancel(fn func(ctx context.Context) error) {
+	s.undo = append(s.undo, fn)
+}
+
+// rollback runs the functions registered with OnCancel. Failures are
+// reported, not returned, so the rest are still undone.
+func (s *State) rollback(ctx context.Context) {
+	if len(s.undo) == 0 {
+		return
+	}
+	ctx, cancel := utils.WithTimeout(context.WithoutCancel(ctx), s.Conf.Timeouts.Environment)
+	defer cancel()
+	fmt.Println("install cancelled, undoing its changes...")
+	for i := len(s.undo) - 1; i >= 0; i-- {
+		if err := s.undo[i](ctx); err != nil {
+			fmt.Printf("warning: could not undo a change of the cancelled install: %v\n", err)
+		}
+	}
+	s.undo = nil
+}
+
+// LibVar returns the variable pointing at the client
+func (s *State) LibVar() string {
+	return s.Conf.LibVar()
+}
+
+// OCILibPath returns the extracted client's directory
+func (s *State) OCILibPath() string {
+	return filepath.Join(s.Conf.InstallPath, s.ClientDir)
+}
+
+// TNSAdminPath returns the directory TNS_ADMIN points at for the client
+func (s *State) TNSAdminPath() string {
+	return s.Conf.TNSAdminPath(s.OCILibPath())
+}
+
+// archives returns the downloaded archives: the package, the SDK and, when
+// installed, SQL*Plus
+func (s *State) archives() []string {
+	paths := []string{s.PkgZipPath, s.SdkZipPath}
+	if path := sqlplusZipPath(s.Conf); path != "" {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// client returns an error unless the client has been extracted, for steps
+// configuring it
+func (s *State) client() error {
+	if s.ClientDir != "" {
+		return nil
+	}
+	return errs.HandleError(
+		fmt.Errorf("no client has been extracted; the %s step must run first", StepExtract),
+		errs.ErrorTypeInstall,
+		"finding extracted client")
+}
+
+// stepFunc is a step built by NewStep
+type stepFunc struct {
+	name string
+	run  func(ctx context.Context, s *State) error
+}
+
+func (f stepFunc) Name() string                            { return f.name }
+func (f stepFunc) Run(ctx context.Context, s *State) error { return f.run(ctx, s) }
+
+// NewStep returns a step named name that calls run, for embedders adding their
+// own steps. It is not resumable, so a resumed install runs it again.
+func NewStep(name string, run func(ctx context.Context, s *State) error) Step {
+	return stepFunc{name: name, run: run}
+}
+
+// Pipeline is the ordered list of steps an install runs
+type Pipeline struct {
+	steps []Step
+}
+
+// NewPipeline returns a pipeline running steps in order
+func NewPipeline(steps ...Step) *Pipeline {
+	return &Pipeline{steps: steps}
+}
+
+// DefaultPipeline returns the steps of a standard install
+func DefaultPipeline() *Pipeline {
+	return NewPipeline(
+		downloadStep{},
+		verifyStep{},
+		extractStep{},
+		configureEnvStep{},
+		launchersStep{},
+		migrateTNSStep{},
+		recordStep{},
+		smokeTestStep{},
+		hooksStep{},
+	)
+}
+
+// Names returns the names of the steps in order
+func (p *Pipeline) Names() []string {
+	names := make([]string, len(p.steps))
+	for i, step := range p.steps {
+		names[i] = step.Name()
+	}
+	return names
+}
+
+// index returns the position of the step named name, or -1
+func (p *Pipeline) index(name string) int {
+	for i, step := range p.steps {
+		if step.Name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Insert adds steps after the step named after, or first when after is empty
+func (p *Pipeline) Insert(after string, steps ...Step) error {
+	for _, step := range steps {
+		if p.index(step.Name()) >= 0 {
+			return errs.HandleError(fmt.Errorf("a step named %q already exists", step.Name()), errs.ErrorTypeValidation, "inserting install step")
+		}
+	}
+	i := 0
+	if after != "" {
+		if i = p.index(after); i < 0 {
+			return errs.HandleError(fmt.Errorf("no step named %q", after), errs.ErrorTypeValidation, "inserting install step")
+		}
+		i++
+	}
+	p.steps = append(p.steps[:i], append(append([]Step(nil), steps...), p.steps[i:]...)...)
+	return nil
+}
+
+// Remove drops the step named name
+func (p *Pipeline) Remove(name string) error {
+	i := p.index(name)
+	if i < 0 {
+		return errs.HandleError(fmt.Errorf("no step named %q", name), errs.ErrorTypeValidation, "removing install step")
+	}
+	p.steps = append(p.steps[:i], p.steps[i+1:]...)
+	return nil
+}
+
+// checkSkips returns the steps to skip by name, or an error for names that
+// are not steps of the pipeline
+func (p *Pipeline) checkSkips(names []string) (map[string]bool, error) {
+	skip := make(map[string]bool, len(names))
+	for _, name := range names {
+		if p.index(name) < 0 {
+			return nil, errs.HandleError(
+				fmt.Errorf("cannot skip unknown step %q: steps are %s", name, strings.Join(p.Names(), ", ")),
+				errs.ErrorTypeValidation,
+				"skipping install steps")
+		}
+		skip[name] = true
+	}
+	return skip, nil
+}
+
+// Run installs and configures the client by running each step in order,
+// skipping those in conf.SkipSteps and those an interrupted install completed.
+// Every step's start, progress and outcome is emitted to the event handlers of ctx.
+//
+// When ctx is cancelled, Run undoes what the steps registered with OnCancel
+// before it returns: partial downloads are removed, as is everything extracted
+// into the install path, the environment is restored and the manifest and
+// tnsnames.ora put back. Completed downloads are kept for the next attempt,
+// and post-install hooks that have run cannot be undone.
+func (p *Pipeline) Run(ctx context.Context, conf *config.InstallConfig, env env.Manager) (err error) {
+	ctx = utils.EnsureContext(ctx)
+	if err := ctx.Err(); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "context cancellation")
+	}
+	skip, err := p.checkSkips(conf.SkipSteps)
+	if err != nil {
+		return err
+	}
+
+	// A shared TNS_ADMIN must be reachable before anything is changed
+	if err := checkTNSAdmin(ctx, conf); err != nil {
+		return err
+	}
+	// Extra variables may only reference known install facts
+	if _, err := extraEnv(conf, conf.LibVar(), conf.InstallPath, conf.TNSAdminPath(conf.InstallPath)); err != nil {
+		return err
+	}
+
+	fmt.Println("\nStarting Oracle InstantClient installation...")
+	s := &State{
+		Conf:       conf,
+		Env:        env,
+		Journal:    journal.Open(conf),
+		PkgZipPath: filepath.Join(conf.DownloadsPath, conf.PkgFile),
+		SdkZipPath: filepath.Join(conf.DownloadsPath, conf.SdkFile),
+	}
+	// Steps completed by an interrupted run are skipped once their outputs are re-validated
+	if j := s.Journal; j != nil {
+		s.ClientDir = j.ClientDir
+		if len(j.Done) > 0 {
+			fmt.Printf("resuming interrupted install; completed steps: %v\n", j.Done)
+		}
+	}
+
+	// A cancelled install is undone, and the journal told to redo its steps
+	var first string
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			s.rollback(ctx)
+			if first != "" {
+				s.Journal.Invalidate(journal.Step(first))
+			}
+		}
+	}()
+
+	// Progress is reported to the context's event handlers, step by step
+	for i, step := range p.steps {
+		if err := ctx.Err(); err != nil {
+			return errs.HandleError(err, errs.ErrorTypeInstall, "context cancellation")
+		}
+		name := step.Name()
+		stepCtx := events.WithStep(ctx, name, i+1, len(p.steps))
+		if skip[name] {
+			events.Emit(stepCtx, events.Event{Kind: events.StepSucceeded, Skipped: "requested"})
+			continue
+		}
+		r, resumable := step.(Resumable)
+		if resumable {
+			if s.Journal.Completed(journal.Step(name)) && r.Done(s) {
+				events.Emit(stepCtx, events.Event{Kind: events.StepSucceeded, Skipped: "already completed"})
+				continue
+			}
+			s.Journal.Invalidate(journal.Step(name))
+			if first == "" {
+				first = name
+			}
+		}
+		events.Emit(stepCtx, events.Event{Kind: events.StepStarted})
+		if err := step.Run(stepCtx, s); err != nil {
+			err = fmt.Errorf("%s step: %w", name, err)
+			events.Emit(stepCtx, events.Event{Kind: events.StepFailed, Error: err.Error()})
+			return err
+		}
+		if resumable {
+			s.Journal.Complete(journal.Step(name))
+		}
+		events.Emit(stepCtx, events.Event{Kind: events.StepSucceeded})
+	}
+	s.Journal.Finish()
+
+	// Remove old versions left by side-by-side installs
+	if err := Prune(conf, env); err != nil {
+		fmt.Printf("warning: could not remove previous client versions: %v\n", err)
+	}
+
+	fmt.Println("\nOracle InstantClient installation and configuration completed successfully!")
+	return nil
+}
+
+// downloadStep downloads the package, SDK and SQL*Plus and records their checksums
+type downloadStep struct{}
+
+func (downloadStep) Name() string { return StepDownload }
+
+func (downloadStep) Done(s *State) bool {
+	return checksumsMatch(s.Journal, s.archives()...)
+}
+
+func (downloadStep) Run(ctx context.Context, s *State) error {
+	if s.Conf.Offline {
+		fmt.Printf("using the archives of the offline bundle in %s\n", s.Conf.DownloadsPath)
+		return recordChecksums(s.Journal, s.archives()...)
+	}
+	if err := download(ctx, s.Conf, s.PkgZipPath, s.SdkZipPath); err != nil {
+		return err
+	}
+	return recordChecksums(s.Journal, s.archives()...)
+}
+
+// verifyStep checks the downloads against the pinned checksums
+type verifyStep struct{}
+
+func (verifyStep) Name() string { return StepVerify }
+
+func (verifyStep) Run(ctx context.Context, s *State) error {
+	return verifyPins(s.Conf, s.PkgZipPath, s.SdkZipPath)
+}
+
+// extractStep unpacks the package, SDK and SQL*Plus and checks they are the
+// same version
+type extractStep struct{}
+
+func (extractStep) Name() string { return StepExtract }
+
+func (extractStep) Done(s *State) bool {
+	return s.ClientDir != "" && isDir(filepath.Join(s.OCILibPath(), "sdk"))
+}
+
+func (extractStep) Run(ctx context.Context, s *State) error {
+	conf := s.Conf
+	before, err := os.ReadDir(conf.InstallPath)
+	existed := err == nil
+	s.OnCancel(func(context.Context) error {
+		return removeAdded(conf.InstallPath, before, existed)
+	})
+
+	pkgDir, sdkDir, err := extract(ctx, conf, s.PkgZipPath, s.SdkZipPath)
+	if err != nil {
+		return err
+	}
+	s.ClientDir = pkgDir
+	if s.Journal != nil {
+		s.Journal.ClientDir = pkgDir
+	}
+
+	// Verify version match
+	if pkgDir != sdkDir {
+		return errs.HandleError(
+			fmt.Errorf("package version (%s) does not match SDK version (%s)", pkgDir, sdkDir),
+			errs.ErrorTypeInstall,
+			"version verification",
+		)
+	}
+	fmt.Println("package and SDK versions match, continuing...")
+	if conf.Pins.ClientDir != "" && pkgDir != conf.Pins.ClientDir {
+		return errs.HandleError(
+			fmt.Errorf("package extracted to %s, but %s pins %s", pkgDir, conf.Pins.Source, conf.Pins.ClientDir),
+			errs.ErrorTypeValidation,
+			"verifying locked client")
+	}
+	return nil
+}
+
+// configureEnvStep writes the persistent environment, unless launcher scripts alone are wanted
+type configureEnvStep struct{}
+
+func (configureEnvStep) Name() string { return StepConfigureEnv }
+
+func (configureEnvStep) Done(s *State) bool {
+	return envConfigured(s.Env, s.LibVar(), s.OCILibPath())
+}
+
+func (configureEnvStep) Run(ctx context.Context, s *State) error {
+	if err := s.client(); err != nil {
+		return err
+	}
+	fmt.Println("\nConfiguring Oracle InstantClient...")
+	if s.Conf.EnvMode == config.EnvModeWrapper {
+		return nil
+	}
+	names := append([]string{s.LibVar(), "TNS_ADMIN", "PATH"}, s.Conf.ExtraEnvNames()...)
+	snap, err := s.Env.WithContext(ctx).Snapshot(names...)
+	if err != nil {
+		return err
+	}
+	s.OnCancel(func(ctx context.Context) error {
+		return s.Env.WithContext(ctx).Restore(snap)
+	})
+	return configureEnv(ctx, s.Conf, s.Env, s.LibVar(), s.OCILibPath(), s.TNSAdminPath())
+}
+
+// launchersStep writes the launcher scripts for per-process configuration;
+// they are cheap, so always rewritten
+type launchersStep struct{}
+
+func (launchersStep) Name() string { return StepLaunchers }
+
+func (launchersStep) Run(ctx context.Context, s *State) error {
+	if s.Conf.EnvMode == config.EnvModeGlobal {
+		return nil
+	}
+	if err := s.client(); err != nil {
+		return err
+	}
+	ociLibPath := s.OCILibPath()
+	for _, w := range generate.Wrappers(s.Conf.OS, generate.WrapperSpec{LibVar: s.LibVar(), ClientDir: ociLibPath, TNSAdmin: s.TNSAdminPath()}) {
+		path := filepath.Join(ociLibPath, w.Name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			s.OnCancel(func(context.Context) error { return os.RemoveAll(path) })
+		}
+		fmt.Printf("writing launcher script %s\n", path)
+		if err := os.WriteFile(path, []byte(w.Content), w.Mode); err != nil {
+			return errs.HandleError(err, errs.ErrorTypeEnvironment, "writing launcher script")
+		}
+	}
+	return nil
+}
+
+// migrateTNSStep moves the tnsnames.ora file saved from a replaced install to
+// TNS_ADMIN; a shared TNS_ADMIN keeps its own
+type migrateTNSStep struct{}
+
+func (migrateTNSStep) Name() string     { return StepMigrateTNS }
+func (migrateTNSStep) Done(*State) bool { return true }
+
+func (migrateTNSStep) Run(ctx context.Context, s *State) error {
+	conf := s.Conf
+	if err := s.client(); err != nil {
+		return err
+	}
+	tnsAdminPath := s.TNSAdminPath()
+	if !conf.Extant || tnsAdminPath == "" || conf.TNSAdmin != "" {
+		return nil
+	}
+	from, to := filepath.Join(conf.DownloadsPath, "tnsnames.ora"), filepath.Join(tnsAdminPath, "tnsnames.ora")
+	fmt.Printf("moving tnsnames.ora from %s to %s\n", from, tnsAdminPath)
+	if err := utils.MigrateFile(from, to, false); err != nil {
+		return err
+	}
+	s.OnCancel(func(context.Context) error { return utils.MigrateFile(to, from, false) })
+	return nil
+}
+
+// recordStep records what was configured so drift can be detected later; a
+// manifest that cannot be written does not fail the install
+type recordStep struct{}
+
+func (recordStep) Name() string { return StepRecord }
+
+func (recordStep) Run(ctx context.Context, s *State) error {
+	if err := s.client(); err != nil {
+		return err
+	}
+	libVar, scope := s.LibVar(), s.Env.Scope()
+	if m, err := manifest.Load(scope); err == nil {
+		prev, had := m.Client(libVar)
+		s.OnCancel(func(context.Context) error {
+			return manifest.Record(scope, func(m *manifest.Manifest) {
+				if had {
+					m.Put(prev)
+				} else {
+					m.Remove(libVar)
+				}
+			})
+		})
+	}
+	if err := recordManifest(s.Conf, s.Env, libVar, s.OCILibPath(), s.TNSAdminPath()); err != nil {
+		fmt.Printf("warning: could not record the installation manifest: %v\n", err)
+	}
+	return nil
+}
+
+// smokeTestStep checks the client library is in place and built for the
+// architecture installed, and SQL*Plus reachable when installed, so a broken
+// archive fails the install rather than the first application using it
+type smokeTestStep struct{}
+
+func (smokeTestStep) Name() string { return StepSmokeTest }
+
+func (smokeTestStep) Run(ctx context.Context, s *State) error {
+	if err := s.client(); err != nil {
+		return err
+	}
+	lib := filepath.Join(s.OCILibPath(), doctor.LibraryName(s.Conf.OS))
+	if _, err := os.Stat(lib); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "finding client library")
+	}
+	arch, err := doctor.LibraryArch(lib)
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "reading client library")
+	}
+	if arch != s.Conf.Arch {
+		return errs.HandleError(
+			fmt.Errorf("%s is built for %s, expected %s", lib, arch, s.Conf.Arch),
+			errs.ErrorTypeInstall,
+			"checking client library")
+	}
+	fmt.Printf("%s is built for %s\n", lib, arch)
+	if s.Conf.SQLPlus {
+		return checkSQLPlus(ctx, s)
+	}
+	return nil
+}
+
+// checkSQLPlus checks SQL*Plus was extracted into the client directory and,
+// unless only launcher scripts configure the client, is found through PATH
+func checkSQLPlus(ctx context.Context, s *State) error {
+	name := doctor.SQLPlusName(s.Conf.OS)
+	if _, err := os.Stat(filepath.Join(s.OCILibPath(), name)); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "finding SQL*Plus")
+	}
+	if s.Conf.EnvMode == config.EnvModeWrapper {
+		return nil
+	}
+	dir, ok := doctor.FindOnPath(ctx, s.Conf.OS, s.Env, name)
+	switch {
+	case !ok:
+		return errs.HandleError(
+			fmt.Errorf("%s is not reachable through the %s PATH", name, s.Env.Scope()),
+			errs.ErrorTypeInstall,
+			"checking SQL*Plus")
+	case filepath.Clean(dir) == filepath.Clean(s.OCILibPath()):
+		fmt.Printf("%s is reachable through PATH\n", filepath.Join(dir, name))
+	case !s.Conf.Secondary:
+		// The 32-bit companion's SQL*Plus is expected to come after the 64-bit one
+		fmt.Printf("warning: %s is run from %s, which comes before %s on PATH\n", name, dir, s.OCILibPath())
+	}
+	return nil
+}
+
+// hooksStep runs the post-install hooks with the new client's environment
+type hooksStep struct{}
+
+func (hooksStep) Name() string     { return StepHooks }
+func (hooksStep) Done(*State) bool { return true }
+
+func (hooksStep) Run(ctx context.Context, s *State) error {
+	if err := s.client(); err != nil {
+		return err
+	}
+	return hooks.Run(ctx, hooks.EventPostInstall, s.Conf.Hooks.PostInstall, hookVars(s.Conf, s.LibVar(), s.OCILibPath(), s.TNSAdminPath()), s.Conf.Timeouts.Hook)
+}
+
+// removeAdded removes the entries of dir that are not among before, or dir
+// itself if it did not exist
+func removeAdded(dir string, before []os.DirEntry, existed bool) error {
+	if !existed {
+		return os.RemoveAll(dir)
+	}
+	had := make(map[string]bool, len(before))
+	for _, e := range before {
+		had[e.Name()] = true
+	}
+	after, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, e := range after {
+		if !had[e.Name()] {
+			if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+				return errs.HandleError(err, errs.ErrorTypeInstall, "removing extracted files")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package oic_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/events"
+	"github.com/mghoff/oraicwinconfig/internal/oic"
+)
+
+func TestPipelineInsert(t *testing.T) {
+	h := newHarness(t)
+	var ran []string
+	record := func(name string) oic.Step {
+		return oic.NewStep(name, func(ctx context.Context, s *oic.State) error {
+			ran = append(ran, name+":"+s.ClientDir)
+			return nil
+		})
+	}
+	p := oic.DefaultPipeline()
+	if err := p.Insert("", record("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Insert(oic.StepExtract, record("after-extract")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Insert(oic.StepExtract, record("first")); err == nil {
+		t.Error("inserting a second step named first succeeded")
+	}
+	if err := p.Insert("missing", record("other")); err == nil {
+		t.Error("inserting after a missing step succeeded")
+	}
+	if err := p.Remove(oic.StepRecord); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"first", oic.StepDownload, oic.StepVerify, oic.StepExtract, "after-extract", oic.StepConfigureEnv,
+		oic.StepLaunchers, oic.StepMigrateTNS, oic.StepSmokeTest, oic.StepHooks}
+	if got := p.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("steps = %v, want %v", got, want)
+	}
+	if err := p.Run(context.Background(), h.conf, h.env); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"first:", "after-extract:instantclient_23_7"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("custom steps ran as %v, want %v", ran, want)
+	}
+}
+
+func TestPipelinePlugins(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil || runtime.GOOS == "windows" {
+		t.Skip("no sh")
+	}
+	h := newHarness(t)
+	h.conf.PluginsDir = t.TempDir()
+	ran := filepath.Join(t.TempDir(), "ran")
+	t.Setenv("PLUGIN_LOG", ran)
+	plugin := func(name, steps string) {
+		script := "#!/bin/sh\ncase \"$1\" in\n" +
+			"describe) echo '{\"steps\": " + steps + "}' ;;\n" +
+			"run) echo \"$2 $ORAICWINCONFIG_STEP $(basename \"$ORAICWINCONFIG_CLIENT_DIR\")\" >> \"$PLUGIN_LOG\" ;;\n" +
+			"esac\n"
+		if err := os.WriteFile(filepath.Join(h.conf.PluginsDir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plugin("10-dsns", `[{"name": "register-dsns", "after": "configure-env"}, {"name": "check-dsns", "after": "register-dsns"}]`)
+	plugin("20-wallet.sh", `[{"name": "install-wallet", "after": "configure-env"}, {"name": "prepare", "after": "download"}]`)
+	// Neither executable nor a script, so not a plugin
+	if err := os.WriteFile(filepath.Join(h.conf.PluginsDir, "README.md"), []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := oic.InstallPipeline(context.Background(), h.conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{oic.StepDownload, "prepare", oic.StepVerify, oic.StepExtract, oic.StepConfigureEnv, "register-dsns", "check-dsns", "install-wallet",
+		oic.StepLaunchers, oic.StepMigrateTNS, oic.StepRecord, oic.StepSmokeTest, oic.StepHooks}
+	if got := p.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("steps = %v, want %v", got, want)
+	}
+	h.conf.SkipSteps = append(h.conf.SkipSteps, "check-dsns")
+	if err := oic.Install(context.Background(), h.conf, h.env); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(ran)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "prepare prepare \nregister-dsns register-dsns instantclient_23_7\ninstall-wallet install-wallet instantclient_23_7\n"; got != want {
+		t.Errorf("plugin steps ran as\n%s\nwant\n%s", got, want)
+	}
+
+	// A plugin may not take the name of another step, nor follow a missing one
+	plugin("30-clash", `[{"name": "extract", "after": "download"}]`)
+	if _, err := oic.InstallPipeline(context.Background(), h.conf); err == nil || !strings.Contains(err.Error(), "30-clash") {
+		t.Errorf("error = %v, want the clashing plugin named", err)
+	}
+	plugin("30-clash", `[{"name": "late", "after": "missing"}]`)
+	if _, err := oic.InstallPipeline(context.Background(), h.conf); err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("error = %v, want the missing step named", err)
+	}
+	h.conf.NoPlugins = true
+	if p, err := oic.InstallPipeline(context.Background(), h.conf); err != nil || len(p.Names()) != len(oic.DefaultPipeline().Names()) {
+		t.Errorf("with plugins disabled, steps = %v, %v", p.Names(), err)
+	}
+}
+
+func TestPluginsDirMachine(t *testing.T) {
+	h := newHarness(t)
+	if err := h.conf.SetScope(env.ScopeMachine); err != nil {
+		t.Fatal(err)
+	}
+	// Any user may write under %ProgramData%, so elevated installs need the directory given
+	if dir, err := oic.PluginsDir(h.conf); err != nil || dir != "" {
+		t.Errorf("machine plugins directory = %q, %v; want none", dir, err)
+	}
+	h.conf.PluginsDir = t.TempDir()
+	if dir, err := oic.PluginsDir(h.conf); err != nil || dir != h.conf.PluginsDir {
+		t.Errorf("machine plugins directory = %q, %v; want %s", dir, err, h.conf.PluginsDir)
+	}
+}
+
+func TestPipelineSkipUnknown(t *testing.T) {
+	h := newHarness(t)
+	h.conf.SkipSteps = append(h.conf.SkipSteps, "unzip")
+	err := oic.Install(context.Background(), h.conf, h.env)
+	if err == nil || !strings.Contains(err.Error(), `"unzip"`) {
+		t.Fatalf("error = %v, want one naming the unknown step", err)
+	}
+	if n := h.server.Requests(h.conf.PkgFile); n != 0 {
+		t.Errorf("package requested %d times before the steps were checked", n)
+	}
+}
+
+func TestPipelineSmokeTest(t *testing.T) {
+	h := newHarness(t)
+	h.conf.SkipSteps = nil
+	err := oic.Install(context.Background(), h.conf, h.env)
+	if err == nil || !strings.Contains(err.Error(), oic.StepSmokeTest) || !strings.Contains(err.Error(), "libclntsh.so") {
+		t.Fatalf("error = %v, want the smoke test to miss the client library", err)
+	}
+}
+
+func TestPipelineResume(t *testing.T) {
+	h := newHarness(t)
+	interrupted := errors.New("interrupted")
+	p := oic.DefaultPipeline()
+	if err := p.Insert(oic.StepConfigureEnv, oic.NewStep("interrupt", func(context.Context, *oic.State) error {
+		return interrupted
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Run(context.Background(), h.conf, h.env); !errors.Is(err, interrupted) {
+		t.Fatalf("error = %v, want the interruption", err)
+	}
+
+	// The resumed install neither downloads nor extracts again
+	if err := oic.Install(context.Background(), h.conf, h.env); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{h.conf.PkgFile, h.conf.SdkFile} {
+		if n := h.server.Requests(file); n != 1 {
+			t.Errorf("%s requested %d times, want 1", file, n)
+		}
+	}
+	if got, _ := h.env.GetEnvVar(h.conf.LibVar()); got == "" {
+		t.Errorf("%s not set by the resumed install", h.conf.LibVar())
+	}
+}
+
+func TestPipelineEvents(t *testing.T) {
+	h := newHarness(t)
+	ch := make(chan events.Event, 1000)
+	ctx := events.WithHandler(context.Background(), events.Channel(ch))
+	h.conf.SkipSteps = append(h.conf.SkipSteps, oic.StepMigrateTNS)
+	if err := oic.Install(ctx, h.conf, h.env); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	var got []string
+	progress := 0
+	for e := range ch {
+		if e.Kind == events.StepProgress {
+			if e.Step != oic.StepDownload || e.Size <= 0 || e.Current > e.Size {
+				t.Errorf("unexpected progress event %+v", e)
+			}
+			progress++
+			continue
+		}
+		if e.Total != len(oic.DefaultPipeline().Names()) {
+			t.Errorf("%s: total = %d", e, e.Total)
+		}
+		got = append(got, e.String())
+	}
+	want := []string{
+		"[1/9] download", "[1/9] download done",
+		"[2/9] verify", "[2/9] verify done",
+		"[3/9] extract", "[3/9] extract done",
+		"[4/9] configure-env", "[4/9] configure-env done",
+		"[5/9] write-launchers", "[5/9] write-launchers done",
+		"[6/9] migrate-tns skipped (requested)",
+		"[7/9] record-manifest", "[7/9] record-manifest done",
+		"[8/9] smoke-test skipped (requested)",
+		"[9/9] post-install-hooks", "[9/9] post-install-hooks done",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if progress < 2 {
+		t.Errorf("%d progress events, want at least one per download", progress)
+	}
+}
@@ -0,0 +1,388 @@
+package oic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/generate"
+	"github.com/mghoff/oraicwinconfig/internal/hooks"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/plan"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+	"github.com/mghoff/oraicwinconfig/internal/version"
+)
+
+// BuildPlan works out the actions an install with conf would perform on this
+// machine without changing anything. Unlike Install it never prompts, so an
+// existing installation must be dealt with through conf.Existing.
+func BuildPlan(ctx context.Context, conf *config.InstallConfig, env env.Manager) (*plan.Plan, error) {
+	ctx = utils.EnsureContext(ctx)
+	if err := checkTNSAdmin(ctx, conf); err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	p := &plan.Plan{
+		FormatVersion: plan.FormatVersion,
+		Version:       version.Version,
+		Created:       time.Now().UTC(),
+		Host:          host,
+		OS:            conf.OS,
+		Arch:          conf.Arch,
+		Scope:         env.Scope(),
+		Options: plan.Options{
+			InstallPath:   conf.InstallPath,
+			DownloadsPath: conf.DownloadsPath,
+			BaseURL:       conf.BaseURL,
+			PkgFile:       conf.PkgFile,
+			SdkFile:       conf.SdkFile,
+			EnvMode:       conf.EnvMode,
+			Existing:      conf.Existing,
+			Secondary:     conf.Secondary,
+			SQLPlus:       conf.SQLPlus,
+			TNSAdmin:      conf.TNSAdmin,
+			ExtraEnv:      conf.ExtraEnv,
+			Hooks:         conf.Hooks,
+		},
+	}
+
+	envCtx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Environment)
+	defer cancel()
+	env = env.WithContext(envCtx)
+
+	// Recorded so applying the plan can tell whether the machine has changed since
+	state, err := captureState(ctx, conf, env)
+	if err != nil {
+		return nil, err
+	}
+	p.State = state
+
+	libVar := conf.LibVar()
+	if state.Existing != "" {
+		if err := planExisting(p, conf, libVar, state.Existing, state.TNSNames); err != nil {
+			return nil, err
+		}
+	}
+	clientDir := state.ClientDir
+
+	pkgZipPath := filepath.Join(conf.DownloadsPath, conf.PkgFile)
+	sdkZipPath := filepath.Join(conf.DownloadsPath, conf.SdkFile)
+	// The archives of an offline bundle are already in place
+	if !conf.Offline {
+		p.Add(plan.Action{Kind: plan.KindDownload, URL: conf.BaseURL + conf.PkgFile, Path: pkgZipPath})
+		p.Add(plan.Action{Kind: plan.KindDownload, URL: conf.BaseURL + conf.SdkFile, Path: sdkZipPath})
+		if path := sqlplusZipPath(conf); path != "" {
+			p.Add(plan.Action{Kind: plan.KindDownload, URL: conf.BaseURL + conf.SQLPlusFile(), Path: path})
+		}
+	}
+	if len(conf.Hooks.PreExtract) > 0 {
+		p.Add(plan.Action{
+			Kind:     plan.KindRunHooks,
+			Event:    string(hooks.EventPreExtract),
+			Commands: conf.Hooks.PreExtract,
+			Vars:     scanVars(conf, pkgZipPath, sdkZipPath),
+		})
+	}
+	p.Add(plan.Action{Kind: plan.KindExtract, Path: pkgZipPath, Dir: conf.InstallPath, ClientDir: clientDir})
+	p.Add(plan.Action{Kind: plan.KindExtract, Path: sdkZipPath, Dir: conf.InstallPath, ClientDir: clientDir})
+	if path := sqlplusZipPath(conf); path != "" {
+		p.Add(plan.Action{Kind: plan.KindExtract, Path: path, Dir: conf.InstallPath, ClientDir: clientDir})
+	}
+
+	ociLibPath := filepath.Join(conf.InstallPath, clientDir)
+	tnsAdminPath := conf.TNSAdminPath(ociLibPath)
+	extra, err := extraEnv(conf, libVar, ociLibPath, tnsAdminPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.EnvMode != config.EnvModeWrapper {
+		p.Add(plan.Action{Kind: plan.KindSetEnv, Name: libVar, Value: ociLibPath})
+		p.Add(plan.Action{Kind: plan.KindAppendPath, Dir: ociLibPath})
+		if conf.OS == "windows" {
+			// Keep the 64-bit client ahead of the 32-bit one, as orderPath does
+			if conf.Arch == "386" {
+				if lib64, err := env.GetEnvVar("OCI_LIB64"); err == nil {
+					p.Add(plan.Action{Kind: plan.KindMovePathBefore, Dir: lib64, Target: ociLibPath})
+				}
+			} else if lib32, err := env.GetEnvVar("OCI_LIB32"); err == nil {
+				p.Add(plan.Action{Kind: plan.KindMovePathBefore, Dir: ociLibPath, Target: lib32})
+			}
+		}
+		if tnsAdminPath != "" {
+			p.Add(plan.Action{Kind: plan.KindSetEnv, Name: "TNS_ADMIN", Value: tnsAdminPath})
+		}
+		for _, name := range conf.ExtraEnvNames() {
+			if value, ok := extra[name]; ok {
+				p.Add(plan.Action{Kind: plan.KindSetEnv, Name: name, Value: value})
+			}
+		}
+	}
+	if conf.EnvMode != config.EnvModeGlobal {
+		for _, w := range generate.Wrappers(conf.OS, generate.WrapperSpec{LibVar: libVar, ClientDir: ociLibPath, TNSAdmin: tnsAdminPath}) {
+			p.Add(plan.Action{Kind: plan.KindWriteFile, Path: filepath.Join(ociLibPath, w.Name), Content: w.Content, Mode: w.Mode})
+		}
+	}
+	if conf.Extant && tnsAdminPath != "" && conf.TNSAdmin == "" {
+		p.Add(plan.Action{
+			Kind:   plan.KindMoveFile,
+			Path:   filepath.Join(conf.DownloadsPath, "tnsnames.ora"),
+			Target: filepath.Join(tnsAdminPath, "tnsnames.ora"),
+		})
+	}
+
+	client := manifestClient(conf, libVar, ociLibPath, tnsAdminPath)
+	p.Add(plan.Action{Kind: plan.KindRecord, Client: &client})
+	if len(conf.Hooks.PostInstall) > 0 {
+		p.Add(plan.Action{
+			Kind:     plan.KindRunHooks,
+			Event:    string(hooks.EventPostInstall),
+			Commands: conf.Hooks.PostInstall,
+			Vars:     hookVars(conf, libVar, ociLibPath, tnsAdminPath),
+		})
+	}
+	return p, nil
+}
+
+// planExisting adds the actions dealing with the existing client in dir, as
+// handleCurrentInstall would, and points the install path at its base directory
+func planExisting(p *plan.Plan, conf *config.InstallConfig, libVar, dir string, extant bool) error {
+	tnsFile := filepath.Join(dir, "network", "admin", "tnsnames.ora")
+	saved := filepath.Join(conf.DownloadsPath, "tnsnames.ora")
+
+	tnsAdminPath := conf.TNSAdminPath(dir)
+	// A shared TNS_ADMIN keeps its own tnsnames.ora, so the client's is not migrated
+	extant = extant && conf.TNSAdmin == ""
+	guard := func(event hooks.Event, commands []string) {
+		if len(commands) > 0 {
+			p.Add(plan.Action{
+				Kind:     plan.KindRunHooks,
+				Event:    string(event),
+				Commands: commands,
+				Vars:     hookVars(conf, libVar, dir, tnsAdminPath),
+				Force:    conf.Hooks.Force,
+			})
+		}
+	}
+
+	switch conf.Existing {
+	case config.ExistingKeep:
+		if extant {
+			p.Add(plan.Action{Kind: plan.KindMoveFile, Path: tnsFile, Target: saved, Copy: true})
+		}
+	case config.ExistingOverwrite:
+		// The hooks may stop the overwrite, so nothing is changed before them,
+		// and tnsnames.ora is copied: it goes with the client directory
+		guard(hooks.EventPreOverwrite, conf.Hooks.PreOverwrite)
+		if extant {
+			p.Add(plan.Action{Kind: plan.KindMoveFile, Path: tnsFile, Target: saved, Copy: true})
+		}
+		p.Add(plan.Action{Kind: plan.KindRemovePath, Dir: dir})
+		p.Add(plan.Action{Kind: plan.KindRemoveEnv, Name: libVar})
+		p.Add(plan.Action{Kind: plan.KindRemoveEnv, Name: "TNS_ADMIN"})
+		if m, err := manifest.Load(conf.Scope); err == nil {
+			if rec, ok := m.Client(libVar); ok {
+				for _, name := range sortedKeys(rec.ExtraEnv) {
+					p.Add(plan.Action{Kind: plan.KindRemoveEnv, Name: name})
+				}
+			}
+		}
+		if !utils.IsUNC(dir) {
+			p.Add(plan.Action{Kind: plan.KindRemoveDir, Dir: dir})
+		}
+	default:
+		return errs.HandleError(
+			fmt.Errorf("%s already points at %s; choose --force-overwrite or --keep-existing for the plan", libVar, dir),
+			errs.ErrorTypeValidation,
+			"planning existing installation")
+	}
+
+	if !conf.InstallPathGiven {
+		if err := conf.SetInstallPath(filepath.Dir(dir)); err != nil {
+			return err
+		}
+	}
+	return conf.SetExtant(extant)
+}
+
+// captureState reads the parts of the machine a plan for conf depends on. The
+// versioned directory is read from the remote package so actions can name it.
+func captureState(ctx context.Context, conf *config.InstallConfig, env env.Manager) (plan.State, error) {
+	state := plan.State{Vars: make(map[string]string)}
+	for _, name := range []string{"OCI_LIB64", "OCI_LIB32", "TNS_ADMIN", "PATH"} {
+		if value, err := env.GetEnvVar(name); err == nil {
+			state.Vars[name] = value
+		}
+	}
+	if dir, err := env.ValidateEnvVar(conf.LibVar()); err == nil {
+		state.Existing = dir
+		_, err := os.Stat(filepath.Join(dir, "network", "admin", "tnsnames.ora"))
+		state.TNSNames = err == nil
+	}
+	clientDir, err := planClientDir(ctx, conf)
+	if err != nil {
+		return state, err
+	}
+	state.ClientDir = clientDir
+	return state, nil
+}
+
+// PlanDrift compares the machine with the state p was made against, with conf
+// configured from the plan, and returns the differences
+func PlanDrift(ctx context.Context, conf *config.InstallConfig, env env.Manager, p *plan.Plan) ([]plan.Drift, error) {
+	ctx = utils.EnsureContext(ctx)
+	envCtx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Environment)
+	defer cancel()
+	current, err := captureState(ctx, conf, env.WithContext(envCtx))
+	if err != nil {
+		return nil, err
+	}
+	return plan.Diff(p.State, current), nil
+}
+
+// planClientDir returns the versioned directory the package extracts to. Servers
+// without range requests have the package fetched to a temporary file to read it.
+func planClientDir(ctx context.Context, conf *config.InstallConfig) (string, error) {
+	url := conf.BaseURL + conf.PkgFile
+	if !strings.HasSuffix(conf.PkgFile, ".zip") {
+		return "", errs.HandleError(
+			fmt.Errorf("the client directory of %s is only known once the disk image is mounted", conf.PkgFile),
+			errs.ErrorTypeValidation,
+			"planning install")
+	}
+	client := utils.NewHTTPClient(conf.HTTP)
+	if dir, err := utils.RemoteClientDir(ctx, client, url); err == nil {
+		return dir, nil
+	}
+
+	f, err := os.CreateTemp("", "oraicwinconfig-plan-*.zip")
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeDownload, "creating temporary file")
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	dlCtx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Download)
+	defer cancel()
+	if _, err := utils.DownloadMirrored(dlCtx, client, conf.BaseURLs(), conf.PkgFile, f.Name(), conf.PkgSize, conf.HTTP.Retry); err != nil {
+		return "", phaseError(dlCtx, err, "download")
+	}
+	return utils.ZipClientDir(f.Name())
+}
+
+// ApplyPlan performs the actions of p in order, exactly as planned, and stops
+// at the first failure. Timeouts and the HTTP client come from conf.
+func ApplyPlan(ctx context.Context, conf *config.InstallConfig, env env.Manager, p *plan.Plan) error {
+	ctx = utils.EnsureContext(ctx)
+	for i, a := range p.Actions {
+		if err := ctx.Err(); err != nil {
+			return errs.HandleError(err, errs.ErrorTypeInstall, "context cancellation")
+		}
+		fmt.Printf("[%d/%d] %s\n", i+1, len(p.Actions), a)
+		if err := applyAction(ctx, conf, env, p, a); err != nil {
+			return fmt.Errorf("action %d (%s): %w", i+1, a.Kind, err)
+		}
+	}
+	fmt.Println("\nPlan applied successfully!")
+	return nil
+}
+
+// applyAction performs a single planned action
+func applyAction(ctx context.Context, conf *config.InstallConfig, m env.Manager, p *plan.Plan, a plan.Action) error {
+	switch a.Kind {
+	case plan.KindDownload:
+		ctx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Download)
+		defer cancel()
+		limits := conf.SdkSize
+		switch filepath.Base(a.Path) {
+		case conf.PkgFile:
+			limits = conf.PkgSize
+		case conf.SQLPlusFile():
+			limits = conf.SQLPlusSize
+		}
+		if err := utils.DownloadArchive(ctx, utils.NewHTTPClient(conf.HTTP), a.URL, a.Path, limits, conf.HTTP.Retry); err != nil {
+			return phaseError(ctx, err, "download")
+		}
+		return nil
+	case plan.KindExtract:
+		ctx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Extract)
+		defer cancel()
+		dir, err := utils.Extract(ctx, a.Path, a.Dir)
+		if err != nil {
+			return phaseError(ctx, errs.HandleError(err, errs.ErrorTypeInstall, "extracting "+filepath.Base(a.Path)), "extract")
+		}
+		if dir != a.ClientDir {
+			return errs.HandleError(
+				fmt.Errorf("%s extracted to %s, but the plan expects %s", a.Path, dir, a.ClientDir),
+				errs.ErrorTypeInstall,
+				"version verification")
+		}
+		if p.OS == "darwin" {
+			return utils.ClearQuarantine(ctx, filepath.Join(a.Dir, dir))
+		}
+		if filepath.Base(a.Path) != conf.PkgFile {
+			return nil
+		}
+		return verifySignatures(ctx, conf, filepath.Join(a.Dir, dir))
+	case plan.KindMoveFile:
+		return utils.MigrateFile(a.Path, a.Target, a.Copy)
+	case plan.KindRemoveDir:
+		if err := os.RemoveAll(a.Dir); err != nil {
+			return errs.HandleError(err, errs.ErrorTypeInstall, "removing directory")
+		}
+		return nil
+	case plan.KindWriteFile:
+		if err := os.WriteFile(a.Path, []byte(a.Content), a.Mode); err != nil {
+			return errs.HandleError(err, errs.ErrorTypeEnvironment, "writing file")
+		}
+		return nil
+	case plan.KindRunHooks:
+		err := hooks.Run(ctx, hooks.Event(a.Event), a.Commands, a.Vars, conf.Timeouts.Hook)
+		if err != nil && a.Force {
+			fmt.Printf("warning: %v; continuing since hooks are forced\n", err)
+			return nil
+		}
+		return err
+	case plan.KindRecord:
+		client := *a.Client
+		client.InstalledAt = time.Now().UTC()
+		if err := manifest.Record(m.Scope(), func(m *manifest.Manifest) { m.Put(client) }); err != nil {
+			fmt.Printf("warning: could not record the installation manifest: %v\n", err)
+		}
+		return nil
+	}
+
+	// The remaining actions change the environment
+	ctx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Environment)
+	defer cancel()
+	m = m.WithContext(ctx)
+	switch a.Kind {
+	case plan.KindSetEnv:
+		return m.SetEnvVar(a.Name, a.Value)
+	case plan.KindRemoveEnv:
+		return m.RemoveEnvVar(a.Name)
+	case plan.KindAppendPath:
+		return m.AppendToPath(a.Dir)
+	case plan.KindRemovePath:
+		return m.RemoveFromPath(a.Dir)
+	case plan.KindMovePathBefore:
+		return m.MovePathBefore(a.Dir, a.Target)
+	}
+	return errs.HandleError(fmt.Errorf("unknown action %q", a.Kind), errs.ErrorTypeValidation, "applying plan")
+}
+
+// sortedKeys returns the keys of m in order, so plans list them stably
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,100 @@
+package oic
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/plugins"
+)
+
+// PluginsDir returns the directory the plugins of an install are loaded
+// from: conf.PluginsDir, or the plugins directory next to the user's manifest.
+// Machine installs run elevated, and any user may add files to a directory
+// under %ProgramData%, so they load plugins only from an explicit
+// conf.PluginsDir; without one the directory is empty.
+func PluginsDir(conf *config.InstallConfig) (string, error) {
+	if conf.PluginsDir != "" {
+		return conf.PluginsDir, nil
+	}
+	if conf.Scope == env.ScopeMachine {
+		return "", nil
+	}
+	dir, err := manifest.Dir(conf.Scope)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, plugins.DirName), nil
+}
+
+// InstallPipeline returns the default pipeline, with the ODP.NET step when
+// a driver is requested and the steps of the plugins added at the positions
+// they declare, unless conf.NoPlugins is set. Steps declared after the same
+// step run in the order they were loaded.
+func InstallPipeline(ctx context.Context, conf *config.InstallConfig) (*Pipeline, error) {
+	p := DefaultPipeline()
+	if conf.ODPNet.Driver != "" {
+		if err := p.Insert(StepConfigureEnv, odpNetStep{}); err != nil {
+			return nil, err
+		}
+	}
+	if conf.NoPlugins {
+		return p, nil
+	}
+	dir, err := PluginsDir(conf)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return p, nil
+	}
+	steps, err := plugins.Load(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	// A step goes after the steps already put after its position, and
+	// those following them
+	parent := make(map[string]string, len(steps))
+	last := make(map[string]string, len(steps))
+	tail := func(name string) string {
+		if t, ok := last[name]; ok {
+			return t
+		}
+		return name
+	}
+	for _, step := range steps {
+		pos := tail(step.After)
+		if err := p.Insert(pos, pluginStep(step)); err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", step.Plugin, err)
+		}
+		parent[step.Name] = step.After
+		for name, ok := step.After, true; ok; name, ok = parent[name] {
+			if tail(name) == pos {
+				last[name] = step.Name
+			}
+		}
+		fmt.Printf("plugin %s adds step %s after %s\n", filepath.Base(step.Plugin), step.Name, step.After)
+	}
+	return p, nil
+}
+
+// pluginStep returns the pipeline step running a plugin's step. It is given
+// the facts known at its position: the downloaded archives, and the client
+// once it is extracted.
+func pluginStep(step plugins.Step) Step {
+	return NewStep(step.Name, func(ctx context.Context, s *State) error {
+		vars := scanVars(s.Conf, s.PkgZipPath, s.SdkZipPath)
+		if s.ClientDir != "" {
+			for name, value := range hookVars(s.Conf, s.LibVar(), s.OCILibPath(), s.TNSAdminPath()) {
+				vars[name] = value
+			}
+		}
+		if step.Description != "" {
+			fmt.Printf("%s: %s\n", step.Name, step.Description)
+		}
+		return step.Run(ctx, vars, s.Conf.Timeouts.Hook)
+	})
+}
@@ -0,0 +1,116 @@
+package oic
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// Prune removes previous client versions from the install path, keeping the
+// newest conf.KeepVersions of them besides the clients in use. A client is in
+// use when a client variable in either scope, or either scope's manifest,
+// including the states kept for rollback, points at it, or when TNS_ADMIN, a
+// TNS_ADMIN profile or an entry of either scope's PATH is inside it.
+// Network shares are never pruned, since other machines may still use older
+// versions.
+func Prune(conf *config.InstallConfig, env env.Manager) error {
+	if conf.KeepVersions < 0 || utils.IsUNC(conf.InstallPath) {
+		return nil
+	}
+	previous, err := expiredClients(conf.InstallPath, conf.KeepVersions, clientsInUse(env))
+	if err != nil {
+		return err
+	}
+	for _, dir := range previous {
+		fmt.Printf("removing previous client version %s\n", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expiredClients returns the client directories in installPath that are not
+// in use, other than the newest keep of them
+func expiredClients(installPath string, keep int, inUse map[string]bool) ([]string, error) {
+	entries, err := os.ReadDir(installPath)
+	if err != nil {
+		return nil, err
+	}
+	var previous []string
+	for _, e := range entries {
+		if !e.IsDir() || utils.ClientVersion(e.Name()) == "" {
+			continue
+		}
+		dir := filepath.Join(installPath, e.Name())
+		if !inUse[env.PathKey(dir)] {
+			previous = append(previous, dir)
+		}
+	}
+	if len(previous) <= keep {
+		return nil, nil
+	}
+
+	// Newest first, so everything past the kept versions is returned
+	sort.Slice(previous, func(i, j int) bool { return newerClient(previous[i], previous[j]) })
+	return previous[keep:], nil
+}
+
+// clientsInUse returns the client directories referenced by the environment or manifest of either scope,
+// including those holding the TNS_ADMIN directory, a TNS_ADMIN profile or a PATH entry, and
+// those a rollback would return to
+func clientsInUse(m env.Manager) map[string]bool {
+	inUse := make(map[string]bool)
+	for _, scope := range []env.Scope{env.ScopeUser, env.ScopeMachine} {
+		sm := m.WithScope(scope)
+		for _, name := range []string{"OCI_LIB64", "OCI_LIB32"} {
+			if dir, err := sm.GetEnvVar(name); err == nil {
+				inUse[env.PathKey(dir)] = true
+			}
+		}
+		if dir, err := sm.GetEnvVar("TNS_ADMIN"); err == nil {
+			markClientOf(inUse, dir)
+		}
+		if path, err := sm.GetEnvVar("PATH"); err == nil {
+			for _, dir := range filepath.SplitList(path) {
+				if strings.TrimSpace(dir) != "" {
+					markClientOf(inUse, dir)
+				}
+			}
+		}
+		if man, err := manifest.Load(scope); err == nil {
+			for _, c := range man.Clients {
+				inUse[env.PathKey(c.ClientDir)] = true
+			}
+			for _, state := range man.History {
+				for _, c := range state.Clients {
+					inUse[env.PathKey(c.ClientDir)] = true
+				}
+			}
+			for _, dir := range man.TNSProfiles {
+				markClientOf(inUse, dir)
+			}
+		}
+	}
+	return inUse
+}
+
+// markClientOf marks the client directory dir is in, if any, as in use
+func markClientOf(inUse map[string]bool, dir string) {
+	for dir = filepath.Clean(dir); ; dir = filepath.Dir(dir) {
+		if utils.ClientVersion(filepath.Base(dir)) != "" {
+			inUse[env.PathKey(dir)] = true
+			return
+		}
+		if filepath.Dir(dir) == dir {
+			return
+		}
+	}
+}
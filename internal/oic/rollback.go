@@ -0,0 +1,106 @@
+package oic
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// Missing returns the client directories of state that no longer exist, such
+// as previous versions removed by Prune
+func Missing(state manifest.State) []string {
+	var missing []string
+	for _, c := range state.Clients {
+		if _, err := os.Stat(c.ClientDir); err != nil {
+			missing = append(missing, c.ClientDir)
+		}
+	}
+	return missing
+}
+
+// Rollback configures the environment of the scope for the clients recorded
+// in state, an entry of the manifest's history, in place of the clients
+// recorded now. The client directories are not touched, so every one of
+// state's must still exist. The rollback is itself recorded in the history,
+// so it can be undone the same way.
+func Rollback(ctx context.Context, conf *config.InstallConfig, m env.Manager, state manifest.State) error {
+	ctx = utils.EnsureContext(ctx)
+	if missing := Missing(state); len(missing) > 0 {
+		return errs.HandleError(
+			fmt.Errorf("%s no longer exists; install that version again instead", missing[0]),
+			errs.ErrorTypeValidation,
+			"checking recorded clients")
+	}
+	current, err := manifest.Load(m.Scope())
+	if err != nil {
+		return err
+	}
+
+	// Every variable either set of clients defines is captured, so a failure
+	// puts all of them back
+	target := make(map[string]string)
+	for _, c := range state.Clients {
+		for name, value := range c.Vars {
+			target[name] = value
+		}
+	}
+	names := make(map[string]string)
+	for _, c := range append(current.Clients, state.Clients...) {
+		for name := range c.Vars {
+			names[name] = ""
+		}
+	}
+
+	ctx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Environment)
+	defer cancel()
+	err = env.Update(m.WithContext(ctx), sortedKeys(names), func(env env.Manager) error {
+		for _, c := range current.Clients {
+			for _, dir := range c.Path {
+				if err := env.RemoveFromPath(dir); err != nil {
+					return err
+				}
+			}
+			for _, name := range sortedKeys(c.Vars) {
+				if _, ok := target[name]; ok {
+					continue
+				}
+				fmt.Printf("removing %s\n", name)
+				if err := env.RemoveEnvVar(name); err != nil {
+					return err
+				}
+			}
+		}
+		for _, name := range sortedKeys(target) {
+			fmt.Printf("setting %s=%s\n", name, target[name])
+			if err := env.SetEnvVar(name, target[name]); err != nil {
+				return err
+			}
+		}
+		for _, c := range state.Clients {
+			for _, dir := range c.Path {
+				fmt.Printf("updating PATH to include %s\n", dir)
+				if err := env.AppendToPath(dir); err != nil {
+					return err
+				}
+			}
+		}
+		// Keep the 64-bit client ahead of the 32-bit one, as orderPath does
+		if conf.OS == "windows" && target["OCI_LIB64"] != "" && target["OCI_LIB32"] != "" {
+			return env.MovePathBefore(target["OCI_LIB64"], target["OCI_LIB32"])
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return manifest.Record(m.Scope(), func(rec *manifest.Manifest) {
+		rec.Clients = append([]manifest.Client(nil), state.Clients...)
+	})
+}
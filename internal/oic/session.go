@@ -0,0 +1,52 @@
+package oic
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/generate"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+)
+
+// ExportSession exports the variables recorded in the manifest of scope into
+// the current process, and writes a snippet to w that does the same for the
+// shell the installer was started from, which persistent changes do not reach.
+// Only PATH entries the process does not already have are added.
+func ExportSession(w io.Writer, conf *config.InstallConfig, scope env.Scope) error {
+	m, err := manifest.Load(scope)
+	if err != nil {
+		return err
+	}
+	vars := make(map[string]string)
+	var dirs []string
+	path := os.Getenv("PATH")
+	for _, c := range m.Clients {
+		for name, value := range c.Vars {
+			vars[name] = value
+		}
+		for _, dir := range c.Path {
+			if !env.PathContains(path, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	if len(vars) == 0 && len(dirs) == 0 {
+		fmt.Fprintln(w, "\nNo persistent variables were set, so there is nothing to export to the current shell.")
+		return nil
+	}
+
+	for name, value := range vars {
+		os.Setenv(name, value)
+	}
+	for _, dir := range dirs {
+		path = dir + string(os.PathListSeparator) + path
+	}
+	os.Setenv("PATH", path)
+
+	fmt.Fprintln(w, "\nTo use the client in the shell you started the installer from, run:")
+	fmt.Fprint(w, generate.SessionSnippet(conf.OS, vars, dirs))
+	return nil
+}
@@ -0,0 +1,212 @@
+package oic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/generate"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+	"github.com/mghoff/oraicwinconfig/internal/tns"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// UseTNSAdmin re-points TNS_ADMIN at dir for the clients recorded in the
+// manifest of env's scope: the variable for clients configured globally, and
+// the launcher scripts of those configured with them. An empty dir returns to
+// the client's own network/admin directory. Secondary clients are skipped, as
+// they share the primary client's TNS_ADMIN.
+func UseTNSAdmin(ctx context.Context, conf *config.InstallConfig, env env.Manager, dir string) error {
+	ctx = utils.EnsureContext(ctx)
+	m, err := manifest.Load(env.Scope())
+	if err != nil {
+		return err
+	}
+	var primary []int
+	for i, c := range m.Clients {
+		if c.Vars == nil || c.Vars["TNS_ADMIN"] != "" {
+			primary = append(primary, i)
+		}
+	}
+	if len(primary) == 0 {
+		return errs.HandleError(
+			fmt.Errorf("no client is recorded in the %s scope manifest; install one first", env.Scope()),
+			errs.ErrorTypeValidation,
+			"switching TNS_ADMIN")
+	}
+
+	if dir == "" {
+		dir = filepath.Join(m.Clients[primary[0]].ClientDir, "network", "admin")
+	}
+	checkCtx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Preflight)
+	defer cancel()
+	if err := utils.CheckDir(checkCtx, dir); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeValidation, "switching TNS_ADMIN")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tnsnames.ora")); err != nil {
+		fmt.Printf("warning: %s has no tnsnames.ora\n", dir)
+	}
+
+	global := false
+	for _, i := range primary {
+		c := m.Clients[i]
+		if c.EnvMode != config.EnvModeGlobal {
+			if err := rewriteLaunchers(conf.OS, c, dir); err != nil {
+				return err
+			}
+		}
+		if c.Vars != nil {
+			global = true
+			c.Vars["TNS_ADMIN"] = dir
+		}
+	}
+	if global {
+		if err := setTNSAdmin(ctx, conf, env, dir); err != nil {
+			return err
+		}
+	}
+	return m.Save(env.Scope())
+}
+
+// setTNSAdmin sets TNS_ADMIN within the environment timeout
+func setTNSAdmin(ctx context.Context, conf *config.InstallConfig, m env.Manager, dir string) error {
+	ctx, cancel := utils.WithTimeout(ctx, conf.Timeouts.Environment)
+	defer cancel()
+
+	return env.Update(m.WithContext(ctx), []string{"TNS_ADMIN"}, func(env env.Manager) error {
+		fmt.Printf("setting TNS_ADMIN=%s\n", dir)
+		return env.SetEnvVar("TNS_ADMIN", dir)
+	})
+}
+
+// rewriteLaunchers writes the launcher scripts of client c again with TNS_ADMIN set to dir
+func rewriteLaunchers(goos string, c manifest.Client, dir string) error {
+	for _, w := range generate.Wrappers(goos, generate.WrapperSpec{LibVar: c.LibVar, ClientDir: c.ClientDir, TNSAdmin: dir}) {
+		path := filepath.Join(c.ClientDir, w.Name)
+		fmt.Printf("writing launcher script %s\n", path)
+		if err := os.WriteFile(path, []byte(w.Content), w.Mode); err != nil {
+			return errs.HandleError(err, errs.ErrorTypeEnvironment, "writing launcher script")
+		}
+	}
+	return nil
+}
+
+// maxTNSFileSize bounds each file fetched by FetchTNSFiles
+const maxTNSFileSize = 16 << 20
+
+// TNSFiles are the network configuration files fetched from a TNSSource
+type TNSFiles struct {
+	TNSNames []byte
+	SQLNet   []byte // Nil when the source has no sqlnet.ora
+}
+
+// FetchTNSFiles fetches the canonical tnsnames.ora, and sqlnet.ora if the
+// source has one, over HTTPS with client or from a shallow clone of the
+// source's Git repository, and checks tnsnames.ora parses
+func FetchTNSFiles(ctx context.Context, client *http.Client, src manifest.TNSSource) (TNSFiles, error) {
+	ctx = utils.EnsureContext(ctx)
+	var files TNSFiles
+	var err error
+	if src.Git {
+		files, err = fetchGit(ctx, src)
+	} else {
+		if files.TNSNames, err = fetchHTTPS(ctx, client, src.URL); err == nil && src.SQLNet != "" {
+			files.SQLNet, err = fetchHTTPS(ctx, client, src.SQLNet)
+		}
+	}
+	if err != nil {
+		return TNSFiles{}, err
+	}
+
+	entries, err := tns.Parse(bytes.NewReader(files.TNSNames))
+	if err != nil {
+		return TNSFiles{}, errs.HandleError(fmt.Errorf("%s is not a valid tnsnames.ora: %w", src.URL, err), errs.ErrorTypeValidation, "fetching tnsnames.ora")
+	}
+	if len(entries) == 0 {
+		return TNSFiles{}, errs.HandleError(fmt.Errorf("%s defines no net service names", src.URL), errs.ErrorTypeValidation, "fetching tnsnames.ora")
+	}
+	return files, nil
+}
+
+// fetchHTTPS downloads the file at rawURL, which must use HTTPS so the
+// connection definitions cannot be tampered with on the way
+func fetchHTTPS(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	if u, err := url.Parse(rawURL); err != nil || u.Scheme != "https" {
+		return nil, errs.HandleError(fmt.Errorf("%s is not an https URL", rawURL), errs.ErrorTypeValidation, "fetching "+path.Base(rawURL))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeDownload, "fetching "+path.Base(rawURL))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeDownload, "fetching "+path.Base(rawURL))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errs.HandleError(fmt.Errorf("%s: HTTP status %s", rawURL, resp.Status), errs.ErrorTypeDownload, "fetching "+path.Base(rawURL))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTNSFileSize+1))
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeDownload, "fetching "+path.Base(rawURL))
+	}
+	if len(data) > maxTNSFileSize {
+		return nil, errs.HandleError(fmt.Errorf("%s is larger than %s", rawURL, utils.FormatBytes(maxTNSFileSize)), errs.ErrorTypeDownload, "fetching "+path.Base(rawURL))
+	}
+	return data, nil
+}
+
+// fetchGit reads the files at src.Path and src.SQLNet from a shallow clone
+// of the repository
+func fetchGit(ctx context.Context, src manifest.TNSSource) (TNSFiles, error) {
+	dir, err := os.MkdirTemp("", "oraicwinconfig-tns-*")
+	if err != nil {
+		return TNSFiles{}, errs.HandleError(err, errs.ErrorTypeDownload, "cloning tnsnames.ora repository")
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if src.Ref != "" {
+		args = append(args, "--branch", src.Ref)
+	}
+	args = append(args, "--", src.URL, dir)
+	if out, err := pwsh.CombinedOutput(ctx, "git", args...); err != nil {
+		return TNSFiles{}, errs.HandleError(fmt.Errorf("git clone %s: %w: %s", src.URL, err, strings.TrimSpace(string(out))), errs.ErrorTypeDownload, "cloning tnsnames.ora repository")
+	}
+
+	read := func(name string) ([]byte, error) {
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, errs.HandleError(fmt.Errorf("%s is not a path within the repository", name), errs.ErrorTypeValidation, "reading "+path.Base(name)+" from repository")
+		}
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, errs.HandleError(err, errs.ErrorTypeValidation, "reading "+path.Base(name)+" from repository")
+		}
+		return data, nil
+	}
+	var files TNSFiles
+	name := src.Path
+	if name == "" {
+		name = "tnsnames.ora"
+	}
+	if files.TNSNames, err = read(name); err != nil {
+		return TNSFiles{}, err
+	}
+	if src.SQLNet != "" {
+		if files.SQLNet, err = read(src.SQLNet); err != nil {
+			return TNSFiles{}, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,91 @@
+package oic_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/oic"
+)
+
+// sqlnet is the sqlnet.ora published alongside tnsnames.ora
+const sqlnet = "NAMES.DIRECTORY_PATH = (TNSNAMES, EZCONNECT)\n"
+
+func TestFetchTNSFilesHTTPS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tnsnames.ora":
+			w.Write([]byte(tnsnames))
+		case "/sqlnet.ora":
+			w.Write([]byte(sqlnet))
+		case "/broken.ora":
+			w.Write([]byte("ORCL = (DESCRIPTION = \n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	files, err := oic.FetchTNSFiles(context.Background(), srv.Client(), manifest.TNSSource{URL: srv.URL + "/tnsnames.ora", SQLNet: srv.URL + "/sqlnet.ora"})
+	if err != nil || string(files.TNSNames) != tnsnames || string(files.SQLNet) != sqlnet {
+		t.Fatalf("fetched %+v, %v", files, err)
+	}
+	for path, want := range map[string]string{"/broken.ora": "not a valid tnsnames.ora", "/missing.ora": "404"} {
+		if _, err := oic.FetchTNSFiles(context.Background(), srv.Client(), manifest.TNSSource{URL: srv.URL + path}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error = %v, want %q", path, err, want)
+		}
+	}
+	plain := "http" + strings.TrimPrefix(srv.URL, "https")
+	if _, err := oic.FetchTNSFiles(context.Background(), srv.Client(), manifest.TNSSource{URL: plain + "/tnsnames.ora"}); err == nil {
+		t.Error("plain http accepted")
+	}
+}
+
+func TestFetchTNSFilesGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, "oracle"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"tnsnames.ora": tnsnames, "sqlnet.ora": sqlnet} {
+		if err := os.WriteFile(filepath.Join(repo, "oracle", name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main"},
+		{"add", "."},
+		{"-c", "user.name=dba", "-c", "user.email=dba@example.com", "commit", "--quiet", "-m", "Add tnsnames.ora"},
+		{"tag", "v1"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", args[0], err, out)
+		}
+	}
+
+	url := "file://" + filepath.ToSlash(repo)
+	files, err := oic.FetchTNSFiles(context.Background(), nil, manifest.TNSSource{URL: url, Git: true, Ref: "v1", Path: "oracle/tnsnames.ora", SQLNet: "oracle/sqlnet.ora"})
+	if err != nil || string(files.TNSNames) != tnsnames || string(files.SQLNet) != sqlnet {
+		t.Fatalf("fetched %+v, %v", files, err)
+	}
+	for _, src := range []manifest.TNSSource{
+		{URL: url, Git: true},
+		{URL: url, Git: true, Path: "../tnsnames.ora"},
+		{URL: url, Git: true, Path: "oracle/tnsnames.ora", SQLNet: "sqlnet.ora"},
+		{URL: url, Git: true, Ref: "missing", Path: "oracle/tnsnames.ora"},
+	} {
+		if _, err := oic.FetchTNSFiles(context.Background(), nil, src); err == nil {
+			t.Errorf("%+v fetched", src)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package oic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/generate"
+	"github.com/mghoff/oraicwinconfig/internal/hooks"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/odpnet"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// UpgradeResult describes the outcome of an upgrade
+type UpgradeResult struct {
+	From     string // Client directory before the upgrade
+	To       string // Client directory after the upgrade
+	Upgraded bool   // False when the installed client was already the latest
+}
+
+// Upgrade replaces the client recorded in the manifest with the latest release,
+// installed side by side in the same directory. The network/admin files are
+// carried over, and any failure restores the previous environment and removes
+// the new client directory.
+func Upgrade(ctx context.Context, conf *config.InstallConfig, env env.Manager) (UpgradeResult, error) {
+	ctx = utils.EnsureContext(ctx)
+	libVar := conf.LibVar()
+	m, err := manifest.Load(env.Scope())
+	if err != nil {
+		return UpgradeResult{}, err
+	}
+	old, ok := m.Client(libVar)
+	if !ok {
+		return UpgradeResult{}, errs.HandleError(
+			fmt.Errorf("no %s client recorded in the %s scope manifest; install one first", libVar, env.Scope()),
+			errs.ErrorTypeValidation,
+			"finding installed client")
+	}
+	result := UpgradeResult{From: old.ClientDir, To: old.ClientDir}
+	if err := conf.SetInstallPath(old.InstallPath); err != nil {
+		return result, err
+	}
+	conf.EnvMode = old.EnvMode
+	conf.ExtraEnv = old.ExtraEnv
+	conf.ODPNet.Driver = old.ODPNet
+	conf.SQLPlus = old.SQLPlus
+	conf.Secondary = old.Vars != nil && old.Vars["TNS_ADMIN"] == ""
+	// A TNS_ADMIN outside the old client directory is shared and stays as it is
+	if admin := old.Vars["TNS_ADMIN"]; admin != "" && filepath.Clean(admin) != filepath.Join(old.ClientDir, "network", "admin") {
+		conf.TNSAdmin = admin
+	}
+
+	// Read the version of the latest release without downloading it, from the
+	// mirror index or where the server allows
+	latest := conf.Pins.ClientDir
+	if latest == "" {
+		latest, _ = utils.RemoteClientDir(ctx, utils.NewHTTPClient(conf.HTTP), conf.BaseURL+conf.PkgFile)
+	}
+	if latest != "" {
+		fmt.Printf("latest release is %s, installed is %s\n", latest, filepath.Base(old.ClientDir))
+		if !newerClient(latest, old.ClientDir) {
+			fmt.Println("the installed client is up to date")
+			return result, nil
+		}
+	}
+
+	pkgZipPath := filepath.Join(conf.DownloadsPath, conf.PkgFile)
+	sdkZipPath := filepath.Join(conf.DownloadsPath, conf.SdkFile)
+	if err := download(ctx, conf, pkgZipPath, sdkZipPath); err != nil {
+		return result, err
+	}
+	if err := verifyPins(conf, pkgZipPath, sdkZipPath); err != nil {
+		return result, err
+	}
+
+	// Extract side by side; the new directory is only removed on failure if it is new
+	pkgDir, err := clientDirOf(conf, pkgZipPath)
+	if err != nil {
+		return result, err
+	}
+	if conf.Pins.ClientDir != "" && pkgDir != conf.Pins.ClientDir {
+		return result, errs.HandleError(
+			fmt.Errorf("package holds %s, but %s pins %s", pkgDir, conf.Pins.Source, conf.Pins.ClientDir),
+			errs.ErrorTypeValidation,
+			"verifying pinned client")
+	}
+	if !newerClient(pkgDir, old.ClientDir) {
+		fmt.Println("the installed client is up to date")
+		return result, nil
+	}
+	newDir := filepath.Join(conf.InstallPath, pkgDir)
+	_, statErr := os.Stat(newDir)
+	created := os.IsNotExist(statErr)
+
+	rollback := func(cause error) (UpgradeResult, error) {
+		fmt.Printf("upgrade failed, rolling back to %s: %v\n", old.ClientDir, cause)
+		if err := restoreEnv(conf, env, old, newDir); err != nil {
+			fmt.Printf("warning: could not restore the environment: %v\n", err)
+		}
+		if conf.ODPNet.Unmanaged() {
+			if _, err := odpnet.Register(context.WithoutCancel(ctx), conf.Arch == "386", old.ClientDir, old.Vars["TNS_ADMIN"], nil); err != nil {
+				fmt.Printf("warning: could not restore the unmanaged ODP.NET driver settings: %v\n", err)
+			}
+		}
+		if created {
+			os.RemoveAll(newDir)
+		}
+		if err := manifest.Record(env.Scope(), func(m *manifest.Manifest) { m.Put(old) }); err != nil {
+			fmt.Printf("warning: could not restore the installation manifest: %v\n", err)
+		}
+		return result, cause
+	}
+
+	extractedDir, sdkDir, err := extract(ctx, conf, pkgZipPath, sdkZipPath)
+	if err != nil {
+		return rollback(err)
+	}
+	if extractedDir != pkgDir || sdkDir != pkgDir {
+		return rollback(errs.HandleError(
+			fmt.Errorf("package version (%s) does not match SDK version (%s)", extractedDir, sdkDir),
+			errs.ErrorTypeInstall,
+			"version verification"))
+	}
+
+	// Carry over tnsnames.ora, sqlnet.ora, wallets and any other Oracle Net files
+	tnsAdminPath := filepath.Join(newDir, "network", "admin")
+	oldAdmin := filepath.Join(old.ClientDir, "network", "admin")
+	if _, err := os.Stat(oldAdmin); err == nil {
+		fmt.Printf("copying %s to %s\n", oldAdmin, tnsAdminPath)
+		if err := utils.CopyDir(ctx, oldAdmin, tnsAdminPath); err != nil {
+			return rollback(errs.HandleError(err, errs.ErrorTypeInstall, "preserving network/admin"))
+		}
+	}
+	tnsAdminPath = conf.TNSAdminPath(newDir)
+
+	if conf.EnvMode != config.EnvModeWrapper {
+		if err := configureEnv(ctx, conf, env, libVar, newDir, tnsAdminPath); err != nil {
+			return rollback(err)
+		}
+		if err := env.WithContext(ctx).RemoveFromPath(old.ClientDir); err != nil {
+			return rollback(err)
+		}
+	}
+	if conf.EnvMode != config.EnvModeGlobal {
+		for _, w := range generate.Wrappers(conf.OS, generate.WrapperSpec{LibVar: libVar, ClientDir: newDir, TNSAdmin: tnsAdminPath}) {
+			if err := os.WriteFile(filepath.Join(newDir, w.Name), []byte(w.Content), w.Mode); err != nil {
+				return rollback(errs.HandleError(err, errs.ErrorTypeEnvironment, "writing launcher script"))
+			}
+		}
+	}
+	if err := setupODPNet(ctx, conf, newDir, tnsAdminPath); err != nil {
+		return rollback(err)
+	}
+	if err := recordManifest(conf, env, libVar, newDir, tnsAdminPath); err != nil {
+		return rollback(err)
+	}
+	if err := hooks.Run(ctx, hooks.EventPostInstall, conf.Hooks.PostInstall, hookVars(conf, libVar, newDir, tnsAdminPath), conf.Timeouts.Hook); err != nil {
+		return rollback(err)
+	}
+
+	if err := Prune(conf, env); err != nil {
+		fmt.Printf("warning: could not remove previous client versions: %v\n", err)
+	}
+
+	result.To, result.Upgraded = newDir, true
+	fmt.Printf("upgraded %s to %s; the previous client is left in place\n", old.ClientDir, newDir)
+	return result, nil
+}
+
+// clientDirOf returns the client directory an archive extracts to
+func clientDirOf(conf *config.InstallConfig, archivePath string) (string, error) {
+	if strings.HasSuffix(archivePath, ".zip") {
+		return utils.ZipClientDir(archivePath)
+	}
+	// Disk images are only identified once mounted, so extract to find out
+	ctx, cancel := utils.WithTimeout(context.Background(), conf.Timeouts.Extract)
+	defer cancel()
+	return utils.Extract(ctx, archivePath, conf.InstallPath)
+}
+
+// restoreEnv puts back the variables and PATH entries recorded for the old client
+func restoreEnv(conf *config.InstallConfig, env env.Manager, old manifest.Client, newDir string) error {
+	ctx, cancel := utils.WithTimeout(context.Background(), conf.Timeouts.Environment)
+	defer cancel()
+	env = env.WithContext(ctx)
+	if old.Vars == nil {
+		return nil
+	}
+	if err := env.RemoveFromPath(newDir); err != nil {
+		return err
+	}
+	for name, value := range old.Vars {
+		if err := env.SetEnvVar(name, value); err != nil {
+			return err
+		}
+	}
+	for _, dir := range old.Path {
+		if err := env.AppendToPath(dir); err != nil {
+			return err
+		}
+	}
+	return orderPath(env, conf, old.ClientDir)
+}
+
+// newerClient reports whether client directory a holds a later version than b
+func newerClient(a, b string) bool {
+	return utils.NewerVersion(utils.ClientVersion(a), utils.ClientVersion(b))
+}
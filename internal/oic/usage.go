@@ -0,0 +1,177 @@
+package oic
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// Kinds of disk usage
+const (
+	UsageClient   = "client"   // A client directory
+	UsageDownload = "download" // A downloaded archive
+	UsageBackup   = "backup"   // A saved or backed-up network configuration file
+	UsageState    = "state"    // The manifest, journal and logs
+)
+
+// Usage is the disk space an item managed by the installer takes
+type Usage struct {
+	Kind string `json:"kind"`
+	Path string `json:"path"`
+	Size int64  `json:"size"` // Bytes in regular files
+	Note string `json:"note,omitempty"`
+
+	Modified time.Time `json:"modified"` // When the file or directory was last modified
+}
+
+// DiskUsage returns the disk space taken by the clients in the install paths
+// of the scope's manifest and conf.InstallPath, the archives in the downloads
+// folder, tnsnames.ora saved for the next install and the backups tns sync
+// leaves in TNS_ADMIN, and the manifest directory. Clients are noted as in use
+// or as previous versions.
+func DiskUsage(conf *config.InstallConfig, m env.Manager) ([]Usage, error) {
+	man, err := manifest.Load(m.Scope())
+	if err != nil {
+		return nil, err
+	}
+	inUse := clientsInUse(m)
+
+	// Install paths and TNS_ADMIN directories, each once
+	var installPaths, admins []string
+	seen := make(map[string]bool)
+	add := func(list *[]string, dir string) {
+		if dir != "" && !seen[env.PathKey(dir)] {
+			seen[env.PathKey(dir)] = true
+			*list = append(*list, dir)
+		}
+	}
+	add(&installPaths, conf.InstallPath)
+	for _, c := range man.Clients {
+		add(&installPaths, c.InstallPath)
+		add(&admins, c.Vars["TNS_ADMIN"])
+		add(&admins, filepath.Join(c.ClientDir, "network", "admin"))
+	}
+	if dir, err := m.GetEnvVar("TNS_ADMIN"); err == nil {
+		add(&admins, dir)
+	}
+
+	var usage []Usage
+	for _, base := range installPaths {
+		entries, err := os.ReadDir(base)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() || utils.ClientVersion(e.Name()) == "" {
+				continue
+			}
+			dir := filepath.Join(base, e.Name())
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			note := "previous version"
+			if inUse[env.PathKey(dir)] {
+				note = "in use"
+			}
+			if utils.IsUNC(dir) {
+				note += ", on a network share"
+			}
+			usage = append(usage, Usage{Kind: UsageClient, Path: dir, Size: dirSize(dir), Note: note, Modified: info.ModTime()})
+		}
+	}
+
+	for _, name := range config.ArchiveNames() {
+		path := filepath.Join(conf.DownloadsPath, name)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			usage = append(usage, Usage{Kind: UsageDownload, Path: path, Size: info.Size(), Modified: info.ModTime()})
+		}
+	}
+
+	// tnsnames.ora saved from a replaced client waits in the downloads folder
+	// for the next install, and tns sync keeps timestamped backups
+	saved := filepath.Join(conf.DownloadsPath, "tnsnames.ora")
+	if info, err := os.Stat(saved); err == nil && info.Mode().IsRegular() {
+		usage = append(usage, Usage{Kind: UsageBackup, Path: saved, Size: info.Size(), Note: "saved for the next install", Modified: info.ModTime()})
+	}
+	for _, dir := range admins {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.bak"))
+		sort.Strings(matches)
+		for _, path := range matches {
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				usage = append(usage, Usage{Kind: UsageBackup, Path: path, Size: info.Size(), Modified: info.ModTime()})
+			}
+		}
+	}
+
+	if dir, err := manifest.Dir(m.Scope()); err == nil {
+		if info, err := os.Stat(dir); err == nil {
+			usage = append(usage, Usage{Kind: UsageState, Path: dir, Size: dirSize(dir), Modified: info.ModTime()})
+		}
+	}
+	return usage, nil
+}
+
+// dirSize returns the total size of the regular files under dir; symbolic
+// links are not followed, and unreadable entries are skipped
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// ReportUsage writes the items as a table followed by the total of each kind,
+// and returns how many bytes can be freed without affecting the configured
+// clients
+func ReportUsage(w io.Writer, usage []Usage) int64 {
+	var total, reclaimable int64
+	totals := make(map[string]int64)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, u := range usage {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", u.Kind, utils.FormatBytes(uint64(u.Size)), u.Path, u.Note)
+		total += u.Size
+		totals[u.Kind] += u.Size
+		if u.Reclaimable() {
+			reclaimable += u.Size
+		}
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	for _, kind := range []string{UsageClient, UsageDownload, UsageBackup, UsageState} {
+		label := kind + "s"
+		if kind == UsageState {
+			label = kind
+		}
+		fmt.Fprintf(w, "%-10s %s\n", label, utils.FormatBytes(uint64(totals[kind])))
+	}
+	fmt.Fprintf(w, "%-10s %s, of which %s can be freed without affecting the configured clients\n",
+		"total", utils.FormatBytes(uint64(total)), utils.FormatBytes(uint64(reclaimable)))
+	return reclaimable
+}
+
+// Reclaimable reports whether removing the item frees space without
+// affecting the configured clients
+func (u Usage) Reclaimable() bool {
+	return u.Kind == UsageDownload || u.Kind == UsageBackup || u.Kind == UsageClient && strings.HasPrefix(u.Note, "previous")
+}
@@ -0,0 +1,69 @@
+package oic_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/oic"
+)
+
+func TestDiskUsage(t *testing.T) {
+	h := newHarness(t)
+	if err := oic.Install(context.Background(), h.conf, h.env); err != nil {
+		t.Fatal(err)
+	}
+	previous := filepath.Join(h.conf.InstallPath, "instantclient_21_9")
+	os.MkdirAll(previous, 0755)
+	os.WriteFile(filepath.Join(previous, "libclntsh.so"), make([]byte, 1000), 0644)
+	admin, err := h.env.GetEnvVar("TNS_ADMIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(admin, 0755)
+	backup := filepath.Join(admin, "tnsnames.ora.20260101-120000.bak")
+	os.WriteFile(backup, make([]byte, 10), 0644)
+	os.WriteFile(filepath.Join(h.conf.DownloadsPath, "unrelated.zip"), nil, 0644)
+
+	usage, err := oic.DiskUsage(h.conf, h.env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := make(map[string]oic.Usage)
+	for _, u := range usage {
+		byPath[u.Path] = u
+	}
+	current := filepath.Join(h.conf.InstallPath, "instantclient_23_7")
+	if u := byPath[current]; u.Kind != oic.UsageClient || u.Note != "in use" || u.Size == 0 || u.Reclaimable() {
+		t.Errorf("installed client = %+v", u)
+	}
+	if u := byPath[previous]; u.Kind != oic.UsageClient || u.Note != "previous version" || u.Size != 1000 || !u.Reclaimable() {
+		t.Errorf("previous client = %+v", u)
+	}
+	if u := byPath[backup]; u.Kind != oic.UsageBackup || u.Size != 10 {
+		t.Errorf("backup = %+v", u)
+	}
+	if _, ok := byPath[filepath.Join(h.conf.DownloadsPath, "unrelated.zip")]; ok {
+		t.Error("a file the installer did not download was counted")
+	}
+	state := 0
+	for _, u := range usage {
+		if u.Kind == oic.UsageState {
+			state++
+		}
+	}
+	if state != 1 {
+		t.Errorf("usage = %+v, want the manifest directory once", usage)
+	}
+
+	var out bytes.Buffer
+	if freed := oic.ReportUsage(&out, usage); freed < 1010 {
+		t.Errorf("reclaimable = %d, want at least the previous client and backup", freed)
+	}
+	if !strings.Contains(out.String(), previous) || !strings.Contains(out.String(), "total") {
+		t.Errorf("report:\n%s", out.String())
+	}
+}
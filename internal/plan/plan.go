@@ -0,0 +1,251 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+)
+
+// FormatVersion is the version of the plan file format written by this installer
+const FormatVersion = 2
+
+// Kind is the type of a planned action
+type Kind string
+
+const (
+	KindDownload       Kind = "download"         // Download URL to Path
+	KindExtract        Kind = "extract"          // Extract the archive at Path into Dir, creating ClientDir
+	KindMoveFile       Kind = "move-file"        // Move, or with Copy copy, Path to Target
+	KindRemoveDir      Kind = "remove-dir"       // Remove Dir and everything under it
+	KindWriteFile      Kind = "write-file"       // Write Content to Path with Mode
+	KindSetEnv         Kind = "set-env"          // Set the variable Name to Value
+	KindRemoveEnv      Kind = "remove-env"       // Remove the variable Name
+	KindAppendPath     Kind = "append-path"      // Add Dir to PATH
+	KindRemovePath     Kind = "remove-path"      // Remove Dir from PATH
+	KindMovePathBefore Kind = "move-path-before" // Move Dir ahead of Target on PATH
+	KindRunHooks       Kind = "run-hooks"        // Run the Commands of Event with Vars in their environment
+	KindRecord         Kind = "record-manifest"  // Record Client in the manifest
+)
+
+// Action is a single change made when a plan is applied. Only the fields
+// relevant to its Kind are set.
+type Action struct {
+	Kind      Kind              `json:"kind"`
+	URL       string            `json:"url,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	Dir       string            `json:"dir,omitempty"`
+	Target    string            `json:"target,omitempty"`
+	ClientDir string            `json:"clientDir,omitempty"`
+	Copy      bool              `json:"copy,omitempty"`
+	Content   string            `json:"content,omitempty"`
+	Mode      os.FileMode       `json:"mode,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Value     string            `json:"value,omitempty"`
+	Event     string            `json:"event,omitempty"`
+	Commands  []string          `json:"commands,omitempty"`
+	Vars      map[string]string `json:"vars,omitempty"`
+	Force     bool              `json:"force,omitempty"` // Continue if guard hooks fail
+	Client    *manifest.Client  `json:"client,omitempty"`
+}
+
+// String describes the action for review
+func (a Action) String() string {
+	switch a.Kind {
+	case KindDownload:
+		return fmt.Sprintf("download %s to %s", a.URL, a.Path)
+	case KindExtract:
+		return fmt.Sprintf("extract %s into %s (creates %s)", a.Path, a.Dir, a.ClientDir)
+	case KindMoveFile:
+		if a.Copy {
+			return fmt.Sprintf("copy %s to %s", a.Path, a.Target)
+		}
+		return fmt.Sprintf("move %s to %s", a.Path, a.Target)
+	case KindRemoveDir:
+		return fmt.Sprintf("remove directory %s", a.Dir)
+	case KindWriteFile:
+		return fmt.Sprintf("write %s (%d bytes, mode %v)", a.Path, len(a.Content), a.Mode)
+	case KindSetEnv:
+		return fmt.Sprintf("set %s=%s", a.Name, a.Value)
+	case KindRemoveEnv:
+		return fmt.Sprintf("remove %s", a.Name)
+	case KindAppendPath:
+		return fmt.Sprintf("add %s to PATH", a.Dir)
+	case KindRemovePath:
+		return fmt.Sprintf("remove %s from PATH", a.Dir)
+	case KindMovePathBefore:
+		return fmt.Sprintf("move %s ahead of %s on PATH", a.Dir, a.Target)
+	case KindRunHooks:
+		return fmt.Sprintf("run %s hooks: %s", a.Event, strings.Join(a.Commands, "; "))
+	case KindRecord:
+		return fmt.Sprintf("record %s in the manifest", a.Client.ClientDir)
+	}
+	return string(a.Kind)
+}
+
+// Plan is the serialized list of actions an install would perform, produced
+// for review and later applied verbatim
+type Plan struct {
+	FormatVersion int       `json:"formatVersion"`
+	Version       string    `json:"version"` // Installer version that produced the plan
+	Created       time.Time `json:"created"`
+	Host          string    `json:"host"`
+	OS            string    `json:"os"`
+	Arch          string    `json:"arch"`
+	Scope         env.Scope `json:"scope"`
+	Options       Options   `json:"options"` // What the plan was made from, for refreshing it
+	State         State     `json:"state"`   // Machine state the actions were worked out against
+	Actions       []Action  `json:"actions"`
+}
+
+// Options holds the settings a plan was made with
+type Options struct {
+	InstallPath   string            `json:"installPath"`
+	DownloadsPath string            `json:"downloadsPath"`
+	BaseURL       string            `json:"baseURL"`
+	PkgFile       string            `json:"pkgFile"`
+	SdkFile       string            `json:"sdkFile"`
+	EnvMode       string            `json:"envMode"`
+	Existing      string            `json:"existing,omitempty"`
+	Secondary     bool              `json:"secondary,omitempty"`
+	SQLPlus       bool              `json:"sqlplus,omitempty"`
+	TNSAdmin      string            `json:"tnsAdmin,omitempty"`
+	ExtraEnv      map[string]string `json:"extraEnv,omitempty"`
+	Hooks         config.HookConfig `json:"hooks"`
+}
+
+// State is the part of the machine a plan depends on
+type State struct {
+	Vars      map[string]string `json:"vars"`               // Client variables and PATH; unset ones are absent
+	Existing  string            `json:"existing,omitempty"` // Directory of the installed client, if any
+	TNSNames  bool              `json:"tnsnames,omitempty"` // Whether the installed client has a tnsnames.ora
+	ClientDir string            `json:"clientDir"`          // Versioned directory the package extracts to
+}
+
+// Drift is a difference between the state a plan was made against and the current one
+type Drift struct {
+	What    string
+	Planned string
+	Current string
+}
+
+// Diff returns the differences between the planned and current states
+func Diff(planned, current State) []Drift {
+	var drifts []Drift
+	add := func(what, a, b string) {
+		if a != b {
+			drifts = append(drifts, Drift{What: what, Planned: a, Current: b})
+		}
+	}
+	names := make(map[string]bool)
+	for name := range planned.Vars {
+		names[name] = true
+	}
+	for name := range current.Vars {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		add(name, planned.Vars[name], current.Vars[name])
+	}
+	add("existing installation", planned.Existing, current.Existing)
+	add("existing tnsnames.ora", fmt.Sprint(planned.TNSNames), fmt.Sprint(current.TNSNames))
+	add("latest release", planned.ClientDir, current.ClientDir)
+	return drifts
+}
+
+// WriteDrift prints the differences as a report
+func WriteDrift(w io.Writer, drifts []Drift) {
+	show := func(v string) string {
+		if v == "" {
+			return "(unset)"
+		}
+		return v
+	}
+	for _, d := range drifts {
+		fmt.Fprintf(w, "  %s\n    planned: %s\n    current: %s\n", d.What, show(d.Planned), show(d.Current))
+	}
+}
+
+// Configure sets conf to the platform, scope and options the plan was made with
+func (p *Plan) Configure(conf *config.InstallConfig) error {
+	if err := conf.SetPlatform(p.OS, p.Arch); err != nil {
+		return err
+	}
+	if err := conf.SetScope(p.Scope); err != nil {
+		return err
+	}
+	o := p.Options
+	if err := conf.SetInstallPath(o.InstallPath); err != nil {
+		return err
+	}
+	if err := conf.SetDownloadsPath(o.DownloadsPath); err != nil {
+		return err
+	}
+	conf.BaseURL, conf.PkgFile, conf.SdkFile = o.BaseURL, o.PkgFile, o.SdkFile
+	conf.EnvMode, conf.Existing, conf.Secondary, conf.Hooks = o.EnvMode, o.Existing, o.Secondary, o.Hooks
+	conf.TNSAdmin, conf.ExtraEnv = o.TNSAdmin, o.ExtraEnv
+	return nil
+}
+
+// Add appends an action to the plan
+func (p *Plan) Add(a Action) {
+	p.Actions = append(p.Actions, a)
+}
+
+// Write prints the actions as a numbered list for review
+func (p *Plan) Write(w io.Writer) {
+	fmt.Fprintf(w, "Plan for %s (%s/%s, %s scope), created %s by oraicwinconfig %s:\n",
+		p.Host, p.OS, p.Arch, p.Scope, p.Created.Local().Format(time.DateTime), p.Version)
+	for i, a := range p.Actions {
+		fmt.Fprintf(w, "  %2d. %s\n", i+1, a)
+	}
+}
+
+// Save writes the plan to path as JSON
+func (p *Plan) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "encoding plan")
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "writing plan")
+	}
+	return nil
+}
+
+// Load reads a plan written by Save
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "reading plan")
+	}
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "parsing plan")
+	}
+	if p.FormatVersion != FormatVersion {
+		return nil, errs.HandleError(
+			fmt.Errorf("plan format version %d is not supported; this installer reads version %d", p.FormatVersion, FormatVersion),
+			errs.ErrorTypeValidation,
+			"parsing plan")
+	}
+	for i, a := range p.Actions {
+		if a.Kind == KindRecord && a.Client == nil {
+			return nil, errs.HandleError(fmt.Errorf("action %d records no client", i+1), errs.ErrorTypeValidation, "parsing plan")
+		}
+	}
+	return &p, nil
+}
@@ -0,0 +1,171 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// Formats a plan can be shown in
+const (
+	FormatText   = "text"   // Numbered list with where and when the plan was made, for review
+	FormatStable = "stable" // One action per line with every field, for diffs
+	FormatJSON   = "json"   // The actions as indented JSON, for tools
+)
+
+// CheckFormat returns an error unless format is one a plan can be shown in
+func CheckFormat(format string) error {
+	switch format {
+	case FormatText, FormatStable, FormatJSON:
+		return nil
+	}
+	return errs.HandleError(
+		fmt.Errorf("invalid format %q: must be %s, %s or %s", format, FormatText, FormatStable, FormatJSON),
+		errs.ErrorTypeValidation,
+		"parsing flags")
+}
+
+// Simulation is what applying a plan would do, without anything that differs
+// from one run or installer version to the next: when and on which host the
+// plan was made, the installer version it records and passes to hooks, and
+// the install time recorded in the manifest. The same options against the
+// same machine give the same simulation, so two can be compared with diff.
+type Simulation struct {
+	OS      string    `json:"os"`
+	Arch    string    `json:"arch"`
+	Scope   env.Scope `json:"scope"`
+	Actions []Action  `json:"actions"`
+}
+
+// versionVar passes the installer version to hooks
+const versionVar = "ORAICWINCONFIG_VERSION"
+
+// Simulation returns the run-independent part of the plan
+func (p *Plan) Simulation() Simulation {
+	s := Simulation{OS: p.OS, Arch: p.Arch, Scope: p.Scope, Actions: make([]Action, len(p.Actions))}
+	for i, a := range p.Actions {
+		if _, ok := a.Vars[versionVar]; ok {
+			vars := make(map[string]string, len(a.Vars))
+			for name, value := range a.Vars {
+				if name != versionVar {
+					vars[name] = value
+				}
+			}
+			a.Vars = vars
+		}
+		if a.Client != nil {
+			c := *a.Client
+			c.Version, c.InstalledAt = "", time.Time{}
+			a.Client = &c
+		}
+		s.Actions[i] = a
+	}
+	return s
+}
+
+// Render shows the plan in format
+func (p *Plan) Render(w io.Writer, format string) error {
+	switch format {
+	case FormatText:
+		p.Write(w)
+		return nil
+	case FormatStable:
+		p.Simulation().Write(w)
+		return nil
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(p.Simulation()); err != nil {
+			return errs.HandleError(err, errs.ErrorTypeInstall, "encoding plan")
+		}
+		return nil
+	}
+	return CheckFormat(format)
+}
+
+// Write prints the simulation one action per line, with its fields as
+// name=value in a fixed order and its lists and maps sorted on indented lines
+// below it
+func (s Simulation) Write(w io.Writer) {
+	fmt.Fprintf(w, "platform %s/%s\n", s.OS, s.Arch)
+	fmt.Fprintf(w, "scope %s\n", s.Scope)
+	for _, a := range s.Actions {
+		line := []string{string(a.Kind)}
+		field := func(name, value string) {
+			if value != "" {
+				line = append(line, name+"="+stableValue(value))
+			}
+		}
+		field("event", a.Event)
+		field("name", a.Name)
+		field("value", a.Value)
+		field("url", a.URL)
+		field("path", a.Path)
+		field("dir", a.Dir)
+		field("target", a.Target)
+		field("clientDir", a.ClientDir)
+		if a.Copy {
+			field("copy", "true")
+		}
+		if a.Kind == KindWriteFile {
+			field("mode", fmt.Sprintf("%#o", uint32(a.Mode.Perm())))
+		}
+		if a.Force {
+			field("force", "true")
+		}
+		if c := a.Client; c != nil {
+			field("libVar", c.LibVar)
+			field("clientDir", c.ClientDir)
+			field("installPath", c.InstallPath)
+			field("arch", c.Arch)
+			field("pkgFile", c.PkgFile)
+			field("envMode", c.EnvMode)
+		}
+		fmt.Fprintln(w, strings.Join(line, " "))
+
+		for _, command := range a.Commands {
+			fmt.Fprintf(w, "  command %s\n", command)
+		}
+		writeVars(w, "var", a.Vars)
+		if a.Kind == KindWriteFile {
+			for _, l := range strings.Split(strings.TrimSuffix(strings.ReplaceAll(a.Content, "\r\n", "\n"), "\n"), "\n") {
+				fmt.Fprintf(w, "  | %s\n", l)
+			}
+		}
+		if c := a.Client; c != nil {
+			writeVars(w, "var", c.Vars)
+			for _, dir := range c.Path {
+				fmt.Fprintf(w, "  path %s\n", dir)
+			}
+			writeVars(w, "extra", c.ExtraEnv)
+		}
+	}
+}
+
+// writeVars prints a map as indented name=value lines sorted by name
+func writeVars(w io.Writer, label string, vars map[string]string) {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s %s=%s\n", label, name, vars[name])
+	}
+}
+
+// stableValue quotes values that would otherwise be ambiguous on a
+// space-separated line
+func stableValue(s string) string {
+	if strings.ContainsAny(s, " \t\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
@@ -0,0 +1,168 @@
+// Package plugins finds the executables dropped into a plugins directory and
+// the install steps they add. A plugin is a PowerShell (.ps1) or batch script
+// or an .exe on Windows, and a shell (.sh) script or any other executable
+// elsewhere. Run with the argument "describe", it prints the steps it adds as
+// JSON:
+//
+//	{"steps": [{"name": "register-dsns", "after": "configure-env", "description": "Register corporate DSNs"}]}
+//
+// Each step is put after the step named by "after", and run as the plugin
+// with the arguments "run" and the step's name, with the install facts in its
+// environment.
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/hooks"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// DirName is the name of the plugins directory next to the manifest
+const DirName = "plugins"
+
+// validName matches the names steps may have: they are given to --skip-step
+// and recorded in the install journal
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Step is an install step added by a plugin
+type Step struct {
+	Name        string `json:"name"`
+	After       string `json:"after"`                 // Step it runs after
+	Description string `json:"description,omitempty"` // What it does, for messages
+	Plugin      string `json:"-"`                     // Path of the plugin providing it
+}
+
+// description is what a plugin prints when run with "describe"
+type description struct {
+	Steps []Step `json:"steps"`
+}
+
+// Find returns the plugins in dir, sorted by file name. A missing directory
+// holds none. On Windows only .exe, .ps1, .cmd and .bat files are plugins, so
+// the desktop.ini and Thumbs.db Explorer leaves behind are not run. Elsewhere
+// .sh scripts and executable files are, except hidden ones and those ending
+// in .json, .md or .txt.
+func Find(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeUserPath, "reading plugins directory")
+	}
+	var paths []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(name))
+		if runtime.GOOS == "windows" {
+			switch ext {
+			case ".exe", ".ps1", ".cmd", ".bat":
+				paths = append(paths, filepath.Join(dir, name))
+			}
+			continue
+		}
+		switch ext {
+		case ".json", ".md", ".txt":
+			continue
+		case ".sh":
+		default:
+			info, err := e.Info()
+			if err != nil || info.Mode()&0111 == 0 {
+				continue
+			}
+		}
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Load asks every plugin in dir for the steps it adds, in the order of Find
+func Load(ctx context.Context, dir string) ([]Step, error) {
+	paths, err := Find(dir)
+	if err != nil {
+		return nil, err
+	}
+	var steps []Step
+	for _, path := range paths {
+		described, err := describe(ctx, path)
+		if err != nil {
+			return nil, errs.HandleError(fmt.Errorf("%s: %w", path, err), errs.ErrorTypeValidation, "loading plugin")
+		}
+		steps = append(steps, described...)
+	}
+	return steps, nil
+}
+
+// describe runs the plugin at path with "describe" and checks the steps it prints
+func describe(ctx context.Context, path string) ([]Step, error) {
+	name, args := hooks.Interpreter(path)
+	out, err := pwsh.Output(ctx, name, append(args, "describe")...)
+	if err != nil {
+		return nil, fmt.Errorf("describe failed: %w", err)
+	}
+	var d description
+	if err := json.Unmarshal(out, &d); err != nil {
+		return nil, fmt.Errorf("describe printed no valid step list: %w", err)
+	}
+	if len(d.Steps) == 0 {
+		return nil, fmt.Errorf("describe listed no steps")
+	}
+	for i := range d.Steps {
+		step := &d.Steps[i]
+		if !validName.MatchString(step.Name) {
+			return nil, fmt.Errorf("invalid step name %q: must be lowercase letters, digits and dashes", step.Name)
+		}
+		if step.After == "" {
+			return nil, fmt.Errorf("step %s does not say which step it runs after", step.Name)
+		}
+		step.Plugin = path
+	}
+	return d.Steps, nil
+}
+
+// Run runs the step with vars added to the plugin's environment, bounded by
+// timeout. ORAICWINCONFIG_STEP is set to the step's name.
+func (s Step) Run(ctx context.Context, vars map[string]string, timeout time.Duration) error {
+	ctx, cancel := utils.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	environ := append(os.Environ(), "ORAICWINCONFIG_STEP="+s.Name)
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		environ = append(environ, name+"="+vars[name])
+	}
+
+	name, args := hooks.Interpreter(s.Plugin)
+	cmd := exec.CommandContext(ctx, name, append(args, "run", s.Name)...)
+	cmd.Env = environ
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		return errs.HandleError(fmt.Errorf("%s: %w", s.Plugin, err), errs.ErrorTypeInstall, "running plugin step "+s.Name)
+	}
+	return nil
+}
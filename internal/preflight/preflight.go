@@ -0,0 +1,147 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/check"
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// minFreeBytes is the free space required on the install and downloads volumes;
+// the extracted Basic Light package and SDK together take roughly 150 MB, and
+// the Basic package twice that
+const minFreeBytes = 500 << 20
+
+// Checks returns the default preflight checks for the given configuration.
+// The configuration is copied so the checks can run alongside later changes to it.
+func Checks(conf *config.InstallConfig, env env.Manager) []check.Check {
+	c := *conf
+	checks := []check.Check{
+		diskSpaceCheck(c),
+		ConnectivityCheck(c),
+		permissionsCheck(c),
+		shareCheck(c),
+		{Name: "dependencies", Run: func(ctx context.Context) check.Result { return checkDependencies() }},
+		{Name: "existing install", Run: func(ctx context.Context) check.Result { return checkExistingInstall(ctx, c, env) }},
+	}
+	// An offline bundle needs no network, and is only read
+	if c.Offline {
+		checks = slices.DeleteFunc(checks, func(ch check.Check) bool { return ch.Name == "connectivity" })
+	}
+	return checks
+}
+
+// PathChecks returns the preflight checks that depend on the install path,
+// to run again once the path the install actually uses is known
+func PathChecks(conf *config.InstallConfig) []check.Check {
+	c := *conf
+	return []check.Check{diskSpaceCheck(c), permissionsCheck(c), shareCheck(c)}
+}
+
+// diskSpaceCheck verifies there is room for the client with checkDiskSpace
+func diskSpaceCheck(c config.InstallConfig) check.Check {
+	return check.Check{Name: "disk space", Run: func(ctx context.Context) check.Result { return checkDiskSpace(ctx, c) }}
+}
+
+// permissionsCheck verifies the locations are writable with checkPermissions
+func permissionsCheck(c config.InstallConfig) check.Check {
+	return check.Check{Name: "permissions", Run: func(ctx context.Context) check.Result { return checkPermissions(c) }}
+}
+
+// shareCheck verifies a network install location with checkShare
+func shareCheck(c config.InstallConfig) check.Check {
+	return check.Check{Name: "network share", Run: func(ctx context.Context) check.Result { return checkShare(ctx, c) }}
+}
+
+// writtenPaths returns the directories the install writes to: the install
+// path and, unless the archives come from an offline bundle, the downloads
+// directory
+func writtenPaths(conf config.InstallConfig) []string {
+	if conf.Offline {
+		return []string{conf.InstallPath}
+	}
+	return []string{conf.InstallPath, conf.DownloadsPath}
+}
+
+// Run executes the checks concurrently, prints their results and returns an error if any failed
+func Run(ctx context.Context, checks []check.Check, timeout time.Duration) error {
+	results := check.Run(ctx, checks, timeout)
+	check.Report(os.Stdout, results)
+	return check.Err(results, "preflight checks")
+}
+
+// ConnectivityCheck verifies the package can be reached on the download host
+func ConnectivityCheck(conf config.InstallConfig) check.Check {
+	return check.Check{Name: "connectivity", Run: func(ctx context.Context) check.Result { return checkConnectivity(ctx, conf) }}
+}
+
+// checkDiskSpace verifies the install and downloads volumes have enough free space
+func checkDiskSpace(ctx context.Context, conf config.InstallConfig) check.Result {
+	var details []string
+	for _, path := range writtenPaths(conf) {
+		free, err := freeSpace(ctx, path)
+		if err != nil {
+			return check.Warn(fmt.Sprintf("could not determine free space for %s: %v", path, err), "make sure there is at least "+utils.FormatBytes(minFreeBytes)+" free")
+		}
+		if free < minFreeBytes {
+			return check.Fail(fmt.Sprintf("only %s free for %s, at least %s required", utils.FormatBytes(free), path, utils.FormatBytes(minFreeBytes)), "free up space or choose another install location")
+		}
+		details = append(details, fmt.Sprintf("%s free for %s", utils.FormatBytes(free), path))
+	}
+	return check.Pass(strings.Join(details, ", "))
+}
+
+// checkPermissions verifies files can be created in the downloads directory
+// and in the nearest existing parent of the install path
+func checkPermissions(conf config.InstallConfig) check.Result {
+	paths := writtenPaths(conf)
+	paths[0] = utils.NearestExistingDir(paths[0])
+	for _, path := range paths {
+		f, err := os.CreateTemp(path, ".oraicwinconfig-*")
+		if err != nil {
+			return check.Fail(fmt.Sprintf("cannot write to %s: %v", path, err), "run from an elevated prompt or choose a location you can write to")
+		}
+		f.Close()
+		os.Remove(f.Name())
+	}
+	return check.Pass("install and downloads locations are writable")
+}
+
+// checkShare verifies a network install location is reachable and writable
+func checkShare(ctx context.Context, conf config.InstallConfig) check.Result {
+	if isMappedDrive(conf.InstallPath) {
+		return check.Warn(fmt.Sprintf("%s is on a mapped network drive, which other users and elevated prompts may not see", conf.InstallPath), "use the UNC path of the share instead")
+	}
+	if !utils.IsUNC(conf.InstallPath) {
+		return check.Pass("install path is local")
+	}
+	if err := utils.CheckShare(ctx, conf.InstallPath); err != nil {
+		return check.Fail(err.Error(), "check the share is online and that you have write access to it")
+	}
+	return check.Pass(fmt.Sprintf("share %s is reachable and writable", utils.ShareRoot(conf.InstallPath)))
+}
+
+// checkExistingInstall reports whether an installation is already configured;
+// the interactive handling of it happens after the preflight checks
+func checkExistingInstall(ctx context.Context, conf config.InstallConfig, env env.Manager) check.Result {
+	libVar := conf.LibVar()
+	path, err := env.WithContext(ctx).GetEnvVar(libVar)
+	if errs.IsErrorType(err, errs.ErrorTypeEnvVarNotFound) {
+		return check.Pass("no existing installation configured")
+	} else if err != nil {
+		return check.Warn(err.Error(), "")
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return check.Warn(fmt.Sprintf("%s points to missing directory %s", libVar, path), "the stale configuration will be replaced by this install")
+	}
+	return check.Warn(fmt.Sprintf("existing installation found at %s", path), "you will be asked whether to overwrite it")
+}
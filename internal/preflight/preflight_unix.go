@@ -0,0 +1,51 @@
+//go:build !windows
+
+package preflight
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/mghoff/oraicwinconfig/internal/check"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// freeSpace returns the bytes available to the user on the volume holding path
+func freeSpace(ctx context.Context, path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(utils.NearestExistingDir(path), &st); err != nil {
+		return 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), nil
+}
+
+// checkDependencies verifies the tools and libraries the client needs are installed:
+// hdiutil and xattr on macOS, and the libaio library the Linux client links against
+func checkDependencies() check.Result {
+	if runtime.GOOS == "darwin" {
+		for _, tool := range []string{"hdiutil", "xattr", "codesign"} {
+			if _, err := exec.LookPath(tool); err != nil {
+				return check.Fail(tool+" not found on PATH", "install the Xcode command line tools: xcode-select --install")
+			}
+		}
+		return check.Pass("hdiutil, xattr and codesign found")
+	}
+
+	out, err := pwsh.Output(context.Background(), "ldconfig", "-p")
+	if err != nil {
+		return check.Warn("could not query the shared library cache to look for libaio", "make sure libaio is installed")
+	}
+	if !strings.Contains(string(out), "libaio.so.1") {
+		return check.Warn("libaio not found", "install it with your package manager, e.g. apt install libaio1 or dnf install libaio")
+	}
+	return check.Pass("libaio found")
+}
+
+// isMappedDrive reports whether path is on a mapped network drive, which only exists on Windows
+func isMappedDrive(path string) bool {
+	return false
+}
@@ -0,0 +1,58 @@
+package preflight
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"github.com/mghoff/oraicwinconfig/internal/check"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+var (
+	kernel32            = syscall.NewLazyDLL("kernel32.dll")
+	getDiskFreeSpaceExW = kernel32.NewProc("GetDiskFreeSpaceExW")
+	getDriveTypeW       = kernel32.NewProc("GetDriveTypeW")
+)
+
+// driveRemote is the GetDriveTypeW result for mapped network drives
+const driveRemote = 4
+
+// freeSpace returns the bytes available to the user on the volume holding path,
+// which may be a drive or a UNC share
+func freeSpace(ctx context.Context, path string) (uint64, error) {
+	p, err := syscall.UTF16PtrFromString(utils.NearestExistingDir(path) + `\`)
+	if err != nil {
+		return 0, err
+	}
+	var free uint64
+	if r, _, err := getDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&free)), 0, 0); r == 0 {
+		return 0, err
+	}
+	return free, nil
+}
+
+// isMappedDrive reports whether path is on a drive letter mapped to a network share.
+// Mappings belong to the logon session, so other users and elevated prompts may not see them.
+func isMappedDrive(path string) bool {
+	vol := filepath.VolumeName(path)
+	if len(vol) != 2 || vol[1] != ':' {
+		return false
+	}
+	p, err := syscall.UTF16PtrFromString(vol + `\`)
+	if err != nil {
+		return false
+	}
+	r, _, _ := getDriveTypeW.Call(uintptr(unsafe.Pointer(p)))
+	return r == driveRemote
+}
+
+// checkDependencies verifies the external programs the installer relies on are available
+func checkDependencies() check.Result {
+	if _, err := exec.LookPath("powershell"); err != nil {
+		return check.Fail("powershell not found on PATH", "add Windows PowerShell (%SystemRoot%\\System32\\WindowsPowerShell\\v1.0) to PATH")
+	}
+	return check.Pass("powershell found")
+}
@@ -0,0 +1,72 @@
+package pwsh
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf16"
+)
+
+// isQuote reports whether PowerShell treats r as a single quote. Besides the
+// ASCII apostrophe, the typographic quotes U+2018 to U+201B also open and
+// close single-quoted strings.
+func isQuote(r rune) bool {
+	switch r {
+	case '\'', '‘', '’', '‚', '‛':
+		return true
+	}
+	return false
+}
+
+// Quote returns s as a PowerShell single-quoted string literal. Nothing inside
+// a single-quoted string is expanded, and every quote character is doubled,
+// so the literal always evaluates to s.
+func Quote(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('\'')
+	for _, r := range s {
+		if isQuote(r) {
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// Encode returns script in the form expected by -EncodedCommand: base64 of its
+// UTF-16LE encoding. Scripts passed this way are not re-parsed by the command
+// line, so double quotes and backslashes reach PowerShell unchanged.
+func Encode(script string) string {
+	units := utf16.Encode([]rune(script))
+	buf := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(buf[2*i:], u)
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// Decode returns the script encoded by Encode
+func Decode(encoded string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(raw)%2 != 0 {
+		return "", errors.New("odd length of UTF-16 data")
+	}
+	units := make([]uint16, len(raw)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(raw[2*i:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// Args returns the powershell arguments running script without loading
+// profiles or prompting, with its output written as UTF-8
+func Args(script string) []string {
+	return []string{"-NoProfile", "-NonInteractive", "-EncodedCommand",
+		Encode("[Console]::OutputEncoding = [Text.Encoding]::UTF8\n" + script)}
+}
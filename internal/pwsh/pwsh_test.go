@@ -0,0 +1,98 @@
+package pwsh
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"os/exec"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// hostile holds values that break naive string building
+var hostile = []string{
+	`C:\Users\O'Brien\OraClient`,
+	`C:\a'; Remove-Item -Recurse -Force C:\; '`,
+	`C:\$(Start-Process calc)\$env:USERPROFILE`,
+	"C:\\tick`n`0\\x",
+	`C:\“double” and "straight" quotes`,
+	`C:\‘typographic’ ‚low‛ quotes`,
+	`C:\trailing backslash\`,
+	`C:\Ünïcödé\日本語\instantclient_23_7`,
+	`'`,
+	`''`,
+	``,
+}
+
+// unquote parses a PowerShell single-quoted literal the way the PowerShell
+// tokenizer does, failing if the literal ends early
+func unquote(t *testing.T, lit string) string {
+	t.Helper()
+	runes := []rune(lit)
+	if len(runes) < 2 || !isQuote(runes[0]) || !isQuote(runes[len(runes)-1]) {
+		t.Fatalf("%q is not a single-quoted literal", lit)
+	}
+	var b strings.Builder
+	body := runes[1 : len(runes)-1]
+	for i := 0; i < len(body); i++ {
+		if isQuote(body[i]) {
+			if i+1 >= len(body) || !isQuote(body[i+1]) {
+				t.Fatalf("%q ends before its last character", lit)
+			}
+			i++
+		}
+		b.WriteRune(body[i])
+	}
+	return b.String()
+}
+
+func TestQuote(t *testing.T) {
+	for _, s := range hostile {
+		lit := Quote(s)
+		if got := unquote(t, lit); got != s {
+			t.Errorf("Quote(%q) = %s, which evaluates to %q", s, lit, got)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, s := range hostile {
+		raw, err := base64.StdEncoding.DecodeString(Encode(s))
+		if err != nil {
+			t.Fatalf("Encode(%q) is not base64: %v", s, err)
+		}
+		units := make([]uint16, len(raw)/2)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(raw[2*i:])
+		}
+		if got := string(utf16.Decode(units)); got != s {
+			t.Errorf("Encode(%q) decodes to %q", s, got)
+		}
+		if got, err := Decode(Encode(s)); err != nil || got != s {
+			t.Errorf("Decode(Encode(%q)) = %q, %v", s, got, err)
+		}
+	}
+}
+
+// TestQuoteRoundTrip has PowerShell itself evaluate the quoted values, where it is installed
+func TestQuoteRoundTrip(t *testing.T) {
+	var shell string
+	for _, name := range []string{"powershell", "pwsh"} {
+		if path, err := exec.LookPath(name); err == nil {
+			shell = path
+			break
+		}
+	}
+	if shell == "" {
+		t.Skip("PowerShell not installed")
+	}
+	for _, s := range hostile {
+		out, err := exec.Command(shell, Args("Write-Output ("+Quote(s)+" + '|')")...).Output()
+		if err != nil {
+			t.Fatalf("running PowerShell with %q: %v", s, err)
+		}
+		if got := strings.TrimSuffix(strings.TrimRight(string(out), "\r\n"), "|"); got != s {
+			t.Errorf("PowerShell evaluated Quote(%q) to %q", s, got)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package pwsh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is the default limit of each external command
+const DefaultTimeout = time.Minute
+
+// CommandTimeout limits each PowerShell or other short external command run
+// through Output, so one that hangs, such as a profile script waiting on an
+// unreachable network drive, fails on its own rather than silently using up
+// the time limit of the whole phase. Zero disables the limit.
+var CommandTimeout = DefaultTimeout
+
+// waitDelay is how long a killed command's output is waited for, should a
+// process it started keep its output open
+const waitDelay = 5 * time.Second
+
+// Call is an external command run through this package
+type Call struct {
+	Name     string
+	Args     []string
+	Input    string // Standard input; may hold secrets
+	Combined bool   // Standard error is returned with standard output
+}
+
+// Runner runs a call and returns its output
+type Runner func(ctx context.Context, c Call) ([]byte, error)
+
+// runner runs every call; Intercept wraps it
+var runner Runner = execute
+
+// Intercept wraps the running of every later call with wrap, which is given
+// the runner it replaces, so a trace can record the commands a run depends
+// on or replay their recorded results instead
+func Intercept(wrap func(next Runner) Runner) {
+	runner = wrap(runner)
+}
+
+// Output runs name with args, killing it once ctx is done or CommandTimeout
+// has passed, and returns its standard output
+func Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return runner(ctx, Call{Name: name, Args: args})
+}
+
+// CombinedOutput is Output returning standard output and error together
+func CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return runner(ctx, Call{Name: name, Args: args, Combined: true})
+}
+
+// CombinedOutputInput is CombinedOutput with input as the command's
+// standard input, for secrets that must not appear among the arguments
+func CombinedOutputInput(ctx context.Context, input, name string, args ...string) ([]byte, error) {
+	return runner(ctx, Call{Name: name, Args: args, Input: input, Combined: true})
+}
+
+// Run runs script in Windows PowerShell and returns its standard output
+func Run(ctx context.Context, script string) ([]byte, error) {
+	return Output(ctx, "powershell", Args(script)...)
+}
+
+// execute runs the command with the command timeout, naming the timeout in
+// the error when it is what stopped the command
+func execute(ctx context.Context, c Call) ([]byte, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmdCtx, cancel := ctx, context.CancelFunc(func() {})
+	if CommandTimeout > 0 {
+		cmdCtx, cancel = context.WithTimeout(ctx, CommandTimeout)
+	}
+	defer cancel()
+
+	cmd := exec.CommandContext(cmdCtx, c.Name, c.Args...)
+	cmd.WaitDelay = waitDelay
+	if c.Input != "" {
+		cmd.Stdin = strings.NewReader(c.Input)
+	}
+	output := (*exec.Cmd).Output
+	if c.Combined {
+		output = (*exec.Cmd).CombinedOutput
+	}
+	out, err := output(cmd)
+	if err != nil && ctx.Err() == nil && errors.Is(cmdCtx.Err(), context.DeadlineExceeded) {
+		return out, fmt.Errorf("%s did not finish within %s and was stopped; raise --command-timeout if it is only slow: %w", c.Name, CommandTimeout, context.DeadlineExceeded)
+	}
+	return out, err
+}
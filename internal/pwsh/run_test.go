@@ -0,0 +1,35 @@
+package pwsh
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutputTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("no sleep command")
+	}
+	defer func(d time.Duration) { CommandTimeout = d }(CommandTimeout)
+	CommandTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	_, err := Output(context.Background(), "sleep", "10")
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "sleep did not finish within 100ms") {
+		t.Errorf("error = %v, want the command timeout", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("hung command stopped after %s", d)
+	}
+
+	// A cancelled run is not blamed on the command timeout
+	CommandTimeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := Output(ctx, "sleep", "10"); err == nil || strings.Contains(err.Error(), "did not finish") {
+		t.Errorf("error = %v, want the kill by cancellation", err)
+	}
+}
@@ -0,0 +1,176 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/events"
+)
+
+// HostEntry is a machine in an inventory; empty fields fall back to the inventory defaults
+type HostEntry struct {
+	Host      string   `json:"host"`
+	User      string   `json:"user,omitempty"`
+	Port      int      `json:"port,omitempty"`
+	Transport string   `json:"transport,omitempty"`
+	Binary    string   `json:"binary,omitempty"`
+	Args      []string `json:"args,omitempty"`
+}
+
+// Inventory lists the machines of a fleet install
+type Inventory struct {
+	Defaults HostEntry   `json:"defaults"`
+	Parallel int         `json:"parallel,omitempty"`
+	Hosts    []HostEntry `json:"hosts"`
+}
+
+// Result is the outcome of the install on one host
+type Result struct {
+	Host     string
+	Err      error
+	Step     string // Install step that failed, when the installer reported one
+	Duration time.Duration
+}
+
+// LoadInventory reads an inventory file. Files ending in .json hold an Inventory;
+// anything else is a plain list with one host per line and # comments.
+func LoadInventory(path string) (*Inventory, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "opening inventory file")
+	}
+	defer f.Close()
+
+	inv := &Inventory{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.NewDecoder(f).Decode(inv); err != nil {
+			return nil, errs.HandleError(err, errs.ErrorTypeValidation, "parsing inventory file")
+		}
+	} else {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			inv.Hosts = append(inv.Hosts, HostEntry{Host: line})
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, errs.HandleError(err, errs.ErrorTypeValidation, "reading inventory file")
+		}
+	}
+
+	for i, h := range inv.Hosts {
+		if h.Host == "" {
+			return nil, errs.HandleError(fmt.Errorf("entry %d has no host", i+1), errs.ErrorTypeValidation, "parsing inventory file")
+		}
+	}
+	if len(inv.Hosts) == 0 {
+		return nil, errs.HandleError(fmt.Errorf("no hosts listed in %s", path), errs.ErrorTypeValidation, "parsing inventory file")
+	}
+	return inv, nil
+}
+
+// resolve applies the inventory defaults to a host entry
+func (inv *Inventory) resolve(h HostEntry) (Target, Options) {
+	d := inv.Defaults
+	t := Target{Host: h.Host, User: h.User, Port: h.Port, Transport: h.Transport}
+	if t.User == "" {
+		t.User = d.User
+	}
+	if t.Port == 0 {
+		t.Port = d.Port
+	}
+	if t.Transport == "" {
+		t.Transport = d.Transport
+	}
+	opts := Options{Binary: h.Binary, Args: h.Args}
+	if opts.Binary == "" {
+		opts.Binary = d.Binary
+	}
+	if opts.Args == nil {
+		opts.Args = d.Args
+	}
+	return t, opts
+}
+
+// InstallFleet installs on every host of the inventory, at most parallel at a time,
+// and returns the results in inventory order. base supplies the binary and arguments
+// for hosts and defaults that do not set them, and where output goes.
+func InstallFleet(ctx context.Context, inv *Inventory, base Options, parallel int) []Result {
+	if parallel <= 0 {
+		parallel = inv.Parallel
+	}
+	if parallel <= 0 {
+		parallel = 4
+	}
+
+	results := make([]Result, len(inv.Hosts))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, h := range inv.Hosts {
+		wg.Add(1)
+		go func(i int, h HostEntry) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			t, opts := inv.resolve(h)
+			if opts.Binary == "" {
+				opts.Binary = base.Binary
+			}
+			if opts.Args == nil {
+				opts.Args = base.Args
+			}
+			opts.Output = base.Output
+
+			// Note the step that failed for the report
+			var failed string
+			if base.Events != nil {
+				opts.Events = func(e events.Event) {
+					if e.Kind == events.StepFailed {
+						failed = e.Step
+					}
+					base.Events(e)
+				}
+			}
+
+			start := time.Now()
+			err := Install(ctx, t, opts)
+			results[i] = Result{Host: h.Host, Err: err, Step: failed, Duration: time.Since(start).Round(time.Second)}
+		}(i, h)
+	}
+	wg.Wait()
+	return results
+}
+
+// Report writes a table of the fleet results to w and returns the number of failed hosts
+func Report(w io.Writer, results []Result) int {
+	failed := 0
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tSTATUS\tSTEP\tDURATION\tERROR")
+	for _, r := range results {
+		status, step, msg := "OK", "", ""
+		if r.Err != nil {
+			status, step, msg = "FAILED", r.Step, r.Err.Error()
+			failed++
+		}
+		if step == "" {
+			step = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Host, status, step, r.Duration, msg)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d of %d hosts succeeded\n", len(results)-failed, len(results))
+	return failed
+}
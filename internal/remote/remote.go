@@ -0,0 +1,212 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/events"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// Transports supported for reaching remote Windows machines
+const (
+	TransportSSH   = "ssh"
+	TransportWinRM = "winrm"
+)
+
+// remoteBinary is the file name the installer is copied to on the remote machine
+const remoteBinary = "oraicwinconfig.exe"
+
+// Target identifies a remote machine
+type Target struct {
+	Host      string // Host name or address
+	User      string // Optional user name; the current user's credentials are used when empty
+	Port      int    // Optional port; the transport's default when zero
+	Transport string // ssh or winrm
+}
+
+// String returns the target as user@host:port
+func (t Target) String() string {
+	s := t.Host
+	if t.User != "" {
+		s = t.User + "@" + s
+	}
+	if t.Port != 0 {
+		s += ":" + strconv.Itoa(t.Port)
+	}
+	return s
+}
+
+// Options controls what is run on the remote machine and where its output goes
+type Options struct {
+	Binary string         // Local path of the Windows installer binary to copy
+	Args   []string       // Extra arguments for the remote installer; --yes is always added
+	Output io.Writer      // Receives the remote output, each line prefixed with the host
+	Events events.Handler // Receives the remote installer's step events, with Host set, instead of Output; nil shows them as output
+}
+
+// Install copies the installer to the target, runs it unattended, streams its
+// output back and removes the copied binary afterwards. With opts.Events set,
+// the installer is asked for its step events, which must be supported by the
+// copied binary.
+func Install(ctx context.Context, t Target, opts Options) error {
+	ctx = utils.EnsureContext(ctx)
+	args := append([]string{"--yes"}, opts.Args...)
+	out := NewPrefixWriter(opts.Output, "["+t.Host+"] ")
+	if opts.Events != nil {
+		args = append(args, "--events")
+		out.events = func(e events.Event) {
+			e.Host = t.Host
+			opts.Events(e)
+		}
+	}
+	defer out.Flush()
+
+	switch t.Transport {
+	case TransportSSH, "":
+		return installSSH(ctx, t, opts.Binary, args, out)
+	case TransportWinRM:
+		return installWinRM(ctx, t, opts.Binary, args, out)
+	default:
+		return errs.HandleError(fmt.Errorf("unknown transport: %s", t.Transport), errs.ErrorTypeValidation, "remote install")
+	}
+}
+
+// installSSH uses the OpenSSH client to copy the binary into the remote user's
+// home directory and run it there
+func installSSH(ctx context.Context, t Target, binary string, args []string, out io.Writer) error {
+	dest := t.Host
+	if t.User != "" {
+		dest = t.User + "@" + t.Host
+	}
+	var scpOpts, sshOpts []string
+	if t.Port != 0 {
+		scpOpts = []string{"-P", strconv.Itoa(t.Port)}
+		sshOpts = []string{"-p", strconv.Itoa(t.Port)}
+	}
+
+	fmt.Fprintf(out, "copying %s over SSH...\n", binary)
+	if err := run(ctx, out, "scp", append(scpOpts, "-q", binary, dest+":"+remoteBinary)...); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "copying installer to "+t.Host)
+	}
+	defer run(context.Background(), io.Discard, "ssh", append(sshOpts, dest, "del "+remoteBinary)...)
+
+	fmt.Fprintln(out, "running installer...")
+	cmd := append(sshOpts, "-T", dest, sshCommand(args))
+	if err := run(ctx, out, "ssh", cmd...); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "running installer on "+t.Host)
+	}
+	return nil
+}
+
+// sshCommand returns the remote command line running the copied installer
+// with args. It runs through PowerShell with the script encoded, so neither
+// cmd.exe nor PowerShell as the remote shell splits or interprets the
+// arguments, which are quoted for PowerShell. The installer is run from the
+// current directory, which PowerShell does not search by itself.
+func sshCommand(args []string) string {
+	script := "& .\\" + remoteBinary
+	for _, a := range args {
+		script += " " + pwsh.Quote(a)
+	}
+	script += "\nexit $LASTEXITCODE"
+	return strings.Join(append([]string{"powershell"}, pwsh.Args(script)...), " ")
+}
+
+// installWinRM uses a PowerShell remoting session to copy the binary into the
+// remote user's temp directory and run it there
+func installWinRM(ctx context.Context, t Target, binary string, args []string, out io.Writer) error {
+	session := "New-PSSession -ComputerName " + pwsh.Quote(t.Host)
+	if t.Port != 0 {
+		session += " -Port " + strconv.Itoa(t.Port)
+	}
+	if t.User != "" {
+		session += " -Credential (Get-Credential -UserName " + pwsh.Quote(t.User) + " -Message " + pwsh.Quote("Credentials for "+t.Host) + ")"
+	}
+	quotedArgs := make([]string, len(args))
+	for i, a := range args {
+		quotedArgs[i] = pwsh.Quote(a)
+	}
+
+	script := strings.Join([]string{
+		"$ErrorActionPreference = 'Stop'",
+		"$s = " + session,
+		"try {",
+		"  $dst = Invoke-Command -Session $s { Join-Path $env:TEMP '" + remoteBinary + "' }",
+		"  Write-Output ('copying ' + " + pwsh.Quote(binary) + " + ' over WinRM...')",
+		"  Copy-Item -ToSession $s -Path " + pwsh.Quote(binary) + " -Destination $dst -Force",
+		"  Write-Output 'running installer...'",
+		"  Invoke-Command -Session $s -ScriptBlock { param($exe, $a) & $exe @a 2>&1 | ForEach-Object { \"$_\" }; $global:exitCode = $LASTEXITCODE } -ArgumentList $dst, @(" + strings.Join(quotedArgs, ", ") + ")",
+		"  $code = Invoke-Command -Session $s { $global:exitCode }",
+		"  Invoke-Command -Session $s { param($exe) Remove-Item $exe -Force } -ArgumentList $dst",
+		"  if ($code -ne 0) { throw \"installer exited with code $code\" }",
+		"} finally { Remove-PSSession $s }",
+	}, "\n")
+	if err := run(ctx, out, "powershell", pwsh.Args(script)...); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeInstall, "running installer on "+t.Host)
+	}
+	return nil
+}
+
+// run executes a local command, streaming its combined output to out
+func run(ctx context.Context, out io.Writer, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
+
+// PrefixWriter prefixes every line written to it, so output from several hosts stays readable
+type PrefixWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+	events events.Handler // Receives event lines instead of w, when set
+}
+
+// NewPrefixWriter wraps w so each line starts with prefix
+func NewPrefixWriter(w io.Writer, prefix string) *PrefixWriter {
+	return &PrefixWriter{w: w, prefix: prefix}
+}
+
+// Write buffers p and writes out every complete line with the prefix
+func (p *PrefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, b...)
+	for {
+		i := strings.IndexByte(string(p.buf), '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(p.buf[:i]), "\r")
+		if e, ok := events.Parse(line); ok && p.events != nil {
+			p.events(e)
+			p.buf = p.buf[i+1:]
+			continue
+		}
+		if _, err := fmt.Fprintf(p.w, "%s%s\n", p.prefix, line); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes any trailing partial line
+func (p *PrefixWriter) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.buf) > 0 {
+		fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.buf)
+		p.buf = nil
+	}
+}
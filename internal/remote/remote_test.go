@@ -0,0 +1,24 @@
+package remote
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+)
+
+func TestSSHCommand(t *testing.T) {
+	cmd := sshCommand([]string{"--yes", "--install-path", `C:\Program Files\Oracle`, "--post-install", "echo a & del b; $x", "it's"})
+	fields := strings.Fields(cmd)
+	if len(fields) != 5 || fields[0] != "powershell" || fields[3] != "-EncodedCommand" {
+		t.Fatalf("command line %q", cmd)
+	}
+	script, err := pwsh.Decode(fields[4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `& .\oraicwinconfig.exe '--yes' '--install-path' 'C:\Program Files\Oracle' '--post-install' 'echo a & del b; $x' 'it''s'` + "\nexit $LASTEXITCODE"
+	if !strings.HasSuffix(script, "\n"+want) {
+		t.Errorf("script:\n%s\nwant it to end with:\n%s", script, want)
+	}
+}
@@ -0,0 +1,10 @@
+// Package sspi produces the tokens of Windows integrated authentication with
+// the credentials of the logged-on user, for proxies that accept no others
+package sspi
+
+// Security packages, named as in the WWW-Authenticate and Proxy-Authenticate
+// headers offering them
+const (
+	Negotiate = "Negotiate" // Kerberos where the domain supports it, otherwise NTLM
+	NTLM      = "NTLM"
+)
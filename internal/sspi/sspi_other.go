@@ -0,0 +1,29 @@
+//go:build !windows
+
+package sspi
+
+import (
+	"fmt"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// Context is a client authentication handshake, which needs SSPI and so
+// Windows
+type Context struct{}
+
+// New starts a handshake, which fails outside Windows
+func New(pkg, target string) (*Context, error) {
+	return nil, errs.HandleError(
+		fmt.Errorf("%s authentication uses the Windows logon and is not available on this platform; put the proxy credentials in the proxy URL instead", pkg),
+		errs.ErrorTypeValidation,
+		"starting proxy authentication")
+}
+
+// Step is never reached outside Windows
+func (c *Context) Step(challenge []byte) ([]byte, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+// Close does nothing outside Windows
+func (c *Context) Close() {}
@@ -0,0 +1,140 @@
+package sspi
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+var (
+	secur32                    = syscall.NewLazyDLL("secur32.dll")
+	acquireCredentialsHandleW  = secur32.NewProc("AcquireCredentialsHandleW")
+	initializeSecurityContextW = secur32.NewProc("InitializeSecurityContextW")
+	deleteSecurityContext      = secur32.NewProc("DeleteSecurityContext")
+	freeCredentialsHandle      = secur32.NewProc("FreeCredentialsHandle")
+	freeContextBuffer          = secur32.NewProc("FreeContextBuffer")
+)
+
+// SSPI constants used by the handshake
+const (
+	secpkgCredOutbound    = 2
+	securityNativeDrep    = 0x10
+	secbufferVersion      = 0
+	secbufferToken        = 2
+	iscReqAllocateMemory  = 0x100
+	iscReqConnection      = 0x800
+	secEOK                = 0
+	secIContinueNeeded    = 0x00090312
+	maxHandshakeTokenSize = 64 << 10
+)
+
+// secHandle mirrors the Win32 SecHandle, used for credential and context handles
+type secHandle struct {
+	lower, upper uintptr
+}
+
+// secBuffer mirrors the Win32 SecBuffer
+type secBuffer struct {
+	size       uint32
+	bufferType uint32
+	buffer     *byte
+}
+
+// secBufferDesc mirrors the Win32 SecBufferDesc
+type secBufferDesc struct {
+	version uint32
+	count   uint32
+	buffers *secBuffer
+}
+
+// Context is a client authentication handshake with one server
+type Context struct {
+	target  *uint16
+	cred    secHandle
+	ctx     secHandle
+	started bool
+}
+
+// New starts a handshake with pkg, Negotiate or NTLM, for the server whose
+// service principal is target, such as HTTP/proxy.corp.example, using the
+// credentials of the logged-on user
+func New(pkg, target string) (*Context, error) {
+	pkgName, err := syscall.UTF16PtrFromString(pkg)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "starting proxy authentication")
+	}
+	targetName, err := syscall.UTF16PtrFromString(target)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "starting proxy authentication")
+	}
+	c := &Context{target: targetName}
+	var expiry int64
+	r, _, _ := acquireCredentialsHandleW.Call(
+		0,
+		uintptr(unsafe.Pointer(pkgName)),
+		secpkgCredOutbound,
+		0, 0, 0, 0,
+		uintptr(unsafe.Pointer(&c.cred)),
+		uintptr(unsafe.Pointer(&expiry)))
+	if status := uint32(r); status != secEOK {
+		return nil, errs.HandleError(fmt.Errorf("acquiring %s credentials of the logged-on user: SSPI status 0x%08X", pkg, status), errs.ErrorTypeDownload, "starting proxy authentication")
+	}
+	return c, nil
+}
+
+// Step returns the next token to send, given the server's last challenge,
+// which is empty for the first
+func (c *Context) Step(challenge []byte) ([]byte, error) {
+	var input *secBufferDesc
+	if len(challenge) > 0 {
+		in := secBuffer{size: uint32(len(challenge)), bufferType: secbufferToken, buffer: &challenge[0]}
+		input = &secBufferDesc{version: secbufferVersion, count: 1, buffers: &in}
+	}
+	out := secBuffer{bufferType: secbufferToken}
+	output := secBufferDesc{version: secbufferVersion, count: 1, buffers: &out}
+
+	var current *secHandle
+	if c.started {
+		current = &c.ctx
+	}
+	var attrs uint32
+	var expiry int64
+	r, _, _ := initializeSecurityContextW.Call(
+		uintptr(unsafe.Pointer(&c.cred)),
+		uintptr(unsafe.Pointer(current)),
+		uintptr(unsafe.Pointer(c.target)),
+		iscReqAllocateMemory|iscReqConnection,
+		0,
+		securityNativeDrep,
+		uintptr(unsafe.Pointer(input)),
+		0,
+		uintptr(unsafe.Pointer(&c.ctx)),
+		uintptr(unsafe.Pointer(&output)),
+		uintptr(unsafe.Pointer(&attrs)),
+		uintptr(unsafe.Pointer(&expiry)))
+	if out.buffer != nil {
+		defer freeContextBuffer.Call(uintptr(unsafe.Pointer(out.buffer)))
+	}
+	if status := uint32(r); status != secEOK && status != secIContinueNeeded {
+		return nil, errs.HandleError(fmt.Errorf("SSPI status 0x%08X", status), errs.ErrorTypeDownload, "authenticating to the proxy")
+	}
+	c.started = true
+	if out.size > maxHandshakeTokenSize {
+		return nil, errs.HandleError(fmt.Errorf("token of %d bytes is implausibly large", out.size), errs.ErrorTypeDownload, "authenticating to the proxy")
+	}
+	token := make([]byte, out.size)
+	if out.size > 0 {
+		copy(token, unsafe.Slice(out.buffer, out.size))
+	}
+	return token, nil
+}
+
+// Close releases the handshake's context and credentials
+func (c *Context) Close() {
+	if c.started {
+		deleteSecurityContext.Call(uintptr(unsafe.Pointer(&c.ctx)))
+	}
+	freeCredentialsHandle.Call(uintptr(unsafe.Pointer(&c.cred)))
+}
@@ -0,0 +1,136 @@
+package status
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/manifest"
+)
+
+// State describes how a setting compares with the manifest
+type State string
+
+const (
+	StateOK        State = "ok"        // Matches the manifest
+	StateEdited    State = "edited"    // Set to a different value than recorded
+	StateRemoved   State = "removed"   // Recorded but no longer set
+	StateDangling  State = "dangling"  // Points at a directory that no longer exists
+	StateUnmanaged State = "unmanaged" // Set, but not recorded by the installer
+)
+
+// Drifted reports whether the state differs from what the manifest records
+func (s State) Drifted() bool {
+	return s != StateOK
+}
+
+// Row compares one setting with its recorded value
+type Row struct {
+	Setting  string // Variable name, or PATH
+	Expected string
+	Actual   string
+	State    State
+}
+
+// clientVars are the variables naming a client directory
+var clientVars = []string{"OCI_LIB64", "OCI_LIB32"}
+
+// Compare returns the expected and actual value of every setting recorded in the manifest,
+// followed by client variables that are set but were not recorded
+func Compare(m *manifest.Manifest, mgr env.Manager) []Row {
+	var rows []Row
+	var path []string
+	if p, err := mgr.GetEnvVar("PATH"); err == nil {
+		path = filepath.SplitList(p)
+	}
+
+	recorded := make(map[string]bool)
+	for _, c := range m.Clients {
+		names := make([]string, 0, len(c.Vars))
+		for name := range c.Vars {
+			names = append(names, name)
+		}
+		// The client variable first, then the rest by name
+		sort.Slice(names, func(i, j int) bool {
+			return names[i] == c.LibVar || (names[j] != c.LibVar && names[i] < names[j])
+		})
+		for _, name := range names {
+			recorded[name] = true
+			// Extra variables hold arbitrary values rather than directories
+			_, extra := c.ExtraEnv[name]
+			rows = append(rows, compareVar(mgr, name, c.Vars[name], !extra))
+		}
+		for _, dir := range c.Path {
+			rows = append(rows, comparePath(path, dir))
+		}
+	}
+
+	for _, name := range clientVars {
+		if recorded[name] {
+			continue
+		}
+		if actual, err := mgr.GetEnvVar(name); err == nil {
+			rows = append(rows, Row{Setting: name, Expected: "-", Actual: actual, State: StateUnmanaged})
+		}
+	}
+	return rows
+}
+
+// compareVar compares a variable with its recorded value; a directory must also exist
+func compareVar(mgr env.Manager, name, expected string, dir bool) Row {
+	row := Row{Setting: name, Expected: expected, Actual: "-"}
+	actual, err := mgr.GetEnvVar(name)
+	switch {
+	case err != nil:
+		row.State = StateRemoved
+	case !env.SamePath(actual, expected):
+		row.Actual, row.State = actual, StateEdited
+	case !dir:
+		row.Actual, row.State = actual, StateOK
+	default:
+		row.Actual, row.State = actual, dirState(actual)
+	}
+	return row
+}
+
+// comparePath checks a recorded directory is still on PATH
+func comparePath(path []string, dir string) Row {
+	row := Row{Setting: "PATH", Expected: dir, Actual: "-", State: StateRemoved}
+	for _, p := range path {
+		if env.SamePath(p, dir) {
+			row.Actual, row.State = p, dirState(p)
+		}
+	}
+	return row
+}
+
+// dirState returns StateOK if dir exists, and StateDangling otherwise
+func dirState(dir string) State {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return StateDangling
+	}
+	return StateOK
+}
+
+// Report writes the rows as a table and returns how many have drifted
+func Report(w io.Writer, rows []Row) int {
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "  nothing recorded or configured")
+		return 0
+	}
+	drift := 0
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  SETTING\tSTATE\tEXPECTED\tACTUAL")
+	for _, r := range rows {
+		if r.State.Drifted() {
+			drift++
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", r.Setting, r.State, r.Expected, r.Actual)
+	}
+	tw.Flush()
+	return drift
+}
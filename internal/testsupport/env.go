@@ -0,0 +1,255 @@
+package testsupport
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// Env is an in-memory env.Manager holding the variables of both scopes, so
+// installs can be tested without the registry or a shell profile. Copies made
+// by WithScope and WithContext share the variables.
+type Env struct {
+	vars      *envVars
+	scope     env.Scope
+	ctx       context.Context
+	downloads string
+}
+
+// envVars holds the variables of each scope; PATH is stored as one value
+type envVars struct {
+	mu     sync.Mutex
+	scopes map[env.Scope]map[string]string
+}
+
+// NewEnv returns an empty environment managing scope, whose downloads
+// directory is downloads
+func NewEnv(scope env.Scope, downloads string) *Env {
+	return &Env{
+		vars: &envVars{scopes: map[env.Scope]map[string]string{
+			env.ScopeUser:    {},
+			env.ScopeMachine: {},
+		}},
+		scope:     scope,
+		downloads: downloads,
+	}
+}
+
+// Vars returns a copy of the variables set in scope
+func (e *Env) Vars(scope env.Scope) map[string]string {
+	e.vars.mu.Lock()
+	defer e.vars.mu.Unlock()
+	out := make(map[string]string, len(e.vars.scopes[scope]))
+	for name, value := range e.vars.scopes[scope] {
+		out[name] = value
+	}
+	return out
+}
+
+// Dump renders the variables of both scopes, sorted by name, one per line with
+// PATH entries on lines of their own. Occurrences of root become $ROOT and
+// separators become slashes, so the output is the same on every host.
+func (e *Env) Dump(root string) string {
+	var b strings.Builder
+	for _, scope := range []env.Scope{env.ScopeUser, env.ScopeMachine} {
+		fmt.Fprintf(&b, "[%s]\n", scope)
+		vars := e.Vars(scope)
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if name == "PATH" {
+				fmt.Fprintf(&b, "%s=\n", name)
+				for _, entry := range filepath.SplitList(vars[name]) {
+					fmt.Fprintf(&b, "  %s\n", Normalize(entry, root))
+				}
+				continue
+			}
+			fmt.Fprintf(&b, "%s=%s\n", name, Normalize(vars[name], root))
+		}
+	}
+	return b.String()
+}
+
+// FetchUserDownloadsPath returns the downloads directory given to NewEnv
+func (e *Env) FetchUserDownloadsPath() (string, error) {
+	if e.downloads == "" {
+		return "", errs.HandleError(fmt.Errorf("no downloads directory"), errs.ErrorTypeUserPath, "getting user downloads directory")
+	}
+	return e.downloads, nil
+}
+
+// GetEnvVar retrieves a variable of the managed scope
+func (e *Env) GetEnvVar(name string) (string, error) {
+	e.vars.mu.Lock()
+	value := e.vars.scopes[e.scope][name]
+	e.vars.mu.Unlock()
+	if value == "" {
+		return "", errs.HandleError(
+			fmt.Errorf("environment variable %s not found", name),
+			errs.ErrorTypeEnvVarNotFound,
+			fmt.Sprintf("getting %s environment variable", name))
+	}
+	return value, nil
+}
+
+// ValidateEnvVar checks that a variable is set and points to an existing directory
+func (e *Env) ValidateEnvVar(name string) (string, error) {
+	path, err := env.CheckEnvVar(e, name)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("%s environment variable found: %s\n", name, path)
+	return path, nil
+}
+
+// SetEnvVar sets a variable of the managed scope
+func (e *Env) SetEnvVar(name, value string) error {
+	return e.update(func(vars map[string]string) {
+		vars[name] = value
+	})
+}
+
+// RemoveEnvVar removes a variable of the managed scope
+func (e *Env) RemoveEnvVar(name string) error {
+	return e.update(func(vars map[string]string) {
+		delete(vars, name)
+	})
+}
+
+// AppendToPath adds a directory to PATH, unless the PATH of this scope or of
+// the machine scope already has it
+func (e *Env) AppendToPath(newPath string) error {
+	for _, scope := range []env.Scope{e.scope, env.ScopeMachine} {
+		if env.PathContains(e.Vars(scope)["PATH"], newPath) {
+			fmt.Printf("path %s already exists in the %s PATH\n", newPath, scope)
+			return nil
+		}
+	}
+	return e.update(func(vars map[string]string) {
+		vars["PATH"] = strings.Join(append(filepath.SplitList(vars["PATH"]), newPath), string(os.PathListSeparator))
+	})
+}
+
+// RemoveFromPath removes a directory from PATH
+func (e *Env) RemoveFromPath(pathToRemove string) error {
+	return e.update(func(vars map[string]string) {
+		var entries []string
+		for _, entry := range filepath.SplitList(vars["PATH"]) {
+			if !env.PathContains(entry, pathToRemove) {
+				entries = append(entries, entry)
+			}
+		}
+		setPath(vars, entries)
+	})
+}
+
+// MovePathBefore moves a directory ahead of another on PATH if both are
+// present and it currently comes after
+func (e *Env) MovePathBefore(path, before string) error {
+	return e.update(func(vars map[string]string) {
+		entries := filepath.SplitList(vars["PATH"])
+		at, beforeAt := -1, -1
+		for i, entry := range entries {
+			if at < 0 && env.PathContains(entry, path) {
+				at = i
+			}
+			if beforeAt < 0 && env.PathContains(entry, before) {
+				beforeAt = i
+			}
+		}
+		if at < 0 || beforeAt < 0 || at < beforeAt {
+			return
+		}
+		moved := entries[at]
+		entries = append(entries[:at], entries[at+1:]...)
+		entries = append(entries[:beforeAt], append([]string{moved}, entries[beforeAt:]...)...)
+		setPath(vars, entries)
+	})
+}
+
+// InvalidateCache is a no-op; nothing is cached
+func (e *Env) InvalidateCache(names ...string) {}
+
+// WithContext returns a copy of the environment bound to ctx
+func (e *Env) WithContext(ctx context.Context) env.Manager {
+	c := *e
+	c.ctx = ctx
+	return &c
+}
+
+// Scope returns the scope variables are read from and written to
+func (e *Env) Scope() env.Scope {
+	return e.scope
+}
+
+// WithScope returns a copy of the environment managing another scope
+func (e *Env) WithScope(scope env.Scope) env.Manager {
+	c := *e
+	c.scope = scope
+	return &c
+}
+
+// Snapshot captures the current values of the named variables
+func (e *Env) Snapshot(names ...string) (env.Snapshot, error) {
+	vars := e.Vars(e.scope)
+	s := env.Snapshot{Names: names, Values: make(map[string]string)}
+	for _, name := range names {
+		if value, ok := vars[name]; ok {
+			s.Values[name] = value
+		}
+	}
+	return s, nil
+}
+
+// Restore puts back the values captured by Snapshot, unsetting those that were unset
+func (e *Env) Restore(s env.Snapshot) error {
+	return e.update(func(vars map[string]string) {
+		for _, name := range s.Names {
+			if value, ok := s.Values[name]; ok {
+				vars[name] = value
+			} else {
+				delete(vars, name)
+			}
+		}
+	})
+}
+
+// update applies fn to the variables of the managed scope unless the context is done
+func (e *Env) update(fn func(map[string]string)) error {
+	if e.ctx != nil {
+		if err := e.ctx.Err(); err != nil {
+			return errs.HandleError(err, errs.ErrorTypeEnvironment, "context cancellation")
+		}
+	}
+	e.vars.mu.Lock()
+	defer e.vars.mu.Unlock()
+	fn(e.vars.scopes[e.scope])
+	return nil
+}
+
+// setPath stores the PATH entries, removing PATH once it is empty
+func setPath(vars map[string]string, entries []string) {
+	if len(entries) == 0 {
+		delete(vars, "PATH")
+		return
+	}
+	vars["PATH"] = strings.Join(entries, string(os.PathListSeparator))
+}
+
+// Normalize replaces root in s with $ROOT and uses slashes as separators
+func Normalize(s, root string) string {
+	if root != "" {
+		s = strings.ReplaceAll(s, root, "$ROOT")
+	}
+	return filepath.ToSlash(s)
+}
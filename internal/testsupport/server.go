@@ -0,0 +1,150 @@
+// Package testsupport provides an in-process stand-in for Oracle's download
+// site, so the download, extract and install pipeline can be tested end to end
+// without network access.
+package testsupport
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/testutil"
+)
+
+// Behavior selects how the server answers requests for the archives
+type Behavior int
+
+const (
+	Valid           Behavior = iota // A matching package and SDK of one version
+	Corrupt                         // Truncated archives that are not valid zips
+	VersionMismatch                 // An SDK of another version than the package
+	Slow                            // Valid archives trickled out in small chunks
+	NotFound                        // 404 Not Found for every file
+)
+
+// ClientDir is the versioned directory of the served package
+const ClientDir = "instantclient_23_7"
+
+// mismatchedClientDir is the directory of the SDK served with VersionMismatch
+const mismatchedClientDir = "instantclient_21_9"
+
+// slowChunk is the number of bytes Slow writes between pauses
+const slowChunk = 512
+
+// Server is an httptest server serving a synthetic package, SDK and SQL*Plus
+// archive under the file names of the configuration it was last given to Configure
+type Server struct {
+	*httptest.Server
+	Pkg       []byte        // Package archive served
+	Sdk       []byte        // SDK archive served
+	SQLPlus   []byte        // SQL*Plus archive served
+	SlowDelay time.Duration // Pause between chunks with Slow
+
+	behavior Behavior
+	pkgFile  string
+	sdkFile  string
+	sqlplus  string
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+// NewServer starts a server with the given behavior. It is closed when the
+// test ends.
+func NewServer(t testing.TB, behavior Behavior) *Server {
+	t.Helper()
+	s := &Server{
+		SlowDelay: 20 * time.Millisecond,
+		behavior:  behavior,
+		requests:  make(map[string]int),
+	}
+	sdkDir := ClientDir
+	if behavior == VersionMismatch {
+		sdkDir = mismatchedClientDir
+	}
+	s.Pkg = encode(t, testutil.ZipFixture{Dir: ClientDir, Files: 3, FileSize: 1 << 10, Seed: 1})
+	s.Sdk = encode(t, testutil.ZipFixture{Dir: sdkDir, Subdir: "sdk", Files: 2, FileSize: 1 << 10, Seed: 2})
+	s.SQLPlus = encode(t, testutil.ZipFixture{Dir: ClientDir, Files: 2, FileSize: 1 << 10, Seed: 3})
+	if behavior == Corrupt {
+		s.Pkg, s.Sdk = s.Pkg[:len(s.Pkg)/2], s.Sdk[:len(s.Sdk)/2]
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Configure points conf at the server, serving the archives under conf's file
+// names, and lifts the download size limits, which the small synthetic
+// archives fall below
+func (s *Server) Configure(conf *config.InstallConfig) {
+	s.mu.Lock()
+	s.pkgFile, s.sdkFile, s.sqlplus = conf.PkgFile, conf.SdkFile, conf.SQLPlusFile()
+	s.mu.Unlock()
+	conf.BaseURL = s.URL + "/"
+	conf.PkgSize, conf.SdkSize, conf.SQLPlusSize = config.SizeLimits{}, config.SizeLimits{}, config.SizeLimits{}
+}
+
+// Requests returns how many times file was requested
+func (s *Server) Requests(file string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[file]
+}
+
+// serve answers a request for an archive according to the behavior
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	file := path.Base(r.URL.Path)
+	s.mu.Lock()
+	s.requests[file]++
+	var data []byte
+	switch file {
+	case s.pkgFile:
+		data = s.Pkg
+	case s.sdkFile:
+		data = s.Sdk
+	case s.sqlplus:
+		data = s.SQLPlus
+	}
+	s.mu.Unlock()
+	if data == nil || s.behavior == NotFound {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	if s.behavior != Slow {
+		http.ServeContent(w, r, file, time.Time{}, bytes.NewReader(data))
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	for len(data) > 0 {
+		n := min(slowChunk, len(data))
+		if _, err := w.Write(data[:n]); err != nil {
+			return
+		}
+		data = data[n:]
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(s.SlowDelay):
+		}
+	}
+}
+
+// encode returns the bytes of a fixture archive
+func encode(t testing.TB, z testutil.ZipFixture) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := z.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,80 @@
+package testutil
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ZipFixture describes a synthetic Instant Client style archive
+type ZipFixture struct {
+	Dir          string // Top-level directory, e.g. instantclient_23_7
+	Subdir       string // Directory within Dir holding the files, e.g. sdk; empty puts them in Dir
+	Files        int    // Number of files inside Dir
+	FileSize     int    // Size in bytes of each file
+	Compressible bool   // Fill files with repetitive rather than random data
+	Seed         int64  // Seed for the random contents, for reproducible archives
+}
+
+// DefaultZipFixture returns a fixture roughly shaped like the Basic Light package
+func DefaultZipFixture() ZipFixture {
+	return ZipFixture{
+		Dir:      "instantclient_23_7",
+		Files:    40,
+		FileSize: 1 << 20,
+		Seed:     1,
+	}
+}
+
+// Size returns the total uncompressed size of the fixture's files
+func (z ZipFixture) Size() int64 {
+	return int64(z.Files) * int64(z.FileSize)
+}
+
+// Write creates the archive at path, creating parent directories as needed
+func (z ZipFixture) Write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := z.Encode(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Encode writes the archive to w
+func (z ZipFixture) Encode(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	if _, err := zw.Create(z.Dir + "/"); err != nil {
+		return err
+	}
+
+	rng := rand.New(rand.NewSource(z.Seed))
+	buf := make([]byte, z.FileSize)
+	for i := 0; i < z.Files; i++ {
+		if z.Compressible {
+			for j := range buf {
+				buf[j] = byte('a' + (i+j)%16)
+			}
+		} else {
+			rng.Read(buf)
+		}
+		fw, err := zw.Create(path.Join(z.Dir, z.Subdir, fmt.Sprintf("file%03d.dll", i)))
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
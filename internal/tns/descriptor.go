@@ -0,0 +1,140 @@
+package tns
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Node is a parameter of a connect descriptor, e.g. (HOST=db01) or (ADDRESS=...)
+type Node struct {
+	Name     string
+	Value    string // Set for leaf parameters
+	Children []Node // Set for parameters holding a list
+}
+
+// Address is a network endpoint from a connect descriptor
+type Address struct {
+	Protocol string `json:"protocol"`
+	Host     string `json:"host,omitempty"`
+	Port     string `json:"port,omitempty"`
+}
+
+// String returns the address as host:port
+func (a Address) String() string {
+	return a.Host + ":" + a.Port
+}
+
+// ParseDescriptor parses a connect descriptor into its top-level parameters
+func ParseDescriptor(s string) ([]Node, error) {
+	p := &descParser{s: s}
+	nodes, err := p.list()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.i < len(p.s) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.s[p.i], p.i)
+	}
+	return nodes, nil
+}
+
+// Addresses returns the endpoints of the entry's descriptor; PROTOCOL
+// defaults to TCP and PORT to 1521 when omitted. Network addresses
+// without a HOST are dropped.
+func (e Entry) Addresses() ([]Address, error) {
+	nodes, err := ParseDescriptor(e.Descriptor)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []Address
+	walk(nodes, func(n Node) {
+		if !strings.EqualFold(n.Name, "ADDRESS") {
+			return
+		}
+		a := Address{Protocol: "TCP", Port: "1521"}
+		for _, c := range n.Children {
+			switch strings.ToUpper(c.Name) {
+			case "PROTOCOL":
+				a.Protocol = strings.ToUpper(c.Value)
+			case "HOST":
+				a.Host = c.Value
+			case "PORT":
+				a.Port = c.Value
+			}
+		}
+		if a.Host != "" || (a.Protocol != "TCP" && a.Protocol != "TCPS") {
+			addrs = append(addrs, a)
+		}
+	})
+	return addrs, nil
+}
+
+// walk calls fn for every node, parents before children
+func walk(nodes []Node, fn func(Node)) {
+	for _, n := range nodes {
+		fn(n)
+		walk(n.Children, fn)
+	}
+}
+
+// descParser is a recursive descent parser for connect descriptors
+type descParser struct {
+	s string
+	i int
+}
+
+// list parses a sequence of (NAME=...) parameters
+func (p *descParser) list() ([]Node, error) {
+	var nodes []Node
+	for {
+		p.skipSpace()
+		if p.i >= len(p.s) || p.s[p.i] != '(' {
+			return nodes, nil
+		}
+		n, err := p.node()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+}
+
+// node parses a single (NAME=value) or (NAME=(...)...) parameter
+func (p *descParser) node() (Node, error) {
+	p.i++ // '('
+	eq := strings.IndexByte(p.s[p.i:], '=')
+	if eq < 0 {
+		return Node{}, fmt.Errorf("missing '=' after offset %d", p.i)
+	}
+	n := Node{Name: strings.TrimSpace(p.s[p.i : p.i+eq])}
+	p.i += eq + 1
+	p.skipSpace()
+
+	if p.i < len(p.s) && p.s[p.i] == '(' {
+		children, err := p.list()
+		if err != nil {
+			return Node{}, err
+		}
+		n.Children = children
+	} else {
+		end := strings.IndexByte(p.s[p.i:], ')')
+		if end < 0 {
+			return Node{}, fmt.Errorf("unterminated value for %s", n.Name)
+		}
+		n.Value = strings.TrimSpace(p.s[p.i : p.i+end])
+		p.i += end
+	}
+
+	p.skipSpace()
+	if p.i >= len(p.s) || p.s[p.i] != ')' {
+		return Node{}, fmt.Errorf("missing ')' after %s", n.Name)
+	}
+	p.i++
+	return n, nil
+}
+
+// skipSpace advances past whitespace
+func (p *descParser) skipSpace() {
+	for p.i < len(p.s) && isSpace(p.s[p.i]) {
+		p.i++
+	}
+}
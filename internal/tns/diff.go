@@ -0,0 +1,77 @@
+package tns
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Change is a net service name defined differently in two versions of a
+// tnsnames.ora file
+type Change struct {
+	Alias string
+	Old   string // Descriptor before; empty when the alias was added
+	New   string // Descriptor after; empty when the alias was removed
+}
+
+// Compare returns the aliases added, removed or given another descriptor
+// between old and new, in the order of new followed by those removed.
+// Aliases are compared without regard to case.
+func Compare(old, new []Entry) []Change {
+	before := descriptors(old)
+	after := descriptors(new)
+	var changes []Change
+	for _, a := range aliasOrder(new) {
+		if d := before[strings.ToUpper(a)]; !strings.EqualFold(d, after[strings.ToUpper(a)]) {
+			changes = append(changes, Change{Alias: a, Old: d, New: after[strings.ToUpper(a)]})
+		}
+	}
+	for _, a := range aliasOrder(old) {
+		if _, ok := after[strings.ToUpper(a)]; !ok {
+			changes = append(changes, Change{Alias: a, Old: before[strings.ToUpper(a)]})
+		}
+	}
+	return changes
+}
+
+// WriteChanges writes the changes one alias at a time, marking added
+// aliases +, removed ones - and changed ones ~ followed by both descriptors
+func WriteChanges(w io.Writer, changes []Change) {
+	for _, c := range changes {
+		switch {
+		case c.Old == "":
+			fmt.Fprintf(w, "+ %s = %s\n", c.Alias, c.New)
+		case c.New == "":
+			fmt.Fprintf(w, "- %s = %s\n", c.Alias, c.Old)
+		default:
+			fmt.Fprintf(w, "~ %s\n    - %s\n    + %s\n", c.Alias, c.Old, c.New)
+		}
+	}
+}
+
+// descriptors maps the upper-cased aliases of entries to their descriptors;
+// a later definition of an alias wins
+func descriptors(entries []Entry) map[string]string {
+	m := make(map[string]string)
+	for _, e := range entries {
+		for _, a := range e.Aliases {
+			m[strings.ToUpper(a)] = e.Descriptor
+		}
+	}
+	return m
+}
+
+// aliasOrder returns the aliases of entries in file order, each once
+func aliasOrder(entries []Entry) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, e := range entries {
+		for _, a := range e.Aliases {
+			if !seen[strings.ToUpper(a)] {
+				seen[strings.ToUpper(a)] = true
+				out = append(out, a)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,39 @@
+package tns_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/tns"
+)
+
+func TestCompare(t *testing.T) {
+	parse := func(s string) []tns.Entry {
+		entries, err := tns.Parse(strings.NewReader(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+	old := parse(existing)
+	new := parse(`# Reformatted
+sales = (DESCRIPTION=(ADDRESS=(PROTOCOL=TCP)(HOST=db01)(PORT=1521))(CONNECT_DATA=(SERVICE_NAME=sales)))
+HR = (DESCRIPTION = (ADDRESS = (PROTOCOL = TCP)(HOST = db09)(PORT = 1521))(CONNECT_DATA = (SERVICE_NAME = hr)))
+WH = (DESCRIPTION = (ADDRESS = (HOST = dw)(PORT = 1521)))
+`)
+	var buf bytes.Buffer
+	tns.WriteChanges(&buf, tns.Compare(old, new))
+	want := `~ HR
+    - (DESCRIPTION=(ADDRESS=(PROTOCOL=TCP)(HOST=db02)(PORT=1521))(CONNECT_DATA=(SERVICE_NAME=hr)))
+    + (DESCRIPTION=(ADDRESS=(PROTOCOL=TCP)(HOST=db09)(PORT=1521))(CONNECT_DATA=(SERVICE_NAME=hr)))
++ WH = (DESCRIPTION=(ADDRESS=(HOST=dw)(PORT=1521)))
+- PAYROLL = (DESCRIPTION=(ADDRESS=(PROTOCOL=TCP)(HOST=db02)(PORT=1521))(CONNECT_DATA=(SERVICE_NAME=hr)))
+`
+	if buf.String() != want {
+		t.Errorf("changes:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if changes := tns.Compare(old, old); len(changes) != 0 {
+		t.Errorf("unchanged file reported as %+v", changes)
+	}
+}
@@ -0,0 +1,59 @@
+package tns
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// exportColumns are the columns WriteCSV writes, which ReadCSV reads back
+var exportColumns = append(importColumns[:len(importColumns):len(importColumns)], "descriptor")
+
+// Records returns a record for every alias of entries, in file order. Host,
+// port and protocol come from the first address and service from
+// SERVICE_NAME, for filtering and sorting; the full descriptor is kept so
+// entries with several addresses, a SID or other parameters survive being
+// imported elsewhere.
+func Records(entries []Entry) []Record {
+	var recs []Record
+	for _, e := range entries {
+		r := Record{Connect: e.Descriptor, Line: e.Line}
+		if addrs, err := e.Addresses(); err == nil && len(addrs) > 0 {
+			r.Host, r.Port, r.Protocol = addrs[0].Host, addrs[0].Port, addrs[0].Protocol
+		}
+		if nodes, err := ParseDescriptor(e.Descriptor); err == nil {
+			walk(nodes, func(n Node) {
+				if r.Service == "" && strings.EqualFold(n.Name, "SERVICE_NAME") {
+					r.Service = n.Value
+				}
+			})
+		}
+		for _, a := range e.Aliases {
+			r.Alias = a
+			recs = append(recs, r)
+		}
+	}
+	return recs
+}
+
+// WriteCSV writes recs as CSV with a header row
+func WriteCSV(w io.Writer, recs []Record) error {
+	cw := csv.NewWriter(w)
+	cw.Write(exportColumns)
+	for _, r := range recs {
+		cw.Write([]string{r.Alias, r.Host, r.Port, r.Service, r.Protocol, r.Connect})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes recs as an indented JSON array
+func WriteJSON(w io.Writer, recs []Record) error {
+	if recs == nil {
+		recs = []Record{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(recs)
+}
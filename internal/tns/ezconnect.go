@@ -0,0 +1,210 @@
+package tns
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EZConnect is an Easy Connect naming string,
+// [protocol://]host[:port]/service[:server][/instance][?name=value&...]
+type EZConnect struct {
+	Protocol string // TCP or TCPS; empty for TCP
+	Host     string
+	Port     string // Empty for 1521
+	Service  string
+	Server   string      // DEDICATED, SHARED or POOLED; empty leaves it to the listener
+	Instance string      // Instance to connect to, in a RAC database
+	Params   [][2]string // Easy Connect Plus parameters, by name and value, in order
+}
+
+// securityParams are the Easy Connect Plus parameters belonging in the
+// descriptor's SECURITY section, by the name they take there
+var securityParams = map[string]string{
+	"SSL_SERVER_DN_MATCH": "SSL_SERVER_DN_MATCH",
+	"SSL_SERVER_CERT_DN":  "SSL_SERVER_CERT_DN",
+	"WALLET_LOCATION":     "MY_WALLET_DIRECTORY",
+	"TOKEN_AUTH":          "TOKEN_AUTH",
+	"TOKEN_LOCATION":      "TOKEN_LOCATION",
+}
+
+// connectDataParams are the Easy Connect Plus parameters belonging in the
+// descriptor's CONNECT_DATA section
+var connectDataParams = map[string]bool{"POOL_CONNECTION_CLASS": true, "POOL_PURITY": true}
+
+// ParseEZConnect parses and validates an Easy Connect string, explaining
+// the mistakes commonly made in writing one
+func ParseEZConnect(s string) (EZConnect, error) {
+	var e EZConnect
+	// The // that may lead the string changes nothing
+	s = strings.TrimPrefix(strings.TrimSpace(s), "//")
+	switch {
+	case s == "":
+		return e, errors.New("empty connect string")
+	case strings.HasPrefix(s, "("):
+		return e, errors.New("this is a connect descriptor, not an Easy Connect string; save it as a tnsnames.ora alias instead")
+	case strings.ContainsAny(s, " \t"):
+		return e, errors.New("an Easy Connect string may not contain spaces")
+	case strings.Contains(s, "@"):
+		return e, errors.New("leave the user name and @ out; the string names the database only")
+	}
+
+	rest, query, hasQuery := strings.Cut(s, "?")
+	if hasQuery {
+		for _, p := range strings.Split(query, "&") {
+			name, value, ok := strings.Cut(p, "=")
+			if !ok || name == "" || value == "" {
+				return e, fmt.Errorf("parameter %q must take the form name=value, separated from the next by &", p)
+			}
+			e.Params = append(e.Params, [2]string{name, value})
+		}
+	}
+	if proto, after, ok := strings.Cut(rest, "://"); ok {
+		e.Protocol, rest = strings.ToUpper(proto), after
+	}
+
+	// An IPv6 address is bracketed, as its colons would read as a port
+	if strings.HasPrefix(rest, "[") {
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			return e, errors.New("unclosed [ around an IPv6 address")
+		}
+		e.Host, rest = rest[1:end], rest[end+1:]
+	} else {
+		end := strings.IndexAny(rest, ":/")
+		if end < 0 {
+			end = len(rest)
+		}
+		e.Host, rest = rest[:end], rest[end:]
+	}
+	if strings.HasPrefix(rest, ":") {
+		end := strings.IndexByte(rest, '/')
+		if end < 0 {
+			end = len(rest)
+		}
+		e.Port, rest = rest[1:end], rest[end:]
+	}
+	if !strings.HasPrefix(rest, "/") {
+		return e, errors.New("missing /service_name after the host and port, e.g. dbhost:1521/sales.example.com")
+	}
+	rest = rest[1:]
+	e.Service, rest, _ = strings.Cut(rest, "/")
+	e.Instance = rest
+	if service, server, ok := strings.Cut(e.Service, ":"); ok {
+		e.Service, e.Server = service, strings.ToUpper(server)
+	}
+	return e, e.Validate()
+}
+
+// Validate checks the string the parts would make is usable
+func (e *EZConnect) Validate() error {
+	e.Protocol, e.Server = strings.ToUpper(e.Protocol), strings.ToUpper(e.Server)
+	switch {
+	case e.Host == "":
+		return errors.New("missing host")
+	case strings.Contains(e.Host, ","):
+		return errors.New("lists of hosts are not supported; define an alias with an ADDRESS_LIST instead")
+	case strings.ContainsAny(e.Host, "()=/?&@ \t"):
+		return fmt.Errorf("invalid host %q", e.Host)
+	case e.Protocol != "" && e.Protocol != "TCP" && e.Protocol != "TCPS":
+		return fmt.Errorf("protocol %q must be tcp or tcps", strings.ToLower(e.Protocol))
+	case e.Service == "":
+		return errors.New("missing service name; find it with lsnrctl services or in V$SERVICES, as it is often not the SID")
+	case strings.ContainsAny(e.Service, "()=:/?&@ \t"):
+		return fmt.Errorf("invalid service name %q", e.Service)
+	case e.Server != "" && e.Server != "DEDICATED" && e.Server != "SHARED" && e.Server != "POOLED":
+		return fmt.Errorf("server type %q must be dedicated, shared or pooled", strings.ToLower(e.Server))
+	case strings.ContainsAny(e.Instance, "()=:/?&@ \t"):
+		return fmt.Errorf("invalid instance name %q", e.Instance)
+	}
+	if e.Port != "" {
+		if port, err := strconv.Atoi(e.Port); err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port %q", e.Port)
+		}
+	}
+	for _, p := range e.Params {
+		if p[0] == "" || p[1] == "" {
+			return fmt.Errorf("parameter %q must take the form name=value", p[0]+"="+p[1])
+		}
+		if strings.Trim(strings.ToUpper(p[0]), "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_") != "" {
+			return fmt.Errorf("invalid parameter name %q", p[0])
+		}
+		if strings.ContainsAny(p[1], "()&?") {
+			return fmt.Errorf("invalid value %q for %s", p[1], p[0])
+		}
+	}
+	return nil
+}
+
+// String returns the Easy Connect string, leaving out the defaults
+func (e EZConnect) String() string {
+	var b strings.Builder
+	if e.Protocol != "" && !strings.EqualFold(e.Protocol, "TCP") {
+		b.WriteString(strings.ToLower(e.Protocol) + "://")
+	}
+	if strings.Contains(e.Host, ":") {
+		b.WriteString("[" + e.Host + "]")
+	} else {
+		b.WriteString(e.Host)
+	}
+	if e.Port != "" && e.Port != "1521" {
+		b.WriteString(":" + e.Port)
+	}
+	b.WriteString("/" + e.Service)
+	if e.Server != "" {
+		b.WriteString(":" + strings.ToLower(e.Server))
+	}
+	if e.Instance != "" {
+		b.WriteString("/" + e.Instance)
+	}
+	for i, p := range e.Params {
+		if i == 0 {
+			b.WriteString("?")
+		} else {
+			b.WriteString("&")
+		}
+		b.WriteString(p[0] + "=" + p[1])
+	}
+	return b.String()
+}
+
+// Descriptor returns the connect descriptor the string stands for, collapsed
+// like Entry.Descriptor, for defining it as a tnsnames.ora alias
+func (e EZConnect) Descriptor() string {
+	protocol, port := "TCP", "1521"
+	if e.Protocol != "" {
+		protocol = strings.ToUpper(e.Protocol)
+	}
+	if e.Port != "" {
+		port = e.Port
+	}
+	var desc, data, security strings.Builder
+	for _, p := range e.Params {
+		name := strings.ToUpper(p[0])
+		switch {
+		case securityParams[name] != "":
+			value := p[1]
+			if name == "SSL_SERVER_CERT_DN" {
+				value = `"` + strings.Trim(value, `"`) + `"`
+			}
+			fmt.Fprintf(&security, "(%s=%s)", securityParams[name], value)
+		case connectDataParams[name]:
+			fmt.Fprintf(&data, "(%s=%s)", name, p[1])
+		default:
+			fmt.Fprintf(&desc, "(%s=%s)", name, p[1])
+		}
+	}
+	d := fmt.Sprintf("(DESCRIPTION=%s(ADDRESS=(PROTOCOL=%s)(HOST=%s)(PORT=%s))(CONNECT_DATA=(SERVICE_NAME=%s)", desc.String(), protocol, e.Host, port, e.Service)
+	if e.Server != "" {
+		d += "(SERVER=" + strings.ToUpper(e.Server) + ")"
+	}
+	if e.Instance != "" {
+		d += "(INSTANCE_NAME=" + e.Instance + ")"
+	}
+	d += data.String() + ")"
+	if security.Len() > 0 {
+		d += "(SECURITY=" + security.String() + ")"
+	}
+	return d + ")"
+}
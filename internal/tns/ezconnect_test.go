@@ -0,0 +1,47 @@
+package tns_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/tns"
+)
+
+func TestParseEZConnect(t *testing.T) {
+	for _, c := range []struct{ in, str, descriptor string }{
+		{"dbhost/sales", "dbhost/sales",
+			"(DESCRIPTION=(ADDRESS=(PROTOCOL=TCP)(HOST=dbhost)(PORT=1521))(CONNECT_DATA=(SERVICE_NAME=sales)))"},
+		{"//dbhost:1522/sales.example.com:DEDICATED/sales1", "dbhost:1522/sales.example.com:dedicated/sales1",
+			"(DESCRIPTION=(ADDRESS=(PROTOCOL=TCP)(HOST=dbhost)(PORT=1522))(CONNECT_DATA=(SERVICE_NAME=sales.example.com)(SERVER=DEDICATED)(INSTANCE_NAME=sales1)))"},
+		{"tcps://[::1]:2484/adb_high?connect_timeout=10&ssl_server_dn_match=true&wallet_location=/wallet", "tcps://[::1]:2484/adb_high?connect_timeout=10&ssl_server_dn_match=true&wallet_location=/wallet",
+			"(DESCRIPTION=(CONNECT_TIMEOUT=10)(ADDRESS=(PROTOCOL=TCPS)(HOST=::1)(PORT=2484))(CONNECT_DATA=(SERVICE_NAME=adb_high))(SECURITY=(SSL_SERVER_DN_MATCH=true)(MY_WALLET_DIRECTORY=/wallet)))"},
+	} {
+		e, err := tns.ParseEZConnect(c.in)
+		if err != nil {
+			t.Errorf("%s: %v", c.in, err)
+			continue
+		}
+		if e.String() != c.str || e.Descriptor() != c.descriptor {
+			t.Errorf("%s:\n got %s\n     %s\nwant %s\n     %s", c.in, e, e.Descriptor(), c.str, c.descriptor)
+		}
+		if _, err := tns.ParseDescriptor(e.Descriptor()); err != nil {
+			t.Errorf("%s: descriptor does not parse: %v", c.in, err)
+		}
+	}
+
+	for in, want := range map[string]string{
+		"dbhost:1521:sales":                "missing /service_name",
+		"scott@dbhost/sales":               "user name",
+		"(DESCRIPTION=(ADDRESS=(HOST=a)))": "connect descriptor",
+		"dbhost:15x1/sales":                "invalid port",
+		"dbhost/sales:fast":                "server type",
+		"dbhost/sales?retry_count":         "name=value",
+		"db1,db2/sales":                    "lists of hosts",
+		"ftp://dbhost/sales":               "protocol",
+		"dbhost/":                          "missing service name",
+	} {
+		if _, err := tns.ParseEZConnect(in); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error = %v, want %q", in, err, want)
+		}
+	}
+}
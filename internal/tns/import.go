@@ -0,0 +1,283 @@
+package tns
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Record is a database published in a spreadsheet, one row of an import or
+// export file
+type Record struct {
+	Alias    string `json:"alias"`
+	Host     string `json:"host,omitempty"`
+	Port     string `json:"port,omitempty"`       // Defaults to 1521
+	Service  string `json:"service,omitempty"`    // SERVICE_NAME to connect to
+	Protocol string `json:"protocol,omitempty"`   // TCP or TCPS; defaults to TCP
+	Connect  string `json:"descriptor,omitempty"` // Full connect descriptor, used instead of the fields above when set
+	Line     int    `json:"-"`                    // Row or array position the record came from, from 1
+}
+
+// importColumns are the columns of an import file; the others are optional
+var importColumns = []string{"alias", "host", "port", "service", "protocol"}
+
+// ReadCSV reads records from CSV with a header row naming the columns alias,
+// host, port, service and protocol in any order and any case. Port and
+// protocol may be left out, and other columns are ignored except
+// descriptor, which as written by WriteCSV holds the full connect descriptor.
+func ReadCSV(r io.Reader) ([]Record, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("no header row")
+	} else if err != nil {
+		return nil, err
+	}
+	cols := make(map[string]int)
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	for _, c := range []string{"alias", "host", "service"} {
+		if _, ok := cols[c]; !ok {
+			return nil, fmt.Errorf("header row has no %s column; expected %s", c, strings.Join(importColumns, ", "))
+		}
+	}
+
+	var recs []Record
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		if strings.Join(row, "") == "" {
+			continue
+		}
+		recs = append(recs, Record{Alias: field("alias"), Host: field("host"), Port: field("port"),
+			Service: field("service"), Protocol: field("protocol"), Connect: field("descriptor"), Line: line})
+	}
+	return recs, validate(recs, "line")
+}
+
+// ReadJSON reads records from a JSON array of objects with the same fields as ReadCSV
+func ReadJSON(r io.Reader) ([]Record, error) {
+	var recs []Record
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&recs); err != nil {
+		return nil, err
+	}
+	for i := range recs {
+		recs[i].Line = i + 1
+	}
+	return recs, validate(recs, "entry")
+}
+
+// validate fills in defaults and rejects records that would not make a
+// usable entry, naming where in the file each problem is
+func validate(recs []Record, unit string) error {
+	seen := make(map[string]int)
+	for i := range recs {
+		r := &recs[i]
+		fail := func(format string, args ...interface{}) error {
+			if unit == "" {
+				return fmt.Errorf(format, args...)
+			}
+			return fmt.Errorf("%s %d: %s", unit, r.Line, fmt.Sprintf(format, args...))
+		}
+		r.Alias, r.Host, r.Service = strings.TrimSpace(r.Alias), strings.TrimSpace(r.Host), strings.TrimSpace(r.Service)
+		if r.Port = strings.TrimSpace(r.Port); r.Port == "" {
+			r.Port = "1521"
+		}
+		if r.Protocol = strings.ToUpper(strings.TrimSpace(r.Protocol)); r.Protocol == "" {
+			r.Protocol = "TCP"
+		}
+
+		if r.Connect = collapse(r.Connect); r.Connect != "" {
+			if _, err := ParseDescriptor(r.Connect); err != nil {
+				return fail("invalid descriptor for %s: %v", r.Alias, err)
+			}
+		}
+
+		switch {
+		case r.Alias == "":
+			return fail("missing alias")
+		case strings.ContainsAny(r.Alias, "()=,#\"' \t"):
+			return fail("alias %q may not contain spaces, quotes or any of ( ) = , #", r.Alias)
+		case r.Connect != "":
+			// The descriptor stands in for the other fields
+		case r.Host == "":
+			return fail("missing host for %s", r.Alias)
+		case strings.ContainsAny(r.Host, "()= \t"):
+			return fail("invalid host %q for %s", r.Host, r.Alias)
+		case r.Service == "":
+			return fail("missing service for %s", r.Alias)
+		case strings.ContainsAny(r.Service, "()= \t"):
+			return fail("invalid service %q for %s", r.Service, r.Alias)
+		case r.Protocol != "TCP" && r.Protocol != "TCPS":
+			return fail("protocol %q for %s must be TCP or TCPS", r.Protocol, r.Alias)
+		}
+		if port, err := strconv.Atoi(r.Port); err != nil || port < 1 || port > 65535 {
+			return fail("invalid port %q for %s", r.Port, r.Alias)
+		}
+		if prev, ok := seen[strings.ToUpper(r.Alias)]; ok {
+			return fail("%s already defined on %s %d", r.Alias, unit, prev)
+		}
+		seen[strings.ToUpper(r.Alias)] = r.Line
+	}
+	return nil
+}
+
+// Validate fills in the record's defaults and checks it would make a usable entry
+func (r *Record) Validate() error {
+	recs := []Record{*r}
+	err := validate(recs, "")
+	*r = recs[0]
+	return err
+}
+
+// Descriptor returns the record's connect descriptor, collapsed like Entry.Descriptor
+func (r Record) Descriptor() string {
+	if r.Connect != "" {
+		return r.Connect
+	}
+	return fmt.Sprintf("(DESCRIPTION=(ADDRESS=(PROTOCOL=%s)(HOST=%s)(PORT=%s))(CONNECT_DATA=(SERVICE_NAME=%s)))",
+		r.Protocol, r.Host, r.Port, r.Service)
+}
+
+// format returns the record as a tnsnames.ora entry laid out the way Oracle's tools write them
+func (r Record) format() string {
+	if r.Connect != "" {
+		return fmt.Sprintf("%s =\n  %s\n", r.Alias, r.Connect)
+	}
+	return fmt.Sprintf("%s =\n  (DESCRIPTION =\n    (ADDRESS = (PROTOCOL = %s)(HOST = %s)(PORT = %s))\n    (CONNECT_DATA =\n      (SERVICE_NAME = %s)\n    )\n  )\n",
+		r.Alias, r.Protocol, r.Host, r.Port, r.Service)
+}
+
+// MergeResult lists what Merge did with each record's alias
+type MergeResult struct {
+	Added     []string // Aliases not defined before
+	Replaced  []string // Aliases whose descriptor was replaced
+	Unchanged []string // Aliases already defined with the same descriptor
+	Conflicts []string // Aliases defined with another descriptor and left alone
+}
+
+// Merge adds recs to the tnsnames.ora content in data and returns the new
+// content. Aliases already defined with the same descriptor are left as
+// they are. Those defined with another descriptor are reported as conflicts
+// and kept, unless replace is set, in which case the new entry takes the old
+// one's place; an alias sharing its old entry with others is split out of
+// it. Comments and the layout of untouched entries are preserved.
+func Merge(data []byte, recs []Record, replace bool) ([]byte, MergeResult, error) {
+	var res MergeResult
+	entries, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, res, err
+	}
+
+	// Find the entry defining each alias
+	defined := make(map[string]int)
+	for i, e := range entries {
+		for _, a := range e.Aliases {
+			defined[strings.ToUpper(a)] = i
+		}
+	}
+
+	// Work out what replaces each entry being rewritten: the aliases it
+	// keeps and the records taking its place
+	type rewrite struct {
+		keep []string
+		recs []Record
+	}
+	rewrites := make(map[int]*rewrite)
+	var added []Record
+	for _, r := range recs {
+		i, ok := defined[strings.ToUpper(r.Alias)]
+		switch {
+		case !ok:
+			added = append(added, r)
+			res.Added = append(res.Added, r.Alias)
+		case strings.EqualFold(entries[i].Descriptor, r.Descriptor()):
+			res.Unchanged = append(res.Unchanged, r.Alias)
+		case !replace:
+			res.Conflicts = append(res.Conflicts, r.Alias)
+		default:
+			rw, ok := rewrites[i]
+			if !ok {
+				rw = &rewrite{keep: entries[i].Aliases}
+				rewrites[i] = rw
+			}
+			var keep []string
+			for _, a := range rw.keep {
+				if !strings.EqualFold(a, r.Alias) {
+					keep = append(keep, a)
+				}
+			}
+			rw.keep = keep
+			rw.recs = append(rw.recs, r)
+			res.Replaced = append(res.Replaced, r.Alias)
+		}
+	}
+
+	// Rewritten entries are replaced line by line, so each must have its
+	// lines to itself
+	lines := strings.SplitAfter(string(data), "\n")
+	replacement := make(map[int]string)
+	for i, rw := range rewrites {
+		e := entries[i]
+		if (i > 0 && entries[i-1].EndLine >= e.Line) || (i+1 < len(entries) && entries[i+1].Line <= e.EndLine) {
+			return nil, res, fmt.Errorf("line %d: %s shares its lines with another entry; split them onto separate lines to replace it",
+				e.Line, strings.Join(e.Aliases, ", "))
+		}
+		var b strings.Builder
+		if len(rw.keep) > 0 {
+			fmt.Fprintf(&b, "%s = %s\n", strings.Join(rw.keep, ", "), e.Descriptor)
+		}
+		for _, r := range rw.recs {
+			b.WriteString(r.format())
+		}
+		replacement[e.Line] = b.String()
+		for l := e.Line + 1; l <= e.EndLine; l++ {
+			replacement[l] = ""
+		}
+	}
+
+	var out strings.Builder
+	for n, l := range lines {
+		if text, ok := replacement[n+1]; ok {
+			out.WriteString(text)
+			continue
+		}
+		out.WriteString(l)
+	}
+	if len(added) > 0 {
+		if s := out.String(); s != "" && !strings.HasSuffix(s, "\n") {
+			out.WriteString("\n")
+		}
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		for i, r := range added {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			out.WriteString(r.format())
+		}
+	}
+	return []byte(out.String()), res, nil
+}
@@ -0,0 +1,165 @@
+package tns_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/tns"
+)
+
+const existing = `# Managed by the DBA team
+SALES = (DESCRIPTION = (ADDRESS = (PROTOCOL = TCP)(HOST = db01)(PORT = 1521))(CONNECT_DATA = (SERVICE_NAME = sales)))
+
+HR, PAYROLL =
+  (DESCRIPTION =
+    (ADDRESS = (PROTOCOL = TCP)(HOST = db02)(PORT = 1521))
+    (CONNECT_DATA = (SERVICE_NAME = hr))
+  )
+`
+
+func TestReadCSV(t *testing.T) {
+	recs, err := tns.ReadCSV(strings.NewReader("\ufeffService,Alias,HOST,Owner\nsales,SALES,db01,finance\n\nhr,HR,db02,people\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tns.Record{
+		{Alias: "SALES", Host: "db01", Port: "1521", Service: "sales", Protocol: "TCP", Line: 2},
+		{Alias: "HR", Host: "db02", Port: "1521", Service: "hr", Protocol: "TCP", Line: 4},
+	}
+	if !reflect.DeepEqual(recs, want) {
+		t.Errorf("records = %+v, want %+v", recs, want)
+	}
+
+	for _, tc := range []struct{ csv, err string }{
+		{"alias,host\nA,db\n", "no service column"},
+		{"alias,host,service,port\nA,db,s,99999\n", "line 2: invalid port"},
+		{"alias,host,service,protocol\nA,db,s,ipc\n", "line 2: protocol"},
+		{"alias,host,service\nA,db,s\na,db,t\n", "line 3: a already defined on line 2"},
+		{"alias,host,service\nA B,db,s\n", "line 2: alias"},
+	} {
+		if _, err := tns.ReadCSV(strings.NewReader(tc.csv)); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%q: error = %v, want %q", tc.csv, err, tc.err)
+		}
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	recs, err := tns.ReadJSON(strings.NewReader(`[{"alias":"WH","host":"dw","port":"2484","service":"dw","protocol":"tcps"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "(DESCRIPTION=(ADDRESS=(PROTOCOL=TCPS)(HOST=dw)(PORT=2484))(CONNECT_DATA=(SERVICE_NAME=dw)))"; recs[0].Descriptor() != want {
+		t.Errorf("descriptor = %s, want %s", recs[0].Descriptor(), want)
+	}
+	if _, err := tns.ReadJSON(strings.NewReader(`[{"alias":"WH","hostname":"dw"}]`)); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	recs, err := tns.ReadCSV(strings.NewReader("alias,host,service\nsales,db01,sales\npayroll,db03,payroll\nwh,dw,dw\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without replace, only new aliases are added
+	out, res, err := tns.Merge([]byte(existing), recs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := tns.MergeResult{Added: []string{"wh"}, Unchanged: []string{"sales"}, Conflicts: []string{"payroll"}}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("result = %+v, want %+v", res, want)
+	}
+	if !strings.HasPrefix(string(out), existing+"\nwh =\n  (DESCRIPTION =\n") {
+		t.Errorf("existing content not kept ahead of the new entry:\n%s", out)
+	}
+
+	// With replace, PAYROLL is split out of the entry it shares with HR
+	out, res, err = tns.Merge([]byte(existing), recs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"payroll"}; !reflect.DeepEqual(res.Replaced, want) {
+		t.Errorf("replaced = %v, want %v", res.Replaced, want)
+	}
+	entries, err := tns.Parse(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("merged file does not parse: %v\n%s", err, out)
+	}
+	got := make(map[string]string)
+	for _, e := range entries {
+		for _, a := range e.Aliases {
+			got[strings.ToUpper(a)] = e.Descriptor
+		}
+	}
+	for alias, host := range map[string]string{"SALES": "db01", "HR": "db02", "PAYROLL": "db03", "WH": "dw"} {
+		if !strings.Contains(got[alias], "(HOST="+host+")") {
+			t.Errorf("%s = %q, want host %s", alias, got[alias], host)
+		}
+	}
+	if len(entries) != 4 || !strings.HasPrefix(string(out), "# Managed by the DBA team\n") {
+		t.Errorf("unexpected merged file:\n%s", out)
+	}
+
+	// Merging into an empty file generates one
+	out, _, err = tns.Merge(nil, recs[2:], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), "wh =\n") {
+		t.Errorf("generated file:\n%s", out)
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	entries, err := tns.Parse(strings.NewReader(existing + "LEGACY = (DESCRIPTION = (ADDRESS_LIST = (ADDRESS = (HOST = a)(PORT = 1522))(ADDRESS = (HOST = b)(PORT = 1522)))(CONNECT_DATA = (SID = LEG)))\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs := tns.Records(entries)
+	var aliases []string
+	for _, r := range recs {
+		aliases = append(aliases, r.Alias)
+	}
+	if want := []string{"SALES", "HR", "PAYROLL", "LEGACY"}; !reflect.DeepEqual(aliases, want) {
+		t.Fatalf("aliases = %v, want %v", aliases, want)
+	}
+	if r := recs[0]; r.Host != "db01" || r.Port != "1521" || r.Service != "sales" || r.Protocol != "TCP" {
+		t.Errorf("SALES exported as %+v", r)
+	}
+
+	for _, format := range []struct {
+		name  string
+		write func(io.Writer, []tns.Record) error
+		read  func(io.Reader) ([]tns.Record, error)
+	}{{"csv", tns.WriteCSV, tns.ReadCSV}, {"json", tns.WriteJSON, tns.ReadJSON}} {
+		var buf bytes.Buffer
+		if err := format.write(&buf, recs); err != nil {
+			t.Fatal(err)
+		}
+		imported, err := format.read(&buf)
+		if err != nil {
+			t.Fatalf("%s: reading the export back: %v", format.name, err)
+		}
+		out, res, err := tns.Merge(nil, imported, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Added) != len(recs) {
+			t.Errorf("%s: %d aliases added, want %d", format.name, len(res.Added), len(recs))
+		}
+		got, err := tns.Parse(bytes.NewReader(out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, e := range got {
+			if e.Descriptor != recs[i].Connect {
+				t.Errorf("%s: %s = %s, want %s", format.name, e.Aliases[0], e.Descriptor, recs[i].Connect)
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package tns
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WalletLocation returns the sqlnet.ora WALLET_LOCATION value for a wallet kept in dir
+func WalletLocation(dir string) string {
+	return fmt.Sprintf("(SOURCE = (METHOD = FILE)(METHOD_DATA = (DIRECTORY = %s)))", dir)
+}
+
+// SetParameter returns the sqlnet.ora content in data with the parameter
+// name set to value, replacing every existing definition of it, which may
+// span lines while its parentheses are open, with one in the place of the
+// first. The parameter is appended when it is not defined. Parameter names
+// are matched without regard to case, and comments and other parameters
+// are kept as they are.
+func SetParameter(data []byte, name, value string) ([]byte, error) {
+	lines := strings.SplitAfter(string(data), "\n")
+	var out strings.Builder
+	set := false
+	for i := 0; i < len(lines); i++ {
+		if !definesParameter(lines[i], name) {
+			out.WriteString(lines[i])
+			continue
+		}
+
+		// Skip the definition up to the line closing its parentheses
+		end, err := parameterEnd(lines, i, name)
+		if err != nil {
+			return nil, err
+		}
+		i = end
+		if !set {
+			fmt.Fprintf(&out, "%s = %s\n", name, value)
+			set = true
+		}
+	}
+	if !set {
+		s := out.String()
+		if s != "" && !strings.HasSuffix(s, "\n") {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "%s = %s\n", name, value)
+	}
+	return []byte(out.String()), nil
+}
+
+// Parameter returns the value of the first definition of the parameter name
+// in the sqlnet.ora content in data, with its lines joined and comments
+// removed, and whether it is defined
+func Parameter(data []byte, name string) (string, bool, error) {
+	lines := strings.SplitAfter(string(data), "\n")
+	for i := range lines {
+		if !definesParameter(lines[i], name) {
+			continue
+		}
+		end, err := parameterEnd(lines, i, name)
+		if err != nil {
+			return "", false, err
+		}
+		var value []string
+		for j := i; j <= end; j++ {
+			line := lines[j]
+			if j == i {
+				line = line[strings.IndexByte(line, '=')+1:]
+			}
+			if c := strings.IndexByte(line, '#'); c >= 0 {
+				line = line[:c]
+			}
+			if line = strings.TrimSpace(line); line != "" {
+				value = append(value, line)
+			}
+		}
+		return strings.Join(value, " "), true, nil
+	}
+	return "", false, nil
+}
+
+// WalletDirectory returns the DIRECTORY of the WALLET_LOCATION set in the
+// sqlnet.ora content in data, without quotes, or "" when none is set
+func WalletDirectory(data []byte) (string, error) {
+	value, ok, err := Parameter(data, "WALLET_LOCATION")
+	if err != nil || !ok {
+		return "", err
+	}
+	upper := strings.ToUpper(value)
+	i := strings.Index(upper, "(DIRECTORY")
+	if i < 0 {
+		return "", nil
+	}
+	dir := strings.TrimLeft(value[i+len("(DIRECTORY"):], " \t")
+	if !strings.HasPrefix(dir, "=") {
+		return "", nil
+	}
+	dir = strings.TrimSpace(dir[1:])
+	if end := strings.IndexByte(dir, ')'); end >= 0 {
+		dir = dir[:end]
+	}
+	return strings.Trim(strings.TrimSpace(dir), `"'`), nil
+}
+
+// parameterEnd returns the index of the line closing the definition of name
+// starting at lines[start]; its value may start on a later line and spans
+// lines while its parentheses are open
+func parameterEnd(lines []string, start int, name string) (int, error) {
+	depth, valued := 0, false
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if i == start {
+			line = line[strings.IndexByte(line, '=')+1:]
+		}
+		if c := strings.IndexByte(line, '#'); c >= 0 {
+			line = line[:c]
+		}
+		depth += strings.Count(line, "(") - strings.Count(line, ")")
+		valued = valued || strings.TrimSpace(line) != ""
+		if valued && depth <= 0 {
+			return i, nil
+		}
+	}
+	return 0, &SyntaxError{Line: start + 1, Msg: fmt.Sprintf("unbalanced '(' or missing value in %s", name)}
+}
+
+// definesParameter reports whether line starts a definition of name; as in
+// all Oracle Net files, definitions start in the first column
+func definesParameter(line, name string) bool {
+	if len(line) < len(name) || !strings.EqualFold(line[:len(name)], name) {
+		return false
+	}
+	return strings.HasPrefix(strings.TrimLeft(line[len(name):], " \t"), "=")
+}
+
+// TraceLevels are the named client trace levels, from least to most detailed
+var TraceLevels = []string{"USER", "ADMIN", "SUPPORT"}
+
+// SetTrace returns the sqlnet.ora content in data with client tracing at
+// level writing to dir, or turned off when level is OFF. Levels are named
+// or numbered from 0 to 16. Tracing to a directory of one's choosing needs
+// Automatic Diagnostic Repository tracing turned off; turning tracing off
+// only resets the level, as the other settings do nothing without it.
+func SetTrace(data []byte, level, dir string) ([]byte, error) {
+	level = strings.ToUpper(level)
+	if level == "OFF" || level == "0" {
+		return SetParameter(data, "TRACE_LEVEL_CLIENT", "OFF")
+	}
+	if !validTraceLevel(level) {
+		return nil, fmt.Errorf("invalid trace level %q; use %s or 1 to 16", level, strings.ToLower(strings.Join(TraceLevels, ", ")))
+	}
+	var err error
+	for _, p := range [][2]string{
+		{"DIAG_ADR_ENABLED", "OFF"},
+		{"TRACE_DIRECTORY_CLIENT", dir},
+		{"TRACE_TIMESTAMP_CLIENT", "ON"},
+		{"TRACE_LEVEL_CLIENT", level},
+	} {
+		if data, err = SetParameter(data, p[0], p[1]); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// validTraceLevel reports whether level is a trace level name or number
+func validTraceLevel(level string) bool {
+	for _, l := range TraceLevels {
+		if level == l {
+			return true
+		}
+	}
+	n, err := strconv.Atoi(level)
+	return err == nil && n >= 1 && n <= 16
+}
@@ -0,0 +1,80 @@
+package tns_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/tns"
+)
+
+func TestSetParameter(t *testing.T) {
+	const sqlnet = `# Site defaults
+NAMES.DIRECTORY_PATH = (TNSNAMES, EZCONNECT)
+wallet_location =
+  (SOURCE =
+    (METHOD = FILE)  # old wallet
+    (METHOD_DATA = (DIRECTORY = C:\old))
+  )
+SQLNET.EXPIRE_TIME = 10`
+
+	out, err := tns.SetParameter([]byte(sqlnet), "WALLET_LOCATION", tns.WalletLocation(`C:\wallet`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err = tns.SetParameter(out, "SQLNET.WALLET_OVERRIDE", "TRUE"); err != nil {
+		t.Fatal(err)
+	}
+	want := `# Site defaults
+NAMES.DIRECTORY_PATH = (TNSNAMES, EZCONNECT)
+WALLET_LOCATION = (SOURCE = (METHOD = FILE)(METHOD_DATA = (DIRECTORY = C:\wallet)))
+SQLNET.EXPIRE_TIME = 10
+SQLNET.WALLET_OVERRIDE = TRUE
+`
+	if string(out) != want {
+		t.Errorf("sqlnet.ora:\n%s\nwant:\n%s", out, want)
+	}
+
+	// Setting it again changes nothing
+	again, err := tns.SetParameter(out, "SQLNET.WALLET_OVERRIDE", "TRUE")
+	if err != nil || string(again) != want {
+		t.Errorf("second update: %v\n%s", err, again)
+	}
+
+	if _, err := tns.SetParameter([]byte("WALLET_LOCATION = (SOURCE =\n"), "WALLET_LOCATION", "x"); err == nil {
+		t.Error("unbalanced definition accepted")
+	}
+}
+
+func TestSetTrace(t *testing.T) {
+	out, err := tns.SetTrace([]byte("TRACE_LEVEL_CLIENT = 4\n"), "admin", `C:\trace`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "TRACE_LEVEL_CLIENT = ADMIN\nDIAG_ADR_ENABLED = OFF\nTRACE_DIRECTORY_CLIENT = C:\\trace\nTRACE_TIMESTAMP_CLIENT = ON\n"
+	if string(out) != want {
+		t.Errorf("on:\n%s\nwant:\n%s", out, want)
+	}
+	if out, err = tns.SetTrace(out, "off", ""); err != nil || !strings.HasPrefix(string(out), "TRACE_LEVEL_CLIENT = OFF\n") {
+		t.Errorf("off: %v\n%s", err, out)
+	}
+	for _, level := range []string{"verbose", "17"} {
+		if _, err := tns.SetTrace(nil, level, "/tmp"); err == nil {
+			t.Errorf("level %s accepted", level)
+		}
+	}
+}
+
+func TestWalletDirectory(t *testing.T) {
+	for data, want := range map[string]string{
+		"WALLET_LOCATION =\n  (SOURCE = (METHOD = FILE)  # wallet\n    (METHOD_DATA = (DIRECTORY = C:\\wallet)))\n": `C:\wallet`,
+		`WALLET_LOCATION = (SOURCE = (METHOD = file) (METHOD_DATA = (DIRECTORY="?/network/admin")))`:                "?/network/admin",
+		"SSL_SERVER_DN_MATCH = yes\n": "",
+	} {
+		if dir, err := tns.WalletDirectory([]byte(data)); err != nil || dir != want {
+			t.Errorf("WalletDirectory(%q) = %q, %v, want %q", data, dir, err, want)
+		}
+	}
+	if _, err := tns.WalletDirectory([]byte("WALLET_LOCATION = (SOURCE =\n")); err == nil {
+		t.Error("unbalanced WALLET_LOCATION accepted")
+	}
+}
@@ -0,0 +1,162 @@
+package tns
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Entry is a net service name definition from a tnsnames.ora file
+type Entry struct {
+	Aliases    []string // Names sharing the descriptor, e.g. ORCL in ORCL = (DESCRIPTION=...)
+	Descriptor string   // Connect descriptor with whitespace collapsed
+	Line       int      // Line the entry starts on
+	EndLine    int      // Line the entry's descriptor ends on
+}
+
+// SyntaxError reports a malformed tnsnames.ora file
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+// Error implements the error interface for SyntaxError
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// ParseFile parses the tnsnames.ora file at path
+func ParseFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads net service name entries of the form
+//
+//	ALIAS[, ALIAS...] = (DESCRIPTION = ...)
+//
+// skipping # comments, and returns a SyntaxError for unbalanced parentheses,
+// missing aliases or missing descriptors
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var name, value strings.Builder
+	var cur *Entry
+	depth, line, startLine := 0, 1, 0
+	inName := true
+
+	br := bufio.NewReader(r)
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case c == '#':
+			// Comments run to the end of the line
+			if _, err := br.ReadString('\n'); err != nil && err != io.EOF {
+				return nil, err
+			}
+			line++
+			if depth > 0 {
+				value.WriteByte(' ')
+			}
+			continue
+		case c == '\n':
+			line++
+		}
+
+		if inName {
+			switch {
+			case c == '=':
+				aliases := splitAliases(name.String())
+				if len(aliases) == 0 {
+					return nil, &SyntaxError{Line: line, Msg: "missing net service name before '='"}
+				}
+				entries = append(entries, Entry{Aliases: aliases, Line: startLine})
+				cur = &entries[len(entries)-1]
+				name.Reset()
+				inName = false
+			case c == '(' || c == ')':
+				return nil, &SyntaxError{Line: line, Msg: fmt.Sprintf("unexpected '%c' outside an entry", c)}
+			case name.Len() == 0 && isSpace(c):
+				// Skip whitespace between entries
+			default:
+				if name.Len() == 0 {
+					startLine = line
+				}
+				name.WriteByte(c)
+			}
+			continue
+		}
+
+		switch {
+		case c == '(':
+			depth++
+			value.WriteByte(c)
+		case c == ')':
+			if depth == 0 {
+				return nil, &SyntaxError{Line: line, Msg: "unbalanced ')'"}
+			}
+			depth--
+			value.WriteByte(c)
+			if depth == 0 {
+				cur.Descriptor = collapse(value.String())
+				cur.EndLine = line
+				value.Reset()
+				inName = true
+			}
+		case depth == 0 && !isSpace(c):
+			return nil, &SyntaxError{Line: line, Msg: fmt.Sprintf("descriptor for %s must start with '('", strings.Join(cur.Aliases, ", "))}
+		default:
+			if depth > 0 {
+				value.WriteByte(c)
+			}
+		}
+	}
+
+	if depth > 0 {
+		return nil, &SyntaxError{Line: line, Msg: fmt.Sprintf("unbalanced '(' in entry %s", strings.Join(cur.Aliases, ", "))}
+	}
+	if !inName {
+		return nil, &SyntaxError{Line: line, Msg: fmt.Sprintf("missing descriptor for %s", strings.Join(cur.Aliases, ", "))}
+	}
+	if strings.TrimSpace(name.String()) != "" {
+		return nil, &SyntaxError{Line: line, Msg: fmt.Sprintf("unexpected text %q", strings.TrimSpace(name.String()))}
+	}
+	return entries, nil
+}
+
+// splitAliases splits a comma-separated alias list
+func splitAliases(s string) []string {
+	var out []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// collapse replaces runs of whitespace with single spaces and drops spaces around parentheses and '='
+func collapse(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	for _, p := range []string{"(", ")", "="} {
+		s = strings.ReplaceAll(s, " "+p, p)
+		s = strings.ReplaceAll(s, p+" ", p)
+	}
+	return s
+}
+
+// isSpace reports whether c is ASCII whitespace
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
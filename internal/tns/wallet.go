@@ -0,0 +1,108 @@
+package tns
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Wallet describes the files of an Oracle wallet directory and the
+// certificates that could be read from it
+type Wallet struct {
+	Dir       string
+	AutoLogin bool                // Holds cwallet.sso, which the client opens without a password
+	PKCS12    bool                // Holds ewallet.p12
+	PEM       bool                // Holds ewallet.pem, whose certificates are listed below
+	Certs     []*x509.Certificate // Certificates found in ewallet.pem
+	Client    *tls.Certificate    // Certificate and key in ewallet.pem identifying the client, for mutual TLS
+}
+
+// ReadWallet inspects the wallet in dir. Only the PEM form of a wallet can
+// be read; cwallet.sso and ewallet.p12 are noted but their certificates are
+// not listed. A client certificate is picked up from an unencrypted key in
+// ewallet.pem.
+func ReadWallet(dir string) (*Wallet, error) {
+	w := &Wallet{Dir: dir}
+	if st, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !st.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	_, err := os.Stat(filepath.Join(dir, "cwallet.sso"))
+	w.AutoLogin = err == nil
+	_, err = os.Stat(filepath.Join(dir, "ewallet.p12"))
+	w.PKCS12 = err == nil
+
+	data, err := os.ReadFile(filepath.Join(dir, "ewallet.pem"))
+	if errors.Is(err, os.ErrNotExist) {
+		return w, nil
+	} else if err != nil {
+		return nil, err
+	}
+	w.PEM = true
+	var keys []byte
+	for rest := data; ; {
+		var b *pem.Block
+		if b, rest = pem.Decode(rest); b == nil {
+			break
+		}
+		switch b.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(b.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("ewallet.pem: %w", err)
+			}
+			w.Certs = append(w.Certs, cert)
+		case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+			keys = append(keys, pem.EncodeToMemory(b)...)
+		}
+	}
+
+	// The client certificate is the one matching the key
+	for _, cert := range w.Certs {
+		pair, err := tls.X509KeyPair(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), keys)
+		if err == nil {
+			pair.Leaf = cert
+			for _, other := range w.Certs {
+				if other != cert {
+					pair.Certificate = append(pair.Certificate, other.Raw)
+				}
+			}
+			w.Client = &pair
+			break
+		}
+	}
+	return w, nil
+}
+
+// Roots returns the wallet's certificates as a pool of trusted certificates
+func (w *Wallet) Roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, cert := range w.Certs {
+		pool.AddCert(cert)
+	}
+	return pool
+}
+
+// StrongCipherSuites are the TLS 1.2 suites with ECDHE key exchange and
+// AES-GCM, which offer forward secrecy, for SSL_CIPHER_SUITES
+var StrongCipherSuites = []string{
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+}
+
+// TCPSDescriptor returns the connect descriptor of a service reached over
+// TCPS, requiring the server certificate's DN to be dn when it is set
+func TCPSDescriptor(host, port, service, dn string) string {
+	d := fmt.Sprintf("(DESCRIPTION=(ADDRESS=(PROTOCOL=TCPS)(HOST=%s)(PORT=%s))(CONNECT_DATA=(SERVICE_NAME=%s))", host, port, service)
+	if dn != "" {
+		d += fmt.Sprintf(`(SECURITY=(SSL_SERVER_CERT_DN="%s"))`, dn)
+	}
+	return d + ")"
+}
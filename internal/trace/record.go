@@ -0,0 +1,168 @@
+package trace
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/input"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+	"github.com/mghoff/oraicwinconfig/internal/version"
+)
+
+// Recorder records a run into a trace
+type Recorder struct {
+	session
+	path  string
+	input strings.Builder
+}
+
+// Record starts recording the run with the given arguments, writing the
+// trace to path once Finish is called: from now on the answers read from
+// stdin, the external commands run through pwsh and the exchanges of the HTTP
+// clients utils builds are recorded
+func Record(path string, args []string) *Recorder {
+	r := &Recorder{path: path}
+	r.san = newSanitizer()
+	r.trace = &Trace{
+		Version: version.Version,
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		Time:    time.Now().UTC(),
+		Args:    r.san.cleanArgs(args),
+		Env:     make(map[string]string),
+	}
+	for _, name := range envNames {
+		if value, ok := os.LookupEnv(name); ok {
+			if strings.HasSuffix(name, "_PROXY") {
+				value = r.san.cleanURL(value)
+			}
+			r.trace.Env[name] = r.san.clean(value)
+		}
+	}
+	input.Capture(&lockedWriter{r})
+	pwsh.Intercept(r.command)
+	utils.InterceptHTTP(func(next http.RoundTripper) http.RoundTripper { return &recordTransport{r: r, next: next} })
+	return r
+}
+
+// Path returns the file the trace is written to
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Finish writes the trace, with how the run ended: runErr, or nil when it
+// succeeded
+func (r *Recorder) Finish(runErr error) error {
+	r.mu.Lock()
+	r.trace.Input = r.san.clean(r.input.String())
+	if runErr != nil {
+		r.trace.Error = r.san.clean(runErr.Error())
+	}
+	r.mu.Unlock()
+	return r.save(r.path)
+}
+
+// lockedWriter appends what is read from stdin to the recorded input
+type lockedWriter struct{ r *Recorder }
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.r.mu.Lock()
+	defer w.r.mu.Unlock()
+	return w.r.input.Write(p)
+}
+
+// command records the commands next runs
+func (r *Recorder) command(next pwsh.Runner) pwsh.Runner {
+	return func(ctx context.Context, c pwsh.Call) ([]byte, error) {
+		out, err := next(ctx, c)
+		rec := r.describe(c)
+		rec.Output = r.san.clean(string(out))
+		if err != nil {
+			rec.Error = r.san.clean(err.Error())
+		}
+		r.mu.Lock()
+		r.trace.Commands = append(r.trace.Commands, rec)
+		r.mu.Unlock()
+		return out, err
+	}
+}
+
+// describe returns the sanitized record of a call, without its result.
+// Encoded PowerShell scripts are decoded, so the trace can be read and
+// sanitized; the call's input is left out, as it holds secrets.
+func (s *session) describe(c pwsh.Call) *Command {
+	rec := &Command{Name: c.Name}
+	for i := 0; i < len(c.Args); i++ {
+		if strings.EqualFold(c.Args[i], "-EncodedCommand") && i+1 < len(c.Args) {
+			if script, err := pwsh.Decode(c.Args[i+1]); err == nil {
+				rec.Script = s.san.clean(script)
+				i++
+				continue
+			}
+		}
+		rec.Args = append(rec.Args, s.san.clean(c.Args[i]))
+	}
+	return rec
+}
+
+// recordTransport records the exchanges of the transport it wraps
+type recordTransport struct {
+	r    *Recorder
+	next http.RoundTripper
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	ex := &Exchange{
+		Method:   req.Method,
+		URL:      t.r.san.cleanURL(req.URL.String()),
+		Header:   t.r.san.cleanHeader(req.Header),
+		Duration: time.Since(start),
+	}
+	t.r.mu.Lock()
+	t.r.trace.HTTP = append(t.r.trace.HTTP, ex)
+	t.r.mu.Unlock()
+	if err != nil {
+		ex.Error = t.r.san.clean(err.Error())
+		return resp, err
+	}
+	ex.Status = resp.StatusCode
+	ex.Response = make(map[string][]string)
+	for name, values := range resp.Header {
+		if strings.EqualFold(name, "Set-Cookie") {
+			values = []string{redacted}
+		}
+		ex.Response[name] = values
+	}
+	resp.Body = &recordBody{ReadCloser: resp.Body, r: t.r, ex: ex}
+	return resp, nil
+}
+
+// recordBody keeps the response body read, up to maxBody
+type recordBody struct {
+	io.ReadCloser
+	r  *Recorder
+	ex *Exchange
+}
+
+func (b *recordBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.r.mu.Lock()
+	b.ex.Length += int64(n)
+	switch {
+	case b.ex.Truncated:
+	case len(b.ex.Body)+n > maxBody:
+		b.ex.Body, b.ex.Truncated = nil, true
+	default:
+		b.ex.Body = append(b.ex.Body, p[:n]...)
+	}
+	b.r.mu.Unlock()
+	return n, err
+}
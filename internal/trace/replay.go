@@ -0,0 +1,167 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/input"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// Replayer replays a recorded run
+type Replayer struct {
+	session
+	misses int
+}
+
+// Replay sets the process up to run again as t recorded it: the recorded
+// answers are fed to the prompts, the results of external commands are
+// taken from the trace instead of running them, and HTTP requests are
+// answered from it, except for bodies too large to have been kept, which
+// are fetched again. Placeholders in the trace take this user's and
+// machine's values. It returns the arguments to run with.
+func Replay(t *Trace) (*Replayer, []string) {
+	r := &Replayer{}
+	r.san = newSanitizer()
+	r.trace = t
+	if t.OS != runtime.GOOS {
+		fmt.Fprintf(os.Stderr, "warning: the trace was recorded on %s; replaying it on %s takes other code paths\n", t.OS, runtime.GOOS)
+	}
+	for name, value := range t.Env {
+		// Proxies of the recording network do not apply here
+		if !strings.HasSuffix(name, "_PROXY") {
+			os.Setenv(name, r.san.expand(value))
+		}
+	}
+	input.Feed(strings.NewReader(r.san.expand(t.Input)))
+	pwsh.Intercept(func(pwsh.Runner) pwsh.Runner { return r.command })
+	utils.InterceptHTTP(func(next http.RoundTripper) http.RoundTripper { return &replayTransport{r: r, next: next} })
+
+	args := make([]string, len(t.Args))
+	for i, arg := range t.Args {
+		args[i] = r.san.expand(arg)
+	}
+	return r, args
+}
+
+// Misses returns how many commands and requests the replay made that the
+// trace holds no result for, a sign it diverged from the recorded run
+func (r *Replayer) Misses() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.misses
+}
+
+// command returns the recorded result of the first unused command matching c
+func (r *Replayer) command(ctx context.Context, c pwsh.Call) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	want := r.describe(c)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rec := range r.trace.Commands {
+		if rec.used || rec.Name != want.Name || rec.Script != want.Script || !slices.Equal(rec.Args, want.Args) {
+			continue
+		}
+		rec.used = true
+		out := []byte(r.san.expand(rec.Output))
+		if rec.Error != "" {
+			return out, errors.New(r.san.expand(rec.Error))
+		}
+		return out, nil
+	}
+	r.misses++
+	fmt.Fprintf(os.Stderr, "replay: no recorded result for %s %s\n", c.Name, summary(want))
+	return nil, fmt.Errorf("replay: %s was not run in the recorded run", c.Name)
+}
+
+// summary returns the start of what a command runs, for messages
+func summary(c *Command) string {
+	s := c.Script
+	if s == "" {
+		s = strings.Join(c.Args, " ")
+	}
+	if line, _, _ := strings.Cut(strings.TrimSpace(s), "\n"); len(line) < 100 {
+		return line
+	}
+	return s[:100] + "..."
+}
+
+// replayTransport answers requests from the trace
+type replayTransport struct {
+	r    *Replayer
+	next http.RoundTripper
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	url := t.r.san.cleanURL(req.URL.String())
+	rangeHeader := req.Header.Get("Range")
+	t.r.mu.Lock()
+	var ex *Exchange
+	for _, rec := range t.r.trace.HTTP {
+		if !rec.used && rec.Method == req.Method && rec.URL == url && firstValue(rec.Header, "Range") == rangeHeader {
+			rec.used, ex = true, rec
+			break
+		}
+	}
+	if ex == nil {
+		t.r.misses++
+	}
+	t.r.mu.Unlock()
+
+	switch {
+	case ex == nil:
+		fmt.Fprintf(os.Stderr, "replay: no recorded response for %s %s; sending it\n", req.Method, url)
+		return t.next.RoundTrip(req)
+	case ex.Error != "":
+		return nil, errors.New(t.r.san.expand(ex.Error))
+	case ex.Truncated || !complete(ex):
+		// The body was too large to keep, or not all read, so it is fetched again
+		return t.next.RoundTrip(req)
+	}
+	resp := &http.Response{
+		Status:        strconv.Itoa(ex.Status) + " " + http.StatusText(ex.Status),
+		StatusCode:    ex.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(ex.Response).Clone(),
+		Body:          io.NopCloser(bytes.NewReader(ex.Body)),
+		ContentLength: int64(len(ex.Body)),
+		Request:       req,
+	}
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	}
+	return resp, nil
+}
+
+// complete reports whether the recorded run read the whole response body
+func complete(ex *Exchange) bool {
+	length := firstValue(ex.Response, "Content-Length")
+	return ex.Method == http.MethodHead || length == "" || length == strconv.Itoa(len(ex.Body))
+}
+
+// firstValue returns the first value of a recorded header
+func firstValue(h map[string][]string, name string) string {
+	for k, v := range h {
+		if strings.EqualFold(k, name) && len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,177 @@
+package trace
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// redacted replaces the secrets left out of a trace
+const redacted = "REDACTED"
+
+// safeHeaders are the request headers recorded with their values; the
+// values of all others, which may carry credentials, are redacted
+var safeHeaders = map[string]bool{
+	"Accept": true, "Accept-Encoding": true, "Range": true, "User-Agent": true,
+	"If-Match": true, "If-None-Match": true, "If-Modified-Since": true, "If-Range": true,
+}
+
+// secretFlags are words that mark a command-line flag's value as a secret
+var secretFlags = []string{"password", "secret", "token", "notify-url"}
+
+// sanitizer replaces what identifies the user and machine with placeholders,
+// and puts the local values back in their place when replaying
+type sanitizer struct {
+	pairs [][2]string // Local value and its placeholder, longest value first
+}
+
+// newSanitizer returns the sanitizer for this user and machine
+func newSanitizer() *sanitizer {
+	s := &sanitizer{}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		s.add(filepath.Clean(home), "${HOME}")
+	}
+	if u, err := user.Current(); err == nil {
+		// Windows names users DOMAIN\user
+		name := u.Username
+		if i := strings.LastIndexByte(name, '\\'); i >= 0 {
+			s.add(name, "${DOMAIN}\\${USER}")
+			name = name[i+1:]
+		}
+		s.add(name, "${USER}")
+	}
+	if host, err := os.Hostname(); err == nil {
+		s.add(host, "${HOST}")
+	}
+	sort.SliceStable(s.pairs, func(i, j int) bool { return len(s.pairs[i][0]) > len(s.pairs[j][0]) })
+	return s
+}
+
+// add replaces value with placeholder; values too short to be told apart
+// from ordinary words are left alone
+func (s *sanitizer) add(value, placeholder string) {
+	if len(value) < 3 {
+		return
+	}
+	s.pairs = append(s.pairs, [2]string{value, placeholder})
+}
+
+// clean replaces the local values in text with their placeholders, ignoring
+// case, as Windows paths and names differ in case from one source to another
+func (s *sanitizer) clean(text string) string {
+	for _, p := range s.pairs {
+		text = replaceFold(text, p[0], p[1])
+	}
+	return text
+}
+
+// expand puts the local values back in place of the placeholders
+func (s *sanitizer) expand(text string) string {
+	for _, p := range s.pairs {
+		text = strings.ReplaceAll(text, p[1], p[0])
+	}
+	return text
+}
+
+// replaceFold replaces every instance of old in s with new, ignoring ASCII case
+func replaceFold(s, old, new string) string {
+	lower, lowerOld := strings.ToLower(s), strings.ToLower(old)
+	if len(lower) != len(s) || len(lowerOld) != len(old) || !utf8.ValidString(s) {
+		return strings.ReplaceAll(s, old, new)
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, lowerOld)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		b.WriteString(new)
+		s, lower = s[i+len(old):], lower[i+len(old):]
+	}
+}
+
+// cleanURL sanitizes a URL, redacting its user information and query values,
+// which may hold credentials or signatures
+func (s *sanitizer) cleanURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return s.clean(raw)
+	}
+	if u.User != nil {
+		u.User = url.User(redacted)
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for name := range q {
+			q[name] = []string{redacted}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return s.clean(u.String())
+}
+
+// cleanHeader sanitizes request headers, redacting the values of all but
+// the safe ones
+func (s *sanitizer) cleanHeader(h http.Header) map[string][]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(h))
+	for name, values := range h {
+		if safeHeaders[http.CanonicalHeaderKey(name)] {
+			for _, v := range values {
+				out[name] = append(out[name], s.clean(v))
+			}
+		} else {
+			out[name] = []string{redacted}
+		}
+	}
+	return out
+}
+
+// cleanArgs sanitizes command-line arguments, redacting the values of
+// flags that name secrets and sanitizing URLs
+func (s *sanitizer) cleanArgs(args []string) []string {
+	out := make([]string, len(args))
+	secret := false
+	for i, arg := range args {
+		switch {
+		case secret:
+			out[i], secret = redacted, false
+		case strings.HasPrefix(arg, "-") && isSecretFlag(arg):
+			if name, _, ok := strings.Cut(arg, "="); ok {
+				out[i] = name + "=" + redacted
+			} else {
+				out[i], secret = arg, true
+			}
+		case strings.Contains(arg, "://"):
+			out[i] = s.cleanURL(arg)
+		default:
+			out[i] = s.clean(arg)
+		}
+	}
+	return out
+}
+
+// isSecretFlag reports whether the flag's value is a secret
+func isSecretFlag(arg string) bool {
+	name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	name = strings.ToLower(name)
+	// Flags naming where a secret is kept, not the secret itself
+	if strings.HasSuffix(name, "-env") || strings.HasSuffix(name, "-file") {
+		return false
+	}
+	for _, w := range secretFlags {
+		if strings.Contains(name, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+// Package trace records what a run depends on outside the program, the
+// answers given at prompts, the results of external commands and the HTTP
+// exchanges, into a sanitized trace file, and replays a run against one, so
+// maintainers can reproduce a failure that only happens on a user's machine.
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// maxBody bounds the response bodies kept in a trace; larger ones, the
+// client archives, are fetched again when replaying
+const maxBody = 256 << 10
+
+// envNames are the variables of the process recorded with the run, as they
+// change what it does
+var envNames = []string{"TNS_ADMIN", "ORACLE_HOME", "OCI_LIB64", "OCI_LIB32", "HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY"}
+
+// Trace is a recorded run
+type Trace struct {
+	Version  string            `json:"version"` // Installer version that recorded the run
+	OS       string            `json:"os"`
+	Arch     string            `json:"arch"`
+	Time     time.Time         `json:"time"`
+	Args     []string          `json:"args"`  // Command-line arguments of the run
+	Env      map[string]string `json:"env"`   // Variables of the process that change what it does
+	Input    string            `json:"input"` // Everything read from standard input
+	Commands []*Command        `json:"commands"`
+	HTTP     []*Exchange       `json:"http"`
+	Error    string            `json:"error,omitempty"` // How the run failed; empty when it succeeded
+}
+
+// Command is an external command run and its result
+type Command struct {
+	Name   string   `json:"name"`
+	Args   []string `json:"args,omitempty"`
+	Script string   `json:"script,omitempty"` // PowerShell script passed encoded, decoded
+	Output string   `json:"output"`
+	Error  string   `json:"error,omitempty"`
+	used   bool
+}
+
+// Exchange is an HTTP request and the response to it
+type Exchange struct {
+	Method    string              `json:"method"`
+	URL       string              `json:"url"`
+	Header    map[string][]string `json:"header,omitempty"` // Request headers; secrets are redacted
+	Status    int                 `json:"status,omitempty"`
+	Response  map[string][]string `json:"response,omitempty"` // Response headers
+	Length    int64               `json:"length"`             // Length of the response body read
+	Body      []byte              `json:"body,omitempty"`     // Response body, when at most maxBody long
+	Truncated bool                `json:"truncated,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	Duration  time.Duration       `json:"duration"`
+	used      bool
+}
+
+// session holds the trace being recorded or replayed
+type session struct {
+	mu    sync.Mutex
+	trace *Trace
+	san   *sanitizer
+}
+
+// Load reads a trace file
+func Load(path string) (*Trace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "reading trace")
+	}
+	var t Trace
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "parsing trace")
+	}
+	return &t, nil
+}
+
+// save writes the trace to path
+func (s *session) save(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s.trace); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, "encoding trace")
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return errs.HandleError(err, errs.ErrorTypeEnvironment, "writing trace")
+	}
+	return nil
+}
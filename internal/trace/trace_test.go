@@ -0,0 +1,121 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+	"github.com/mghoff/oraicwinconfig/internal/utils"
+)
+
+// get fetches url with a client built by utils, as the installer's are
+func get(t *testing.T, url string) (int, string, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := utils.NewHTTPClient(config.New().HTTP).Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), err
+}
+
+func TestRecordReplay(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "index for "+r.URL.Path)
+	}))
+	path := filepath.Join(t.TempDir(), "trace.json")
+
+	// Record: the command leaves a marker behind, which a replay must not
+	rec := Record(path, []string{"--notify-url", "https://hooks.example.com/x?sig=abc", "--install-path", filepath.Join(home, "oracle")})
+	script := `echo "$HOME"; touch "$HOME/ran"`
+	out, err := pwsh.Output(context.Background(), "sh", "-c", script)
+	if err != nil || strings.TrimSpace(string(out)) != home {
+		t.Fatalf("recorded command = %q, %v", out, err)
+	}
+	if status, body, err := get(t, srv.URL+"/index?token=abc"); err != nil || status != 200 || body != "index for /index" {
+		t.Fatalf("recorded request = %d %q, %v", status, body, err)
+	}
+	if err := rec.Finish(errors.New("install failed in " + home)); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{home, "sig=abc", "token=abc", "s3cret", "hooks.example.com"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("trace holds %q:\n%s", secret, data)
+		}
+	}
+	tr, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Error != "install failed in ${HOME}" || len(tr.Commands) != 1 || len(tr.HTTP) != 1 {
+		t.Fatalf("trace = %+v", tr)
+	}
+
+	// Replay as another user: the placeholders take the new home, the command
+	// is answered without running and the request without the server
+	home2 := t.TempDir()
+	t.Setenv("HOME", home2)
+	t.Setenv("USERPROFILE", home2)
+	r, args := Replay(tr)
+	if want := filepath.Join(home2, "oracle"); args[3] != want {
+		t.Errorf("replayed args = %q, want %s in the new home", args, want)
+	}
+	out, err = pwsh.Output(context.Background(), "sh", "-c", script)
+	if err != nil || strings.TrimSpace(string(out)) != home2 {
+		t.Errorf("replayed command = %q, %v", out, err)
+	}
+	if _, err := os.Stat(filepath.Join(home2, "ran")); err == nil {
+		t.Error("the replayed command ran")
+	}
+	if status, body, err := get(t, srv.URL+"/index?token=xyz"); err != nil || status != 200 || body != "index for /index" {
+		t.Errorf("replayed request = %d %q, %v", status, body, err)
+	}
+	if r.Misses() != 0 {
+		t.Errorf("misses = %d, want 0", r.Misses())
+	}
+
+	// Anything beyond the recording is a miss
+	if _, err := pwsh.Output(context.Background(), "sh", "-c", script); err == nil {
+		t.Error("a command run more often than recorded was answered")
+	}
+	if r.Misses() != 1 {
+		t.Errorf("misses = %d, want 1", r.Misses())
+	}
+}
+
+func TestCleanArgs(t *testing.T) {
+	s := &sanitizer{}
+	s.add("/home/alice", "${HOME}")
+	got := s.cleanArgs([]string{"--password-env", "DB_PASS", "--token=abc", "--secret", "x", "--install-path", "/HOME/alice/oracle", "https://bob:pw@mirror.example.com/ic.zip?X-Amz-Signature=1"})
+	want := []string{"--password-env", "DB_PASS", "--token=REDACTED", "--secret", "REDACTED", "--install-path", "${HOME}/oracle", "https://REDACTED@mirror.example.com/ic.zip?X-Amz-Signature=REDACTED"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("cleanArgs = %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+)
+
+// oracleSigners are the organizations Oracle signs its Windows binaries as
+var oracleSigners = []string{"Oracle America, Inc.", "Oracle Corporation"}
+
+// Signature is the Authenticode signature status of a file
+type Signature struct {
+	Path   string
+	Status string // As reported by Get-AuthenticodeSignature, e.g. Valid, NotSigned or HashMismatch
+	Signer string // Subject of the signing certificate; empty when unsigned
+}
+
+// Trusted reports whether the file has a valid signature chaining to a trusted
+// root, made with a certificate issued to Oracle
+func (s Signature) Trusted() bool {
+	if s.Status != "Valid" {
+		return false
+	}
+	orgs := subjectAttr(s.Signer, "O")
+	if len(orgs) != 1 {
+		return false
+	}
+	for _, signer := range oracleSigners {
+		if orgs[0] == signer {
+			return true
+		}
+	}
+	return false
+}
+
+// subjectAttr returns the values of the attribute key in a distinguished name
+// as .NET writes it, such as CN="Oracle America, Inc.", O="Oracle America, Inc.", C=US.
+// Values are quoted or escaped with backslashes where they hold separators.
+func subjectAttr(subject, key string) []string {
+	var values []string
+	for len(subject) > 0 {
+		var rdn string
+		rdn, subject = nextRDN(subject)
+		name, value, ok := strings.Cut(rdn, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(name), key) {
+			values = append(values, unquoteRDN(strings.TrimSpace(value)))
+		}
+	}
+	return values
+}
+
+// nextRDN splits the first attribute off a distinguished name, at the first
+// comma or semicolon outside quotes and escapes
+func nextRDN(dn string) (string, string) {
+	quoted := false
+	for i := 0; i < len(dn); i++ {
+		switch c := dn[i]; {
+		case c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case (c == ',' || c == ';') && !quoted:
+			return dn[:i], dn[i+1:]
+		}
+	}
+	return dn, ""
+}
+
+// unquoteRDN removes the quotes and escapes around an attribute value
+func unquoteRDN(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = strings.ReplaceAll(value[1:len(value)-1], `""`, `"`)
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// String describes the signature for reports
+func (s Signature) String() string {
+	if s.Signer == "" {
+		return fmt.Sprintf("%s: %s", s.Path, s.Status)
+	}
+	return fmt.Sprintf("%s: %s, signed by %s", s.Path, s.Status, s.Signer)
+}
+
+// VerifyAuthenticode checks the signatures of the DLLs and executables in dir
+// through the Windows trust APIs, in a single PowerShell call
+func VerifyAuthenticode(ctx context.Context, dir string) ([]Signature, error) {
+	ctx = EnsureContext(ctx)
+	script := fmt.Sprintf("Get-ChildItem -LiteralPath %s -File | Where-Object { $_.Extension -in '.dll','.exe' } | "+
+		"Get-AuthenticodeSignature | ForEach-Object { \"{0}`t{1}`t{2}\" -f $_.Status, $_.SignerCertificate.Subject, $_.Path }", pwsh.Quote(dir))
+	out, err := pwsh.Run(ctx, script)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeInstall, "verifying Authenticode signatures")
+	}
+
+	var sigs []Signature
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.SplitN(strings.TrimRight(line, "\r"), "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		sigs = append(sigs, Signature{Status: fields[0], Signer: fields[1], Path: fields[2]})
+	}
+	return sigs, nil
+}
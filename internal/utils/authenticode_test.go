@@ -0,0 +1,26 @@
+package utils
+
+import "testing"
+
+func TestSignatureTrusted(t *testing.T) {
+	tests := []struct {
+		status, signer string
+		want           bool
+	}{
+		{"Valid", `CN="Oracle America, Inc.", O="Oracle America, Inc.", L=Redwood City, S=California, C=US`, true},
+		{"Valid", "CN=Oracle Corporation, O=Oracle Corporation, C=US", true},
+		{"Valid", `CN=x, O=Oracle America\, Inc., C=US`, true},
+		{"HashMismatch", "CN=Oracle Corporation, O=Oracle Corporation, C=US", false},
+		{"Valid", "CN=x, O=Oracle Fans LLC, C=US", false},
+		{"Valid", "CN=x, OU=x, O=Oracle-ish, C=US", false},
+		{"Valid", `CN=x, OU="O=Oracle Corporation", O=Mallory`, false},
+		{"Valid", "CN=Oracle Corporation, C=US", false},
+		{"Valid", "O=Oracle Corporation, O=Mallory", false},
+		{"Valid", "", false},
+	}
+	for _, tt := range tests {
+		if got := (Signature{Status: tt.status, Signer: tt.signer}).Trusted(); got != tt.want {
+			t.Errorf("Trusted(%s, %s) = %t, want %t", tt.status, tt.signer, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// firstBusyBackoff is the wait before the first retry when a busy server
+// sends no Retry-After; it doubles with each further retry
+const firstBusyBackoff = 2 * time.Second
+
+// busyTransport retries requests a rate-limiting or overloaded server answers
+// with 429 or 503, waiting as long as its Retry-After header asks
+type busyTransport struct {
+	base    http.RoundTripper
+	retries int
+	maxWait time.Duration
+}
+
+// RoundTrip sends the request, retrying while the server reports it is busy
+func (t *busyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := firstBusyBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || !busyStatus(resp.StatusCode) {
+			return resp, err
+		}
+		// Only requests whose body can be sent again are retried
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, nil
+		}
+
+		wait, asked := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !asked {
+			wait, backoff = backoff, 2*backoff
+		}
+		status := resp.Status
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		switch {
+		case attempt == t.retries:
+			return nil, busyError(req, fmt.Errorf("server busy (%s), retried %d times; try again later", status, attempt))
+		case t.maxWait > 0 && wait > t.maxWait:
+			return nil, busyError(req, fmt.Errorf("server busy (%s) and asks to retry after %s, longer than the %s allowed; try again later", status, wait.Round(time.Second), t.maxWait))
+		}
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < wait {
+			return nil, busyError(req, fmt.Errorf("server busy (%s), and waiting %s to retry would pass the time limit", status, wait.Round(time.Second)))
+		}
+
+		fmt.Fprintf(os.Stderr, "%s is busy (%s), retrying in %s (retry %d of %d)\n", req.URL.Host, status, wait.Round(time.Second), attempt+1, t.retries)
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// busyStatus reports whether a status code says the server is rate limiting or overloaded
+func busyStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// busyError wraps the final failure of a request to a busy server
+func busyError(req *http.Request, err error) error {
+	return errs.HandleError(fmt.Errorf("%s: %w", req.URL.Host, err), errs.ErrorTypeDownload, "waiting for busy server")
+}
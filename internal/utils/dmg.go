@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// dmgDirPattern finds the versioned directory name in the DMG's install_ic.sh script
+// or, failing that, the version in the DMG file name
+var (
+	dmgDirPattern  = regexp.MustCompile(`instantclient_[0-9]{1,2}_[0-9]{1,2}`)
+	dmgNamePattern = regexp.MustCompile(`macos[.-](?:x64|arm64)-([0-9]{1,2})\.([0-9]{1,2})\.`)
+)
+
+// ExtractDMG mounts a macOS Instant Client disk image, copies its contents into
+// the versioned directory under installPath, and returns that directory name
+func ExtractDMG(ctx context.Context, dmgPath, installPath string) (string, error) {
+	ctx = EnsureContext(ctx)
+	mountPoint, err := os.MkdirTemp("", "oraicwinconfig-dmg-")
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeInstall, "creating mount point")
+	}
+	defer os.Remove(mountPoint)
+
+	attach := exec.CommandContext(ctx, "hdiutil", "attach", "-quiet", "-nobrowse", "-readonly", "-noautoopen", "-mountpoint", mountPoint, dmgPath)
+	if out, err := attach.CombinedOutput(); err != nil {
+		return "", errs.HandleError(fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out))), errs.ErrorTypeInstall, "mounting disk image")
+	}
+	defer exec.Command("hdiutil", "detach", "-quiet", "-force", mountPoint).Run()
+
+	dir := dmgDirName(mountPoint, dmgPath)
+	if dir == "" {
+		return "", errs.HandleError(
+			fmt.Errorf("no instant client version found in %s", filepath.Base(dmgPath)),
+			errs.ErrorTypeInstall,
+			"validating disk image contents")
+	}
+
+	dst := filepath.Join(installPath, dir)
+	if err := copyTree(ctx, mountPoint, dst); err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeInstall, "copying disk image contents")
+	}
+	return dir, nil
+}
+
+// dmgDirName works out the instantclient_XX_Y directory the image installs into
+func dmgDirName(mountPoint, dmgPath string) string {
+	if script, err := os.ReadFile(filepath.Join(mountPoint, "install_ic.sh")); err == nil {
+		if m := dmgDirPattern.Find(script); m != nil {
+			return string(m)
+		}
+	}
+	if m := dmgNamePattern.FindStringSubmatch(filepath.Base(dmgPath)); m != nil {
+		return fmt.Sprintf("instantclient_%s_%s", m[1], m[2])
+	}
+	return ""
+}
+
+// copyTree copies the files, directories and symlinks under src into dst,
+// skipping the image's own install script
+func copyTree(ctx context.Context, src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == "install_ic.sh" || strings.HasPrefix(rel, ".") && rel != "." {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			os.Remove(target)
+			return os.Symlink(link, target)
+		default:
+			return copyRegular(path, target, info.Mode().Perm()|0200)
+		}
+	})
+}
+
+// copyRegular copies a single file, creating it with the given permissions
+func copyRegular(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// ClearQuarantine removes the quarantine attribute Gatekeeper would otherwise use
+// to block loading the copied libraries, and checks their code signatures are intact
+func ClearQuarantine(ctx context.Context, dir string) error {
+	ctx = EnsureContext(ctx)
+	if out, err := exec.CommandContext(ctx, "xattr", "-dr", "com.apple.quarantine", dir).CombinedOutput(); err != nil {
+		return errs.HandleError(fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out))), errs.ErrorTypeInstall, "clearing quarantine attribute")
+	}
+	libs, _ := filepath.Glob(filepath.Join(dir, "libclntsh.dylib*"))
+	for _, lib := range libs {
+		if info, err := os.Lstat(lib); err != nil || info.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		if out, err := exec.CommandContext(ctx, "codesign", "--verify", lib).CombinedOutput(); err != nil {
+			return errs.HandleError(fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out))), errs.ErrorTypeInstall, "verifying code signature of "+filepath.Base(lib))
+		}
+	}
+	return nil
+}
+
+// Extract unpacks a downloaded archive into installPath according to its type
+// and returns the versioned directory name it created
+func Extract(ctx context.Context, archivePath, installPath string) (string, error) {
+	if strings.EqualFold(filepath.Ext(archivePath), ".dmg") {
+		if err := os.MkdirAll(installPath, 0777); err != nil {
+			return "", errs.HandleError(err, errs.ErrorTypeInstall, "creating base installation directory")
+		}
+		return ExtractDMG(ctx, archivePath, installPath)
+	}
+	return UnZip(ctx, archivePath, installPath)
+}
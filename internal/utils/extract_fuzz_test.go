@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// entryNames are seeds for the entry name fuzzers: ordinary names, traversal
+// attempts in both separators, absolute and drive paths, odd unicode and
+// names longer than any file system allows
+var entryNames = []string{
+	"instantclient_23_7/libclntsh.so.23.1",
+	"instantclient_23_7/sdk/include/oci.h",
+	"instantclient_23_7/",
+	"../../etc/profile",
+	"instantclient_23_7/../../escape",
+	`..\..\Windows\System32\evil.dll`,
+	`instantclient_23_7\..\..\escape`,
+	"/etc/passwd",
+	`\\server\share\evil.dll`,
+	`C:\Windows\evil.dll`,
+	"c:evil.dll",
+	"./instantclient_23_7/./a",
+	"instantclient_23_7/a/../../..",
+	"..",
+	".",
+	"",
+	"a\x00b",
+	"instantclient_23_7/ünïcödé/日本語.dll",
+	"instantclient_23_7/\u202eexe.dll",
+	"instantclient_23_7/ﹼ..ﹼ/x",
+	strings.Repeat("a/", 5000) + "x",
+	strings.Repeat("x", 70000),
+}
+
+// within reports whether path is dir or somewhere below it
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func FuzzEntryPath(f *testing.F) {
+	for _, name := range entryNames {
+		f.Add(name)
+	}
+	installPath := filepath.Join(f.TempDir(), "install")
+	f.Fuzz(func(t *testing.T, name string) {
+		out, err := entryPath(installPath, name)
+		if err != nil {
+			return
+		}
+		if !within(installPath, out) {
+			t.Fatalf("entry %q extracts to %s, outside %s", name, out, installPath)
+		}
+	})
+}
+
+// writeEntries writes a zip holding the client directory and the given
+// entries, where a non-empty link makes an entry a symlink to it
+func writeEntries(t *testing.T, path string, entries [][2]string) bool {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	if _, err := zw.Create("instantclient_23_7/"); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		h := &zip.FileHeader{Name: e[0], Method: zip.Store}
+		content := "data"
+		if e[1] != "" {
+			h.SetMode(os.ModeSymlink | 0777)
+			content = e[1]
+		}
+		// Entries the zip format cannot hold, such as directories with
+		// content, never reach an extractor
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			return false
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			return false
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return false
+	}
+	return true
+}
+
+func FuzzUnZip(f *testing.F) {
+	for _, name := range entryNames {
+		f.Add(name, "")
+	}
+	for _, link := range []string{".", "..", "../..", "/etc", `..\..`, "sdk", "./x/../..", `C:\Windows`, "instantclient_23_7/../.."} {
+		f.Add("instantclient_23_7/lib", link)
+	}
+	f.Fuzz(func(t *testing.T, name, link string) {
+		if link != "" && runtime.GOOS == "windows" {
+			t.Skip("creating symlinks needs extra privileges on Windows")
+		}
+		root := t.TempDir()
+		installPath := filepath.Join(root, "install")
+		entries := [][2]string{{name, link}}
+		if link != "" {
+			// A file written through the link must not escape either
+			entries = append(entries, [2]string{name + "/planted", ""})
+		}
+		archive := filepath.Join(t.TempDir(), "fuzz.zip")
+		if !writeEntries(t, archive, entries) {
+			return
+		}
+
+		UnZip(context.Background(), archive, installPath)
+
+		// Only the install directory may have been created next to it
+		siblings, err := os.ReadDir(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range siblings {
+			if e.Name() != "install" {
+				t.Fatalf("entry %q (link %q) created %s outside the installation directory", name, link, e.Name())
+			}
+		}
+		filepath.WalkDir(installPath, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.Type()&os.ModeSymlink == 0 {
+				return nil
+			}
+			target, err := filepath.EvalSymlinks(path)
+			if err == nil && !within(installPath, target) {
+				t.Fatalf("entry %q left a symlink %s to %s, outside the installation directory", name, path, target)
+			}
+			return nil
+		})
+	})
+}
+
+func TestUnZipSymlinkChains(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks needs extra privileges on Windows")
+	}
+	tests := []struct {
+		name    string
+		entries [][2]string
+	}{
+		{"file through a chain of links", [][2]string{{"a", "."}, {"a/x", ".."}, {"a/x/evil.txt", ""}}},
+		{"chain within the client directory", [][2]string{
+			{"instantclient_23_7/a", "."}, {"instantclient_23_7/a/x", ".."}, {"instantclient_23_7/a/x/x/evil.txt", ""},
+		}},
+		{"link made upward by a later link", [][2]string{{"instantclient_23_7/c", "e/../.."}, {"instantclient_23_7/e", ".."}}},
+		{"file over a link", [][2]string{{"instantclient_23_7/lib", "libclntsh.so"}, {"instantclient_23_7/lib", ""}}},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		installPath := filepath.Join(root, "install")
+		archive := filepath.Join(t.TempDir(), "chain.zip")
+		if !writeEntries(t, archive, tt.entries) {
+			t.Fatalf("%s: writing the archive", tt.name)
+		}
+		if _, err := UnZip(context.Background(), archive, installPath); err == nil {
+			t.Errorf("%s: UnZip succeeded", tt.name)
+		}
+		siblings, err := os.ReadDir(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range siblings {
+			if e.Name() != "install" {
+				t.Errorf("%s: created %s outside the installation directory", tt.name, e.Name())
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// DownloadMirrored downloads file from the first of the base URLs bases that
+// serves it, trying each in order with DownloadArchive, and returns that base
+// URL. When every one fails, the error gives the reason for each.
+func DownloadMirrored(ctx context.Context, client *http.Client, bases []string, file, downloadsPath string, limits config.SizeLimits, retry config.RetryPolicy) (string, error) {
+	ctx = EnsureContext(ctx)
+	if len(bases) == 1 {
+		return bases[0], DownloadArchive(ctx, client, bases[0]+file, downloadsPath, limits, retry)
+	}
+	var reasons []string
+	for i, base := range bases {
+		err := DownloadArchive(ctx, client, base+file, downloadsPath, limits, retry)
+		if err == nil {
+			if i > 0 {
+				fmt.Printf("downloaded %s from mirror %s\n", file, DisplayBaseURL(base))
+			}
+			return base, nil
+		}
+		// A cancelled run is not the mirror's fault
+		if ctx.Err() != nil {
+			return "", err
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %v", DisplayBaseURL(base), err))
+		if i < len(bases)-1 {
+			fmt.Printf("downloading %s from %s failed (%v), trying %s\n", file, DisplayBaseURL(base), err, DisplayBaseURL(bases[i+1]))
+		}
+	}
+	return "", errs.HandleError(
+		fmt.Errorf("%s could not be downloaded from any of %d mirrors:\n  %s", file, len(bases), strings.Join(reasons, "\n  ")),
+		errs.ErrorTypeDownload,
+		"downloading from mirrors")
+}
+
+// DisplayBaseURL returns base with any password in it hidden, for messages
+func DisplayBaseURL(base string) string {
+	if u, err := url.Parse(base); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return base
+}
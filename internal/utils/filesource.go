@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// LocalSource returns the file system path of a download source that names a
+// file rather than a web resource: a file:// URL, a UNC path such as
+// \\fileserver\oracle\pkg.zip, or a local path
+func LocalSource(source string) (string, bool) {
+	if IsUNC(source) || filepath.IsAbs(source) || filepath.VolumeName(source) != "" {
+		return source, true
+	}
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	p := u.Path
+	if u.Host != "" && u.Host != "localhost" {
+		// file://fileserver/oracle/pkg.zip names \\fileserver\oracle\pkg.zip
+		return filepath.FromSlash("//" + u.Host + p), true
+	}
+	// file:///C:/oracle/pkg.zip names C:\oracle\pkg.zip
+	if len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p), true
+}
+
+// copyArchive copies a package or SDK archive from a file share or local
+// directory, checking its size against limits as a download's is, and deletes
+// the partial file if the copy fails. Shares can stop responding, so the copy
+// gives up once ctx is done.
+func copyArchive(ctx context.Context, src, dst string, limits config.SizeLimits) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeDownload, "opening source archive")
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeDownload, "opening source archive")
+	}
+	// Downloading into the directory the archives are served from leaves nothing to do
+	if out, err := os.Stat(dst); err == nil && os.SameFile(fi, out) {
+		return nil
+	}
+	if err := checkSize(fi.Size(), limits, ""); err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return errs.HandleError(err, errs.ErrorTypeDownload, "creating download file")
+	}
+	progress := &progressWriter{ctx: ctx, name: filepath.Base(dst), size: fi.Size()}
+	_, err = io.Copy(io.MultiWriter(out, progress), &ctxReader{ctx: ctx, r: in})
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return errs.HandleError(err, errs.ErrorTypeDownload, "copying archive from "+filepath.Dir(src))
+	}
+	return nil
+}
+
+// ctxReader stops reading once its context is done
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+)
+
+func TestLocalSource(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+		ok     bool
+	}{
+		{"https://download.oracle.com/pkg.zip", "", false},
+		{`\\fileserver\oracle\pkg.zip`, `\\fileserver\oracle\pkg.zip`, true},
+		{"file://fileserver/oracle/pkg.zip", filepath.FromSlash("//fileserver/oracle/pkg.zip"), true},
+		{"file:///srv/oracle/pkg%20copy.zip", filepath.FromSlash("/srv/oracle/pkg copy.zip"), true},
+		{"file://localhost/srv/oracle/pkg.zip", filepath.FromSlash("/srv/oracle/pkg.zip"), true},
+		{"file:///C:/oracle/pkg.zip", filepath.FromSlash("C:/oracle/pkg.zip"), true},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			source string
+			want   string
+			ok     bool
+		}{"/srv/oracle/pkg.zip", "/srv/oracle/pkg.zip", true})
+	}
+	for _, tt := range tests {
+		got, ok := LocalSource(tt.source)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("LocalSource(%q) = %q, %v; want %q, %v", tt.source, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDownloadArchiveFromShare(t *testing.T) {
+	share := t.TempDir()
+	data := bytes.Repeat([]byte("instantclient"), 1000)
+	if err := os.WriteFile(filepath.Join(share, "pkg.zip"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	base := "file://" + filepath.ToSlash(share) + "/"
+	if runtime.GOOS == "windows" {
+		base = "file:///" + filepath.ToSlash(share) + "/"
+	}
+
+	dst := filepath.Join(t.TempDir(), "pkg.zip")
+	if err := DownloadArchive(context.Background(), nil, base+"pkg.zip", dst, config.SizeLimits{}, config.DefaultRetryPolicy()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(dst); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("copied %d bytes (%v), want %d", len(got), err, len(data))
+	}
+
+	// Size limits apply as to downloads, and nothing is left behind
+	os.Remove(dst)
+	err := DownloadArchive(context.Background(), nil, filepath.Join(share, "pkg.zip"), dst, config.SizeLimits{Min: 1 << 20}, config.DefaultRetryPolicy())
+	if err == nil || !strings.Contains(err.Error(), "less than the expected minimum") {
+		t.Errorf("copying an undersized archive: %v", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("undersized archive was written: %v", err)
+	}
+
+	// A download into the directory it is served from leaves the archive as it is
+	src := filepath.Join(share, "pkg.zip")
+	if err := DownloadArchive(context.Background(), nil, src, src, config.SizeLimits{}, config.DefaultRetryPolicy()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(src); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("archive changed by copying it onto itself: %d bytes, %v", len(got), err)
+	}
+
+	if err := DownloadArchive(context.Background(), nil, base+"missing.zip", dst, config.SizeLimits{}, config.DefaultRetryPolicy()); err == nil {
+		t.Error("copying a missing archive succeeded")
+	}
+}
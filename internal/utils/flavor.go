@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"os"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+)
+
+// ClientFlavor returns the package flavor of the client in dir, told apart by
+// its NLS data library: Basic has the one with every language and character
+// set (oraociei*.dll, libociei), Basic Light the English-only one
+// (oraociicus*.dll, libociicus). It is "" when dir holds neither.
+func ClientFlavor(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		name := strings.ToLower(e.Name())
+		switch {
+		case strings.HasPrefix(name, "oraociei") || strings.HasPrefix(name, "libociei"):
+			return config.FlavorBasic
+		case strings.HasPrefix(name, "oraociicus") || strings.HasPrefix(name, "libociicus"):
+			return config.FlavorBasicLite
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+)
+
+// mirrorTransport adds the configured headers to requests for authenticated
+// mirrors. Headers are keyed by host, so a redirect to another host, such as
+// a storage backend, never carries the mirror's credentials.
+type mirrorTransport struct {
+	base    *http.Transport
+	mirrors map[string]config.MirrorConfig
+}
+
+// RoundTrip resolves the headers of the request's mirror, if any, and sends it
+func (t *mirrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	m, ok := t.mirror(req.URL.Host, req.URL.Hostname())
+	if !ok || len(m.Headers) == 0 {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for name, h := range m.Headers {
+		value, err := h.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(name, value)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// mirror returns the settings of the mirror at host:port, or at host alone
+func (t *mirrorTransport) mirror(hostPort, host string) (config.MirrorConfig, bool) {
+	for key, m := range t.mirrors {
+		if strings.EqualFold(key, hostPort) || strings.EqualFold(key, host) {
+			return m, true
+		}
+	}
+	return config.MirrorConfig{}, false
+}
+
+// BaseTransport returns the transport underlying a client built by NewHTTPClient
+func BaseTransport(client *http.Client) *http.Transport {
+	rt := client.Transport
+	for {
+		switch t := rt.(type) {
+		case *busyTransport:
+			rt = t.base
+		case *mirrorTransport:
+			return t.base
+		default:
+			return rt.(*http.Transport)
+		}
+	}
+}
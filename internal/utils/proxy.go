@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/pwsh"
+)
+
+// ProxyFunc returns the proxy function of the download transport for a proxy
+// setting: the proxy environment variables when empty, no proxy for direct,
+// the system settings for system, and otherwise the proxy URL given for every
+// request
+func ProxyFunc(setting string) func(*http.Request) (*url.URL, error) {
+	switch setting {
+	case "":
+		return http.ProxyFromEnvironment
+	case config.ProxyDirect:
+		return nil
+	case config.ProxySystem:
+		return systemProxy()
+	}
+	proxy, err := config.ParseProxy(setting)
+	return func(*http.Request) (*url.URL, error) { return proxy, err }
+}
+
+// systemProxy returns a proxy function looking up the system settings once for
+// each scheme and host. Where they name no proxy the environment variables
+// apply, so HTTPS_PROXY still works on other platforms.
+func systemProxy() func(*http.Request) (*url.URL, error) {
+	var mu sync.Mutex
+	cache := make(map[string]*url.URL)
+	return func(req *http.Request) (*url.URL, error) {
+		key := req.URL.Scheme + "://" + req.URL.Host
+		mu.Lock()
+		defer mu.Unlock()
+		if proxy, ok := cache[key]; ok {
+			return proxy, nil
+		}
+		proxy := lookupSystemProxy(req.Context(), req.URL)
+		if proxy == nil {
+			var err error
+			if proxy, err = http.ProxyFromEnvironment(req); err != nil {
+				return nil, err
+			}
+		}
+		cache[key] = proxy
+		return proxy, nil
+	}
+}
+
+// lookupSystemProxy returns the proxy the system settings name for target, or
+// nil for none. On Windows these are the WinINet settings, evaluating PAC files
+// and WPAD as browsers do, then the WinHTTP settings services use; on macOS the
+// network settings, without evaluating PAC files.
+func lookupSystemProxy(ctx context.Context, target *url.URL) *url.URL {
+	switch runtime.GOOS {
+	case "windows":
+		script := "$uri = [Uri]" + pwsh.Quote(target.String()) + "\n" +
+			"$proxy = [Net.WebRequest]::GetSystemWebProxy()\n" +
+			"if (-not $proxy.IsBypassed($uri)) { $proxy.GetProxy($uri).AbsoluteUri }"
+		if out, err := pwsh.Run(ctx, script); err == nil {
+			if s := strings.TrimSpace(string(out)); s != "" && s != target.String() {
+				if proxy, err := config.ParseProxy(s); err == nil {
+					return proxy
+				}
+			}
+		}
+		if out, err := pwsh.Output(ctx, "netsh", "winhttp", "show", "proxy"); err == nil {
+			return parseWinHTTPProxy(out, target.Scheme)
+		}
+	case "darwin":
+		if out, err := pwsh.Output(ctx, "scutil", "--proxy"); err == nil {
+			return parseScutilProxy(out, target.Scheme)
+		}
+	}
+	return nil
+}
+
+// parseWinHTTPProxy returns the proxy for scheme in the output of netsh
+// winhttp show proxy, which is either a single host:port or a list of
+// scheme=host:port entries
+func parseWinHTTPProxy(out []byte, scheme string) *url.URL {
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		name, value, ok := strings.Cut(sc.Text(), ":")
+		if !ok || strings.TrimSpace(name) != "Proxy Server(s)" {
+			continue
+		}
+		var server string
+		for _, entry := range strings.Split(strings.TrimSpace(value), ";") {
+			entry = strings.TrimSpace(entry)
+			if s, p, ok := strings.Cut(entry, "="); ok {
+				if strings.EqualFold(s, scheme) {
+					server = p
+					break
+				}
+			} else if server == "" {
+				server = entry
+			}
+		}
+		if server == "" {
+			return nil
+		}
+		proxy, err := config.ParseProxy(server)
+		if err != nil {
+			return nil
+		}
+		return proxy
+	}
+	return nil
+}
+
+// parseScutilProxy returns the proxy for scheme enabled in the output of
+// scutil --proxy
+func parseScutilProxy(out []byte, scheme string) *url.URL {
+	values := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		if name, value, ok := strings.Cut(sc.Text(), " : "); ok {
+			values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	prefix := strings.ToUpper(scheme)
+	if values[prefix+"Enable"] != "1" || values[prefix+"Proxy"] == "" {
+		return nil
+	}
+	host := values[prefix+"Proxy"]
+	if port := values[prefix+"Port"]; port != "" {
+		host += ":" + port
+	}
+	proxy, err := config.ParseProxy(host)
+	if err != nil {
+		return nil
+	}
+	return proxy
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/sspi"
+)
+
+// maxAuthRounds bounds the round trips of a proxy authentication handshake;
+// NTLM takes two and Kerberos one
+const maxAuthRounds = 4
+
+// proxyHandshake is a connection-based authentication handshake, as
+// sspi.Context is
+type proxyHandshake interface {
+	Step(challenge []byte) ([]byte, error)
+	Close()
+}
+
+// startSSPI starts a handshake as the logged-on Windows user
+func startSSPI(scheme, target string) (proxyHandshake, error) {
+	c, err := sspi.New(scheme, target)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// tunnelDialer connects through the proxy with a CONNECT tunnel it
+// authenticates itself. Negotiate and NTLM authenticate a connection rather
+// than a request, over several round trips on it, which http.Transport cannot
+// do, so the transport is given the tunnel as if it were a direct connection.
+type tunnelDialer struct {
+	dial   func(ctx context.Context, network, addr string) (net.Conn, error)
+	proxy  func(*http.Request) (*url.URL, error)
+	scheme string // Negotiate or NTLM
+	start  func(scheme, target string) (proxyHandshake, error)
+}
+
+// newTunnelDialer returns the dialer authenticating to the proxies of proxy
+// with the given config.ProxyAuth scheme
+func newTunnelDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error), proxy func(*http.Request) (*url.URL, error), auth string) *tunnelDialer {
+	scheme := sspi.Negotiate
+	if auth == config.ProxyAuthNTLM {
+		scheme = sspi.NTLM
+	}
+	return &tunnelDialer{dial: dial, proxy: proxy, scheme: scheme, start: startSSPI}
+}
+
+// DialContext connects to addr, through an authenticated tunnel when the proxy
+// function names a proxy for it
+func (d *tunnelDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodConnect, "https://"+addr, nil)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := d.proxy(req)
+	if err != nil {
+		return nil, err
+	}
+	if proxy == nil {
+		return d.dial(ctx, network, addr)
+	}
+	if proxy.Scheme != "http" {
+		return nil, errs.HandleError(fmt.Errorf("%s authentication needs an http:// proxy, not %s://", d.scheme, proxy.Scheme), errs.ErrorTypeValidation, "connecting to the proxy")
+	}
+	proxyAddr := proxy.Host
+	if proxy.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxy.Hostname(), "80")
+	}
+	conn, err := d.dial(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.connect(ctx, conn, addr, proxy.Hostname()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// connect opens the tunnel to addr on conn, answering the proxy's challenges
+func (d *tunnelDialer) connect(ctx context.Context, conn net.Conn, addr, proxyHost string) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	hs, err := d.start(d.scheme, "HTTP/"+proxyHost)
+	if err != nil {
+		return err
+	}
+	defer hs.Close()
+
+	br := bufio.NewReader(conn)
+	var challenge []byte
+	for round := 0; round < maxAuthRounds; round++ {
+		token, err := hs.Step(challenge)
+		if err != nil {
+			return err
+		}
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "CONNECT %s HTTP/1.1\r\nHost: %s\r\nProxy-Authorization: %s %s\r\nProxy-Connection: Keep-Alive\r\n\r\n",
+			addr, addr, d.scheme, base64.StdEncoding.EncodeToString(token))
+		if _, err := conn.Write(b.Bytes()); err != nil {
+			return proxyAuthError(err)
+		}
+		resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+		if err != nil {
+			return proxyAuthError(fmt.Errorf("reading the proxy's answer: %w", err))
+		}
+		if resp.StatusCode == http.StatusOK {
+			if br.Buffered() > 0 {
+				return proxyAuthError(fmt.Errorf("the proxy sent data before the tunnel to %s was open", addr))
+			}
+			return nil
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusProxyAuthRequired {
+			return proxyAuthError(fmt.Errorf("the proxy answered CONNECT %s with %s", addr, resp.Status))
+		}
+		challenge = proxyChallenge(resp.Header, d.scheme)
+		if challenge == nil || resp.Close {
+			return proxyAuthError(fmt.Errorf("the proxy rejected %s authentication as the logged-on user", d.scheme))
+		}
+	}
+	return proxyAuthError(fmt.Errorf("%s authentication did not complete in %d round trips", d.scheme, maxAuthRounds))
+}
+
+// proxyChallenge returns the token of the proxy's challenge for scheme, or nil
+// if it offers none
+func proxyChallenge(h http.Header, scheme string) []byte {
+	for _, v := range h.Values("Proxy-Authenticate") {
+		name, token, ok := strings.Cut(strings.TrimSpace(v), " ")
+		if !ok || !strings.EqualFold(name, scheme) {
+			continue
+		}
+		if b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(token)); err == nil && len(b) > 0 {
+			return b
+		}
+	}
+	return nil
+}
+
+// proxyAuthError wraps a failure to open an authenticated tunnel
+func proxyAuthError(err error) error {
+	return errs.HandleError(err, errs.ErrorTypeDownload, "authenticating to the proxy")
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeHandshake answers the challenge "challenge" with "answer" after opening with "hello"
+type fakeHandshake struct{}
+
+func (fakeHandshake) Step(challenge []byte) ([]byte, error) {
+	if challenge == nil {
+		return []byte("hello"), nil
+	}
+	return []byte("answer to " + string(challenge)), nil
+}
+
+func (fakeHandshake) Close() {}
+
+// ntlmProxy serves CONNECT requests on l, challenging the first one on each
+// connection and opening the tunnel once the challenge is answered; reject
+// refuses every token instead
+func ntlmProxy(t *testing.T, l net.Listener, reject bool) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			br := bufio.NewReader(conn)
+			for {
+				req, err := http.ReadRequest(br)
+				if err != nil {
+					return
+				}
+				token, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(req.Header.Get("Proxy-Authorization"), "NTLM "))
+				switch {
+				case reject || string(token) == "hello":
+					challenge := ""
+					if !reject {
+						challenge = " " + base64.StdEncoding.EncodeToString([]byte("challenge"))
+					}
+					io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\nProxy-Authenticate: NTLM"+challenge+"\r\nContent-Length: 0\r\n\r\n")
+				case string(token) == "answer to challenge":
+					target, err := net.Dial("tcp", req.Host)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					defer target.Close()
+					io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+					go io.Copy(target, br)
+					io.Copy(conn, target)
+					return
+				default:
+					t.Errorf("unexpected token %q", token)
+					return
+				}
+			}
+		}()
+	}
+}
+
+func TestTunnelDialer(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "archive")
+	}))
+	defer target.Close()
+
+	for _, reject := range []bool{false, true} {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go ntlmProxy(t, l, reject)
+		proxy, _ := url.Parse("http://" + l.Addr().String())
+		d := &tunnelDialer{
+			dial:   (&net.Dialer{}).DialContext,
+			proxy:  http.ProxyURL(proxy),
+			scheme: "NTLM",
+			start:  func(scheme, target string) (proxyHandshake, error) { return fakeHandshake{}, nil },
+		}
+		client := &http.Client{Transport: &http.Transport{DialContext: d.DialContext}}
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, target.URL, nil)
+		resp, err := client.Do(req)
+		if reject {
+			if err == nil || !strings.Contains(err.Error(), "rejected") {
+				t.Errorf("rejected handshake gave %v", err)
+			}
+		} else if err != nil {
+			t.Error(err)
+		} else {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "archive" {
+				t.Errorf("body through the tunnel = %q", body)
+			}
+		}
+		l.Close()
+	}
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// RemoteClientDir returns the versioned top-level directory of an Instant Client
+// zip on a web server without downloading it, by reading the zip's central
+// directory with HTTP range requests. Servers that do not support ranges fail.
+// A package on a file share is read directly.
+func RemoteClientDir(ctx context.Context, client *http.Client, url string) (string, error) {
+	if path, ok := LocalSource(url); ok {
+		return ZipClientDir(path)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeDownload, "creating HTTP request")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeDownload, "checking remote package")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errs.HandleError(fmt.Errorf("HTTP status %s", resp.Status), errs.ErrorTypeDownload, "checking remote package")
+	}
+	if resp.ContentLength <= 0 || resp.Header.Get("Accept-Ranges") != "bytes" {
+		return "", errs.HandleError(fmt.Errorf("server does not support range requests"), errs.ErrorTypeDownload, "checking remote package")
+	}
+
+	r, err := zip.NewReader(&httpReaderAt{ctx: ctx, client: client, url: url}, resp.ContentLength)
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeDownload, "reading remote zip directory")
+	}
+	for _, f := range r.File {
+		if clientDirPattern.MatchString(f.Name) {
+			return f.Name[:len(f.Name)-1], nil
+		}
+	}
+	return "", errs.HandleError(fmt.Errorf("no valid instant client directory found in zip"), errs.ErrorTypeDownload, "validating remote zip contents")
+}
+
+// httpReaderAt reads ranges of a remote file
+type httpReaderAt struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+}
+
+// ReadAt implements io.ReaderAt with a range request per call
+func (h *httpReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	req, err := http.NewRequestWithContext(h.ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("range request returned HTTP status %s", resp.Status)
+	}
+	n, err := io.ReadFull(resp.Body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+)
+
+func TestDownloadArchiveRetry(t *testing.T) {
+	payload := []byte("PK archive contents")
+	retry := config.RetryPolicy{Attempts: 3, Backoff: time.Millisecond, Statuses: []int{http.StatusBadGateway}}
+
+	tests := []struct {
+		name     string
+		fail     func(w http.ResponseWriter) // Answers the failing requests
+		failures int32                       // Requests failing before the payload is served
+		attempts int32                       // Requests expected
+		ok       bool
+	}{
+		{
+			name:     "retryable status",
+			fail:     func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+			failures: 2,
+			attempts: 3,
+			ok:       true,
+		},
+		{
+			name: "connection dropped mid-body",
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Length", "1000")
+				w.Write(payload)
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+			},
+			failures: 1,
+			attempts: 2,
+			ok:       true,
+		},
+		{
+			name:     "gives up after the last attempt",
+			fail:     func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+			failures: 5,
+			attempts: 3,
+		},
+		{
+			name:     "status not retried",
+			fail:     func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound) },
+			failures: 5,
+			attempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) <= tt.failures {
+					tt.fail(w)
+					return
+				}
+				w.Write(payload)
+			}))
+			defer srv.Close()
+
+			dst := filepath.Join(t.TempDir(), "pkg.zip")
+			err := DownloadArchive(context.Background(), srv.Client(), srv.URL+"/pkg.zip", dst, config.SizeLimits{}, retry)
+			if got := requests.Load(); got != tt.attempts {
+				t.Errorf("%d requests, want %d", got, tt.attempts)
+			}
+			if !tt.ok {
+				if err == nil {
+					t.Fatal("download succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(dst); string(got) != string(payload) {
+				t.Errorf("downloaded %q, want %q", got, payload)
+			}
+		})
+	}
+}
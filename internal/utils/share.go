@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// IsUNC reports whether path is a UNC network path such as \\server\share\dir
+func IsUNC(path string) bool {
+	return strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, "//")
+}
+
+// ShareRoot returns the \\server\share prefix of a UNC path
+func ShareRoot(path string) string {
+	parts := strings.FieldsFunc(path, func(r rune) bool { return r == '\\' || r == '/' })
+	if len(parts) < 2 {
+		return path
+	}
+	return `\\` + parts[0] + `\` + parts[1]
+}
+
+// CheckShare verifies the share holding a UNC path is reachable and writable.
+// Nothing is created: writability is checked in path or, until the install
+// creates it, its nearest existing parent.
+// Unreachable servers can block file system calls for a long time, so the checks
+// give up once ctx is done.
+func CheckShare(ctx context.Context, path string) error {
+	ctx = EnsureContext(ctx)
+	root := ShareRoot(path)
+
+	done := make(chan error, 1)
+	go func() {
+		if _, err := os.Stat(root); err != nil {
+			done <- fmt.Errorf("share %s is not reachable: %w", root, err)
+			return
+		}
+		f, err := os.CreateTemp(NearestExistingDir(path), ".oraicwinconfig-*")
+		if err != nil {
+			done <- fmt.Errorf("share %s is not writable: %w", root, err)
+			return
+		}
+		f.Close()
+		os.Remove(f.Name())
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		return errs.HandleError(err, errs.ErrorTypeValidation, "checking network share")
+	case <-ctx.Done():
+		return errs.HandleError(fmt.Errorf("share %s did not respond: %w", root, ctx.Err()), errs.ErrorTypeValidation, "checking network share")
+	}
+}
+
+// NearestExistingDir walks up from path until it finds a directory that exists
+func NearestExistingDir(path string) string {
+	path = filepath.Clean(path)
+	for {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// CheckDir verifies path is a reachable directory without writing to it, giving
+// up once ctx is done, for read-only locations such as a corporate TNS_ADMIN share
+func CheckDir(ctx context.Context, path string) error {
+	ctx = EnsureContext(ctx)
+	done := make(chan error, 1)
+	go func() {
+		info, err := os.Stat(path)
+		if err == nil && !info.IsDir() {
+			err = fmt.Errorf("not a directory")
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return errs.HandleError(fmt.Errorf("%s is not reachable: %w", path, err), errs.ErrorTypeValidation, "checking directory")
+		}
+		return nil
+	case <-ctx.Done():
+		return errs.HandleError(fmt.Errorf("%s did not respond: %w", path, ctx.Err()), errs.ErrorTypeValidation, "checking directory")
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// tlsConfig returns the TLS configuration of the download client, combining
+// the certificate pins, the extra root certificates and skipping verification,
+// or nil when none is configured. Unreadable CA files fail every connection
+// rather than silently falling back to the system roots.
+func tlsConfig(hc config.HTTPConfig) *tls.Config {
+	if !hc.TLSPin.Enabled() && len(hc.CACerts) == 0 && !hc.Insecure {
+		return nil
+	}
+	c := &tls.Config{}
+	if hc.TLSPin.Enabled() {
+		c = pinnedTLSConfig(hc.TLSPin)
+	}
+	if len(hc.CACerts) > 0 {
+		roots, err := extraRoots(hc.CACerts)
+		if err != nil {
+			verify := c.VerifyConnection
+			c.VerifyConnection = func(cs tls.ConnectionState) error {
+				if verify != nil {
+					if verr := verify(cs); verr != nil {
+						return verr
+					}
+				}
+				return err
+			}
+		}
+		c.RootCAs = roots
+	}
+	c.InsecureSkipVerify = hc.Insecure
+	return c
+}
+
+// extraRoots returns the system roots together with the certificates in the
+// PEM files
+func extraRoots(paths []string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errs.HandleError(err, errs.ErrorTypeValidation, "reading CA certificate file")
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errs.HandleError(fmt.Errorf("%s contains no PEM certificates", path), errs.ErrorTypeValidation, "reading CA certificate file")
+		}
+	}
+	return pool, nil
+}
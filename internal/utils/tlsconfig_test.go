@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+)
+
+func TestTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	cert := srv.Certificate()
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	notPEM := filepath.Join(dir, "not.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		hc     config.HTTPConfig
+		wantOK bool
+	}{
+		{"system roots", config.HTTPConfig{}, false},
+		{"extra root", config.HTTPConfig{CACerts: []string{caFile}}, true},
+		{"missing file", config.HTTPConfig{CACerts: []string{caFile, filepath.Join(dir, "missing.pem")}}, false},
+		{"no certificates", config.HTTPConfig{CACerts: []string{notPEM}}, false},
+		{"insecure", config.HTTPConfig{Insecure: true}, true},
+		{"insecure with unreadable file", config.HTTPConfig{CACerts: []string{notPEM}, Insecure: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &http.Transport{TLSClientConfig: tlsConfig(tt.hc)}
+			defer transport.CloseIdleConnections()
+			resp, err := (&http.Client{Transport: transport}).Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if ok := err == nil; ok != tt.wantOK {
+				t.Fatalf("request succeeded %v, want %v (err %v)", ok, tt.wantOK, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/errs"
+)
+
+// pinnedTLSConfig returns a TLS configuration that, on top of the usual
+// verification, rejects connections to the pinned hosts whose chain matches
+// none of the pins. Invalid pins or an unreadable CA file fail every
+// connection to those hosts rather than silently disabling the pinning.
+func pinnedTLSConfig(p config.TLSPinConfig) *tls.Config {
+	pins := make(map[string]bool, len(p.SHA256))
+	var setupErr error
+	for _, s := range p.SHA256 {
+		pin, err := config.ParseFingerprint(s)
+		if err != nil {
+			setupErr = err
+		}
+		pins[pin] = true
+	}
+	var roots *x509.CertPool
+	if p.CAFile != "" {
+		pool, err := loadCAFile(p.CAFile)
+		if err != nil {
+			setupErr = err
+		}
+		roots = pool
+	}
+
+	return &tls.Config{
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if !pinnedHost(p.Hosts, cs.ServerName) {
+				return nil
+			}
+			if setupErr != nil {
+				return setupErr
+			}
+			if roots != nil {
+				if err := verifyCA(cs, roots); err != nil {
+					return err
+				}
+			}
+			if len(pins) > 0 && !chainMatches(cs, pins) {
+				return errs.HandleError(
+					fmt.Errorf("the certificate presented by the download host (SHA-256 %s) matches none of the pinned fingerprints; a TLS-intercepting proxy or DNS hijack may be in the path",
+						FingerprintSHA256(cs.PeerCertificates[0].Raw)),
+					errs.ErrorTypeDownload,
+					"verifying pinned certificate")
+			}
+			return nil
+		},
+	}
+}
+
+// pinnedHost reports whether the pins apply to host. No server name is sent
+// for IP addresses, so an empty host is pinned whenever an IP address is.
+func pinnedHost(hosts []string, host string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	for _, h := range hosts {
+		if strings.EqualFold(h, host) || host == "" && net.ParseIP(h) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// chainMatches reports whether the certificate or public key of any
+// certificate presented or verified for the connection is pinned
+func chainMatches(cs tls.ConnectionState, pins map[string]bool) bool {
+	certs := cs.PeerCertificates
+	for _, chain := range cs.VerifiedChains {
+		certs = append(certs, chain...)
+	}
+	for _, cert := range certs {
+		if pins[FingerprintSHA256(cert.Raw)] || pins[FingerprintSHA256(cert.RawSubjectPublicKeyInfo)] {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyCA checks that the presented chain leads to one of the pinned CAs
+func verifyCA(cs tls.ConnectionState, roots *x509.CertPool) error {
+	opts := x509.VerifyOptions{DNSName: cs.ServerName, Roots: roots, Intermediates: x509.NewCertPool()}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+		return errs.HandleError(
+			fmt.Errorf("the certificate presented by the download host is not issued by the pinned CA; a TLS-intercepting proxy or DNS hijack may be in the path: %w", err),
+			errs.ErrorTypeDownload,
+			"verifying pinned certificate")
+	}
+	return nil
+}
+
+// loadCAFile reads the PEM certificates of a CA file into a pool
+func loadCAFile(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.HandleError(err, errs.ErrorTypeValidation, "reading pinned CA file")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errs.HandleError(fmt.Errorf("%s contains no PEM certificates", path), errs.ErrorTypeValidation, "reading pinned CA file")
+	}
+	return pool, nil
+}
+
+// FingerprintSHA256 returns the lowercase hex SHA-256 of data, the form
+// pins are compared in
+func FingerprintSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
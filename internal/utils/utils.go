@@ -3,16 +3,85 @@ package utils
 import (
 	"archive/zip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"path"
 	"path/filepath"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/mghoff/oraicwinconfig/internal/config"
 	"github.com/mghoff/oraicwinconfig/internal/errs"
+	"github.com/mghoff/oraicwinconfig/internal/events"
 )
 
+// clientDirPattern matches the versioned top-level directory of an Instant Client zip
+var clientDirPattern = regexp.MustCompilePOSIX(`^(instantclient_){1}([0-9]{1,2})_([0-9]{1,2})\/$`)
+
+// ZipClientDir returns the versioned top-level directory of an Instant Client zip
+// without extracting it, e.g. instantclient_23_7
+func ZipClientDir(zipPath string) (string, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeInstall, "opening zip archive")
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if clientDirPattern.MatchString(f.Name) {
+			return filepath.Clean(f.Name), nil
+		}
+	}
+	return "", errs.HandleError(
+		fmt.Errorf("no valid instant client directory found in zip"),
+		errs.ErrorTypeInstall,
+		"validating zip contents",
+	)
+}
+
+// ClientVersion returns the version of a versioned client directory,
+// e.g. 23.7 for instantclient_23_7, or "" if dir is not one
+func ClientVersion(dir string) string {
+	m := clientDirPattern.FindStringSubmatch(filepath.Base(dir) + "/")
+	if m == nil {
+		return ""
+	}
+	return m[2] + "." + m[3]
+}
+
+// NewerVersion reports whether dotted version a is later than b
+func NewerVersion(a, b string) bool {
+	va, vb := versionParts(a), versionParts(b)
+	for i := 0; i < len(va) && i < len(vb); i++ {
+		if va[i] != vb[i] {
+			return va[i] > vb[i]
+		}
+	}
+	return len(va) > len(vb)
+}
+
+// versionParts splits a dotted version into numbers
+func versionParts(v string) []int {
+	var parts []int
+	for _, f := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return parts
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
 // ensureContext returns context.Background() if ctx is nil, otherwise returns ctx.
 func EnsureContext(ctx context.Context) context.Context {
 	if ctx == nil {
@@ -21,48 +90,251 @@ func EnsureContext(ctx context.Context) context.Context {
 	return ctx
 }
 
+// WithTimeout derives a context bounded by d from ctx.
+// A zero or negative d only makes the returned context cancellable.
+func WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	ctx = EnsureContext(ctx)
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// NewHTTPClient builds the HTTP client used for downloads from the given settings
+func NewHTTPClient(hc config.HTTPConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   hc.DialTimeout,
+		KeepAlive: hc.KeepAlive,
+	}
+	dial := dialer.DialContext
+	if hc.IPVersion == config.IPVersion4 || hc.IPVersion == config.IPVersion6 {
+		// Restrict tcp to tcp4 or tcp6, for networks where the other family is broken
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network+hc.IPVersion, addr)
+		}
+	}
+	transport := &http.Transport{
+		Proxy:                 ProxyFunc(hc.Proxy),
+		DialContext:           dial,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   hc.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: hc.ResponseHeaderTimeout,
+		IdleConnTimeout:       hc.IdleConnTimeout,
+		MaxIdleConns:          hc.MaxIdleConns,
+		MaxIdleConnsPerHost:   hc.MaxIdleConns,
+		ExpectContinueTimeout: time.Second,
+	}
+	if hc.ProxyAuth != "" && transport.Proxy != nil {
+		// The dialer tunnels through the proxy itself, so the transport sees no proxy
+		transport.DialContext = newTunnelDialer(dial, transport.Proxy, hc.ProxyAuth).DialContext
+		transport.Proxy = nil
+	}
+	transport.TLSClientConfig = tlsConfig(hc)
+	var rt http.RoundTripper = transport
+	if len(hc.Mirrors) > 0 {
+		rt = &mirrorTransport{base: transport, mirrors: hc.Mirrors}
+	}
+	if hc.BusyRetries > 0 {
+		rt = &busyTransport{base: rt, retries: hc.BusyRetries, maxWait: hc.MaxRetryAfter}
+	}
+	if wrapTransport != nil {
+		rt = wrapTransport(rt)
+	}
+	return &http.Client{
+		Transport: rt,
+		Timeout:   hc.RequestTimeout,
+	}
+}
+
+// wrapTransport wraps the transport of every client NewHTTPClient returns
+var wrapTransport func(http.RoundTripper) http.RoundTripper
+
+// InterceptHTTP has wrap wrap the transport of the clients NewHTTPClient
+// returns from now on, outside any earlier wrapping, so a trace can record
+// the exchanges a run depends on or replay them
+func InterceptHTTP(wrap func(http.RoundTripper) http.RoundTripper) {
+	prev := wrapTransport
+	wrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if prev != nil {
+			rt = prev(rt)
+		}
+		return wrap(rt)
+	}
+}
+
 // downloadZip downloads the Oracle Instant Client zip file from the specified URL
-func DownloadZip(ctx context.Context, urlPath, downloadsPath string) error {
+func DownloadZip(ctx context.Context, client *http.Client, urlPath, downloadsPath string) error {
+	return DownloadArchive(ctx, client, urlPath, downloadsPath, config.SizeLimits{}, config.DefaultRetryPolicy())
+}
+
+// DownloadArchive downloads a package or SDK archive, or copies it from a file
+// share, failing before and while writing it if the size is outside limits,
+// and deleting the partial file.
+// A download failing in a way retry deems transient is started over, waiting
+// longer before each attempt.
+func DownloadArchive(ctx context.Context, client *http.Client, urlPath, downloadsPath string, limits config.SizeLimits, retry config.RetryPolicy) error {
 	ctx = EnsureContext(ctx)
+	// Archives on a file share or local directory are copied instead
+	if src, ok := LocalSource(urlPath); ok {
+		return copyArchive(ctx, src, downloadsPath, limits)
+	}
+	wait := retry.Backoff
+	for attempt := 1; ; attempt++ {
+		transient, err := downloadOnce(ctx, client, urlPath, downloadsPath, limits, retry)
+		if err == nil || !transient || attempt >= retry.Attempts || ctx.Err() != nil {
+			if err != nil && attempt > 1 {
+				return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+			}
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return fmt.Errorf("waiting %s to retry would pass the time limit: %w", wait, err)
+		}
+
+		fmt.Fprintf(os.Stderr, "downloading %s failed (%v), retrying in %s (attempt %d of %d)\n", filepath.Base(downloadsPath), err, wait, attempt+1, retry.Attempts)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errs.HandleError(ctx.Err(), errs.ErrorTypeDownload, "context cancellation")
+		case <-timer.C:
+		}
+		wait *= 2
+		if retry.MaxBackoff > 0 && wait > retry.MaxBackoff {
+			wait = retry.MaxBackoff
+		}
+	}
+}
+
+// downloadOnce makes a single attempt at a download, and reports whether a
+// failure is transient enough for retry to try again
+func downloadOnce(ctx context.Context, client *http.Client, urlPath, downloadsPath string, limits config.SizeLimits, retry config.RetryPolicy) (bool, error) {
 	// Check for context cancellation
 	if err := ctx.Err(); err != nil {
-		return errs.HandleError(err, errs.ErrorTypeDownload, "context cancellation")
+		return false, errs.HandleError(err, errs.ErrorTypeDownload, "context cancellation")
 	}
 
 	// Create HTTP request with context
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlPath, nil)
 	if err != nil {
-		return errs.HandleError(err, errs.ErrorTypeDownload, "creating HTTP request")
+		return false, errs.HandleError(err, errs.ErrorTypeDownload, "creating HTTP request")
 	}
 
 	// Get zip archive from URL
-	resp, err := http.DefaultClient.Do(req)
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
 	if err != nil {
-		return errs.HandleError(err, errs.ErrorTypeDownload, "downloading from URL")
+		return transientError(err), errs.HandleError(err, errs.ErrorTypeDownload, "downloading from URL")
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return errs.HandleError(fmt.Errorf("HTTP status %s", resp.Status), errs.ErrorTypeDownload, "checking response status")
+		return retry.RetryStatus(resp.StatusCode), errs.HandleError(fmt.Errorf("HTTP status %s", resp.Status), errs.ErrorTypeDownload, "checking response status")
+	}
+	if resp.ContentLength >= 0 {
+		if err := checkSize(resp.ContentLength, limits, resp.Header.Get("Content-Type")); err != nil {
+			return false, err
+		}
 	}
-	defer resp.Body.Close()
 
 	// Create file
 	out, err := os.Create(downloadsPath)
 	if err != nil {
-		return errs.HandleError(err, errs.ErrorTypeDownload, "creating download file")
+		return false, errs.HandleError(err, errs.ErrorTypeDownload, "creating download file")
 	}
 	defer out.Close()
 
-	// Write response body to file
-	_, err = io.Copy(out, resp.Body)
+	// Write response body to file, reading one byte past the maximum to detect an oversized body
+	body := io.Reader(resp.Body)
+	if limits.Max > 0 {
+		body = io.LimitReader(resp.Body, limits.Max+1)
+	}
+	progress := &progressWriter{ctx: ctx, name: filepath.Base(downloadsPath), size: resp.ContentLength}
+	n, err := io.Copy(io.MultiWriter(out, progress), body)
 	if err != nil {
-		return errs.HandleError(err, errs.ErrorTypeDownload, "writing download to file")
+		// A partial download is of no use to a later attempt
+		out.Close()
+		os.Remove(downloadsPath)
+		return transientError(err), errs.HandleError(err, errs.ErrorTypeDownload, "writing download to file")
 	}
-	return nil
+	if err := checkSize(n, limits, resp.Header.Get("Content-Type")); err != nil {
+		out.Close()
+		os.Remove(downloadsPath)
+		return false, err
+	}
+	return false, nil
+}
+
+// transientError reports whether a failed request or body read may succeed
+// when tried again: the connection dropped, was refused or timed out, or a
+// name lookup failed temporarily. Cancellation, certificate and pinning
+// failures, and errors writing the file are not.
+func transientError(err error) bool {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return true
+	case errors.As(err, &dnsErr):
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	case errors.As(err, &opErr):
+		return true
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	}
+	return false
+}
+
+// progressWriter reports the bytes written through it as progress events, at
+// most once per percent of size, or once per megabyte when size is unknown
+type progressWriter struct {
+	ctx         context.Context
+	name        string
+	size        int64
+	n, reported int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.n += int64(len(b))
+	every := int64(1 << 20)
+	if p.size > 0 {
+		every = max(p.size/100, 1)
+	}
+	if p.n-p.reported >= every || p.n == p.size {
+		p.reported = p.n
+		events.Progress(p.ctx, p.name, p.n, max(p.size, 0))
+	}
+	return len(b), nil
+}
+
+// checkSize fails if a download of n bytes is outside limits
+func checkSize(n int64, limits config.SizeLimits, contentType string) error {
+	var problem string
+	switch {
+	case limits.Min > 0 && n < limits.Min:
+		problem = fmt.Sprintf("%s, less than the expected minimum of %s", FormatBytes(uint64(n)), FormatBytes(uint64(limits.Min)))
+		if strings.HasPrefix(contentType, "text/") {
+			problem += "; the server sent " + contentType + ", likely an error or login page"
+		} else {
+			problem += "; it is likely an error page or a truncated file"
+		}
+	case limits.Max > 0 && n > limits.Max:
+		problem = fmt.Sprintf("more than the expected maximum of %s", FormatBytes(uint64(limits.Max)))
+	default:
+		return nil
+	}
+	return errs.HandleError(fmt.Errorf("the download is %s", problem), errs.ErrorTypeDownload, "checking download size")
 }
 
 // unZip extracts the Oracle Instant Client zip file to the specified destination path
 // and returns the directory name of the extracted files
-func UnZip(downloadsPath, installPath string) (string, error) {
+func UnZip(ctx context.Context, downloadsPath, installPath string) (string, error) {
+	ctx = EnsureContext(ctx)
+
 	// Create base install directory
 	if err := os.MkdirAll(installPath, 0777); err != nil {
 		return "", errs.HandleError(err, errs.ErrorTypeInstall, "creating base installation directory")
@@ -78,14 +350,24 @@ func UnZip(downloadsPath, installPath string) (string, error) {
 	// Iterate through the files in the zip archive,
 	// and extract contents into the Installation directory
 	var outPath string
+	var skipped int
 	for k, f := range r.File {
-		re := regexp.MustCompilePOSIX(`^(instantclient_){1}([0-9]{1,2})_([0-9]{1,2})\/$`)
-		if re.Match([]byte(f.Name)) {
+		if err := ctx.Err(); err != nil {
+			return "", errs.HandleError(err, errs.ErrorTypeInstall, "context cancellation")
+		}
+		if clientDirPattern.MatchString(f.Name) {
 			outPath = f.Name
 		}
-		if err := extractFile(f, installPath); err != nil {
+		unchanged, err := extractFile(f, installPath)
+		if err != nil {
 			return "", errs.HandleError(err, errs.ErrorTypeInstall, fmt.Sprintf("extracting file %d", k))
 		}
+		if unchanged {
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		fmt.Printf("skipped %d of %d files already present and unchanged\n", skipped, len(r.File))
 	}
 
 	if outPath == "" {
@@ -100,38 +382,193 @@ func UnZip(downloadsPath, installPath string) (string, error) {
 }
 
 // Helper function to extract a single file from zip archive to specified install path
-// It creates necessary directories and handles file creation
-func extractFile(f *zip.File, installPath string) error {
-	outName := filepath.Join(installPath, f.Name)
+// It creates necessary directories and handles file creation.
+// Files already on disk with the same size and checksum are left untouched,
+// in which case true is returned.
+func extractFile(f *zip.File, installPath string) (bool, error) {
+	outName, err := entryPath(installPath, f.Name)
+	if err != nil {
+		return false, err
+	}
+	if err := checkNoLinks(installPath, outName, f.Mode()&os.ModeSymlink != 0); err != nil {
+		return false, err
+	}
 
 	if f.FileInfo().IsDir() {
-		return os.MkdirAll(outName, 0777)
+		return false, os.MkdirAll(outName, 0777)
+	}
+
+	if f.Mode()&os.ModeSymlink != 0 {
+		return extractSymlink(f, outName)
+	}
+
+	if unchanged(f, outName) {
+		return true, nil
 	}
 
 	if err := os.MkdirAll(filepath.Dir(outName), 0777); err != nil {
-		return fmt.Errorf("creating directories: %w", err)
+		return false, fmt.Errorf("creating directories: %w", err)
 	}
 
 	rc, err := f.Open()
 	if err != nil {
-		return fmt.Errorf("opening zip file: %w", err)
+		return false, fmt.Errorf("opening zip file: %w", err)
 	}
 	defer rc.Close()
 
-	out, err := os.Create(outName)
+	out, err := os.OpenFile(outName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, filePerm(f))
 	if err != nil {
-		return fmt.Errorf("creating output file: %w", err)
+		return false, fmt.Errorf("creating output file: %w", err)
 	}
 	defer out.Close()
 
 	_, err = io.Copy(out, rc)
 	if err != nil {
-		return fmt.Errorf("writing file contents: %w", err)
+		return false, fmt.Errorf("writing file contents: %w", err)
 	}
 
+	return false, nil
+}
+
+// filePerm returns the permissions to create an extracted file with.
+// Unix archives carry executable bits the shared libraries need;
+// Windows archives are extracted with the default permissions.
+func filePerm(f *zip.File) os.FileMode {
+	perm := f.Mode().Perm()
+	if runtime.GOOS == "windows" || perm == 0 {
+		return 0666
+	}
+	return perm | 0200
+}
+
+// entryPath returns where the zip entry name is extracted to under installPath.
+// Names that would land outside it, such as ../../etc/profile, absolute paths
+// and Windows drive or UNC paths, are rejected rather than cleaned up, since
+// no genuine package contains them. Backslashes count as separators on every
+// platform, as Windows tools write them.
+func entryPath(installPath, name string) (string, error) {
+	slashed := strings.ReplaceAll(name, `\`, "/")
+	switch {
+	case name == "" || strings.ContainsRune(name, 0):
+		return "", fmt.Errorf("invalid entry name %q", name)
+	case strings.HasPrefix(slashed, "/") || filepath.VolumeName(filepath.FromSlash(slashed)) != "" || hasDrive(slashed):
+		return "", fmt.Errorf("entry %q has an absolute path", name)
+	}
+	rel := path.Clean(slashed)
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("entry %q points outside the installation directory", name)
+	}
+	return filepath.Join(installPath, filepath.FromSlash(rel)), nil
+}
+
+// hasDrive reports whether a slash-separated name starts with a Windows drive
+// letter, which filepath.VolumeName only recognizes on Windows
+func hasDrive(name string) bool {
+	return len(name) >= 2 && name[1] == ':' && ('a' <= name[0]|0x20 && name[0]|0x20 <= 'z')
+}
+
+// extractSymlink recreates a symbolic link stored in the archive,
+// such as libclntsh.so pointing at the versioned library in Linux packages.
+// Links must stay within their directory, and no later entry is written
+// through one, so none can lead somewhere else.
+func extractSymlink(f *zip.File, outName string) (bool, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return false, fmt.Errorf("opening zip file: %w", err)
+	}
+	defer rc.Close()
+	target, err := io.ReadAll(rc)
+	if err != nil {
+		return false, fmt.Errorf("reading symlink target: %w", err)
+	}
+	if err := checkLinkTarget(outName, string(target)); err != nil {
+		return false, err
+	}
+
+	if existing, err := os.Readlink(outName); err == nil && existing == string(target) {
+		return true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(outName), 0777); err != nil {
+		return false, fmt.Errorf("creating directories: %w", err)
+	}
+	if err := os.Remove(outName); err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("replacing symlink: %w", err)
+	}
+	if err := os.Symlink(string(target), outName); err != nil {
+		return false, fmt.Errorf("creating symlink: %w", err)
+	}
+	return false, nil
+}
+
+// checkLinkTarget returns an error unless the link at outName, pointing at
+// target, stays within its own directory. Links pointing upwards are refused
+// even where the text of the target stays within the installation directory, since a link
+// extracted later can change what the .. in them resolves to; the packages
+// only link libraries to their versioned names next to them.
+func checkLinkTarget(outName, target string) error {
+	slashed := strings.ReplaceAll(target, `\`, "/")
+	if target == "" || strings.ContainsRune(target, 0) || strings.HasPrefix(slashed, "/") || filepath.IsAbs(target) || hasDrive(slashed) {
+		return fmt.Errorf("symlink %s has an invalid or absolute target %q", filepath.Base(outName), target)
+	}
+	for _, elem := range strings.Split(slashed, "/") {
+		if elem == ".." {
+			return fmt.Errorf("symlink %s points outside its directory: %q", filepath.Base(outName), target)
+		}
+	}
+	return nil
+}
+
+// checkNoLinks returns an error if any directory between installPath and
+// outName is a symlink, so that no entry is written through a link extracted
+// earlier, however the links chain. Unless the entry is a link itself,
+// replacing the one at outName, outName may not be a link either.
+func checkNoLinks(installPath, outName string, link bool) error {
+	rel, err := filepath.Rel(installPath, outName)
+	if err != nil || rel == "." {
+		return err
+	}
+	elems := strings.Split(rel, string(filepath.Separator))
+	if link {
+		elems = elems[:len(elems)-1]
+	}
+	current := installPath
+	for _, elem := range elems {
+		current = filepath.Join(current, elem)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("checking %s: %w", current, err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("entry %s runs through the symlink %s", filepath.Base(outName), current)
+		}
+	}
 	return nil
 }
 
+// unchanged reports whether the file at path matches the zip entry's size and CRC-32,
+// so that reinstalling the same version only rewrites files that differ
+func unchanged(f *zip.File, path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || uint64(info.Size()) != f.UncompressedSize64 {
+		return false
+	}
+
+	existing, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer existing.Close()
+
+	h := crc32.NewIEEE()
+	if _, err := io.Copy(h, existing); err != nil {
+		return false
+	}
+	return h.Sum32() == f.CRC32
+}
+
 // migrate (move or copy file from source to destination)
 func MigrateFile(from, to string, copy bool) error {
 	if copy {
@@ -194,4 +631,43 @@ func copyFile(src, dst string) error {
 	} 
 
 	return nil
-}
\ No newline at end of file
+}
+// CopyDir copies the files and directories under src into dst, replacing files
+// that already exist there
+func CopyDir(ctx context.Context, src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case info.Mode().IsRegular():
+			return copyRegular(path, target, info.Mode().Perm()|0200)
+		}
+		return nil
+	})
+}
+
+// FileSHA256 returns the hex-encoded SHA-256 digest of the file at path
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeInstall, "opening file for checksum")
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errs.HandleError(err, errs.ErrorTypeInstall, "computing checksum")
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/testutil"
+)
+
+// writeFixture writes the fixture into a temporary directory and returns its path
+func writeFixture(b *testing.B, z testutil.ZipFixture) string {
+	b.Helper()
+	path := filepath.Join(b.TempDir(), "fixture.zip")
+	if err := z.Write(path); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+func BenchmarkUnZip(b *testing.B) {
+	for _, tc := range []struct {
+		name         string
+		compressible bool
+	}{
+		{"random", false},
+		{"compressible", true},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			z := testutil.DefaultZipFixture()
+			z.Compressible = tc.compressible
+			src := writeFixture(b, z)
+			b.SetBytes(z.Size())
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := UnZip(context.Background(), src, filepath.Join(b.TempDir(), "install")); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnZipUnchanged(b *testing.B) {
+	z := testutil.DefaultZipFixture()
+	src := writeFixture(b, z)
+	dst := filepath.Join(b.TempDir(), "install")
+	if _, err := UnZip(context.Background(), src, dst); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(z.Size())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := UnZip(context.Background(), src, dst); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExtractFile(b *testing.B) {
+	z := testutil.ZipFixture{Dir: "instantclient_23_7", Files: 1, FileSize: 16 << 20, Seed: 1}
+	src := writeFixture(b, z)
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer r.Close()
+	f := r.File[1]
+
+	b.SetBytes(z.Size())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := extractFile(f, b.TempDir()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDownloadZip(b *testing.B) {
+	z := testutil.DefaultZipFixture()
+	var buf bytes.Buffer
+	if err := z.Encode(&buf); err != nil {
+		b.Fatal(err)
+	}
+	payload := buf.Bytes()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write(payload)
+	}))
+	defer srv.Close()
+
+	client := NewHTTPClient(config.DefaultHTTPConfig())
+	dst := filepath.Join(b.TempDir(), "download.zip")
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := DownloadZip(context.Background(), client, srv.URL+"/pkg.zip", dst); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	os.Remove(dst)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mghoff/oraicwinconfig/internal/config"
+	"github.com/mghoff/oraicwinconfig/internal/env"
+	"github.com/mghoff/oraicwinconfig/internal/input"
+	"github.com/mghoff/oraicwinconfig/internal/lastrun"
+	"github.com/mghoff/oraicwinconfig/internal/testsupport"
+)
+
+// runMainEnv makes the test binary run the installer instead of the tests
+const runMainEnv = "ORAICWINCONFIG_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// run runs the installer with args and stdin closed, in the sandbox the test
+// set up, and returns its output and exit status
+func run(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return string(out), cmd.ProcessState.ExitCode()
+}
+
+func TestClosedStdinExitStatus(t *testing.T) {
+	home := testsupport.Sandbox(t)
+	out, code := run(t, "tns", "tcps", "--scope", "user",
+		"--tns-file", filepath.Join(home, "tnsnames.ora"),
+		"--sqlnet-file", filepath.Join(home, "sqlnet.ora"))
+	if code != input.ExitNoInput {
+		t.Fatalf("a prompt on closed stdin exited with %d, want %d:\n%s", code, input.ExitNoInput, out)
+	}
+	if !strings.Contains(out, "pass --yes") {
+		t.Errorf("the failure does not suggest --yes:\n%s", out)
+	}
+	// Nothing was asked again, so the first prompt is the only one
+	if n := strings.Count(out, "Net service name to define"); n != 1 {
+		t.Errorf("the prompt was shown %d times, want once:\n%s", n, out)
+	}
+	s, err := lastrun.Read(env.ScopeUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ExitCode != input.ExitNoInput || s.Result != lastrun.ResultFailure {
+		t.Errorf("status file records %s with exit code %d, want a failure with %d", s.Result, s.ExitCode, input.ExitNoInput)
+	}
+}
+
+// installArgs returns the flags of a user-scope install into home from srv
+// that asks nothing
+func installArgs(t *testing.T, home string, srv *testsupport.Server) []string {
+	t.Helper()
+	srv.Configure(config.New())
+	for _, dir := range []string{"oracle", "downloads"} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return []string{"--scope", "user", "--yes", "--base-url", srv.URL + "/",
+		"--install-path", filepath.Join(home, "oracle"), "--downloads-dir", filepath.Join(home, "downloads"),
+		"--pkg-size", "off", "--sdk-size", "off"}
+}
+
+func TestDryRun(t *testing.T) {
+	home := testsupport.Sandbox(t)
+	srv := testsupport.NewServer(t, testsupport.Valid)
+	conf := config.New()
+	out, code := run(t, append(installArgs(t, home, srv), "--dry-run")...)
+	if code != 0 {
+		t.Fatalf("dry run exited with %d:\n%s", code, out)
+	}
+
+	clientDir := filepath.Join(home, "oracle", testsupport.ClientDir)
+	for _, want := range []string{
+		"download " + srv.URL + "/" + conf.PkgFile + " to " + filepath.Join(home, "downloads", conf.PkgFile),
+		"download " + srv.URL + "/" + conf.SdkFile + " to " + filepath.Join(home, "downloads", conf.SdkFile),
+		"extract " + filepath.Join(home, "downloads", conf.PkgFile) + " into " + filepath.Join(home, "oracle") + " (creates " + testsupport.ClientDir + ")",
+		"set " + conf.LibVar() + "=" + clientDir,
+		"add " + clientDir + " to PATH",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dry run output lacks %q:\n%s", want, out)
+		}
+	}
+
+	// Nothing was downloaded, extracted or configured
+	for _, dir := range []string{"oracle", "downloads"} {
+		if tree := testsupport.Tree(t, home, filepath.Join(home, dir)); tree != dir+"/\n" {
+			t.Errorf("dry run changed %s:\n%s", dir, tree)
+		}
+	}
+	if n := srv.Requests(conf.SdkFile); n != 0 {
+		t.Errorf("dry run requested the SDK %d times", n)
+	}
+	if value, err := env.New(env.ScopeUser).GetEnvVar(conf.LibVar()); err == nil && value != "" {
+		t.Errorf("dry run set %s=%s", conf.LibVar(), value)
+	}
+	if _, err := lastrun.Read(env.ScopeUser); err == nil {
+		t.Error("dry run wrote the status file")
+	}
+}
+
+func TestPlanFileApply(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("apply writes the user environment in the registry")
+	}
+	home := testsupport.Sandbox(t)
+	srv := testsupport.NewServer(t, testsupport.Valid)
+	conf := config.New()
+	planFile := filepath.Join(home, "client.plan.json")
+	out, code := run(t, append(installArgs(t, home, srv), "--plan-file", planFile)...)
+	if code != 0 {
+		t.Fatalf("dry run with --plan-file exited with %d:\n%s", code, out)
+	}
+	if _, err := os.Stat(filepath.Join(home, "oracle", testsupport.ClientDir)); !os.IsNotExist(err) {
+		t.Fatalf("saving the plan installed the client: %v", err)
+	}
+
+	// apply performs the saved plan, with the options it was made with
+	out, code = run(t, "apply", "--yes", "--pkg-size", "off", "--sdk-size", "off", planFile)
+	if code != 0 {
+		t.Fatalf("apply exited with %d:\n%s", code, out)
+	}
+	clientDir := filepath.Join(home, "oracle", testsupport.ClientDir)
+	if fi, err := os.Stat(filepath.Join(clientDir, "sdk")); err != nil || !fi.IsDir() {
+		t.Errorf("apply did not extract the client: %v", err)
+	}
+	if value, err := env.New(env.ScopeUser).GetEnvVar(conf.LibVar()); err != nil || value != clientDir {
+		t.Errorf("%s = %q, %v after apply; want %q", conf.LibVar(), value, err, clientDir)
+	}
+
+	// The plan was made before the client was installed, so it no longer holds
+	out, code = run(t, "apply", "--yes", "--pkg-size", "off", "--sdk-size", "off", planFile)
+	if code == 0 || !strings.Contains(out, "drifted") {
+		t.Errorf("applying a plan the machine has drifted from exited with %d:\n%s", code, out)
+	}
+}